@@ -0,0 +1,85 @@
+// Package stationtest sets up the backend the station suites run against.
+//
+// The suites run against SQLite by default, and against the in-memory repository
+// when BackendEnv is memory, such as:
+//
+//	STATION_BACKEND=memory go test ./_test/...
+package stationtest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/handler/router"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+// BackendEnv is the environment variable choosing the backend.
+const BackendEnv = "STATION_BACKEND"
+
+// Backends chosen by BackendEnv.
+const (
+	BackendSQLite = "sqlite"
+	BackendMemory = "memory"
+)
+
+// A Backend stores the TODOs of a suite.
+type Backend struct {
+	repo service.TODORepository
+	// db and path are of the SQLite database, which are zero for the in-memory repository.
+	db   *sql.DB
+	path string
+}
+
+// Open returns the backend chosen by BackendEnv, of which the SQLite database is created at dbPath.
+func Open(dbPath string) (*Backend, error) {
+	switch backend := os.Getenv(BackendEnv); backend {
+	case "", BackendSQLite:
+		todoDB, err := db.NewDB(dbPath)
+		if err != nil {
+			return nil, err
+		}
+		return &Backend{repo: service.NewSQLiteTODORepository(todoDB), db: todoDB, path: dbPath}, nil
+	case BackendMemory:
+		return &Backend{repo: service.NewMemoryTODORepository()}, nil
+	default:
+		return nil, fmt.Errorf("stationtest: unknown %s, given = %q", BackendEnv, backend)
+	}
+}
+
+// Router returns the router serving the TODOs of b.
+func (b *Backend) Router() http.Handler {
+	if b.db != nil {
+		return router.NewRouter(b.db)
+	}
+	return router.NewRouterWithRepository(b.repo)
+}
+
+// Service returns TODOService of the TODOs of b.
+func (b *Backend) Service() *service.TODOService {
+	if b.db != nil {
+		return service.NewTODOService(b.db)
+	}
+	return service.NewTODOServiceWithRepository(b.repo)
+}
+
+// Insert stores a TODO of subject and description as a fixture of the suite.
+func (b *Backend) Insert(subject, description string) error {
+	_, err := b.repo.Create(context.Background(), &service.TODOInput{Subject: subject, Description: description})
+	return err
+}
+
+// Close closes the SQLite database and removes its file.
+func (b *Backend) Close() error {
+	if b.db == nil {
+		return nil
+	}
+	if err := b.db.Close(); err != nil {
+		return err
+	}
+	return os.Remove(b.path)
+}
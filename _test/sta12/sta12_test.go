@@ -2,7 +2,6 @@ package sta12_test
 
 import (
 	"context"
-	"os"
 	"reflect"
 	"testing"
 	"time"
@@ -11,45 +10,28 @@ import (
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/mattn/go-sqlite3"
 
-	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/_test/internal/stationtest"
 	"github.com/TechBowl-japan/go-stations/model"
-	"github.com/TechBowl-japan/go-stations/service"
 )
 
 func TestStation12(t *testing.T) {
 	t.Parallel()
 
 	dbpath := "./temp_test.db"
-	d, err := db.NewDB(dbpath)
+	backend, err := stationtest.Open(dbpath)
 	if err != nil {
 		t.Errorf("データベースの作成に失敗しました: %v", err)
 		return
 	}
 
 	t.Cleanup(func() {
-		if err := d.Close(); err != nil {
+		if err := backend.Close(); err != nil {
 			t.Errorf("データベースのクローズに失敗しました: %v", err)
 			return
 		}
-		if err := os.Remove(dbpath); err != nil {
-			t.Errorf("テスト用のDBファイルの削除に失敗しました: %v", err)
-			return
-		}
-	})
-
-	stmt, err := d.Prepare(`INSERT INTO todos(subject) VALUES(?)`)
-	if err != nil {
-		t.Errorf("ステートメントの作成に失敗しました: %v", err)
-		return
-	}
-	t.Cleanup(func() {
-		if err := stmt.Close(); err != nil {
-			t.Errorf("ステートメントのクローズに失敗しました: %v", err)
-			return
-		}
 	})
 
-	_, err = stmt.Exec("todo subject")
+	err = backend.Insert("todo subject", "")
 	if err != nil {
 		t.Errorf("todoの追加に失敗しました: %v", err)
 		return
@@ -83,7 +65,7 @@ func TestStation12(t *testing.T) {
 		name := name
 		tc := tc
 		t.Run(name, func(t *testing.T) {
-			svc := service.NewTODOService(d)
+			svc := backend.Service()
 			got, err := svc.UpdateTODO(context.Background(), tc.ID, tc.Subject, tc.Description)
 			switch tc.WantError {
 			case nil:
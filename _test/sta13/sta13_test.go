@@ -10,8 +10,7 @@ import (
 	"testing"
 	"time"
 
-	"github.com/TechBowl-japan/go-stations/db"
-	"github.com/TechBowl-japan/go-stations/handler/router"
+	"github.com/TechBowl-japan/go-stations/_test/internal/stationtest"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -24,43 +23,26 @@ func TestStation13(t *testing.T) {
 		return
 	}
 
-	todoDB, err := db.NewDB(dbPath)
+	backend, err := stationtest.Open(dbPath)
 	if err != nil {
 		t.Errorf("データベースの作成に失敗しました: %v", err)
 		return
 	}
 
 	t.Cleanup(func() {
-		if err := todoDB.Close(); err != nil {
+		if err := backend.Close(); err != nil {
 			t.Errorf("DBのクローズに失敗しました: %v", err)
 			return
 		}
-		if err := os.Remove(dbPath); err != nil {
-			t.Errorf("テスト用のDBファイルの削除に失敗しました: %v", err)
-			return
-		}
-	})
-
-	stmt, err := todoDB.Prepare(`INSERT INTO todos(subject) VALUES(?)`)
-	if err != nil {
-		t.Errorf("ステートメントの作成に失敗しました: %v", err)
-		return
-	}
-
-	t.Cleanup(func() {
-		if err := stmt.Close(); err != nil {
-			t.Errorf("ステートメントのクローズに失敗しました: %v", err)
-			return
-		}
 	})
 
-	_, err = stmt.Exec("todo subject")
+	err = backend.Insert("todo subject", "")
 	if err != nil {
 		t.Errorf("todoの追加に失敗しました: %v", err)
 		return
 	}
 
-	r := router.NewRouter(todoDB)
+	r := backend.Router()
 	srv := httptest.NewServer(r)
 	defer srv.Close()
 
@@ -2,15 +2,13 @@ package sta15_test
 
 import (
 	"context"
-	"os"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 
-	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/_test/internal/stationtest"
 	"github.com/TechBowl-japan/go-stations/model"
-	"github.com/TechBowl-japan/go-stations/service"
 )
 
 func TestStation15(t *testing.T) {
@@ -32,38 +30,21 @@ func TestStation15(t *testing.T) {
 	}
 
 	dbpath := "./temp_test.db"
-	d, err := db.NewDB(dbpath)
+	backend, err := stationtest.Open(dbpath)
 	if err != nil {
 		t.Errorf("データベースの作成に失敗しました: %v", err)
 		return
 	}
 
 	t.Cleanup(func() {
-		if err := d.Close(); err != nil {
+		if err := backend.Close(); err != nil {
 			t.Errorf("データベースのクローズに失敗しました: %v", err)
 			return
 		}
-		if err := os.Remove(dbpath); err != nil {
-			t.Errorf("テスト用のデータベースの削除に失敗しました: %v", err)
-			return
-		}
-	})
-
-	stmt, err := d.Prepare(`INSERT INTO todos(subject, description) VALUES(?, ?)`)
-	if err != nil {
-		t.Errorf("データベースのステートメントの作成に失敗しました: %v", err)
-		return
-	}
-
-	t.Cleanup(func() {
-		if err := stmt.Close(); err != nil {
-			t.Errorf("データベースのステートメントのクローズに失敗しました: %v", err)
-			return
-		}
 	})
 
 	for _, todo := range []*model.TODO{todos[2], todos[1], todos[0]} {
-		if _, err := stmt.Exec(todo.Subject, todo.Description); err != nil {
+		if err := backend.Insert(todo.Subject, todo.Description); err != nil {
 			t.Errorf("データベースのステートメントの実行に失敗しました: %v", err)
 			return
 		}
@@ -114,7 +95,7 @@ func TestStation15(t *testing.T) {
 	for name, tc := range testcases {
 		tc := tc
 		t.Run(name, func(t *testing.T) {
-			svc := service.NewTODOService(d)
+			svc := backend.Service()
 			ret, err := svc.ReadTODO(context.Background(), tc.PrevID, tc.Size)
 			if err != nil {
 				t.Errorf("ReadTODOに失敗しました: %v", err)
@@ -8,8 +8,7 @@ import (
 	"os"
 	"testing"
 
-	"github.com/TechBowl-japan/go-stations/db"
-	"github.com/TechBowl-japan/go-stations/handler/router"
+	"github.com/TechBowl-japan/go-stations/_test/internal/stationtest"
 )
 
 func TestStation16(t *testing.T) {
@@ -19,44 +18,27 @@ func TestStation16(t *testing.T) {
 		return
 	}
 
-	todoDB, err := db.NewDB(dbPath)
+	backend, err := stationtest.Open(dbPath)
 	if err != nil {
 		t.Errorf("データベースの作成に失敗しました: %v", err)
 		return
 	}
 
 	t.Cleanup(func() {
-		if err := todoDB.Close(); err != nil {
+		if err := backend.Close(); err != nil {
 			t.Errorf("データベースのクローズに失敗しました: %v", err)
 			return
 		}
-		if err := os.Remove(dbPath); err != nil {
-			t.Errorf("テスト用のDBファイルの削除に失敗しました: %v", err)
-			return
-		}
-	})
-
-	stmt, err := todoDB.Prepare(`INSERT INTO todos(subject) VALUES(?)`)
-	if err != nil {
-		t.Errorf("ステートメントの作成に失敗しました: %v", err)
-		return
-	}
-
-	t.Cleanup(func() {
-		if err := stmt.Close(); err != nil {
-			t.Errorf("ステートメントのクローズに失敗しました: %v", err)
-			return
-		}
 	})
 
 	for i := 0; i < 3; i++ {
-		if _, err := stmt.Exec(fmt.Sprintf("todo subject %d", i+1)); err != nil {
+		if err := backend.Insert(fmt.Sprintf("todo subject %d", i+1), ""); err != nil {
 			t.Errorf("todoの追加に失敗しました: %v", err)
 			return
 		}
 	}
 
-	r := router.NewRouter(todoDB)
+	r := backend.Router()
 	srv := httptest.NewServer(r)
 	defer srv.Close()
 
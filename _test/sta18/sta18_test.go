@@ -2,13 +2,11 @@ package sta18_test
 
 import (
 	"context"
-	"os"
 	"reflect"
 	"testing"
 
-	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/_test/internal/stationtest"
 	"github.com/TechBowl-japan/go-stations/model"
-	"github.com/TechBowl-japan/go-stations/service"
 )
 
 func TestStation18(t *testing.T) {
@@ -30,38 +28,21 @@ func TestStation18(t *testing.T) {
 	}
 
 	dbPath := "./temp_test.db"
-	todoDB, err := db.NewDB(dbPath)
+	backend, err := stationtest.Open(dbPath)
 	if err != nil {
 		t.Errorf("データベースの作成に失敗しました: %v", err)
 		return
 	}
 
 	t.Cleanup(func() {
-		if err := todoDB.Close(); err != nil {
+		if err := backend.Close(); err != nil {
 			t.Errorf("データベースのクローズに失敗しました: %v", err)
 			return
 		}
-		if err := os.Remove(dbPath); err != nil {
-			t.Errorf("テスト用のDBファイルの削除に失敗しました: %v", err)
-			return
-		}
-	})
-
-	stmt, err := todoDB.Prepare(`INSERT INTO todos(subject, description) VALUES(?, ?)`)
-	if err != nil {
-		t.Errorf("データベースのステートメントの作成に失敗しました: %v", err)
-		return
-	}
-
-	t.Cleanup(func() {
-		if err := stmt.Close(); err != nil {
-			t.Errorf("データベースのステートメントのクローズに失敗しました: %v", err)
-			return
-		}
 	})
 
 	for _, todo := range []*model.TODO{todos[2], todos[1], todos[0]} {
-		if _, err = stmt.Exec(todo.Subject, todo.Description); err != nil {
+		if err = backend.Insert(todo.Subject, todo.Description); err != nil {
 			t.Errorf("データベースのステートメントの実行に失敗しました: %v", err)
 			return
 		}
@@ -91,7 +72,7 @@ func TestStation18(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
-			err := service.NewTODOService(todoDB).DeleteTODO(context.Background(), tc.IDs)
+			err := backend.Service().DeleteTODO(context.Background(), tc.IDs)
 
 			switch tc.WantError {
 			case nil:
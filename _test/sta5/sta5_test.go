@@ -7,8 +7,7 @@ import (
 	"os"
 	"testing"
 
-	"github.com/TechBowl-japan/go-stations/db"
-	"github.com/TechBowl-japan/go-stations/handler/router"
+	"github.com/TechBowl-japan/go-stations/_test/internal/stationtest"
 )
 
 func TestStation5(t *testing.T) {
@@ -18,24 +17,20 @@ func TestStation5(t *testing.T) {
 		return
 	}
 
-	todoDB, err := db.NewDB(dbPath)
+	backend, err := stationtest.Open(dbPath)
 	if err != nil {
 		t.Error("DBの作成に失敗しました。", err)
 		return
 	}
 
 	t.Cleanup(func() {
-		if err := todoDB.Close(); err != nil {
+		if err := backend.Close(); err != nil {
 			t.Errorf("DBのクローズに失敗しました: %v", err)
 			return
 		}
-		if err := os.Remove(dbPath); err != nil {
-			t.Errorf("テスト用のDBファイルの削除に失敗しました: %v", err)
-			return
-		}
 	})
 
-	r := router.NewRouter(todoDB)
+	r := backend.Router()
 	srv := httptest.NewServer(r)
 	defer srv.Close()
 	req, err := http.NewRequest(http.MethodGet, srv.URL+"/healthz", nil)
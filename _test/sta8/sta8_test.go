@@ -3,7 +3,6 @@ package sta8_test
 import (
 	"context"
 	"errors"
-	"os"
 	"testing"
 	"time"
 
@@ -11,9 +10,8 @@ import (
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/mattn/go-sqlite3"
 
-	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/_test/internal/stationtest"
 	"github.com/TechBowl-japan/go-stations/model"
-	"github.com/TechBowl-japan/go-stations/service"
 )
 
 func TestStation8(t *testing.T) {
@@ -34,21 +32,17 @@ func TestStation8(t *testing.T) {
 	}
 
 	dbpath := "./temp_test.db"
-	d, err := db.NewDB(dbpath)
+	backend, err := stationtest.Open(dbpath)
 	if err != nil {
 		t.Error("DBの作成に失敗しました。", err)
 		return
 	}
 
 	t.Cleanup(func() {
-		if err := d.Close(); err != nil {
+		if err := backend.Close(); err != nil {
 			t.Error("DBのクローズに失敗しました。", err)
 			return
 		}
-		if err := os.Remove(dbpath); err != nil {
-			t.Errorf("テスト用のDBファイルの削除に失敗しました: %v", err)
-			return
-		}
 	})
 
 	var sqlite3Err sqlite3.Error
@@ -59,7 +53,7 @@ func TestStation8(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
-			svc := service.NewTODOService(d)
+			svc := backend.Service()
 			got, err := svc.CreateTODO(context.Background(), tc.Subject, tc.Description)
 			if err != nil {
 				if !errors.As(err, &sqlite3Err) {
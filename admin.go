@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+const (
+	userUsage  = "usage: user create <name> | user passwd <name>"
+	tokenUsage = "usage: token issue <user> [name] | token list <user> | token revoke <id>"
+	adoptUsage = "usage: adopt <user>"
+)
+
+// runUser runs the user sub command against the database opened by open.
+// The passwd sub command reads the password from the first line of r, so that it is not left in the shell history.
+func runUser(open func() (*sql.DB, error), args []string, r io.Reader, w io.Writer) error {
+	if len(args) != 2 || (args[0] != "create" && args[0] != "passwd") {
+		return errors.New(userUsage)
+	}
+
+	todoDB, err := open()
+	if err != nil {
+		return err
+	}
+	defer todoDB.Close()
+
+	ctx := context.Background()
+	if args[0] == "passwd" {
+		password, err := bufio.NewReader(r).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if err := service.NewSessionService(todoDB).SetPassword(ctx, args[1], strings.TrimRight(password, "\r\n")); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "set password of %s\n", args[1])
+		return nil
+	}
+
+	user, err := service.NewUserService(todoDB).CreateUser(ctx, args[1])
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "created user %s (id %d)\n", user.Name, user.ID)
+	return nil
+}
+
+// runToken runs the token sub command against the database opened by open.
+// An issued token is printed only once, since only its hash is stored.
+func runToken(open func() (*sql.DB, error), args []string, w io.Writer) error {
+	if len(args) < 2 {
+		return errors.New(tokenUsage)
+	}
+
+	todoDB, err := open()
+	if err != nil {
+		return err
+	}
+	defer todoDB.Close()
+
+	ctx := context.Background()
+	users := service.NewUserService(todoDB)
+	switch {
+	case args[0] == "issue" && len(args) <= 3:
+		var name string
+		if len(args) == 3 {
+			name = args[2]
+		}
+		token, issued, err := users.IssueToken(ctx, args[1], name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "issued token %d for %s, which is not shown again:\n%s\n", issued.ID, args[1], token)
+	case args[0] == "list" && len(args) == 2:
+		tokens, err := users.ListTokens(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tCREATED AT\tLAST USED AT\tREVOKED AT")
+		for _, t := range tokens {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", t.ID, t.Name, formatTime(&t.CreatedAt), formatTime(t.LastUsedAt), formatTime(t.RevokedAt))
+		}
+		return tw.Flush()
+	case args[0] == "revoke" && len(args) == 2:
+		id, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return errors.New(tokenUsage)
+		}
+		revoked, err := users.RevokeToken(ctx, id)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "revoked token %d at %s\n", revoked.ID, formatTime(revoked.RevokedAt))
+	default:
+		return errors.New(tokenUsage)
+	}
+	return nil
+}
+
+// runAdopt runs the adopt sub command against the database opened by open, which makes the user own
+// the TODOs created anonymously, so that they stay reachable once authentication is required.
+func runAdopt(open func() (*sql.DB, error), args []string, w io.Writer) error {
+	if len(args) != 1 {
+		return errors.New(adoptUsage)
+	}
+
+	todoDB, err := open()
+	if err != nil {
+		return err
+	}
+	defer todoDB.Close()
+
+	n, err := service.NewUserService(todoDB).AdoptTODOs(context.Background(), args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "adopted %d TODOs by %s\n", n, args[0])
+	return nil
+}
+
+// formatTime formats t in the local time zone, or - when t is nil.
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.In(time.Local).Format(time.RFC3339)
+}
@@ -0,0 +1,349 @@
+// Package config loads the configuration of the server from defaults, a config file,
+// environment variables and command-line flags, each taking precedence over the former.
+//
+// Every setting is named by its key in the config file, such as server.read_timeout,
+// and may be given by the environment variable and the flag in its env and flag tags.
+// Durations are written as time.ParseDuration accepts, such as 30s.
+package config
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// A Config is the configuration of the server.
+	Config struct {
+		// TimeZone is the time zone the days of due dates are delimited in.
+		TimeZone  string    `toml:"time_zone" yaml:"time_zone" env:"TIME_ZONE" flag:"time-zone" usage:"time zone the days of due dates are delimited in"`
+		Server    Server    `toml:"server" yaml:"server"`
+		DB        DB        `toml:"db" yaml:"db"`
+		Trash     Trash     `toml:"trash" yaml:"trash"`
+		Auth      Auth      `toml:"auth" yaml:"auth"`
+		Session   Session   `toml:"session" yaml:"session"`
+		OIDC      OIDC      `toml:"oidc" yaml:"oidc"`
+		RateLimit RateLimit `toml:"rate_limit" yaml:"rate_limit"`
+		Health    Health    `toml:"health" yaml:"health"`
+		Metrics   Metrics   `toml:"metrics" yaml:"metrics"`
+		Log       Log       `toml:"log" yaml:"log"`
+		Features  Features  `toml:"features" yaml:"features"`
+	}
+
+	// A Server configures the HTTP server.
+	Server struct {
+		Addr              string   `toml:"addr" yaml:"addr" env:"PORT" flag:"addr" usage:"address to listen on"`
+		ReadHeaderTimeout Duration `toml:"read_header_timeout" yaml:"read_header_timeout" env:"READ_HEADER_TIMEOUT" flag:"read-header-timeout" usage:"timeout of reading request headers"`
+		ReadTimeout       Duration `toml:"read_timeout" yaml:"read_timeout" env:"READ_TIMEOUT" flag:"read-timeout" usage:"timeout of reading requests"`
+		WriteTimeout      Duration `toml:"write_timeout" yaml:"write_timeout" env:"WRITE_TIMEOUT" flag:"write-timeout" usage:"timeout of writing responses"`
+		IdleTimeout       Duration `toml:"idle_timeout" yaml:"idle_timeout" env:"IDLE_TIMEOUT" flag:"idle-timeout" usage:"timeout of idle keep-alive connections"`
+		MaxHeaderBytes    int      `toml:"max_header_bytes" yaml:"max_header_bytes" env:"MAX_HEADER_BYTES" flag:"max-header-bytes" usage:"maximum size of request headers"`
+		// ShutdownTimeout is how long in-flight requests are drained for on shutdown.
+		ShutdownTimeout Duration `toml:"shutdown_timeout" yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" usage:"timeout of draining in-flight requests on shutdown"`
+		// MaxInFlight is the number of the requests for the API served at once, beyond which they are answered with 503
+		// before they pile up waiting for the database.
+		MaxInFlight int `toml:"max_in_flight" yaml:"max_in_flight" env:"MAX_IN_FLIGHT" flag:"max-in-flight" usage:"maximum number of API requests served at once, 0 for unlimited"`
+	}
+
+	// A DB configures the SQLite database.
+	DB struct {
+		Path string `toml:"path" yaml:"path" env:"DB_PATH" flag:"db-path" usage:"path of the SQLite database"`
+		// BusyTimeout is how long a connection waits for the lock held by another one.
+		BusyTimeout  Duration `toml:"busy_timeout" yaml:"busy_timeout" env:"DB_BUSY_TIMEOUT" flag:"db-busy-timeout" usage:"timeout of waiting for database locks"`
+		MaxOpenConns int      `toml:"max_open_conns" yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS" flag:"db-max-open-conns" usage:"maximum number of open database connections, 0 for unlimited"`
+	}
+
+	// A Trash configures purging the TODOs in the trash.
+	Trash struct {
+		Retention     Duration `toml:"retention" yaml:"retention" env:"TRASH_RETENTION" flag:"trash-retention" usage:"how long TODOs are kept in the trash"`
+		PurgeInterval Duration `toml:"purge_interval" yaml:"purge_interval" env:"PURGE_INTERVAL" flag:"purge-interval" usage:"interval of purging the trash"`
+	}
+
+	// An Auth configures authenticating the requests by API tokens.
+	Auth struct {
+		// Required rejects the requests for TODOs and tags without an API token,
+		// which are served anonymously with the TODOs of no owner otherwise.
+		// The TODOs created anonymously so far are left unreachable until a user adopts them by the adopt sub command.
+		Required bool `toml:"required" yaml:"required" env:"AUTH_REQUIRED" flag:"auth-required" usage:"reject requests without an API token"`
+	}
+
+	// A Session configures the cookie sessions browser clients log in to with passwords.
+	Session struct {
+		TTL            Duration `toml:"ttl" yaml:"ttl" env:"SESSION_TTL" flag:"session-ttl" usage:"how long sessions last after login"`
+		RotateInterval Duration `toml:"rotate_interval" yaml:"rotate_interval" env:"SESSION_ROTATE_INTERVAL" flag:"session-rotate-interval" usage:"interval of replacing the tokens of sessions"`
+		// SecureCookies limits the cookies to HTTPS, which may be disabled in local development over HTTP.
+		SecureCookies bool `toml:"secure_cookies" yaml:"secure_cookies" env:"SESSION_SECURE_COOKIES" flag:"session-secure-cookies" usage:"send the cookies of sessions only over HTTPS"`
+	}
+
+	// An OIDC configures logging in with an OpenID Connect provider into cookie sessions,
+	// which is disabled unless Issuer is given.
+	OIDC struct {
+		Issuer       string `toml:"issuer" yaml:"issuer" env:"OIDC_ISSUER" flag:"oidc-issuer" usage:"issuer URL of the OpenID Connect provider, disabled when empty"`
+		ClientID     string `toml:"client_id" yaml:"client_id" env:"OIDC_CLIENT_ID" flag:"oidc-client-id" usage:"client ID registered to the OpenID Connect provider"`
+		ClientSecret Secret `toml:"client_secret" yaml:"client_secret" env:"OIDC_CLIENT_SECRET" flag:"oidc-client-secret" usage:"client secret registered to the OpenID Connect provider"`
+		// RedirectURL is the public URL of /login/oidc/callback registered to the provider.
+		RedirectURL string `toml:"redirect_url" yaml:"redirect_url" env:"OIDC_REDIRECT_URL" flag:"oidc-redirect-url" usage:"URL of /login/oidc/callback registered to the OpenID Connect provider"`
+		// PostLoginURL is where browsers logged in are redirected to.
+		PostLoginURL string `toml:"post_login_url" yaml:"post_login_url" env:"OIDC_POST_LOGIN_URL" flag:"oidc-post-login-url" usage:"URL browsers logged in with the OpenID Connect provider are redirected to"`
+	}
+
+	// A RateLimit configures limiting the rates of the requests for the API of each IP address,
+	// and of each user authenticated, by token buckets of reads and writes.
+	// A bucket holds the burst of requests at most and is refilled by the rate of requests per second.
+	RateLimit struct {
+		Enabled    bool `toml:"enabled" yaml:"enabled" env:"RATE_LIMIT_ENABLED" flag:"rate-limit" usage:"limit the rates of requests of each client"`
+		ReadRate   int  `toml:"read_rate" yaml:"read_rate" env:"RATE_LIMIT_READ_RATE" flag:"rate-limit-read-rate" usage:"reads allowed per second on average for each client"`
+		ReadBurst  int  `toml:"read_burst" yaml:"read_burst" env:"RATE_LIMIT_READ_BURST" flag:"rate-limit-read-burst" usage:"reads allowed at once for each client"`
+		WriteRate  int  `toml:"write_rate" yaml:"write_rate" env:"RATE_LIMIT_WRITE_RATE" flag:"rate-limit-write-rate" usage:"writes allowed per second on average for each client"`
+		WriteBurst int  `toml:"write_burst" yaml:"write_burst" env:"RATE_LIMIT_WRITE_BURST" flag:"rate-limit-write-burst" usage:"writes allowed at once for each client"`
+	}
+
+	// A Health configures the readiness check.
+	Health struct {
+		CheckTimeout Duration `toml:"check_timeout" yaml:"check_timeout" env:"HEALTH_CHECK_TIMEOUT" flag:"health-check-timeout" usage:"timeout of each readiness check"`
+	}
+
+	// A Metrics configures exporting the metrics in the Prometheus text format at /metrics.
+	Metrics struct {
+		Enabled bool `toml:"enabled" yaml:"enabled" env:"METRICS_ENABLED" flag:"metrics" usage:"export metrics at /metrics"`
+		// Addr is the address of the admin listener serving the metrics apart from the API,
+		// which are served by the server at server.addr when it is empty.
+		Addr string `toml:"addr" yaml:"addr" env:"METRICS_ADDR" flag:"metrics-addr" usage:"address of the admin listener serving metrics, server.addr when empty"`
+	}
+
+	// A Log configures logging.
+	Log struct {
+		Level LogLevel `toml:"level" yaml:"level" env:"LOG_LEVEL" flag:"log-level" usage:"minimum level of logs: debug, info, warn or error"`
+	}
+
+	// Features toggles the optional features.
+	Features struct {
+		// AutoMigrate applies pending schema migrations on startup.
+		AutoMigrate bool `toml:"auto_migrate" yaml:"auto_migrate" env:"AUTO_MIGRATE" flag:"auto-migrate" usage:"apply pending schema migrations on startup"`
+		// TrashPurge purges the TODOs kept in the trash longer than the retention in background.
+		TrashPurge bool `toml:"trash_purge" yaml:"trash_purge" env:"TRASH_PURGE" flag:"trash-purge" usage:"purge the trash in background"`
+	}
+)
+
+// Default returns the configuration of the defaults.
+func Default() *Config {
+	return &Config{
+		TimeZone: "Asia/Tokyo",
+		Server: Server{
+			Addr:              ":8080",
+			ReadHeaderTimeout: Duration(5 * time.Second),
+			ReadTimeout:       Duration(10 * time.Second),
+			WriteTimeout:      Duration(30 * time.Second),
+			IdleTimeout:       Duration(2 * time.Minute),
+			MaxHeaderBytes:    1 << 20,
+			ShutdownTimeout:   Duration(30 * time.Second),
+			MaxInFlight:       64,
+		},
+		DB: DB{
+			Path:        ".sqlite3/todo.db",
+			BusyTimeout: Duration(5 * time.Second),
+		},
+		// trashed TODOs are kept for 30 days and purged hourly
+		Trash: Trash{
+			Retention:     Duration(30 * 24 * time.Hour),
+			PurgeInterval: Duration(time.Hour),
+		},
+		Auth: Auth{Required: true},
+		Session: Session{
+			TTL:            Duration(12 * time.Hour),
+			RotateInterval: Duration(15 * time.Minute),
+			SecureCookies:  true,
+		},
+		OIDC: OIDC{PostLoginURL: "/"},
+		// writes are limited tighter since SQLite has a single writer
+		RateLimit: RateLimit{
+			Enabled:    true,
+			ReadRate:   20,
+			ReadBurst:  40,
+			WriteRate:  5,
+			WriteBurst: 10,
+		},
+		Health:  Health{CheckTimeout: Duration(2 * time.Second)},
+		Metrics: Metrics{Enabled: true},
+		Log:     Log{Level: LogInfo},
+		Features: Features{
+			AutoMigrate: true,
+			TrashPurge:  true,
+		},
+	}
+}
+
+// Validate returns *Error listing every invalid setting of c, or nil when c is valid.
+func (c *Config) Validate() error {
+	var problems []string
+	check := func(ok bool, key, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, key+" "+fmt.Sprintf(format, args...))
+		}
+	}
+
+	_, err := time.LoadLocation(c.TimeZone)
+	check(c.TimeZone != "" && err == nil, "time_zone", "must be a time zone such as Asia/Tokyo, given = %q", c.TimeZone)
+	check(c.Server.Addr != "", "server.addr", "must not be empty")
+	for key, d := range map[string]Duration{
+		"server.read_header_timeout": c.Server.ReadHeaderTimeout,
+		"server.read_timeout":        c.Server.ReadTimeout,
+		"server.write_timeout":       c.Server.WriteTimeout,
+		"server.idle_timeout":        c.Server.IdleTimeout,
+		"server.shutdown_timeout":    c.Server.ShutdownTimeout,
+		"trash.retention":            c.Trash.Retention,
+		"trash.purge_interval":       c.Trash.PurgeInterval,
+		"health.check_timeout":       c.Health.CheckTimeout,
+		"session.ttl":                c.Session.TTL,
+		"session.rotate_interval":    c.Session.RotateInterval,
+	} {
+		check(d > 0, key, "must be positive, given = %s", d)
+	}
+	check(c.Server.MaxHeaderBytes > 0, "server.max_header_bytes", "must be positive, given = %d", c.Server.MaxHeaderBytes)
+	check(c.Server.MaxInFlight >= 0, "server.max_in_flight", "must not be negative, given = %d", c.Server.MaxInFlight)
+	check(c.DB.Path != "", "db.path", "must not be empty")
+	check(c.DB.BusyTimeout >= 0, "db.busy_timeout", "must not be negative, given = %s", c.DB.BusyTimeout)
+	check(c.DB.MaxOpenConns >= 0, "db.max_open_conns", "must not be negative, given = %d", c.DB.MaxOpenConns)
+	check(c.Metrics.Addr == "" || c.Metrics.Addr != c.Server.Addr, "metrics.addr", "must differ from server.addr, given = %q", c.Metrics.Addr)
+	if c.OIDC.Issuer != "" {
+		check(c.OIDC.ClientID != "", "oidc.client_id", "must not be empty when oidc.issuer is given")
+		check(c.OIDC.RedirectURL != "", "oidc.redirect_url", "must not be empty when oidc.issuer is given")
+	}
+	if c.RateLimit.Enabled {
+		for key, n := range map[string]int{
+			"rate_limit.read_rate":   c.RateLimit.ReadRate,
+			"rate_limit.read_burst":  c.RateLimit.ReadBurst,
+			"rate_limit.write_rate":  c.RateLimit.WriteRate,
+			"rate_limit.write_burst": c.RateLimit.WriteBurst,
+		} {
+			check(n > 0, key, "must be positive when rate_limit.enabled is true, given = %d", n)
+		}
+	}
+	check(c.Log.Level.valid(), "log.level", "must be one of debug, info, warn and error, given = %q", c.Log.Level)
+
+	if len(problems) == 0 {
+		return nil
+	}
+	// the order of the checks of durations and rates is random
+	sort.Strings(problems)
+	return &Error{Problems: problems}
+}
+
+// Print writes c in YAML, which can be loaded as a config file, with secrets redacted.
+func (c *Config) Print(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// DSN returns the data source name of the database at Path with the options of d.
+func (d *DB) DSN() string {
+	sep := "?"
+	if strings.ContainsRune(d.Path, '?') {
+		sep = "&"
+	}
+	return d.Path + sep + "_busy_timeout=" + strconv.FormatInt(time.Duration(d.BusyTimeout).Milliseconds(), 10)
+}
+
+// File returns the path of the database file, without the scheme and the options of a URI.
+func (d *DB) File() string {
+	path := strings.TrimPrefix(d.Path, "file:")
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	return path
+}
+
+// An Error lists the problems of an invalid configuration.
+type Error struct {
+	Problems []string
+}
+
+// Error implements error interface.
+func (e *Error) Error() string {
+	return "config: invalid configuration:\n\t" + strings.Join(e.Problems, "\n\t")
+}
+
+// A Duration is time.Duration written as time.ParseDuration accepts in config files.
+type Duration time.Duration
+
+// String returns d as time.Duration does.
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// MarshalText implements encoding.TextMarshaler interface.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler interface.
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// A LogLevel is the minimum level of the logs written.
+type LogLevel string
+
+// Levels of logs, from the lowest.
+const (
+	LogDebug LogLevel = "debug"
+	LogInfo  LogLevel = "info"
+	LogWarn  LogLevel = "warn"
+	LogError LogLevel = "error"
+)
+
+// logLevels lists the levels in order.
+var logLevels = []LogLevel{LogDebug, LogInfo, LogWarn, LogError}
+
+// Enabled reports whether the logs of level are written at l.
+func (l LogLevel) Enabled(level LogLevel) bool {
+	return level.rank() >= l.rank()
+}
+
+// valid reports whether l is one of the levels.
+func (l LogLevel) valid() bool {
+	return l.rank() >= 0
+}
+
+// rank returns the index of l in logLevels, or -1 when l is not a level.
+func (l LogLevel) rank() int {
+	for i, level := range logLevels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+// A Secret is a string such as a password, which is redacted whenever it is printed.
+type Secret string
+
+// redacted replaces non-empty secrets in outputs.
+const redacted = "[REDACTED]"
+
+// String implements fmt.Stringer interface, which redacts s.
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// MarshalText implements encoding.TextMarshaler interface, which redacts s.
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
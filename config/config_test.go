@@ -0,0 +1,212 @@
+package config_test
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/TechBowl-japan/go-stations/config"
+)
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal("failed to write file, err =", err)
+		}
+		return path
+	}
+	tomlPath := writeFile("config.toml", `
+time_zone = "UTC"
+
+[server]
+addr = ":9000"
+read_timeout = "1m"
+
+[features]
+trash_purge = false
+`)
+	yamlPath := writeFile("config.yaml", `
+time_zone: UTC
+server:
+  addr: ":9000"
+  read_timeout: 1m
+features:
+  trash_purge: false
+`)
+	unknownPath := writeFile("unknown.toml", "[server]\nport = 80\n")
+	invalidPath := writeFile("invalid.yml", "server:\n  read_timeout: -1s\n  max_header_bytes: 0\n  max_in_flight: -1\nrate_limit:\n  write_burst: 0\nmetrics:\n  addr: \":8080\"\nlog:\n  level: loud\noidc:\n  issuer: https://idp.example.com\n")
+
+	fromFile := func() *config.Config {
+		cfg := config.Default()
+		cfg.TimeZone = "UTC"
+		cfg.Server.Addr = ":9000"
+		cfg.Server.ReadTimeout = config.Duration(time.Minute)
+		cfg.Features.TrashPurge = false
+		return cfg
+	}
+
+	cases := map[string]struct {
+		args []string
+		env  map[string]string
+		want func() *config.Config
+		err  string
+	}{
+		"Defaults": {want: config.Default},
+		"TOML":     {args: []string{"--config", tomlPath}, want: fromFile},
+		"YAML":     {env: map[string]string{config.FileEnv: yamlPath}, want: fromFile},
+		"Env over file": {
+			args: []string{"-config", tomlPath},
+			env:  map[string]string{"PORT": ":9001", "TRASH_PURGE": "true", "OIDC_CLIENT_SECRET": "secret", "MAX_IN_FLIGHT": "16"},
+			want: func() *config.Config {
+				cfg := fromFile()
+				cfg.Server.Addr = ":9001"
+				cfg.Server.MaxInFlight = 16
+				cfg.Features.TrashPurge = true
+				cfg.OIDC.ClientSecret = "secret"
+				return cfg
+			},
+		},
+		"Flags over env": {
+			args: []string{"--config", tomlPath, "--addr", ":9002", "--auto-migrate=false", "--log-level", "warn", "--metrics-addr", ":9090", "--auth-required=false", "--session-secure-cookies=false", "--rate-limit=false"},
+			env:  map[string]string{"PORT": ":9001", "LOG_LEVEL": "debug"},
+			want: func() *config.Config {
+				cfg := fromFile()
+				cfg.Server.Addr = ":9002"
+				cfg.Features.AutoMigrate = false
+				cfg.Log.Level = config.LogWarn
+				cfg.Metrics.Addr = ":9090"
+				cfg.Auth.Required = false
+				cfg.Session.SecureCookies = false
+				cfg.RateLimit.Enabled = false
+				return cfg
+			},
+		},
+		"Unknown key":    {args: []string{"--config", unknownPath}, err: "unknown key server.port"},
+		"Malformed env":  {env: map[string]string{"READ_TIMEOUT": "soon"}, err: "invalid READ_TIMEOUT of server.read_timeout"},
+		"Malformed flag": {args: []string{"--db-max-open-conns", "many"}, err: `invalid value "many"`},
+		"Invalid": {
+			args: []string{"--config", invalidPath},
+			err: "config: invalid configuration:\n" +
+				"\tlog.level must be one of debug, info, warn and error, given = \"loud\"\n" +
+				"\tmetrics.addr must differ from server.addr, given = \":8080\"\n" +
+				"\toidc.client_id must not be empty when oidc.issuer is given\n" +
+				"\toidc.redirect_url must not be empty when oidc.issuer is given\n" +
+				"\trate_limit.write_burst must be positive when rate_limit.enabled is true, given = 0\n" +
+				"\tserver.max_header_bytes must be positive, given = 0\n" +
+				"\tserver.max_in_flight must not be negative, given = -1\n" +
+				"\tserver.read_timeout must be positive, given = -1s",
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.SetOutput(ioutil.Discard)
+			got, err := config.Load(fs, c.args, func(key string) string { return c.env[key] })
+			if c.err != "" {
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Errorf("unexpected error, given = %v, expected = %s", err, c.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("failed to load config, err =", err)
+			}
+			if diff := cmp.Diff(c.want(), got); diff != "" {
+				t.Errorf("unexpected config (-expected +given):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConfig_Print(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	want := config.Default()
+	if err := want.Print(&buf); err != nil {
+		t.Fatal("failed to print config, err =", err)
+	}
+	if !strings.Contains(buf.String(), "read_timeout: 10s\n") {
+		t.Errorf("unexpected output, given = %s", buf.String())
+	}
+
+	// the output is loaded as a config file
+	path := filepath.Join(t.TempDir(), "printed.yaml")
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal("failed to write file, err =", err)
+	}
+	got, err := config.Load(flag.NewFlagSet("test", flag.ContinueOnError), []string{"--config", path}, func(string) string { return "" })
+	if err != nil {
+		t.Fatal("failed to load config, err =", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected config (-expected +given):\n%s", diff)
+	}
+}
+
+func TestSecret(t *testing.T) {
+	t.Parallel()
+
+	secret := config.Secret("password")
+	b, err := secret.MarshalText()
+	if err != nil {
+		t.Fatal("failed to marshal secret, err =", err)
+	}
+	if strings.Contains(string(b), "password") || strings.Contains(secret.String(), "password") {
+		t.Errorf("unexpected unredacted secret, given = %s", b)
+	}
+	if got := config.Secret("").String(); got != "" {
+		t.Errorf("unexpected empty secret, given = %s", got)
+	}
+}
+
+func TestLoad_Help(t *testing.T) {
+	t.Parallel()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	var buf bytes.Buffer
+	fs.SetOutput(&buf)
+	if _, err := config.Load(fs, []string{"-h"}, func(string) string { return "" }); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("unexpected error, given = %v, expected = %v", err, flag.ErrHelp)
+	}
+	if !strings.Contains(buf.String(), "-read-timeout value") || !strings.Contains(buf.String(), "(default 10s)") {
+		t.Errorf("unexpected usage, given = %s", buf.String())
+	}
+}
+
+func TestDB_File(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		path, file string
+	}{
+		"Path":    {path: ".sqlite3/todo.db", file: ".sqlite3/todo.db"},
+		"Options": {path: ".sqlite3/todo.db?_fk=1", file: ".sqlite3/todo.db"},
+		"URI":     {path: "file:/var/lib/todo.db?mode=rw", file: "/var/lib/todo.db"},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			d := &config.DB{Path: c.path}
+			if got := d.File(); got != c.file {
+				t.Errorf("unexpected file, given = %s, expected = %s", got, c.file)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv is the environment variable giving the path of the config file, unless given by the config flag.
+const FileEnv = "CONFIG_FILE"
+
+// Load returns the configuration merged from the defaults, the config file, the environment variables
+// getenv returns and the flags of args parsed by fs, each taking precedence over the former.
+// The flags of the settings and the config flag giving the path of the config file are defined to fs,
+// which may define other flags beforehand. The config file is TOML or YAML by its extension,
+// and it must not have keys of no settings. The configuration is validated by Config.Validate.
+func Load(fs *flag.FlagSet, args []string, getenv func(string) string) (*Config, error) {
+	cfg := Default()
+	settings := settingsOf(reflect.ValueOf(cfg).Elem(), "")
+
+	path := fs.String("config", getenv(FileEnv), "path of the TOML or YAML config file")
+	// flags are applied after the config file and the environment variables
+	var flags []func() error
+	for _, s := range settings {
+		if s.flag != "" {
+			fs.Var(&flagValue{setting: s, flags: &flags}, s.flag, s.usage)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *path != "" {
+		if err := loadFile(cfg, *path); err != nil {
+			return nil, err
+		}
+	}
+	for _, s := range settings {
+		if v := getenv(s.env); v != "" {
+			if err := s.set(v); err != nil {
+				return nil, fmt.Errorf("config: invalid %s of %s, given = %q, err = %v", s.env, s.key, v, err)
+			}
+		}
+	}
+	for _, f := range flags {
+		if err := f(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile overwrites cfg by the settings of the config file at path.
+func loadFile(cfg *Config, path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: failed to read config file, err = %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".toml":
+		md, err := toml.Decode(string(b), cfg)
+		if err != nil {
+			return fmt.Errorf("config: failed to parse %s, err = %v", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("config: unknown key %s in %s", undecoded[0], path)
+		}
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		// an empty file has no document
+		if err := dec.Decode(cfg); err != nil && len(bytes.TrimSpace(b)) > 0 {
+			return fmt.Errorf("config: failed to parse %s, err = %v", path, err)
+		}
+	default:
+		return fmt.Errorf("config: config file must be .toml, .yaml or .yml, given = %s", path)
+	}
+	return nil
+}
+
+// A setting is a field of Config which is given by a value in the config file.
+type setting struct {
+	key, env, flag, usage string
+	v                     reflect.Value
+}
+
+// settingsOf returns the settings of the struct v, of which the keys are prefixed by prefix.
+func settingsOf(v reflect.Value, prefix string) []*setting {
+	var settings []*setting
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		key := prefix + f.Tag.Get("toml")
+		if f.Type.Kind() == reflect.Struct {
+			settings = append(settings, settingsOf(v.Field(i), key+".")...)
+			continue
+		}
+		settings = append(settings, &setting{
+			key:   key,
+			env:   f.Tag.Get("env"),
+			flag:  f.Tag.Get("flag"),
+			usage: f.Tag.Get("usage"),
+			v:     v.Field(i),
+		})
+	}
+	return settings
+}
+
+// set parses s into the value of the setting.
+func (s *setting) set(str string) error {
+	if u, ok := s.v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(str))
+	}
+	switch s.v.Kind() {
+	case reflect.String:
+		s.v.SetString(str)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(str)
+		if err != nil {
+			return err
+		}
+		s.v.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(str)
+		if err != nil {
+			return err
+		}
+		s.v.SetInt(int64(n))
+	default:
+		panic("config: unsupported type of " + s.key + ", given = " + s.v.Type().String())
+	}
+	return nil
+}
+
+// A flagValue implements flag.Value of a setting, deferring the setting until flags is applied.
+type flagValue struct {
+	setting *setting
+	flags   *[]func() error
+}
+
+// String implements flag.Value interface.
+func (f *flagValue) String() string {
+	// flag package calls String of the zero value
+	if f.setting == nil {
+		return ""
+	}
+	return fmt.Sprint(f.setting.v.Interface())
+}
+
+// Set implements flag.Value interface, which parses s beforehand to report a malformed flag.
+func (f *flagValue) Set(s string) error {
+	if err := f.check(s); err != nil {
+		return err
+	}
+	*f.flags = append(*f.flags, func() error {
+		return f.setting.set(s)
+	})
+	return nil
+}
+
+// IsBoolFlag makes a boolean flag given without a value true.
+func (f *flagValue) IsBoolFlag() bool {
+	return f.setting != nil && f.setting.v.Kind() == reflect.Bool
+}
+
+// check reports whether s is parsed into the value of the setting, without changing it.
+func (f *flagValue) check(s string) error {
+	tmp := reflect.New(f.setting.v.Type()).Elem()
+	return (&setting{key: f.setting.key, v: tmp}).set(s)
+}
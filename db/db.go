@@ -1,25 +1,64 @@
 package db
 
 import (
+	"context"
 	"database/sql"
-	_ "embed"
+	"strings"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/TechBowl-japan/go-stations/ngram"
 )
 
-//go:embed schema.sql
-var schema string
+// DriverName is the name of go-sqlite3 based driver which enforces foreign keys
+// and is extended with the SQL functions below.
+//
+//	ngram_index(text) returns the normalized bigrams of text (see ngram.Index).
+//
+// The functions are called only by the migrations, never by the triggers,
+// so that other SQLite clients can still write the tables.
+const DriverName = "sqlite3_todo"
+
+func init() {
+	sql.Register(DriverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if _, err := conn.Exec(`PRAGMA foreign_keys = ON`, nil); err != nil {
+				return err
+			}
+			return conn.RegisterFunc("ngram_index", ngram.Index, true)
+		},
+	})
+}
 
 // NewDB returns go-sqlite3 driver based *sql.DB.
+// Pending schema migrations are applied before it returns.
 func NewDB(path string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", path)
+	db, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := NewMigrator(db)
 	if err != nil {
+		_ = db.Close()
 		return nil, err
 	}
 
-	if _, err := db.Exec(schema); err != nil {
+	if err := m.Up(context.Background()); err != nil {
+		_ = db.Close()
 		return nil, err
 	}
 
 	return db, nil
 }
+
+// Open returns go-sqlite3 driver based *sql.DB without touching the schema.
+// Transactions begin with BEGIN IMMEDIATE, so that what a transaction reads
+// is not changed by another connection until it ends.
+func Open(path string) (*sql.DB, error) {
+	sep := "?"
+	if strings.ContainsRune(path, '?') {
+		sep = "&"
+	}
+	return sql.Open(DriverName, path+sep+"_txlock=immediate")
+}
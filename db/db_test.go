@@ -1,8 +1,10 @@
 package db_test
 
 import (
+	"database/sql"
 	"errors"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/TechBowl-japan/go-stations/db"
@@ -44,3 +46,36 @@ func TestNewDB(t *testing.T) {
 		})
 	}
 }
+
+func TestNewDB_OtherClient(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "db_test.db")
+	d, err := db.NewDB(path)
+	if err != nil {
+		t.Fatal("failed to create db, err =", err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal("failed to close db, err =", err)
+	}
+
+	// the plain driver, as the sqlite3 shell, lacks the functions of db.DriverName
+	other, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal("failed to open db, err =", err)
+	}
+	t.Cleanup(func() {
+		if err := other.Close(); err != nil {
+			t.Error("failed to close db, err =", err)
+		}
+	})
+	for _, query := range []string{
+		`INSERT INTO todos(subject, description) VALUES('subject', 'description')`,
+		`UPDATE todos SET subject = 'updated' WHERE subject = 'subject'`,
+		`DELETE FROM todos WHERE subject = 'updated'`,
+	} {
+		if _, err := other.Exec(query); err != nil {
+			t.Errorf("failed to write todos by other client, query = %s, err = %v", query, err)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+// ErrIrreversible is returned when rolling back a migration that has no down script.
+var ErrIrreversible = errors.New("db: migration has no down script")
+
+const (
+	createVersionTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+  version    INTEGER  NOT NULL PRIMARY KEY,
+  name       TEXT     NOT NULL,
+  applied_at DATETIME NOT NULL DEFAULT (DATETIME('now'))
+)`
+	readVersions  = `SELECT version, applied_at FROM schema_migrations ORDER BY version`
+	compileOption = `SELECT sqlite_compileoption_used(?)`
+	insertVersion = `INSERT INTO schema_migrations(version, name) VALUES(?, ?)`
+	deleteVersion = `DELETE FROM schema_migrations WHERE version = ?`
+)
+
+var (
+	// migrationFile matches file names such as 0001_create_todos.up.sql.
+	migrationFile = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+	// requiresLine matches the header line such as "-- requires: ENABLE_FTS5".
+	requiresLine = regexp.MustCompile(`(?m)^--\s*requires:(.*)$`)
+	// buildTags are the build tags of go-sqlite3 providing the compile options.
+	buildTags = map[string]string{"ENABLE_FTS5": "sqlite_fts5"}
+)
+
+// A Migration expresses a numbered schema change with its up and down scripts.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+	// Requires lists the SQLite compile options the up script depends on,
+	// declared by "-- requires:" lines. The migration stays pending until
+	// the linked SQLite provides all of them, and is applied by the first
+	// Up that can, even after later migrations.
+	Requires []string
+}
+
+// A MigrationStatus expresses whether a Migration has been applied.
+type MigrationStatus struct {
+	*Migration
+	// AppliedAt is nil when the migration is pending.
+	AppliedAt *time.Time
+	// Missing lists the required compile options the linked SQLite lacks.
+	Missing []string
+}
+
+// A Migrator applies and rolls back Migrations against *sql.DB.
+type Migrator struct {
+	db         *sql.DB
+	migrations []*Migration
+}
+
+// NewMigrator returns a Migrator for the migrations embedded in this package.
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	sub, err := fs.Sub(migrationFS, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return NewMigratorFS(db, sub)
+}
+
+// NewMigratorFS returns a Migrator for the migrations found in the root of fsys.
+func NewMigratorFS(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// Up applies every pending migration in version order, each in its own transaction.
+// The migrations the linked SQLite cannot apply are logged and left pending, so that
+// the features depending on them are unavailable while the others are served.
+func (m *Migrator) Up(ctx context.Context) error {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+		missing, err := m.missing(ctx, mig)
+		if err != nil {
+			return err
+		}
+		if len(missing) > 0 {
+			log.Printf("db: migration %04d_%s is left pending, which requires %s%s", mig.Version, mig.Name, strings.Join(missing, ", "), buildHint(missing))
+			continue
+		}
+
+		err = m.inTx(ctx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, mig.Up); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, insertVersion, mig.Version, mig.Name)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("db: failed to apply migration %04d_%s: %w", mig.Version, mig.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Down rolls back the latest applied migration. It does nothing when no migration is applied.
+func (m *Migrator) Down(ctx context.Context) error {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		if mig.Down == "" {
+			return fmt.Errorf("%w: %04d_%s", ErrIrreversible, mig.Version, mig.Name)
+		}
+
+		err := m.inTx(ctx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, mig.Down); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, deleteVersion, mig.Version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("db: failed to roll back migration %04d_%s: %w", mig.Version, mig.Name, err)
+		}
+		return nil
+	}
+
+	return nil
+}
+
+// Status reports every known migration and when it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]*MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		st := &MigrationStatus{Migration: mig}
+		if at, ok := applied[mig.Version]; ok {
+			at := at
+			st.AppliedAt = &at
+		}
+		if st.Missing, err = m.missing(ctx, mig); err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, st)
+	}
+
+	return statuses, nil
+}
+
+// Version returns the latest migration version up to which every migration is applied, or 0 when none is applied.
+// A migration left pending before later ones, such as by a build lacking its compile options, limits the version.
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var version int64
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; !ok && mig.Version <= version {
+			return mig.Version - 1, nil
+		}
+	}
+	return version, nil
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int64]time.Time, error) {
+	if _, err := m.db.ExecContext(ctx, createVersionTable); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.QueryContext(ctx, readVersions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var (
+			version   int64
+			appliedAt time.Time
+		)
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// missing returns the compile options mig requires but the linked SQLite lacks.
+func (m *Migrator) missing(ctx context.Context, mig *Migration) ([]string, error) {
+	var missing []string
+	for _, opt := range mig.Requires {
+		var used bool
+		if err := m.db.QueryRowContext(ctx, compileOption, opt).Scan(&used); err != nil {
+			return nil, err
+		}
+		if !used {
+			missing = append(missing, opt)
+		}
+	}
+	return missing, nil
+}
+
+// buildHint returns how to build with the missing compile options, or an empty string when unknown.
+func buildHint(missing []string) string {
+	var tags []string
+	for _, opt := range missing {
+		if tag, ok := buildTags[opt]; ok {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) == 0 {
+		return ""
+	}
+	return ", build with -tags " + strings.Join(tags, ",")
+}
+
+func (m *Migrator) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func loadMigrations(fsys fs.FS) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		match := migrationFile.FindStringSubmatch(e.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("db: invalid migration version %q: %w", e.Name(), err)
+		}
+		if version <= 0 {
+			return nil, fmt.Errorf("db: migration version must be positive: %s", e.Name())
+		}
+
+		b, err := fs.ReadFile(fsys, path.Join(".", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = mig
+		}
+		if mig.Name != match[2] {
+			return nil, fmt.Errorf("db: migration %d has conflicting names %q and %q", version, mig.Name, match[2])
+		}
+
+		switch match[3] {
+		case "up":
+			mig.Up = string(b)
+			for _, match := range requiresLine.FindAllStringSubmatch(mig.Up, -1) {
+				for _, opt := range strings.Split(match[1], ",") {
+					if opt = strings.TrimSpace(opt); opt != "" {
+						mig.Requires = append(mig.Requires, opt)
+					}
+				}
+			}
+		case "down":
+			mig.Down = string(b)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("db: migration %04d_%s has no up script", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
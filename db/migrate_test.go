@@ -0,0 +1,311 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/TechBowl-japan/go-stations/db"
+)
+
+func TestMigrator(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"0001_create_items.up.sql":   {Data: []byte(`CREATE TABLE items (id INTEGER PRIMARY KEY);`)},
+		"0001_create_items.down.sql": {Data: []byte(`DROP TABLE items;`)},
+		"0002_add_name.up.sql":       {Data: []byte(`ALTER TABLE items ADD COLUMN name TEXT NOT NULL DEFAULT '';`)},
+		"0002_add_name.down.sql":     {Data: []byte(`ALTER TABLE items DROP COLUMN name;`)},
+		"README.md":                  {Data: []byte(`ignored`)},
+	}
+
+	d, err := db.Open(filepath.Join(t.TempDir(), "migrate_test.db"))
+	if err != nil {
+		t.Fatal("failed to open db, err =", err)
+	}
+	t.Cleanup(func() {
+		if err := d.Close(); err != nil {
+			t.Error("failed to close db, err =", err)
+		}
+	})
+
+	m, err := db.NewMigratorFS(d, fsys)
+	if err != nil {
+		t.Fatal("failed to create migrator, err =", err)
+	}
+
+	ctx := context.Background()
+	assertVersion := func(want int64) {
+		t.Helper()
+		got, err := m.Version(ctx)
+		if err != nil {
+			t.Fatal("failed to read version, err =", err)
+		}
+		if got != want {
+			t.Fatalf("unexpected version, given = %d, expected = %d", got, want)
+		}
+	}
+
+	assertVersion(0)
+
+	if err := m.Up(ctx); err != nil {
+		t.Fatal("failed to migrate up, err =", err)
+	}
+	assertVersion(2)
+	if _, err := d.Exec(`INSERT INTO items(name) VALUES('a')`); err != nil {
+		t.Fatal("migrated schema is not usable, err =", err)
+	}
+
+	// applying again is a no-op
+	if err := m.Up(ctx); err != nil {
+		t.Fatal("failed to migrate up twice, err =", err)
+	}
+	assertVersion(2)
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal("failed to read status, err =", err)
+	}
+	if len(statuses) != 2 {
+		t.Fatalf("unexpected status length, given = %d, expected = %d", len(statuses), 2)
+	}
+	for _, st := range statuses {
+		if st.AppliedAt == nil {
+			t.Errorf("migration %d is not applied", st.Version)
+		}
+	}
+
+	if err := m.Down(ctx); err != nil {
+		t.Fatal("failed to migrate down, err =", err)
+	}
+	assertVersion(1)
+	if _, err := d.Exec(`INSERT INTO items(name) VALUES('a')`); err == nil {
+		t.Error("column is not dropped by down migration")
+	}
+
+	if err := m.Down(ctx); err != nil {
+		t.Fatal("failed to migrate down, err =", err)
+	}
+	assertVersion(0)
+
+	// rolling back with nothing applied is a no-op
+	if err := m.Down(ctx); err != nil {
+		t.Fatal("failed to migrate down on empty db, err =", err)
+	}
+}
+
+func TestMigrator_FailedMigrationIsRolledBack(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"0001_create_items.up.sql": {Data: []byte(`CREATE TABLE items (id INTEGER PRIMARY KEY);`)},
+		"0002_broken.up.sql":       {Data: []byte(`CREATE TABLE broken (id INTEGER); INSERT INTO nothing VALUES(1);`)},
+	}
+
+	d, err := db.Open(filepath.Join(t.TempDir(), "migrate_test.db"))
+	if err != nil {
+		t.Fatal("failed to open db, err =", err)
+	}
+	t.Cleanup(func() {
+		if err := d.Close(); err != nil {
+			t.Error("failed to close db, err =", err)
+		}
+	})
+
+	m, err := db.NewMigratorFS(d, fsys)
+	if err != nil {
+		t.Fatal("failed to create migrator, err =", err)
+	}
+
+	ctx := context.Background()
+	if err := m.Up(ctx); err == nil {
+		t.Fatal("broken migration is applied")
+	}
+
+	version, err := m.Version(ctx)
+	if err != nil {
+		t.Fatal("failed to read version, err =", err)
+	}
+	if version != 1 {
+		t.Errorf("unexpected version, given = %d, expected = %d", version, 1)
+	}
+
+	var n int
+	if err := d.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'broken'`).Scan(&n); err != nil {
+		t.Fatal("failed to read schema, err =", err)
+	}
+	if n != 0 {
+		t.Error("partially applied migration is left behind")
+	}
+
+	if err := m.Down(ctx); !errors.Is(err, db.ErrIrreversible) {
+		t.Errorf("unexpected error, given = %v, expected = %v", err, db.ErrIrreversible)
+	}
+}
+
+func TestMigrator_Requires(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"0001_create_items.up.sql": {Data: []byte(`CREATE TABLE items (id INTEGER PRIMARY KEY);`)},
+		"0002_create_index.up.sql": {Data: []byte("-- requires: ENABLE_NOTHING_SUCH\nCREATE INDEX items_id ON items(id);")},
+		"0003_add_name.up.sql":     {Data: []byte(`ALTER TABLE items ADD COLUMN name TEXT;`)},
+	}
+
+	d, err := db.Open(filepath.Join(t.TempDir(), "migrate_test.db"))
+	if err != nil {
+		t.Fatal("failed to open db, err =", err)
+	}
+	t.Cleanup(func() {
+		if err := d.Close(); err != nil {
+			t.Error("failed to close db, err =", err)
+		}
+	})
+
+	m, err := db.NewMigratorFS(d, fsys)
+	if err != nil {
+		t.Fatal("failed to create migrator, err =", err)
+	}
+
+	// the migration the linked SQLite cannot apply is left pending
+	ctx := context.Background()
+	if err := m.Up(ctx); err != nil {
+		t.Fatal("failed to migrate up, err =", err)
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal("failed to read status, err =", err)
+	}
+	for _, st := range statuses {
+		if pending := st.Version == 2; (st.AppliedAt == nil) != pending {
+			t.Errorf("unexpected status of migration %d, applied at = %v", st.Version, st.AppliedAt)
+		}
+		if st.Version == 2 && (len(st.Missing) != 1 || st.Missing[0] != "ENABLE_NOTHING_SUCH") {
+			t.Errorf("unexpected missing options, given = %v", st.Missing)
+		}
+	}
+	if version, err := m.Version(ctx); err != nil || version != 1 {
+		t.Errorf("unexpected version, given = %d, expected = %d, err = %v", version, 1, err)
+	}
+}
+
+func TestMigrator_Gap(t *testing.T) {
+	t.Parallel()
+
+	d, err := db.Open(filepath.Join(t.TempDir(), "migrate_test.db"))
+	if err != nil {
+		t.Fatal("failed to open db, err =", err)
+	}
+	t.Cleanup(func() {
+		if err := d.Close(); err != nil {
+			t.Error("failed to close db, err =", err)
+		}
+	})
+
+	// the database was migrated past 0002 by an older build skipping it
+	ctx := context.Background()
+	older, err := db.NewMigratorFS(d, fstest.MapFS{
+		"0001_create_items.up.sql": {Data: []byte(`CREATE TABLE items (id INTEGER PRIMARY KEY);`)},
+		"0003_add_name.up.sql":     {Data: []byte(`ALTER TABLE items ADD COLUMN name TEXT;`)},
+	})
+	if err != nil {
+		t.Fatal("failed to create migrator, err =", err)
+	}
+	if err := older.Up(ctx); err != nil {
+		t.Fatal("failed to migrate up, err =", err)
+	}
+
+	m, err := db.NewMigratorFS(d, fstest.MapFS{
+		"0001_create_items.up.sql": {Data: []byte(`CREATE TABLE items (id INTEGER PRIMARY KEY);`)},
+		"0002_create_index.up.sql": {Data: []byte(`CREATE INDEX items_id ON items(id);`)},
+		"0003_add_name.up.sql":     {Data: []byte(`ALTER TABLE items ADD COLUMN name TEXT;`)},
+	})
+	if err != nil {
+		t.Fatal("failed to create migrator, err =", err)
+	}
+	if version, err := m.Version(ctx); err != nil || version != 1 {
+		t.Errorf("unexpected version with gap, given = %d, expected = %d, err = %v", version, 1, err)
+	}
+	if err := m.Up(ctx); err != nil {
+		t.Fatal("failed to migrate up, err =", err)
+	}
+	if version, err := m.Version(ctx); err != nil || version != 3 {
+		t.Errorf("unexpected version, given = %d, expected = %d, err = %v", version, 3, err)
+	}
+}
+
+func TestNewMigratorFS_Invalid(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]fstest.MapFS{
+		"Missing up script": {
+			"0001_create_items.down.sql": {Data: []byte(`DROP TABLE items;`)},
+		},
+		"Conflicting names": {
+			"0001_create_items.up.sql": {Data: []byte(`CREATE TABLE items (id INTEGER);`)},
+			"0001_create_other.up.sql": {Data: []byte(`CREATE TABLE other (id INTEGER);`)},
+		},
+		"Zero version": {
+			"0000_create_items.up.sql": {Data: []byte(`CREATE TABLE items (id INTEGER);`)},
+		},
+	}
+
+	for name, fsys := range cases {
+		fsys := fsys
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := db.NewMigratorFS(nil, fsys); err == nil {
+				t.Error("invalid migrations are accepted")
+			}
+		})
+	}
+}
+
+func TestNewDB_LegacySchema(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "legacy.db")
+
+	// a database created before schema_migrations existed
+	legacy, err := db.Open(path)
+	if err != nil {
+		t.Fatal("failed to open db, err =", err)
+	}
+	const schema = `CREATE TABLE todos (
+  id          INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
+  subject     TEXT     NOT NULL,
+  description TEXT     NOT NULL DEFAULT '',
+  created_at  DATETIME NOT NULL DEFAULT (DATETIME('now')),
+  updated_at  DATETIME NOT NULL DEFAULT (DATETIME('now')),
+  CHECK(subject <> '')
+);
+INSERT INTO todos(subject) VALUES('legacy');`
+	if _, err := legacy.Exec(schema); err != nil {
+		t.Fatal("failed to create legacy schema, err =", err)
+	}
+	if err := legacy.Close(); err != nil {
+		t.Fatal("failed to close db, err =", err)
+	}
+
+	d, err := db.NewDB(path)
+	if err != nil {
+		t.Fatal("failed to migrate legacy db, err =", err)
+	}
+	t.Cleanup(func() {
+		if err := d.Close(); err != nil {
+			t.Error("failed to close db, err =", err)
+		}
+	})
+
+	var subject string
+	if err := d.QueryRow(`SELECT subject FROM todos WHERE id = 1`).Scan(&subject); err != nil {
+		t.Fatal("legacy data is lost, err =", err)
+	}
+	if subject != "legacy" {
+		t.Errorf("unexpected value, given = %s, expected = %s", subject, "legacy")
+	}
+}
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS trigger_todos_updated_at;
+
+DROP TABLE IF EXISTS todos;
@@ -0,0 +1,26 @@
+-- requires: ENABLE_FTS5
+CREATE VIRTUAL TABLE todos_fts USING fts5(
+  subject,
+  description,
+  content='todos',
+  content_rowid='id',
+  tokenize='unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER trigger_todos_fts_insert AFTER INSERT ON todos
+BEGIN
+  INSERT INTO todos_fts(rowid, subject, description) VALUES(NEW.id, NEW.subject, NEW.description);
+END;
+
+CREATE TRIGGER trigger_todos_fts_delete AFTER DELETE ON todos
+BEGIN
+  INSERT INTO todos_fts(todos_fts, rowid, subject, description) VALUES('delete', OLD.id, OLD.subject, OLD.description);
+END;
+
+CREATE TRIGGER trigger_todos_fts_update AFTER UPDATE OF subject, description ON todos
+BEGIN
+  INSERT INTO todos_fts(todos_fts, rowid, subject, description) VALUES('delete', OLD.id, OLD.subject, OLD.description);
+  INSERT INTO todos_fts(rowid, subject, description) VALUES(NEW.id, NEW.subject, NEW.description);
+END;
+
+INSERT INTO todos_fts(todos_fts) VALUES('rebuild');
@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS trigger_todos_ngram_update;
+
+DROP TRIGGER IF EXISTS trigger_todos_ngram_delete;
+
+DROP TRIGGER IF EXISTS trigger_todos_ngram_insert;
+
+DROP TABLE IF EXISTS todos_ngram;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS index_todo_tags_tag_id;
+
+DROP TABLE IF EXISTS todo_tags;
+
+DROP TABLE IF EXISTS tags;
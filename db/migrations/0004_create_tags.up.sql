@@ -0,0 +1,14 @@
+CREATE TABLE tags (
+  id         INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
+  name       TEXT     NOT NULL UNIQUE COLLATE NOCASE,
+  created_at DATETIME NOT NULL DEFAULT (DATETIME('now')),
+  CHECK(name <> '')
+);
+
+CREATE TABLE todo_tags (
+  todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+  tag_id  INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+  PRIMARY KEY (todo_id, tag_id)
+);
+
+CREATE INDEX index_todo_tags_tag_id ON todo_tags(tag_id, todo_id);
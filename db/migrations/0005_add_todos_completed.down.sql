@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS index_todos_completed;
+
+ALTER TABLE todos DROP COLUMN completed_at;
+
+ALTER TABLE todos DROP COLUMN completed;
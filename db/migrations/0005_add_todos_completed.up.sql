@@ -0,0 +1,5 @@
+ALTER TABLE todos ADD COLUMN completed BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE todos ADD COLUMN completed_at DATETIME;
+
+CREATE INDEX index_todos_completed ON todos(completed, id);
@@ -0,0 +1,4 @@
+-- due_at is stored in UTC with the format of DATETIME('now') so that it compares as text.
+ALTER TABLE todos ADD COLUMN due_at DATETIME;
+
+CREATE INDEX index_todos_due_at ON todos(due_at, id) WHERE due_at IS NOT NULL;
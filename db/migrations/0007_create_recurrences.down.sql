@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS index_todos_recurrence_id;
+
+ALTER TABLE todos DROP COLUMN recurrence_id;
+
+DROP TABLE IF EXISTS recurrences;
@@ -0,0 +1,18 @@
+-- A recurrence is shared by the occurrences of a recurring TODO. Each occurrence is a row of todos
+-- and the completed ones are kept as the history of the recurrence.
+-- dtstart is the due date of the first occurrence, stored like todos.due_at, and
+-- tzid is the time zone in which the wall clock of dtstart is repeated.
+CREATE TABLE recurrences (
+  id         INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
+  rrule      TEXT     NOT NULL,
+  dtstart    DATETIME NOT NULL,
+  tzid       TEXT     NOT NULL,
+  created_at DATETIME NOT NULL DEFAULT (DATETIME('now')),
+  updated_at DATETIME NOT NULL DEFAULT (DATETIME('now')),
+  CHECK(rrule <> '')
+);
+
+-- recurrence_id is not a foreign key so that the down migration can drop it.
+ALTER TABLE todos ADD COLUMN recurrence_id INTEGER;
+
+CREATE INDEX index_todos_recurrence_id ON todos(recurrence_id, id) WHERE recurrence_id IS NOT NULL;
@@ -0,0 +1,5 @@
+-- deleted_at is set when a TODO is moved to the trash, and trashed TODOs are
+-- deleted permanently once they have been kept longer than the retention.
+ALTER TABLE todos ADD COLUMN deleted_at DATETIME;
+
+CREATE INDEX index_todos_deleted_at ON todos(deleted_at, id) WHERE deleted_at IS NOT NULL;
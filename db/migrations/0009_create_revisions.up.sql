@@ -0,0 +1,16 @@
+-- A revision records a change of a TODO. Revisions are numbered from 1 for each TODO,
+-- and before_json and after_json hold the TODO before and after the change as JSON,
+-- where before_json is NULL on create and restore, and after_json is NULL on delete.
+CREATE TABLE revisions (
+  id          INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
+  todo_id     INTEGER  NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+  revision    INTEGER  NOT NULL,
+  action      TEXT     NOT NULL,
+  actor       TEXT     NOT NULL DEFAULT '',
+  reverted    INTEGER,
+  before_json TEXT,
+  after_json  TEXT,
+  created_at  DATETIME NOT NULL DEFAULT (DATETIME('now')),
+  UNIQUE(todo_id, revision),
+  CHECK(action <> '')
+);
@@ -0,0 +1,20 @@
+-- A user owns TODOs and authenticates with API tokens. Only the SHA-256 hashes of
+-- tokens are stored, and a revoked token is kept with revoked_at set.
+CREATE TABLE users (
+  id         INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
+  name       TEXT     NOT NULL UNIQUE COLLATE NOCASE,
+  created_at DATETIME NOT NULL DEFAULT (DATETIME('now')),
+  CHECK(name <> '')
+);
+
+CREATE TABLE api_tokens (
+  id           INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
+  user_id      INTEGER  NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name         TEXT     NOT NULL DEFAULT '',
+  hash         TEXT     NOT NULL UNIQUE,
+  created_at   DATETIME NOT NULL DEFAULT (DATETIME('now')),
+  last_used_at DATETIME,
+  revoked_at   DATETIME
+);
+
+CREATE INDEX index_api_tokens_user_id ON api_tokens(user_id, id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS index_todos_owner_id;
+
+ALTER TABLE todos DROP COLUMN owner_id;
@@ -0,0 +1,5 @@
+-- owner_id is the user owning a TODO, and NULL for the TODOs created anonymously,
+-- which are only visible to anonymous callers.
+ALTER TABLE todos ADD COLUMN owner_id INTEGER REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE INDEX index_todos_owner_id ON todos(owner_id, id);
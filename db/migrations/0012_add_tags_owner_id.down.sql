@@ -0,0 +1,23 @@
+-- tags of the same name owned by different users are merged into one
+CREATE TABLE shared_tags (
+  id         INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
+  name       TEXT     NOT NULL UNIQUE COLLATE NOCASE,
+  created_at DATETIME NOT NULL DEFAULT (DATETIME('now')),
+  CHECK(name <> '')
+);
+INSERT INTO shared_tags(id, name, created_at) SELECT MIN(id), name, MIN(created_at) FROM tags GROUP BY name;
+
+CREATE TABLE shared_todo_tags (
+  todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+  tag_id  INTEGER NOT NULL REFERENCES shared_tags(id) ON DELETE CASCADE,
+  PRIMARY KEY (todo_id, tag_id)
+);
+INSERT OR IGNORE INTO shared_todo_tags(todo_id, tag_id)
+SELECT tt.todo_id, s.id FROM todo_tags tt JOIN tags t ON t.id = tt.tag_id JOIN shared_tags s ON s.name = t.name;
+
+DROP TABLE todo_tags;
+DROP TABLE tags;
+ALTER TABLE shared_tags RENAME TO tags;
+ALTER TABLE shared_todo_tags RENAME TO todo_tags;
+
+CREATE INDEX index_todo_tags_tag_id ON todo_tags(tag_id, todo_id);
@@ -0,0 +1,27 @@
+-- Tags are owned as TODOs are, so that renaming and merging tags never touch the TODOs
+-- of others. The unique constraint of names is replaced with the one per owner, which
+-- requires rebuilding tags and todo_tags referring to it. The tags existing so far are
+-- anonymous as every TODO is.
+CREATE TABLE owned_tags (
+  id         INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
+  owner_id   INTEGER  REFERENCES users(id) ON DELETE CASCADE,
+  name       TEXT     NOT NULL COLLATE NOCASE,
+  created_at DATETIME NOT NULL DEFAULT (DATETIME('now')),
+  CHECK(name <> '')
+);
+INSERT INTO owned_tags(id, name, created_at) SELECT id, name, created_at FROM tags;
+
+CREATE TABLE owned_todo_tags (
+  todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+  tag_id  INTEGER NOT NULL REFERENCES owned_tags(id) ON DELETE CASCADE,
+  PRIMARY KEY (todo_id, tag_id)
+);
+INSERT INTO owned_todo_tags(todo_id, tag_id) SELECT todo_id, tag_id FROM todo_tags;
+
+DROP TABLE todo_tags;
+DROP TABLE tags;
+ALTER TABLE owned_tags RENAME TO tags;
+ALTER TABLE owned_todo_tags RENAME TO todo_tags;
+
+CREATE UNIQUE INDEX index_tags_owner_id_name ON tags(COALESCE(owner_id, 0), name);
+CREATE INDEX index_todo_tags_tag_id ON todo_tags(tag_id, todo_id);
@@ -0,0 +1,18 @@
+-- A user logs in with a password, of which only the argon2id or bcrypt hash is stored.
+ALTER TABLE users ADD COLUMN password_hash TEXT;
+
+-- A session is a login of a browser client by a cookie, of which only the SHA-256 hash of the token
+-- is stored. A rotated session keeps the hash of the replaced token in previous_hash for a short grace.
+CREATE TABLE sessions (
+  id            INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
+  user_id       INTEGER  NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  hash          TEXT     NOT NULL UNIQUE,
+  previous_hash TEXT     UNIQUE,
+  csrf_token    TEXT     NOT NULL,
+  created_at    DATETIME NOT NULL DEFAULT (DATETIME('now')),
+  rotated_at    DATETIME NOT NULL,
+  expires_at    DATETIME NOT NULL
+);
+
+CREATE INDEX index_sessions_user_id ON sessions(user_id);
+CREATE INDEX index_sessions_expires_at ON sessions(expires_at);
@@ -0,0 +1,12 @@
+-- An identity links a user to the subject of an OpenID Connect provider by its issuer.
+-- Users logging in with a provider for the first time are created with their identities.
+CREATE TABLE user_identities (
+  id         INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
+  user_id    INTEGER  NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  issuer     TEXT     NOT NULL,
+  subject    TEXT     NOT NULL,
+  created_at DATETIME NOT NULL DEFAULT (DATETIME('now')),
+  UNIQUE(issuer, subject)
+);
+
+CREATE INDEX index_user_identities_user_id ON user_identities(user_id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS index_todos_list_id;
+
+ALTER TABLE todos DROP COLUMN list_id;
+
+DROP TABLE list_members;
+DROP TABLE lists;
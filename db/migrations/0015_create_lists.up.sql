@@ -0,0 +1,30 @@
+-- A list groups TODOs shared by its members, each of whom has the role of viewer, editor or owner.
+-- The TODOs of a list are visible to every member and keep owner_id of the user who created them.
+CREATE TABLE lists (
+  id         INTEGER  NOT NULL PRIMARY KEY AUTOINCREMENT,
+  name       TEXT     NOT NULL,
+  created_at DATETIME NOT NULL DEFAULT (DATETIME('now')),
+  updated_at DATETIME NOT NULL DEFAULT (DATETIME('now')),
+  CHECK(length(name) BETWEEN 1 AND 100)
+);
+
+CREATE TRIGGER IF NOT EXISTS trigger_lists_updated_at AFTER UPDATE ON lists
+BEGIN
+  UPDATE lists SET updated_at = DATETIME('now') WHERE id == NEW.id;
+END;
+
+CREATE TABLE list_members (
+  list_id    INTEGER  NOT NULL REFERENCES lists(id) ON DELETE CASCADE,
+  user_id    INTEGER  NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  role       TEXT     NOT NULL,
+  created_at DATETIME NOT NULL DEFAULT (DATETIME('now')),
+  PRIMARY KEY (list_id, user_id),
+  CHECK(role IN ('viewer', 'editor', 'owner'))
+);
+
+CREATE INDEX index_list_members_user_id ON list_members(user_id, list_id);
+
+-- the TODOs of a deleted list are kept as the TODOs of no list
+ALTER TABLE todos ADD COLUMN list_id INTEGER REFERENCES lists(id) ON DELETE SET NULL;
+
+CREATE INDEX index_todos_list_id ON todos(list_id, id);
@@ -0,0 +1,3 @@
+ALTER TABLE todos DROP COLUMN description_ngram;
+
+ALTER TABLE todos DROP COLUMN subject_ngram;
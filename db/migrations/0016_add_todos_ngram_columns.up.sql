@@ -0,0 +1,15 @@
+-- The bigrams of TODOs are stored in the columns written by the server, so that they can be indexed
+-- by todos_ngram without ngram_index, which is registered by the sqlite3_todo driver only.
+ALTER TABLE todos ADD COLUMN subject_ngram TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE todos ADD COLUMN description_ngram TEXT NOT NULL DEFAULT '';
+
+-- the bigrams are filled without bumping updated_at, which the versions of TODOs are derived from
+DROP TRIGGER trigger_todos_updated_at;
+
+UPDATE todos SET subject_ngram = ngram_index(subject), description_ngram = ngram_index(description);
+
+CREATE TRIGGER trigger_todos_updated_at AFTER UPDATE ON todos
+BEGIN
+  UPDATE todos SET updated_at = DATETIME('now') WHERE id == NEW.id;
+END;
@@ -0,0 +1,37 @@
+DROP TRIGGER IF EXISTS trigger_todos_ngram_update;
+
+DROP TRIGGER IF EXISTS trigger_todos_ngram_delete;
+
+DROP TRIGGER IF EXISTS trigger_todos_ngram_insert;
+
+DROP TABLE IF EXISTS todos_ngram;
+
+CREATE VIRTUAL TABLE todos_ngram USING fts5(
+  subject,
+  description,
+  content='',
+  tokenize='ascii'
+);
+
+CREATE TRIGGER trigger_todos_ngram_insert AFTER INSERT ON todos
+BEGIN
+  INSERT INTO todos_ngram(rowid, subject, description)
+    VALUES(NEW.id, ngram_index(NEW.subject), ngram_index(NEW.description));
+END;
+
+CREATE TRIGGER trigger_todos_ngram_delete AFTER DELETE ON todos
+BEGIN
+  INSERT INTO todos_ngram(todos_ngram, rowid, subject, description)
+    VALUES('delete', OLD.id, ngram_index(OLD.subject), ngram_index(OLD.description));
+END;
+
+CREATE TRIGGER trigger_todos_ngram_update AFTER UPDATE OF subject, description ON todos
+BEGIN
+  INSERT INTO todos_ngram(todos_ngram, rowid, subject, description)
+    VALUES('delete', OLD.id, ngram_index(OLD.subject), ngram_index(OLD.description));
+  INSERT INTO todos_ngram(rowid, subject, description)
+    VALUES(NEW.id, ngram_index(NEW.subject), ngram_index(NEW.description));
+END;
+
+INSERT INTO todos_ngram(rowid, subject, description)
+  SELECT id, ngram_index(subject), ngram_index(description) FROM todos;
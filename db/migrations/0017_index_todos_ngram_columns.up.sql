@@ -0,0 +1,38 @@
+-- requires: ENABLE_FTS5
+-- todos_ngram indexes the bigram columns instead of computing the bigrams by ngram_index in the
+-- triggers, which fail outside the sqlite3_todo driver. Other SQLite clients, such as the sqlite3
+-- shell, can write todos, while the TODOs they write are not found by the ngram search until the
+-- server updates them.
+DROP TRIGGER IF EXISTS trigger_todos_ngram_insert;
+
+DROP TRIGGER IF EXISTS trigger_todos_ngram_delete;
+
+DROP TRIGGER IF EXISTS trigger_todos_ngram_update;
+
+DROP TABLE IF EXISTS todos_ngram;
+
+CREATE VIRTUAL TABLE todos_ngram USING fts5(
+  subject_ngram,
+  description_ngram,
+  content='todos',
+  content_rowid='id',
+  tokenize='ascii'
+);
+
+CREATE TRIGGER trigger_todos_ngram_insert AFTER INSERT ON todos
+BEGIN
+  INSERT INTO todos_ngram(rowid, subject_ngram, description_ngram) VALUES(NEW.id, NEW.subject_ngram, NEW.description_ngram);
+END;
+
+CREATE TRIGGER trigger_todos_ngram_delete AFTER DELETE ON todos
+BEGIN
+  INSERT INTO todos_ngram(todos_ngram, rowid, subject_ngram, description_ngram) VALUES('delete', OLD.id, OLD.subject_ngram, OLD.description_ngram);
+END;
+
+CREATE TRIGGER trigger_todos_ngram_update AFTER UPDATE OF subject_ngram, description_ngram ON todos
+BEGIN
+  INSERT INTO todos_ngram(todos_ngram, rowid, subject_ngram, description_ngram) VALUES('delete', OLD.id, OLD.subject_ngram, OLD.description_ngram);
+  INSERT INTO todos_ngram(rowid, subject_ngram, description_ngram) VALUES(NEW.id, NEW.subject_ngram, NEW.description_ngram);
+END;
+
+INSERT INTO todos_ngram(todos_ngram) VALUES('rebuild');
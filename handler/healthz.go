@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
 )
 
 // A HealthzHandler implements health check endpoint.
@@ -16,5 +17,34 @@ func NewHealthzHandler() *HealthzHandler {
 
 // ServeHTTP implements http.Handler interface.
 func (h *HealthzHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	_ = &model.HealthzResponse{}
+	writeJSON(w, http.StatusOK, &model.HealthzResponse{Message: "OK"})
+}
+
+// A HealthCheckHandler implements the liveness and readiness check endpoints.
+type HealthCheckHandler struct {
+	svc *service.HealthService
+}
+
+// NewHealthCheckHandler returns HealthCheckHandler, of which the methods prefixed by Serve are registered to the router.
+func NewHealthCheckHandler(svc *service.HealthService) *HealthCheckHandler {
+	return &HealthCheckHandler{
+		svc: svc,
+	}
+}
+
+// ServeLive serves GET /healthz/live, which answers as long as the process handles requests.
+func (h *HealthCheckHandler) ServeLive(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, &model.HealthReport{Status: model.HealthStatusOK, Checks: []*model.HealthCheck{}})
+}
+
+// ServeReady serves GET /healthz/ready, which answers 503 when a critical check fails.
+func (h *HealthCheckHandler) ServeReady(w http.ResponseWriter, r *http.Request) {
+	report := h.svc.Check(r.Context())
+	code := http.StatusOK
+	if report.Status == model.HealthStatusFail {
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, code, report)
 }
@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/TechBowl-japan/go-stations/handler/mux"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+var _ ListService = (*service.ListService)(nil)
+
+// A ListService is the service ListHandler handles the requests by, implemented by *service.ListService.
+type ListService interface {
+	CreateList(ctx context.Context, name string) (*model.List, error)
+	ReadLists(ctx context.Context) ([]*model.List, error)
+	GetList(ctx context.Context, id int64) (*model.List, error)
+	RenameList(ctx context.Context, id int64, name string) (*model.List, error)
+	DeleteList(ctx context.Context, id int64) error
+	ReadMembers(ctx context.Context, id int64) ([]*model.Member, error)
+	PutMember(ctx context.Context, id int64, userName string, role model.Role) (*model.Member, error)
+	DeleteMember(ctx context.Context, id int64, userName string) error
+}
+
+// A ListHandler implements handling REST endpoints of lists and their members.
+type ListHandler struct {
+	svc ListService
+}
+
+// NewListHandler returns ListHandler, of which the methods prefixed by Serve are registered to the router.
+func NewListHandler(svc ListService) *ListHandler {
+	return &ListHandler{
+		svc: svc,
+	}
+}
+
+// ServeRead serves GET /lists.
+func (h *ListHandler) ServeRead(w http.ResponseWriter, r *http.Request) {
+	resp, err := h.Read(r.Context())
+	respond(w, r, resp, err)
+}
+
+// ServeCreate serves POST /lists.
+func (h *ListHandler) ServeCreate(w http.ResponseWriter, r *http.Request) {
+	req := &model.CreateListRequest{}
+	if err := decodeJSON(r, req); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	resp, err := h.Create(r.Context(), req)
+	respond(w, r, resp, err)
+}
+
+// ServeGet serves GET /lists/{id}.
+func (h *ListHandler) ServeGet(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	resp, err := h.Get(r.Context(), &model.GetListRequest{ID: id})
+	respond(w, r, resp, err)
+}
+
+// ServeUpdate serves PUT /lists/{id}.
+func (h *ListHandler) ServeUpdate(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	req := &model.UpdateListRequest{ID: id}
+	if err := decodeJSON(r, req); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	resp, err := h.Update(r.Context(), req)
+	respond(w, r, resp, err)
+}
+
+// ServeDelete serves DELETE /lists/{id}.
+func (h *ListHandler) ServeDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	resp, err := h.Delete(r.Context(), &model.DeleteListRequest{ID: id})
+	respond(w, r, resp, err)
+}
+
+// ServeMembers serves GET /lists/{id}/members.
+func (h *ListHandler) ServeMembers(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	resp, err := h.Members(r.Context(), &model.GetListRequest{ID: id})
+	respond(w, r, resp, err)
+}
+
+// ServePutMember serves PUT /lists/{id}/members/{name}.
+func (h *ListHandler) ServePutMember(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	req := &model.PutMemberRequest{ListID: id, UserName: mux.Param(r, "name")}
+	if err := decodeJSON(r, req); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	resp, err := h.PutMember(r.Context(), req)
+	respond(w, r, resp, err)
+}
+
+// ServeDeleteMember serves DELETE /lists/{id}/members/{name}.
+func (h *ListHandler) ServeDeleteMember(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	resp, err := h.DeleteMember(r.Context(), &model.DeleteMemberRequest{ListID: id, UserName: mux.Param(r, "name")})
+	respond(w, r, resp, err)
+}
+
+// Read handles the endpoint that reads the lists of the caller.
+func (h *ListHandler) Read(ctx context.Context) (*model.ReadListResponse, error) {
+	lists, err := h.svc.ReadLists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ReadListResponse{Lists: lists}, nil
+}
+
+// Create handles the endpoint that creates the list.
+func (h *ListHandler) Create(ctx context.Context, req *model.CreateListRequest) (*model.CreateListResponse, error) {
+	if err := service.Validate(req); err != nil {
+		return nil, err
+	}
+
+	list, err := h.svc.CreateList(ctx, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &model.CreateListResponse{List: list}, nil
+}
+
+// Get handles the endpoint that reads the list.
+func (h *ListHandler) Get(ctx context.Context, req *model.GetListRequest) (*model.GetListResponse, error) {
+	list, err := h.svc.GetList(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.GetListResponse{List: list}, nil
+}
+
+// Update handles the endpoint that renames the list.
+func (h *ListHandler) Update(ctx context.Context, req *model.UpdateListRequest) (*model.UpdateListResponse, error) {
+	if err := service.Validate(req); err != nil {
+		return nil, err
+	}
+
+	list, err := h.svc.RenameList(ctx, req.ID, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &model.UpdateListResponse{List: list}, nil
+}
+
+// Delete handles the endpoint that deletes the list.
+func (h *ListHandler) Delete(ctx context.Context, req *model.DeleteListRequest) (*model.DeleteListResponse, error) {
+	if err := h.svc.DeleteList(ctx, req.ID); err != nil {
+		return nil, err
+	}
+	return &model.DeleteListResponse{}, nil
+}
+
+// Members handles the endpoint that reads the members of the list.
+func (h *ListHandler) Members(ctx context.Context, req *model.GetListRequest) (*model.ReadMemberResponse, error) {
+	members, err := h.svc.ReadMembers(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ReadMemberResponse{Members: members}, nil
+}
+
+// PutMember handles the endpoint that adds the user to the list or changes the role of the member.
+func (h *ListHandler) PutMember(ctx context.Context, req *model.PutMemberRequest) (*model.PutMemberResponse, error) {
+	if err := service.Validate(req); err != nil {
+		return nil, err
+	}
+
+	member, err := h.svc.PutMember(ctx, req.ListID, req.UserName, req.Role)
+	if err != nil {
+		return nil, err
+	}
+	return &model.PutMemberResponse{Member: member}, nil
+}
+
+// DeleteMember handles the endpoint that removes the member from the list.
+func (h *ListHandler) DeleteMember(ctx context.Context, req *model.DeleteMemberRequest) (*model.DeleteMemberResponse, error) {
+	if err := h.svc.DeleteMember(ctx, req.ListID, req.UserName); err != nil {
+		return nil, err
+	}
+	return &model.DeleteMemberResponse{}, nil
+}
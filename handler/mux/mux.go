@@ -0,0 +1,200 @@
+// Package mux implements an HTTP request router matching methods and paths with parameters.
+//
+// A pattern is a path whose segments are either literal or a parameter such as {id},
+// which matches any non-empty segment. A literal segment takes precedence over a parameter,
+// so /todos/trash is routed to its own handlers rather than those of /todos/{id}.
+//
+// A path matching a pattern but none of its methods is answered with 405 and the Allow header,
+// and OPTIONS of a pattern is answered with 204 and the Allow header unless handled explicitly.
+// HEAD is served by the handler of GET unless handled explicitly.
+package mux
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// A Router routes requests to the handlers registered by their methods and path patterns.
+type Router struct {
+	// NotFound answers requests matching no pattern. http.NotFound is used when it is nil.
+	NotFound http.Handler
+	// MethodNotAllowed answers requests matching a pattern but none of its methods,
+	// after the Allow header is set. Only the status code is written when it is nil.
+	MethodNotAllowed http.Handler
+
+	routes []*route
+}
+
+// A route holds the handlers of a pattern by method.
+type route struct {
+	pattern  string
+	segments []string
+	handlers map[string]http.Handler
+}
+
+// New returns a new Router without routes.
+func New() *Router {
+	return &Router{}
+}
+
+// Handle registers h for method and pattern.
+// It panics when the pattern is malformed or h is already registered for them.
+func (rt *Router) Handle(method, pattern string, h http.Handler) {
+	if !strings.HasPrefix(pattern, "/") {
+		panic("mux: pattern must start with /, given = " + pattern)
+	}
+	segments := split(pattern)
+	for _, s := range segments {
+		if s == "" || (isParam(s) && len(s) == 2) {
+			panic("mux: pattern has an empty segment, given = " + pattern)
+		}
+	}
+
+	for _, r := range rt.routes {
+		if strings.Join(r.segments, "/") != strings.Join(segments, "/") {
+			continue
+		}
+		if _, ok := r.handlers[method]; ok {
+			panic("mux: multiple registrations for " + method + " " + pattern)
+		}
+		r.handlers[method] = h
+		return
+	}
+	rt.routes = append(rt.routes, &route{pattern: pattern, segments: segments, handlers: map[string]http.Handler{method: h}})
+}
+
+// HandleFunc registers f for method and pattern.
+func (rt *Router) HandleFunc(method, pattern string, f func(http.ResponseWriter, *http.Request)) {
+	rt.Handle(method, pattern, http.HandlerFunc(f))
+}
+
+// ServeHTTP implements http.Handler interface.
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route, params := rt.match(split(r.URL.Path))
+	if route == nil {
+		if rt.NotFound != nil {
+			rt.NotFound.ServeHTTP(w, r)
+			return
+		}
+		http.NotFound(w, r)
+		return
+	}
+	if len(params) > 0 {
+		r = r.WithContext(context.WithValue(r.Context(), paramsKey{}, params))
+	}
+
+	if h, ok := route.handlers[r.Method]; ok {
+		h.ServeHTTP(w, r)
+		return
+	}
+	if h, ok := route.handlers[http.MethodGet]; ok && r.Method == http.MethodHead {
+		h.ServeHTTP(w, r)
+		return
+	}
+
+	w.Header().Set("Allow", route.allow())
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if rt.MethodNotAllowed != nil {
+		rt.MethodNotAllowed.ServeHTTP(w, r)
+		return
+	}
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
+
+// Pattern returns the pattern r is routed to, or an empty string when r matches no pattern,
+// so that requests are told apart by their routes rather than their paths.
+func (rt *Router) Pattern(r *http.Request) string {
+	route, _ := rt.match(split(r.URL.Path))
+	if route == nil {
+		return ""
+	}
+	return route.pattern
+}
+
+// match returns the route matching the segments of a path with its parameters.
+// Of the routes matching the path, the one having a literal segment earliest is returned.
+func (rt *Router) match(segments []string) (*route, map[string]string) {
+	var (
+		best   *route
+		params map[string]string
+	)
+	for _, r := range rt.routes {
+		p, ok := r.match(segments)
+		if ok && (best == nil || r.precedes(best)) {
+			best, params = r, p
+		}
+	}
+	return best, params
+}
+
+// match returns the parameters of the path segments if r matches them.
+func (r *route) match(segments []string) (map[string]string, bool) {
+	if len(segments) != len(r.segments) {
+		return nil, false
+	}
+
+	var params map[string]string
+	for i, s := range r.segments {
+		switch {
+		case isParam(s) && segments[i] != "":
+			if params == nil {
+				params = map[string]string{}
+			}
+			params[s[1:len(s)-1]] = segments[i]
+		case s != segments[i]:
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// precedes reports whether r takes precedence over other, both matching the same path.
+func (r *route) precedes(other *route) bool {
+	for i, s := range r.segments {
+		if p, q := isParam(s), isParam(other.segments[i]); p != q {
+			return q
+		}
+	}
+	return false
+}
+
+// allow returns the value of the Allow header listing the methods of r.
+func (r *route) allow() string {
+	methods := []string{http.MethodOptions}
+	for method := range r.handlers {
+		if method != http.MethodOptions {
+			methods = append(methods, method)
+		}
+	}
+	if _, ok := r.handlers[http.MethodGet]; ok {
+		if _, ok := r.handlers[http.MethodHead]; !ok {
+			methods = append(methods, http.MethodHead)
+		}
+	}
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
+}
+
+// paramsKey is the context key of the path parameters.
+type paramsKey struct{}
+
+// Param returns the value of the path parameter by name of r, or an empty string if it does not exist.
+func Param(r *http.Request, name string) string {
+	params, _ := r.Context().Value(paramsKey{}).(map[string]string)
+	return params[name]
+}
+
+// split splits path into its segments without the leading slash.
+func split(path string) []string {
+	return strings.Split(strings.TrimPrefix(path, "/"), "/")
+}
+
+// isParam reports whether the pattern segment s is a parameter.
+func isParam(s string) bool {
+	return strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}")
+}
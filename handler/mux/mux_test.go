@@ -0,0 +1,119 @@
+package mux_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/TechBowl-japan/go-stations/handler/mux"
+)
+
+func TestRouter(t *testing.T) {
+	t.Parallel()
+
+	r := mux.New()
+	echo := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, name+":"+mux.Param(r, "id")+":"+mux.Param(r, "tag"))
+		}
+	}
+	r.HandleFunc(http.MethodGet, "/todos", echo("list"))
+	r.HandleFunc(http.MethodPost, "/todos", echo("create"))
+	r.HandleFunc(http.MethodGet, "/todos/{id}", echo("get"))
+	r.HandleFunc(http.MethodPut, "/todos/{id}", echo("put"))
+	r.HandleFunc(http.MethodGet, "/todos/trash", echo("trash"))
+	r.HandleFunc(http.MethodPost, "/todos/{id}/tags/{tag}", echo("tag"))
+	r.HandleFunc(http.MethodOptions, "/custom", echo("options"))
+
+	cases := map[string]struct {
+		method, path string
+		code         int
+		body, allow  string
+	}{
+		"Collection":         {method: http.MethodGet, path: "/todos", code: http.StatusOK, body: "list::"},
+		"Another method":     {method: http.MethodPost, path: "/todos", code: http.StatusOK, body: "create::"},
+		"Parameter":          {method: http.MethodGet, path: "/todos/12", code: http.StatusOK, body: "get:12:"},
+		"Literal first":      {method: http.MethodGet, path: "/todos/trash", code: http.StatusOK, body: "trash::"},
+		"Two parameters":     {method: http.MethodPost, path: "/todos/3/tags/work", code: http.StatusOK, body: "tag:3:work"},
+		"Head of get":        {method: http.MethodHead, path: "/todos/12", code: http.StatusOK},
+		"Method not allowed": {method: http.MethodDelete, path: "/todos/12", code: http.StatusMethodNotAllowed, allow: "GET, HEAD, OPTIONS, PUT"},
+		"Literal allows":     {method: http.MethodPut, path: "/todos/trash", code: http.StatusMethodNotAllowed, allow: "GET, HEAD, OPTIONS"},
+		"Options":            {method: http.MethodOptions, path: "/todos", code: http.StatusNoContent, allow: "GET, HEAD, OPTIONS, POST"},
+		"Explicit options":   {method: http.MethodOptions, path: "/custom", code: http.StatusOK, body: "options::"},
+		"Empty parameter":    {method: http.MethodGet, path: "/todos/", code: http.StatusNotFound},
+		"Unknown path":       {method: http.MethodGet, path: "/todos/1/unknown", code: http.StatusNotFound},
+		"Root":               {method: http.MethodGet, path: "/", code: http.StatusNotFound},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(c.method, c.path, nil))
+			if w.Code != c.code {
+				t.Errorf("unexpected status code, given = %d, expected = %d", w.Code, c.code)
+			}
+			if c.body != "" && w.Body.String() != c.body {
+				t.Errorf("unexpected body, given = %q, expected = %q", w.Body.String(), c.body)
+			}
+			if got := w.Header().Get("Allow"); got != c.allow {
+				t.Errorf("unexpected allow, given = %q, expected = %q", got, c.allow)
+			}
+		})
+	}
+}
+
+func TestRouter_Handle(t *testing.T) {
+	t.Parallel()
+
+	for name, pattern := range map[string]string{
+		"Relative":        "todos",
+		"Empty segment":   "/todos//complete",
+		"Empty parameter": "/todos/{}",
+		"Duplicated":      "/todos",
+	} {
+		pattern := pattern
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			r := mux.New()
+			r.HandleFunc(http.MethodGet, "/todos", func(http.ResponseWriter, *http.Request) {})
+			defer func() {
+				if recover() == nil {
+					t.Errorf("unexpected success of %q", pattern)
+				}
+			}()
+			r.HandleFunc(http.MethodGet, pattern, func(http.ResponseWriter, *http.Request) {})
+		})
+	}
+}
+
+func TestRouter_Pattern(t *testing.T) {
+	t.Parallel()
+
+	r := mux.New()
+	noop := func(http.ResponseWriter, *http.Request) {}
+	r.HandleFunc(http.MethodGet, "/todos/{id}", noop)
+	r.HandleFunc(http.MethodGet, "/todos/trash", noop)
+
+	cases := map[string]struct {
+		method, path, pattern string
+	}{
+		"Parameter":          {method: http.MethodGet, path: "/todos/12", pattern: "/todos/{id}"},
+		"Literal first":      {method: http.MethodGet, path: "/todos/trash", pattern: "/todos/trash"},
+		"Method not allowed": {method: http.MethodPost, path: "/todos/12", pattern: "/todos/{id}"},
+		"Unknown path":       {method: http.MethodGet, path: "/todos", pattern: ""},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := r.Pattern(httptest.NewRequest(c.method, c.path, nil)); got != c.pattern {
+				t.Errorf("unexpected pattern, given = %q, expected = %q", got, c.pattern)
+			}
+		})
+	}
+}
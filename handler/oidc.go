@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/oidc"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+// OIDCCookie carries the state, the nonce and the PKCE code verifier of the authorization request in flight,
+// which only the callback reads.
+const OIDCCookie = "todo_oidc"
+
+// oidcCookiePath limits OIDCCookie to the endpoints of logging in with the provider.
+const oidcCookiePath = "/login/oidc"
+
+// oidcRequestTTL is how long the user may take to authorize at the provider, in seconds.
+const oidcRequestTTL = 10 * 60
+
+// An OIDCHandler implements logging in with an OpenID Connect provider into cookie sessions.
+type OIDCHandler struct {
+	rp         *oidc.RelyingParty
+	identities *service.IdentityService
+	sessions   *SessionHandler
+	redirect   string
+}
+
+// NewOIDCHandler returns OIDCHandler, of which the methods prefixed by Serve are registered to the router.
+// The user agent logged in is redirected to redirect.
+func NewOIDCHandler(rp *oidc.RelyingParty, identities *service.IdentityService, sessions *SessionHandler, redirect string) *OIDCHandler {
+	return &OIDCHandler{
+		rp:         rp,
+		identities: identities,
+		sessions:   sessions,
+		redirect:   redirect,
+	}
+}
+
+// ServeLogin serves GET /login/oidc, which redirects the user agent to the provider for the authorization.
+func (h *OIDCHandler) ServeLogin(w http.ResponseWriter, r *http.Request) {
+	var values [3]string
+	for i := range values {
+		v, err := oidc.RandomString()
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		values[i] = v
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     OIDCCookie,
+		Value:    strings.Join(values[:], "."),
+		Path:     oidcCookiePath,
+		MaxAge:   oidcRequestTTL,
+		Secure:   h.sessions.secure,
+		HttpOnly: true,
+		// the cookie must be sent with the top-level navigation redirected back from the provider
+		SameSite: http.SameSiteLaxMode,
+	})
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, h.rp.AuthCodeURL(state, nonce, verifier), http.StatusFound)
+}
+
+// ServeCallback serves GET /login/oidc/callback, to which the provider redirects the user agent back.
+// It starts the session of the user, who is created on the first login, and redirects to the redirect URL.
+func (h *OIDCHandler) ServeCallback(w http.ResponseWriter, r *http.Request) {
+	var inFlight string
+	if c, err := r.Cookie(OIDCCookie); err == nil {
+		inFlight = c.Value
+	}
+	// the authorization request is answered once whether it succeeds or not
+	http.SetCookie(w, &http.Cookie{
+		Name:     OIDCCookie,
+		Path:     oidcCookiePath,
+		MaxAge:   -1,
+		Secure:   h.sessions.secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	token, session, err := h.Callback(r.Context(), inFlight, r.URL.Query())
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	h.sessions.SetCookies(w, token, session)
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, h.redirect, http.StatusSeeOther)
+}
+
+// Callback handles the callback of the authorization request in flight, which joins its state, nonce
+// and code verifier by dots, returning the token of the new session of the user authorized by the provider.
+func (h *OIDCHandler) Callback(ctx context.Context, inFlight string, query url.Values) (string, *model.Session, error) {
+	parts := strings.Split(inFlight, ".")
+	if len(parts) != 3 {
+		return "", nil, fmt.Errorf("%w: no authorization request is in flight", errBadRequest)
+	}
+	state, nonce, verifier := parts[0], parts[1], parts[2]
+	if subtle.ConstantTimeCompare([]byte(state), []byte(query.Get("state"))) != 1 {
+		return "", nil, fmt.Errorf("%w: state mismatch", errBadRequest)
+	}
+	if e := query.Get("error"); e != "" {
+		return "", nil, fmt.Errorf("%w: the provider answered %s", errBadRequest, e)
+	}
+	code := query.Get("code")
+	if code == "" {
+		return "", nil, fmt.Errorf("%w: code is required", errBadRequest)
+	}
+
+	claims, err := h.rp.Exchange(ctx, code, verifier, nonce)
+	if errors.Is(err, oidc.ErrInvalidToken) || errors.Is(err, oidc.ErrExchangeRejected) {
+		log.Println("handler: failed to log in with provider, err =", err)
+		return "", nil, service.ErrUnauthenticated
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	user, err := h.identities.Provision(ctx, claims.Issuer, claims.Subject, userName(claims))
+	if err != nil {
+		return "", nil, err
+	}
+	return h.sessions.Start(ctx, user)
+}
+
+// userName returns the name a user logging in for the first time is created by,
+// the preferred username or the local part of the email address.
+func userName(claims *oidc.Claims) string {
+	if claims.PreferredUsername != "" {
+		return claims.PreferredUsername
+	}
+	if i := strings.IndexByte(claims.Email, '@'); i > 0 {
+		return claims.Email[:i]
+	}
+	return claims.Name
+}
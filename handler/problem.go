@@ -0,0 +1,299 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+const (
+	// problemContentType is the media type of problem details (RFC 7807).
+	problemContentType = "application/problem+json"
+	// problemTypePrefix prefixes the slugs of problem types into their URIs.
+	problemTypePrefix = "urn:todo:problem:"
+	// requestIDHeader is the header the router sets to the ID of the request.
+	requestIDHeader = "X-Request-ID"
+)
+
+// Languages the messages of problems are written in. The first one is the default.
+const (
+	langEnglish  = "en"
+	langJapanese = "ja"
+)
+
+// supportedLanguages lists the languages of the messages in the order of preference among equals.
+var supportedLanguages = []string{langEnglish, langJapanese}
+
+var (
+	// ErrMethodNotAllowed is written by the router when the resource does not support the method.
+	ErrMethodNotAllowed = errors.New("handler: method not allowed")
+	// ErrInternal is written as an internal error without being logged, for callers logging the cause by themselves.
+	ErrInternal = errors.New("handler: internal server error")
+	// ErrRateLimited is written by the router when the client has made too many requests.
+	ErrRateLimited = errors.New("handler: rate limited")
+	// ErrOverloaded is written by the router when the server sheds the request under load.
+	ErrOverloaded = errors.New("handler: overloaded")
+	// errUnsupportedMediaType is returned by handlers when the body is not of a media type they accept.
+	errUnsupportedMediaType = errors.New("handler: unsupported media type")
+)
+
+// A problemType describes a type of problems, identified by slug, with its messages by language.
+type problemType struct {
+	slug   string
+	status int
+	title  map[string]string
+	detail map[string]string
+}
+
+var (
+	problemBadRequest = &problemType{
+		slug:   "bad-request",
+		status: http.StatusBadRequest,
+		title:  map[string]string{langEnglish: "Bad request", langJapanese: "不正なリクエスト"},
+		detail: map[string]string{langEnglish: "The request is malformed.", langJapanese: "リクエストの形式が正しくありません。"},
+	}
+	problemValidation = &problemType{
+		slug:   "validation",
+		status: http.StatusBadRequest,
+		title:  map[string]string{langEnglish: "Validation failed", langJapanese: "入力値が不正です"},
+		detail: map[string]string{langEnglish: "Some parameters are invalid.", langJapanese: "不正なパラメータがあります。"},
+	}
+	problemConstraint = &problemType{
+		slug:   "constraint-violation",
+		status: http.StatusBadRequest,
+		title:  map[string]string{langEnglish: "Constraint violation", langJapanese: "制約違反"},
+		detail: map[string]string{langEnglish: "The change violates a constraint of the storage.", langJapanese: "変更がデータの制約に違反しています。"},
+	}
+	problemUnauthenticated = &problemType{
+		slug:   "unauthenticated",
+		status: http.StatusUnauthorized,
+		title:  map[string]string{langEnglish: "Unauthenticated", langJapanese: "認証されていません"},
+		detail: map[string]string{langEnglish: "A valid API token is required.", langJapanese: "有効な API トークンが必要です。"},
+	}
+	problemCSRF = &problemType{
+		slug:   "csrf-token-mismatch",
+		status: http.StatusForbidden,
+		title:  map[string]string{langEnglish: "CSRF token mismatch", langJapanese: "CSRF トークンが一致しません"},
+		detail: map[string]string{langEnglish: "The request must submit the CSRF token in both the cookie and the X-CSRF-Token header.", langJapanese: "リクエストは Cookie と X-CSRF-Token ヘッダの両方で CSRF トークンを送信する必要があります。"},
+	}
+	problemForbidden = &problemType{
+		slug:   "forbidden",
+		status: http.StatusForbidden,
+		title:  map[string]string{langEnglish: "Forbidden", langJapanese: "禁止されています"},
+		detail: map[string]string{langEnglish: "Your role does not allow the action.", langJapanese: "あなたのロールではこの操作は許可されていません。"},
+	}
+	problemNotFound = &problemType{
+		slug:   "not-found",
+		status: http.StatusNotFound,
+		title:  map[string]string{langEnglish: "Not found", langJapanese: "見つかりません"},
+		detail: map[string]string{langEnglish: "The resource does not exist.", langJapanese: "リソースが存在しません。"},
+	}
+	problemMethodNotAllowed = &problemType{
+		slug:   "method-not-allowed",
+		status: http.StatusMethodNotAllowed,
+		title:  map[string]string{langEnglish: "Method not allowed", langJapanese: "許可されていないメソッド"},
+		detail: map[string]string{langEnglish: "The resource does not support the method.", langJapanese: "リソースはこのメソッドに対応していません。"},
+	}
+	problemConflict = &problemType{
+		slug:   "conflict",
+		status: http.StatusConflict,
+		title:  map[string]string{langEnglish: "Conflict", langJapanese: "競合"},
+		detail: map[string]string{langEnglish: "The request conflicts with the current state.", langJapanese: "リクエストが現在の状態と競合しています。"},
+	}
+	problemPreconditionFailed = &problemType{
+		slug:   "precondition-failed",
+		status: http.StatusPreconditionFailed,
+		title:  map[string]string{langEnglish: "Precondition failed", langJapanese: "前提条件が満たされていません"},
+		detail: map[string]string{langEnglish: "The TODO has been changed since the version.", langJapanese: "TODO は指定されたバージョンから変更されています。"},
+	}
+	problemUnsupportedMediaType = &problemType{
+		slug:   "unsupported-media-type",
+		status: http.StatusUnsupportedMediaType,
+		title:  map[string]string{langEnglish: "Unsupported media type", langJapanese: "対応していないメディアタイプ"},
+		detail: map[string]string{langEnglish: "The body is not of a supported media type.", langJapanese: "本文のメディアタイプに対応していません。"},
+	}
+	problemRateLimited = &problemType{
+		slug:   "rate-limited",
+		status: http.StatusTooManyRequests,
+		title:  map[string]string{langEnglish: "Too many requests", langJapanese: "リクエストが多すぎます"},
+		detail: map[string]string{langEnglish: "You have made too many requests. Retry after the time in the Retry-After header.", langJapanese: "リクエストが多すぎます。Retry-After ヘッダの時間が経過してから再試行してください。"},
+	}
+	problemNotImplemented = &problemType{
+		slug:   "not-implemented",
+		status: http.StatusNotImplemented,
+		title:  map[string]string{langEnglish: "Not implemented", langJapanese: "利用できません"},
+		detail: map[string]string{langEnglish: "The feature is unavailable in this build.", langJapanese: "この機能はこのビルドでは利用できません。"},
+	}
+	problemInternal = &problemType{
+		slug:   "internal",
+		status: http.StatusInternalServerError,
+		title:  map[string]string{langEnglish: "Internal server error", langJapanese: "サーバ内部エラー"},
+		detail: map[string]string{langEnglish: "The server failed to handle the request.", langJapanese: "サーバがリクエストを処理できませんでした。"},
+	}
+	problemOverloaded = &problemType{
+		slug:   "overloaded",
+		status: http.StatusServiceUnavailable,
+		title:  map[string]string{langEnglish: "Service unavailable", langJapanese: "サービス利用不可"},
+		detail: map[string]string{langEnglish: "The server is too busy to handle the request. Retry later.", langJapanese: "サーバが混み合っているためリクエストを処理できません。しばらくしてから再試行してください。"},
+	}
+)
+
+// violationReasons are the reasons of the violations by code and language.
+var violationReasons = map[service.ViolationCode]map[string]string{
+	service.ViolationRequired:   {langEnglish: "must not be empty", langJapanese: "必須です"},
+	service.ViolationInvalid:    {langEnglish: "is invalid", langJapanese: "不正な値です"},
+	service.ViolationOutOfRange: {langEnglish: "is out of range", langJapanese: "範囲外の値です"},
+	service.ViolationTooLong:    {langEnglish: "is too long", langJapanese: "長すぎます"},
+	service.ViolationUnknown:    {langEnglish: "is unknown", langJapanese: "未定義の項目です"},
+}
+
+// WriteError writes err as problem details, of which the messages are written in the language r accepts.
+// Errors of the storage are translated by service.DomainError, and unknown errors are logged and
+// written as internal errors. A stale update is answered with the current representation of the TODO.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	var (
+		lang       = acceptLanguage(r)
+		typ        = problemInternal
+		detail     string
+		violations []*service.Violation
+		todo       *model.TODO
+
+		validation *service.ErrValidation
+		constraint *service.ErrConstraint
+		notFound   *service.ErrNotFound
+		conflict   *service.ErrConflict
+		stale      *service.ErrPreconditionFailed
+	)
+
+	err = service.DomainError(err)
+	switch {
+	case errors.Is(err, errBadRequest):
+		typ = problemBadRequest
+		detail = strings.TrimPrefix(strings.TrimPrefix(err.Error(), errBadRequest.Error()), ": ")
+	case errors.As(err, &validation):
+		typ = problemValidation
+		violations = validation.Violations
+	case errors.Is(err, service.ErrInvalidRecurrence):
+		typ = problemValidation
+		violations = []*service.Violation{{
+			Field:   "recurrence",
+			Code:    service.ViolationInvalid,
+			Message: strings.TrimPrefix(strings.TrimPrefix(err.Error(), service.ErrInvalidRecurrence.Error()), ": "),
+		}}
+	case errors.As(err, &constraint):
+		typ = problemConstraint
+		if constraint.Kind == service.ConstraintUnique {
+			typ = problemConflict
+		}
+		if constraint.Violation != nil {
+			violations = []*service.Violation{constraint.Violation}
+		}
+	case errors.Is(err, service.ErrUnauthenticated):
+		typ = problemUnauthenticated
+	case errors.Is(err, ErrCSRFTokenMismatch):
+		typ = problemCSRF
+	case errors.Is(err, service.ErrForbidden):
+		typ = problemForbidden
+	case errors.As(err, &notFound):
+		typ = problemNotFound
+	case errors.As(err, &conflict):
+		typ = problemConflict
+		detail = conflict.Message
+	case errors.As(err, &stale):
+		typ = problemPreconditionFailed
+		// the current representation lets the client merge the changes and retry
+		todo = stale.TODO
+		w.Header().Set("ETag", etag(todo))
+	case errors.Is(err, ErrMethodNotAllowed):
+		typ = problemMethodNotAllowed
+	case errors.Is(err, errUnsupportedMediaType):
+		typ = problemUnsupportedMediaType
+	case errors.Is(err, ErrRateLimited):
+		typ = problemRateLimited
+	case errors.Is(err, service.ErrSearchUnavailable), errors.Is(err, service.ErrRevisionUnavailable):
+		typ = problemNotImplemented
+	case errors.Is(err, ErrOverloaded):
+		typ = problemOverloaded
+	case errors.Is(err, ErrInternal):
+	default:
+		log.Println("handler: failed to handle request, err =", err)
+	}
+
+	// the messages given by errors are written in English
+	if detail == "" || lang != langEnglish {
+		detail = typ.detail[lang]
+	}
+	problem := &model.Problem{
+		Type:      problemTypePrefix + typ.slug,
+		Title:     typ.title[lang],
+		Status:    typ.status,
+		Detail:    detail,
+		RequestID: w.Header().Get(requestIDHeader),
+		TODO:      todo,
+	}
+	for _, v := range violations {
+		reason := violationReasons[v.Code][lang]
+		if v.Message != "" && lang == langEnglish {
+			reason = v.Message
+		}
+		problem.InvalidParams = append(problem.InvalidParams, &model.InvalidParam{
+			Name:   v.Field,
+			Code:   string(v.Code),
+			Reason: reason,
+		})
+	}
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set("Content-Language", lang)
+	w.WriteHeader(typ.status)
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		log.Println("handler: failed to encode response, err =", err)
+	}
+}
+
+// acceptLanguage returns the supported language r prefers most by Accept-Language,
+// or the default language when r accepts none of them.
+func acceptLanguage(r *http.Request) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if i := strings.IndexByte(tag, '-'); i >= 0 {
+			tag = tag[:i]
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				v, err := strconv.ParseFloat(param[2:], 64)
+				if err != nil {
+					v = 0
+				}
+				q = v
+			}
+		}
+		for _, lang := range supportedLanguages {
+			if q > 0 && (tag == lang || tag == "*") {
+				candidates = append(candidates, candidate{lang: lang, q: q})
+				break
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return supportedLanguages[0]
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].lang
+}
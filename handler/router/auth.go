@@ -0,0 +1,56 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/TechBowl-japan/go-stations/handler"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+// bearerPrefix prefixes the credentials of the Bearer scheme (RFC 6750) in the Authorization header.
+const bearerPrefix = "bearer "
+
+// Authenticate returns the middleware authenticating each request by the API token given in the
+// Authorization header with the Bearer scheme. The request context carries the user of the token,
+// to whom the TODOs are scoped, and the user's name as the actor of the revisions.
+// A request without the header is served anonymously unless required or authenticated by Sessions before,
+// and a request with a malformed, unknown or revoked token is answered with 401 and the WWW-Authenticate header.
+func Authenticate(users *service.UserService, required bool) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			if header == "" {
+				if required && service.UserFromContext(r.Context()) == nil {
+					unauthenticated(w, r, `Bearer`)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			// the scheme is case-insensitive
+			if len(header) <= len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+				unauthenticated(w, r, `Bearer error="invalid_request"`)
+				return
+			}
+
+			user, err := users.Authenticate(r.Context(), strings.TrimSpace(header[len(bearerPrefix):]))
+			if err == service.ErrUnauthenticated {
+				unauthenticated(w, r, `Bearer error="invalid_token"`)
+				return
+			}
+			if err != nil {
+				handler.WriteError(w, r, err)
+				return
+			}
+			ctx := service.WithActor(service.WithUser(r.Context(), user), user.Name)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// unauthenticated answers r with 401 and the challenge in the WWW-Authenticate header.
+func unauthenticated(w http.ResponseWriter, r *http.Request, challenge string) {
+	w.Header().Set("WWW-Authenticate", challenge)
+	handler.WriteError(w, r, service.ErrUnauthenticated)
+}
@@ -0,0 +1,175 @@
+package router
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/handler"
+	"github.com/TechBowl-japan/go-stations/handler/mux"
+	"github.com/TechBowl-japan/go-stations/metrics"
+)
+
+// RequestIDHeader is the header carrying the request ID both in requests and responses.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength is the maximum length of request IDs propagated from requests.
+const maxRequestIDLength = 128
+
+// requestIDKey is the context key of the request ID.
+type requestIDKey struct{}
+
+// RequestID returns the middleware identifying each request by the ID in RequestIDHeader,
+// or by a newly generated one when the request has none or a malformed one.
+// The ID is set to RequestIDHeader of the response and carried by the request context.
+func RequestID() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, id)
+			next.ServeHTTP(w, WithValue(r, requestIDKey{}, id))
+		})
+	}
+}
+
+// RequestIDFromContext returns the request ID ctx carries, which is empty unless the request passed RequestID.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Recover returns the middleware answering a panic of the handler with 500 and problem details,
+// logging the panic with its stack. Nothing is written if the response is already started,
+// and http.ErrAbortHandler is panicked again to abort the response as the server does.
+func Recover() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := &responseRecorder{ResponseWriter: w}
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+
+				id := RequestIDFromContext(r.Context())
+				log.Printf("router: panic serving %s %s, request_id = %s, err = %v\n%s", r.Method, r.URL.Path, id, v, debug.Stack())
+				if rec.status != 0 {
+					return
+				}
+				handler.WriteError(w, r, handler.ErrInternal)
+			}()
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
+
+// AccessLog returns the middleware logging each request with the status code, the number of bytes
+// of the response body and the latency to logger. A nil logger means the standard logger.
+// It should wrap Recover, so that the status code of a recovered panic is logged.
+func AccessLog(logger *log.Logger) Middleware {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &responseRecorder{ResponseWriter: w}
+			defer func() {
+				status := rec.status
+				if status == 0 {
+					// the server responds 200 to a handler writing nothing
+					status = http.StatusOK
+				}
+				logger.Printf("router: access method=%s path=%s status=%d bytes=%d latency=%s request_id=%s remote=%s",
+					r.Method, strconv.Quote(r.URL.Path), status, rec.bytes, time.Since(start), RequestIDFromContext(r.Context()), r.RemoteAddr)
+			}()
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
+
+// A RequestObserver observes the requests served, such as to export metrics.
+type RequestObserver interface {
+	// ObserveRequest is called after each request with the pattern it is routed to, which is empty
+	// when it matches no pattern, the method as labeled by metrics.Method, the status code of the response and the latency.
+	ObserveRequest(route, method string, status int, elapsed time.Duration)
+}
+
+// Observe returns the middleware reporting each request to o with the pattern m routes it to.
+// It should wrap Recover, so that the status code of a recovered panic is reported.
+func Observe(o RequestObserver, m *mux.Router) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &responseRecorder{ResponseWriter: w}
+			defer func() {
+				status := rec.status
+				if status == 0 {
+					status = http.StatusOK
+				}
+				o.ObserveRequest(m.Pattern(r), metrics.Method(r.Method), status, time.Since(start))
+			}()
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
+
+// A responseRecorder records the status code and the number of bytes of the response it writes.
+type responseRecorder struct {
+	http.ResponseWriter
+	// status is 0 until the response is started.
+	status int
+	bytes  int64
+}
+
+// WriteHeader implements http.ResponseWriter interface.
+func (w *responseRecorder) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements http.ResponseWriter interface.
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// validRequestID reports whether id is a non-empty request ID of printable ASCII characters without spaces.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random request ID.
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// the ID only has to be unique enough to correlate logs
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,65 @@
+package router
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/TechBowl-japan/go-stations/handler/mux"
+)
+
+// A Middleware wraps a handler with behavior shared by many routes.
+type Middleware func(http.Handler) http.Handler
+
+// Chain returns the middleware applying middlewares in order, the first being the outermost.
+func Chain(middlewares ...Middleware) Middleware {
+	return func(h http.Handler) http.Handler {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			h = middlewares[i](h)
+		}
+		return h
+	}
+}
+
+// A Group registers routes sharing a path prefix and a middleware stack to mux.Router.
+//
+// The handler of a route is wrapped by the middlewares of its groups from the outermost group,
+// then by those given on registration. Middlewares wrapping the whole router run before any of them,
+// and are the only ones to run for requests not routed to a handler, such as those answered with 404 or 405.
+type Group struct {
+	mux         *mux.Router
+	prefix      string
+	middlewares []Middleware
+}
+
+// NewGroup returns the root group of m, whose routes are wrapped by middlewares.
+func NewGroup(m *mux.Router, middlewares ...Middleware) *Group {
+	return &Group{mux: m, middlewares: middlewares}
+}
+
+// Group returns the group of routes prefixed by prefix inside g, whose routes are wrapped
+// by middlewares inside those of g.
+func (g *Group) Group(prefix string, middlewares ...Middleware) *Group {
+	stack := make([]Middleware, 0, len(g.middlewares)+len(middlewares))
+	stack = append(stack, g.middlewares...)
+	stack = append(stack, middlewares...)
+	return &Group{mux: g.mux, prefix: g.prefix + prefix, middlewares: stack}
+}
+
+// Handle registers h for method and pattern prefixed by g, wrapped by middlewares inside those of g.
+func (g *Group) Handle(method, pattern string, h http.Handler, middlewares ...Middleware) {
+	h = Chain(middlewares...)(h)
+	h = Chain(g.middlewares...)(h)
+	g.mux.Handle(method, g.prefix+pattern, h)
+}
+
+// HandleFunc registers f for method and pattern prefixed by g, wrapped by middlewares inside those of g.
+func (g *Group) HandleFunc(method, pattern string, f func(http.ResponseWriter, *http.Request), middlewares ...Middleware) {
+	g.Handle(method, pattern, http.HandlerFunc(f), middlewares...)
+}
+
+// WithValue returns a shallow copy of r whose context carries val by key,
+// so that a middleware passes a request-scoped value to the handlers it wraps.
+// As with context.WithValue, key should be of an unexported type of the package defining it.
+func WithValue(r *http.Request, key, val interface{}) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), key, val))
+}
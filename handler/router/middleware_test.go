@@ -0,0 +1,236 @@
+package router_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/TechBowl-japan/go-stations/handler/mux"
+	"github.com/TechBowl-japan/go-stations/handler/router"
+	"github.com/TechBowl-japan/go-stations/ratelimit"
+)
+
+func TestGroup(t *testing.T) {
+	t.Parallel()
+
+	type traceKey struct{}
+	trace := func(name string) router.Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls, _ := r.Context().Value(traceKey{}).([]string)
+				next.ServeHTTP(w, router.WithValue(r, traceKey{}, append(calls, name)))
+			})
+		}
+	}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		calls, _ := r.Context().Value(traceKey{}).([]string)
+		io.WriteString(w, strings.Join(calls, ","))
+	}
+
+	m := mux.New()
+	root := router.NewGroup(m, trace("root"))
+	root.HandleFunc(http.MethodGet, "/healthz", handler)
+	todos := root.Group("/todos", trace("todos 1"), trace("todos 2"))
+	todos.HandleFunc(http.MethodGet, "", handler)
+	todos.HandleFunc(http.MethodGet, "/{id}", handler, trace("route 1"), trace("route 2"))
+	todos.Group("/{id}/revisions", trace("revisions")).HandleFunc(http.MethodGet, "", handler, trace("route"))
+	h := router.Chain(trace("global 1"), trace("global 2"))(m)
+
+	cases := map[string]struct {
+		path, want string
+	}{
+		"Root":         {path: "/healthz", want: "global 1,global 2,root"},
+		"Group":        {path: "/todos", want: "global 1,global 2,root,todos 1,todos 2"},
+		"Route":        {path: "/todos/1", want: "global 1,global 2,root,todos 1,todos 2,route 1,route 2"},
+		"Nested group": {path: "/todos/1/revisions", want: "global 1,global 2,root,todos 1,todos 2,revisions,route"},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, c.path, nil))
+			if got := w.Body.String(); got != c.want {
+				t.Errorf("unexpected middlewares, given = %q, expected = %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	t.Parallel()
+
+	h := router.RequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, router.RequestIDFromContext(r.Context()))
+	}))
+
+	cases := map[string]struct {
+		given     string
+		propagate bool
+	}{
+		"Propagated": {given: "abc-123", propagate: true},
+		"Generated":  {given: ""},
+		"Malformed":  {given: "has space"},
+		"Too long":   {given: strings.Repeat("a", 129)},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(router.RequestIDHeader, c.given)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			id := w.Header().Get(router.RequestIDHeader)
+			if id == "" || id != w.Body.String() || (id == c.given) != c.propagate {
+				t.Errorf("unexpected request id, given = %q, context = %q", id, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRecover(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		handler http.HandlerFunc
+		code    int
+		body    string
+	}{
+		"Panic": {
+			handler: func(http.ResponseWriter, *http.Request) { panic("boom") },
+			code:    http.StatusInternalServerError,
+			body:    `{"type":"urn:todo:problem:internal","title":"Internal server error","status":500,"detail":"The server failed to handle the request.","request_id":"id"}`,
+		},
+		"Panic after response": {
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+				panic("boom")
+			},
+			code: http.StatusAccepted,
+		},
+		"No panic": {
+			handler: func(w http.ResponseWriter, _ *http.Request) { io.WriteString(w, "ok") },
+			code:    http.StatusOK,
+			body:    "ok",
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(router.RequestIDHeader, "id")
+			w := httptest.NewRecorder()
+			router.Chain(router.RequestID(), router.Recover())(c.handler).ServeHTTP(w, req)
+
+			if w.Code != c.code {
+				t.Errorf("unexpected status code, given = %d, expected = %d", w.Code, c.code)
+			}
+			if got := strings.TrimSpace(w.Body.String()); got != c.body {
+				t.Errorf("unexpected body, given = %s, expected = %s", got, c.body)
+			}
+		})
+	}
+
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Errorf("unexpected panic, given = %v", v)
+		}
+	}()
+	router.Recover()(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic(http.ErrAbortHandler)
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestAccessLog(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	h := router.Chain(router.RequestID(), router.AccessLog(log.New(&buf, "", 0)), router.Recover())(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/panic" {
+				panic("boom")
+			}
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]string{"a": "b"})
+		}))
+
+	for _, path := range []string{"/todos", "/panic"} {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set(router.RequestIDHeader, "id")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	expected := []*regexp.Regexp{
+		regexp.MustCompile(`^router: access method=POST path="/todos" status=201 bytes=10 latency=\S+ request_id=id remote=\S+$`),
+		regexp.MustCompile(`^router: access method=POST path="/panic" status=500 bytes=\d+ latency=\S+ request_id=id remote=\S+$`),
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("unexpected log, given = %q", buf.String())
+	}
+	for i, re := range expected {
+		if !re.MatchString(lines[i]) {
+			t.Errorf("unexpected log line, given = %q, expected = %s", lines[i], re)
+		}
+	}
+}
+
+func TestShed(t *testing.T) {
+	t.Parallel()
+
+	entered, release := make(chan struct{}), make(chan struct{})
+	h := router.Shed(ratelimit.NewGate(1))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+	}))
+
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/todos", nil))
+		done <- w.Code
+	}()
+	<-entered
+
+	// the gate is full while the first request is in flight
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/todos", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("unexpected status code, given = %d, expected = %d", w.Code, http.StatusServiceUnavailable)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("unexpected Retry-After, given = %q, expected = %q", got, "1")
+	}
+	var problem struct {
+		Type string `json:"type"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&problem); err != nil {
+		t.Fatal("failed to decode response, err =", err)
+	}
+	if problem.Type != "urn:todo:problem:overloaded" {
+		t.Errorf("unexpected problem type, given = %s", problem.Type)
+	}
+
+	close(release)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("unexpected status code of request in flight, given = %d, expected = %d", code, http.StatusOK)
+	}
+	go func() { <-entered }()
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/todos", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("unexpected status code after leave, given = %d, expected = %d", w.Code, http.StatusOK)
+	}
+}
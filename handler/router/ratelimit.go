@@ -0,0 +1,106 @@
+package router
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/handler"
+	"github.com/TechBowl-japan/go-stations/ratelimit"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+// shedRetryAfter is the Retry-After of the requests shed under load, long enough for the requests in flight to finish.
+const shedRetryAfter = time.Second
+
+// RateLimit returns the middleware limiting the rate of requests of each remote IP address, taking a token of read
+// for the safe methods and of write for the others, so that reading clients are not starved by writing ones.
+// It should run before the authentication, so that the requests with made-up credentials are limited as well.
+// X-Forwarded-For is not trusted, since any client could make one up to get a new bucket.
+// Each response carries the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers of the bucket,
+// and a request over the limit is answered with 429 and the Retry-After header.
+// A nil limiter leaves the requests of the methods unlimited.
+func RateLimit(read, write *ratelimit.Limiter) Middleware {
+	return rateLimit(read, write, addrKey)
+}
+
+// RateLimitUser returns the middleware limiting the rate of requests of each authenticated user as RateLimit does,
+// so that a user is limited across addresses. It must follow the authentication, and leaves anonymous requests to RateLimit.
+// The headers are of the bucket of the user when it has fewer requests remaining than the bucket of the address.
+func RateLimitUser(read, write *ratelimit.Limiter) Middleware {
+	return rateLimit(read, write, userKey)
+}
+
+// rateLimit returns the middleware limiting the rate of requests by the buckets of the keys returned by key.
+// The requests of the empty key are not limited.
+func rateLimit(read, write *ratelimit.Limiter, key func(r *http.Request) string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			l := write
+			if safeMethod(r.Method) {
+				l = read
+			}
+			k := key(r)
+			if l == nil || k == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			d := l.Allow(k)
+			h := w.Header()
+			if remaining, err := strconv.Atoi(h.Get("RateLimit-Remaining")); err != nil || d.Remaining < remaining || !d.Allowed {
+				h.Set("RateLimit-Limit", strconv.Itoa(d.Limit))
+				h.Set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+				h.Set("RateLimit-Reset", seconds(d.Reset))
+			}
+			if !d.Allowed {
+				h.Set("Retry-After", seconds(d.RetryAfter))
+				handler.WriteError(w, r, handler.ErrRateLimited)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Shed returns the middleware answering requests with 503 and the Retry-After header while g is full,
+// so that the excess requests fail fast instead of queueing for the database until they time out.
+func Shed(g *ratelimit.Gate) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !g.Enter() {
+				w.Header().Set("Retry-After", seconds(shedRetryAfter))
+				handler.WriteError(w, r, handler.ErrOverloaded)
+				return
+			}
+			defer g.Leave()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// addrKey returns the key of the bucket of the remote IP address of r.
+// The credentials of r are not used, since a client could make up new ones to get a new bucket.
+func addrKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// userKey returns the key of the bucket of the user r is authenticated as, which is empty for anonymous requests.
+func userKey(r *http.Request) string {
+	user := service.UserFromContext(r.Context())
+	if user == nil {
+		return ""
+	}
+	return "user:" + strconv.FormatInt(user.ID, 10)
+}
+
+// seconds formats d as the number of seconds rounded up, as the headers of rate limits are given.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -2,11 +2,209 @@ package router
 
 import (
 	"database/sql"
+	"log"
 	"net/http"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/handler"
+	"github.com/TechBowl-japan/go-stations/handler/mux"
+	"github.com/TechBowl-japan/go-stations/metrics"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/oidc"
+	"github.com/TechBowl-japan/go-stations/ratelimit"
+	"github.com/TechBowl-japan/go-stations/service"
 )
 
-func NewRouter(todoDB *sql.DB) *http.ServeMux {
+// NewRouter returns the router serving TODOs stored in todoDB.
+func NewRouter(todoDB *sql.DB) http.Handler {
+	return NewRouterWithRepository(service.NewSQLiteTODORepository(todoDB))
+}
+
+// NewRouterWithRepository returns the router serving TODOs stored in repo with the access log written by the standard logger.
+func NewRouterWithRepository(repo service.TODORepository) http.Handler {
+	return NewRouterWithOptions(repo, &Options{AccessLog: log.Default()})
+}
+
+// Options configures the router.
+type Options struct {
+	// AccessLog is the logger of the access log, which is not written when it is nil.
+	AccessLog *log.Logger
+	// HealthCheckTimeout is how long each readiness check may take, DefaultHealthCheckTimeout when it is zero.
+	HealthCheckTimeout time.Duration
+	// Checkers are run by the readiness check in addition to the checkers of the repository.
+	Checkers []*service.Checker
+	// Metrics records the requests and the calls of TODOService, which are not recorded when it is nil.
+	Metrics *metrics.Metrics
+	// ServeMetrics exposes Metrics at /metrics, which is false when they are served by another listener.
+	ServeMetrics bool
+	// RequireAuth answers the requests for TODOs and tags without an API token or a session with 401,
+	// which are served anonymously otherwise. Tokens are accepted only when the repository stores users.
+	RequireAuth bool
+	// SessionTTL is how long sessions last after login, service.DefaultSessionTTL when it is zero.
+	SessionTTL time.Duration
+	// SessionRotateInterval is how often the tokens of sessions are replaced,
+	// service.DefaultSessionRotateInterval when it is zero.
+	SessionRotateInterval time.Duration
+	// InsecureCookies lets the cookies of sessions be sent over plain HTTP, such as in local development.
+	InsecureCookies bool
+	// OIDC logs users in with an OpenID Connect provider at /login/oidc when the repository stores
+	// sessions and identities, creating the users on their first login. It is disabled when nil.
+	OIDC *oidc.RelyingParty
+	// OIDCRedirect is where the user agent logged in with the provider is redirected to, / when it is empty.
+	OIDCRedirect string
+	// ReadLimit and WriteLimit limit the rates of the requests for the API with the safe methods and with the others
+	// respectively, of each IP address and of each authenticated user. The requests are not limited when they are nil.
+	ReadLimit, WriteLimit *ratelimit.Limiter
+	// MaxInFlight is the number of the requests for the API served at once, beyond which the requests
+	// are answered with 503. The requests are not limited when it is zero.
+	MaxInFlight int
+}
+
+// DefaultHealthCheckTimeout is the timeout of each readiness check unless Options gives one.
+const DefaultHealthCheckTimeout = 2 * time.Second
+
+// NewRouterWithOptions returns the router serving TODOs stored in repo configured by opts.
+// Every request is given a request ID, logged, recorded by the metrics if any, and answered with 500 when a handler panics.
+// The requests for TODOs and tags are authenticated by API tokens when repo stores users,
+// and by cookie sessions logged in at /login, or at /login/oidc with the provider of opts, when repo stores sessions.
+// The requests for the API, other than the health checks and the metrics, are limited by the rates
+// and the number in flight of opts, so that a client does not saturate the database.
+// Errors, including unknown routes and methods, are answered with problem details.
+func NewRouterWithOptions(repo service.TODORepository, opts *Options) http.Handler {
 	// register routes
-	mux := http.NewServeMux()
-	return mux
+	m := mux.New()
+	m.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.WriteError(w, r, &model.ErrNotFound{})
+	})
+	m.MethodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.WriteError(w, r, handler.ErrMethodNotAllowed)
+	})
+	root := NewGroup(m)
+	root.Handle(http.MethodGet, "/healthz", handler.NewHealthzHandler())
+
+	timeout := opts.HealthCheckTimeout
+	if timeout == 0 {
+		timeout = DefaultHealthCheckTimeout
+	}
+	health := service.NewHealthService(timeout)
+	if repo, ok := repo.(service.CheckedRepository); ok {
+		for _, c := range repo.Checkers() {
+			health.Register(c)
+		}
+	}
+	for _, c := range opts.Checkers {
+		health.Register(c)
+	}
+	checks := handler.NewHealthCheckHandler(health)
+	root.HandleFunc(http.MethodGet, "/healthz/live", checks.ServeLive)
+	root.HandleFunc(http.MethodGet, "/healthz/ready", checks.ServeReady)
+
+	if opts.Metrics != nil && opts.ServeMetrics {
+		root.Handle(http.MethodGet, "/metrics", opts.Metrics.Handler())
+	}
+
+	// the rate limits of addresses run first so that the requests over them do not take the slots in flight,
+	// nor reach the authentication with made-up credentials
+	var limits []Middleware
+	limited := opts.ReadLimit != nil || opts.WriteLimit != nil
+	if limited {
+		limits = append(limits, RateLimit(opts.ReadLimit, opts.WriteLimit))
+	}
+	if opts.MaxInFlight > 0 {
+		limits = append(limits, Shed(ratelimit.NewGate(opts.MaxInFlight)))
+	}
+	api := root.Group("", limits...)
+
+	var auth []Middleware
+	if repo, ok := repo.(service.SessionRepository); ok {
+		svc := service.NewSessionServiceWithRepository(repo)
+		ttl, rotate := opts.SessionTTL, opts.SessionRotateInterval
+		if ttl == 0 {
+			ttl = service.DefaultSessionTTL
+		}
+		if rotate == 0 {
+			rotate = service.DefaultSessionRotateInterval
+		}
+		svc.SetLifetime(ttl, rotate)
+		sessions := handler.NewSessionHandler(svc, !opts.InsecureCookies)
+		auth = append(auth, Sessions(sessions))
+		api.HandleFunc(http.MethodGet, "/login", sessions.ServeCSRF)
+		api.HandleFunc(http.MethodPost, "/login", sessions.ServeLogin)
+		api.HandleFunc(http.MethodPost, "/logout", sessions.ServeLogout, auth...)
+
+		if repo, ok := repo.(service.IdentityRepository); ok && opts.OIDC != nil {
+			redirect := opts.OIDCRedirect
+			if redirect == "" {
+				redirect = "/"
+			}
+			h := handler.NewOIDCHandler(opts.OIDC, service.NewIdentityServiceWithRepository(repo), sessions, redirect)
+			api.HandleFunc(http.MethodGet, "/login/oidc", h.ServeLogin)
+			api.HandleFunc(http.MethodGet, "/login/oidc/callback", h.ServeCallback)
+		}
+	}
+	if repo, ok := repo.(service.UserRepository); ok {
+		auth = append(auth, Authenticate(service.NewUserServiceWithRepository(repo), opts.RequireAuth))
+	}
+	if limited {
+		auth = append(auth, RateLimitUser(opts.ReadLimit, opts.WriteLimit))
+	}
+
+	svc := service.NewTODOServiceWithRepository(repo)
+	if opts.Metrics != nil {
+		svc.SetObserver(opts.Metrics)
+	}
+	todos := handler.NewTODOHandler(svc)
+	g := api.Group("/todos", auth...)
+	g.HandleFunc(http.MethodGet, "", todos.ServeRead)
+	g.HandleFunc(http.MethodPost, "", todos.ServeCreate)
+	g.HandleFunc(http.MethodPut, "", todos.ServeUpdate)
+	g.HandleFunc(http.MethodDelete, "", todos.ServeDelete)
+	g.HandleFunc(http.MethodGet, "/{id}", todos.ServeGet)
+	g.HandleFunc(http.MethodPut, "/{id}", todos.ServeUpdate)
+	g.HandleFunc(http.MethodPatch, "/{id}", todos.ServePatch)
+	g.HandleFunc(http.MethodDelete, "/{id}", todos.ServeDelete)
+	g.HandleFunc(http.MethodPost, "/{id}/complete", todos.ServeComplete)
+	g.HandleFunc(http.MethodPost, "/{id}/reopen", todos.ServeReopen)
+	g.HandleFunc(http.MethodGet, "/{id}/occurrences", todos.ServeOccurrences)
+	g.HandleFunc(http.MethodGet, "/{id}/history", todos.ServeHistory)
+	g.HandleFunc(http.MethodGet, "/{id}/revisions", todos.ServeRevisions)
+	g.HandleFunc(http.MethodGet, "/{id}/diff", todos.ServeDiff)
+	g.HandleFunc(http.MethodPost, "/{id}/revert", todos.ServeRevert)
+	g.Handle(http.MethodGet, "/overdue", todos.ServeDue(model.DueViewOverdue))
+	g.Handle(http.MethodGet, "/today", todos.ServeDue(model.DueViewToday))
+	g.Handle(http.MethodGet, "/upcoming", todos.ServeDue(model.DueViewUpcoming))
+	g.HandleFunc(http.MethodGet, "/trash", todos.ServeTrash)
+	g.HandleFunc(http.MethodPost, "/restore", todos.ServeRestore)
+
+	if repo, ok := repo.(service.ListRepository); ok {
+		lists := handler.NewListHandler(service.NewListServiceWithRepository(repo))
+		g := api.Group("/lists", auth...)
+		g.HandleFunc(http.MethodGet, "", lists.ServeRead)
+		g.HandleFunc(http.MethodPost, "", lists.ServeCreate)
+		g.HandleFunc(http.MethodGet, "/{id}", lists.ServeGet)
+		g.HandleFunc(http.MethodPut, "/{id}", lists.ServeUpdate)
+		g.HandleFunc(http.MethodDelete, "/{id}", lists.ServeDelete)
+		g.HandleFunc(http.MethodGet, "/{id}/members", lists.ServeMembers)
+		g.HandleFunc(http.MethodPut, "/{id}/members/{name}", lists.ServePutMember)
+		g.HandleFunc(http.MethodDelete, "/{id}/members/{name}", lists.ServeDeleteMember)
+	}
+
+	if repo, ok := repo.(service.TagRepository); ok {
+		tags := handler.NewTagHandler(service.NewTagServiceWithRepository(repo))
+		g := api.Group("/tags", auth...)
+		g.HandleFunc(http.MethodGet, "", tags.ServeRead)
+		g.HandleFunc(http.MethodPost, "/rename", tags.ServeRename)
+		g.HandleFunc(http.MethodPost, "/merge", tags.ServeMerge)
+	}
+
+	// the request ID is given first so that the access log and the recovered panics are logged with it
+	middlewares := []Middleware{RequestID()}
+	if opts.AccessLog != nil {
+		middlewares = append(middlewares, AccessLog(opts.AccessLog))
+	}
+	if opts.Metrics != nil {
+		middlewares = append(middlewares, Observe(opts.Metrics, m))
+	}
+	middlewares = append(middlewares, Recover())
+	return Chain(middlewares...)(m)
 }
@@ -0,0 +1,1523 @@
+package router_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/TechBowl-japan/go-stations/handler"
+	"github.com/TechBowl-japan/go-stations/handler/router"
+	"github.com/TechBowl-japan/go-stations/metrics"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/oidc"
+	"github.com/TechBowl-japan/go-stations/oidc/oidctest"
+	"github.com/TechBowl-japan/go-stations/ratelimit"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestNewRouterWithRepository(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(router.NewRouterWithRepository(service.NewMemoryTODORepository()))
+	t.Cleanup(srv.Close)
+
+	resp, err := http.Post(srv.URL+"/todos", "application/json", bytes.NewBufferString(`{"subject":"subject"}`))
+	if err != nil {
+		t.Fatal("failed to send request, err =", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status code, given = %d, expected = %d", resp.StatusCode, http.StatusOK)
+	}
+	if resp.Header.Get(router.RequestIDHeader) == "" {
+		t.Error("unexpected empty request id")
+	}
+
+	resp, err = http.Get(srv.URL + "/todos")
+	if err != nil {
+		t.Fatal("failed to send request, err =", err)
+	}
+	defer resp.Body.Close()
+
+	var body model.ReadTODOResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal("failed to decode response, err =", err)
+	}
+	if len(body.TODOs) != 1 || body.TODOs[0].Subject != "subject" {
+		t.Errorf("unexpected todos, given = %+v", body.TODOs)
+	}
+}
+
+func TestNewRouterWithRepository_Tags(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(router.NewRouterWithRepository(service.NewMemoryTODORepository()))
+	t.Cleanup(srv.Close)
+
+	for _, body := range []string{
+		`{"subject":"subject 1","tags":["work"]}`,
+		`{"subject":"subject 2","tags":["home"]}`,
+		`{"subject":"subject 3","tags":["errand"]}`,
+	} {
+		resp, err := http.Post(srv.URL+"/todos", "application/json", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatal("failed to send request, err =", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("unexpected status code, given = %d, expected = %d", resp.StatusCode, http.StatusOK)
+		}
+	}
+
+	cases := map[string]struct {
+		method, path, body string
+		code               int
+	}{
+		"Filter":             {method: http.MethodGet, path: "/todos?tag=work", code: http.StatusOK},
+		"Invalid tag mode":   {method: http.MethodGet, path: "/todos?tag=work&tag_mode=none", code: http.StatusBadRequest},
+		"Read tags":          {method: http.MethodGet, path: "/tags", code: http.StatusOK},
+		"Rename conflict":    {method: http.MethodPost, path: "/tags/rename", body: `{"from":"work","to":"HOME"}`, code: http.StatusConflict},
+		"Rename missing":     {method: http.MethodPost, path: "/tags/rename", body: `{"from":"missing","to":"other"}`, code: http.StatusNotFound},
+		"Rename empty":       {method: http.MethodPost, path: "/tags/rename", body: `{"from":"work","to":" "}`, code: http.StatusBadRequest},
+		"Merge":              {method: http.MethodPost, path: "/tags/merge", body: `{"sources":["errand"],"into":"chore"}`, code: http.StatusOK},
+		"Method not allowed": {method: http.MethodDelete, path: "/tags", code: http.StatusMethodNotAllowed},
+		"Unknown path":       {method: http.MethodGet, path: "/tags/unknown", code: http.StatusNotFound},
+	}
+	for name, c := range cases {
+		req, err := http.NewRequest(c.method, srv.URL+c.path, bytes.NewBufferString(c.body))
+		if err != nil {
+			t.Fatal("failed to create request, err =", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal("failed to send request, err =", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != c.code {
+			t.Errorf("%s: unexpected status code, given = %d, expected = %d", name, resp.StatusCode, c.code)
+		}
+	}
+}
+
+func TestNewRouterWithRepository_Complete(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(router.NewRouterWithRepository(service.NewMemoryTODORepository()))
+	t.Cleanup(srv.Close)
+
+	for _, subject := range []string{"subject 1", "subject 2"} {
+		resp, err := http.Post(srv.URL+"/todos", "application/json", bytes.NewBufferString(`{"subject":"`+subject+`"}`))
+		if err != nil {
+			t.Fatal("failed to send request, err =", err)
+		}
+		resp.Body.Close()
+	}
+
+	resp, err := http.Post(srv.URL+"/todos/1/complete", "application/json", nil)
+	if err != nil {
+		t.Fatal("failed to send request, err =", err)
+	}
+	defer resp.Body.Close()
+	var completed model.CompleteTODOResponse
+	if err := json.NewDecoder(resp.Body).Decode(&completed); err != nil {
+		t.Fatal("failed to decode response, err =", err)
+	}
+	if !completed.TODO.Completed || completed.TODO.CompletedAt == nil {
+		t.Errorf("unexpected todo, given = %+v", completed.TODO)
+	}
+
+	cases := map[string]struct {
+		method, path string
+		code         int
+	}{
+		"Open":               {method: http.MethodGet, path: "/todos?status=open", code: http.StatusOK},
+		"Invalid status":     {method: http.MethodGet, path: "/todos?status=closed", code: http.StatusBadRequest},
+		"Reopen open todo":   {method: http.MethodPost, path: "/todos/2/reopen", code: http.StatusOK},
+		"Complete missing":   {method: http.MethodPost, path: "/todos/3/complete", code: http.StatusNotFound},
+		"Method not allowed": {method: http.MethodGet, path: "/todos/1/complete", code: http.StatusMethodNotAllowed},
+		"Invalid id":         {method: http.MethodPost, path: "/todos/one/complete", code: http.StatusNotFound},
+		"Unknown action":     {method: http.MethodPost, path: "/todos/1/archive", code: http.StatusNotFound},
+	}
+	for name, c := range cases {
+		req, err := http.NewRequest(c.method, srv.URL+c.path, nil)
+		if err != nil {
+			t.Fatal("failed to create request, err =", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal("failed to send request, err =", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != c.code {
+			t.Errorf("%s: unexpected status code, given = %d, expected = %d", name, resp.StatusCode, c.code)
+		}
+	}
+
+	resp, err = http.Get(srv.URL + "/todos?status=done")
+	if err != nil {
+		t.Fatal("failed to send request, err =", err)
+	}
+	defer resp.Body.Close()
+	var body model.ReadTODOResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal("failed to decode response, err =", err)
+	}
+	if len(body.TODOs) != 1 || body.TODOs[0].ID != 1 {
+		t.Errorf("unexpected todos, given = %+v", body.TODOs)
+	}
+}
+
+func TestNewRouterWithRepository_Due(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(router.NewRouterWithRepository(service.NewMemoryTODORepository()))
+	t.Cleanup(srv.Close)
+
+	now := time.Now()
+	for _, dueAt := range []time.Time{now.AddDate(0, 0, -2), now.AddDate(0, 0, 2)} {
+		body := `{"subject":"subject","due_at":"` + dueAt.Format(time.RFC3339) + `"}`
+		resp, err := http.Post(srv.URL+"/todos", "application/json", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatal("failed to send request, err =", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("unexpected status code, given = %d, expected = %d", resp.StatusCode, http.StatusOK)
+		}
+	}
+
+	cases := map[string]struct {
+		method, path, body string
+		code               int
+		ids                []int64
+	}{
+		"Overdue":            {method: http.MethodGet, path: "/todos/overdue", code: http.StatusOK, ids: []int64{1}},
+		"Today":              {method: http.MethodGet, path: "/todos/today", code: http.StatusOK, ids: []int64{}},
+		"Upcoming":           {method: http.MethodGet, path: "/todos/upcoming?days=3", code: http.StatusOK, ids: []int64{2}},
+		"Upcoming too short": {method: http.MethodGet, path: "/todos/upcoming?days=0", code: http.StatusBadRequest},
+		"Upcoming too long":  {method: http.MethodGet, path: "/todos/upcoming?days=367", code: http.StatusBadRequest},
+		"Method not allowed": {method: http.MethodPost, path: "/todos/today", code: http.StatusMethodNotAllowed},
+		"Unknown view":       {method: http.MethodGet, path: "/todos/tomorrow", code: http.StatusNotFound},
+	}
+	for name, c := range cases {
+		req, err := http.NewRequest(c.method, srv.URL+c.path, nil)
+		if err != nil {
+			t.Fatal("failed to create request, err =", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal("failed to send request, err =", err)
+		}
+		if resp.StatusCode != c.code {
+			t.Errorf("%s: unexpected status code, given = %d, expected = %d", name, resp.StatusCode, c.code)
+		}
+		if c.ids != nil {
+			var body model.ReadTODOResponse
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("%s: failed to decode response, err = %v", name, err)
+			}
+			if len(body.TODOs) != len(c.ids) || (len(c.ids) > 0 && body.TODOs[0].ID != c.ids[0]) {
+				t.Errorf("%s: unexpected todos, given = %+v", name, body.TODOs)
+			}
+		}
+		resp.Body.Close()
+	}
+
+	// null removes the due date while omission keeps it
+	for _, c := range []struct {
+		body string
+		due  bool
+	}{
+		{body: `{"id":1,"subject":"updated"}`, due: true},
+		{body: `{"id":1,"subject":"updated","due_at":null}`, due: false},
+	} {
+		req, err := http.NewRequest(http.MethodPut, srv.URL+"/todos", bytes.NewBufferString(c.body))
+		if err != nil {
+			t.Fatal("failed to create request, err =", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal("failed to send request, err =", err)
+		}
+		var body model.UpdateTODOResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal("failed to decode response, err =", err)
+		}
+		resp.Body.Close()
+		if (body.TODO.DueAt != nil) != c.due {
+			t.Errorf("unexpected due_at of %s, given = %v", c.body, body.TODO.DueAt)
+		}
+	}
+}
+
+func TestNewRouterWithRepository_Recurrence(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(router.NewRouterWithRepository(service.NewMemoryTODORepository()))
+	t.Cleanup(srv.Close)
+
+	for _, body := range []string{
+		`{"subject":"subject","due_at":"2026-10-19T09:00:00+09:00","recurrence":{"frequency":"weekly","weekdays":["mo","we"]}}`,
+		`{"subject":"subject","recurrence":{"frequency":"weekly"}}`,
+		`{"subject":"subject","due_at":"2026-10-19T09:00:00+09:00","recurrence":{"rrule":"FREQ=HOURLY"}}`,
+	} {
+		resp, err := http.Post(srv.URL+"/todos", "application/json", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatal("failed to send request, err =", err)
+		}
+		resp.Body.Close()
+	}
+
+	resp, err := http.Post(srv.URL+"/todos/1/complete", "application/json", nil)
+	if err != nil {
+		t.Fatal("failed to send request, err =", err)
+	}
+	defer resp.Body.Close()
+	var completed model.CompleteTODOResponse
+	if err := json.NewDecoder(resp.Body).Decode(&completed); err != nil {
+		t.Fatal("failed to decode response, err =", err)
+	}
+	if completed.Next == nil || completed.Next.ID != 2 || completed.Next.Recurrence == nil {
+		t.Fatalf("unexpected next occurrence, given = %+v", completed.Next)
+	}
+
+	resp, err = http.Get(srv.URL + "/todos/2/occurrences?count=2")
+	if err != nil {
+		t.Fatal("failed to send request, err =", err)
+	}
+	defer resp.Body.Close()
+	var occurrences model.OccurrencesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&occurrences); err != nil {
+		t.Fatal("failed to decode response, err =", err)
+	}
+	want := time.Date(2026, time.October, 26, 0, 0, 0, 0, time.UTC)
+	if len(occurrences.Occurrences) != 2 || !occurrences.Occurrences[0].Equal(want) {
+		t.Errorf("unexpected occurrences, given = %v", occurrences.Occurrences)
+	}
+
+	cases := map[string]struct {
+		method, path string
+		code         int
+	}{
+		"History":             {method: http.MethodGet, path: "/todos/2/history", code: http.StatusOK},
+		"Missing history":     {method: http.MethodGet, path: "/todos/3/history", code: http.StatusNotFound},
+		"Too many":            {method: http.MethodGet, path: "/todos/2/occurrences?count=101", code: http.StatusBadRequest},
+		"Method not allowed":  {method: http.MethodPost, path: "/todos/2/occurrences", code: http.StatusMethodNotAllowed},
+		"Invalid recurrences": {method: http.MethodPut, path: "/todos", code: http.StatusBadRequest},
+	}
+	for name, c := range cases {
+		req, err := http.NewRequest(c.method, srv.URL+c.path, bytes.NewBufferString(`{"id":2,"subject":"subject","recurrence":{"rrule":"FREQ=DAILY","frequency":"daily"}}`))
+		if err != nil {
+			t.Fatal("failed to create request, err =", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal("failed to send request, err =", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != c.code {
+			t.Errorf("%s: unexpected status code, given = %d, expected = %d", name, resp.StatusCode, c.code)
+		}
+	}
+}
+
+func TestNewRouterWithRepository_Trash(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(router.NewRouterWithRepository(service.NewMemoryTODORepository()))
+	t.Cleanup(srv.Close)
+
+	send := func(method, path, body string) *http.Response {
+		req, err := http.NewRequest(method, srv.URL+path, bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatal("failed to create request, err =", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal("failed to send request, err =", err)
+		}
+		return resp
+	}
+
+	for _, subject := range []string{"subject 1", "subject 2", "subject 3"} {
+		send(http.MethodPost, "/todos", `{"subject":"`+subject+`"}`).Body.Close()
+	}
+	send(http.MethodDelete, "/todos", `{"ids":[1,2]}`).Body.Close()
+
+	resp := send(http.MethodGet, "/todos/trash", "")
+	defer resp.Body.Close()
+	var trash model.ReadTODOResponse
+	if err := json.NewDecoder(resp.Body).Decode(&trash); err != nil {
+		t.Fatal("failed to decode response, err =", err)
+	}
+	if len(trash.TODOs) != 2 || trash.TODOs[0].ID != 2 || trash.TODOs[0].DeletedAt == nil {
+		t.Errorf("unexpected todos, given = %+v", trash.TODOs)
+	}
+
+	cases := map[string]struct {
+		method, path, body string
+		code               int
+	}{
+		"Trash with size":    {method: http.MethodGet, path: "/todos/trash?size=1", code: http.StatusOK},
+		"Invalid size":       {method: http.MethodGet, path: "/todos/trash?size=-1", code: http.StatusBadRequest},
+		"Delete trashed":     {method: http.MethodDelete, path: "/todos", body: `{"ids":[1]}`, code: http.StatusNotFound},
+		"Restore":            {method: http.MethodPost, path: "/todos/restore", body: `{"ids":[2]}`, code: http.StatusOK},
+		"Restore open todo":  {method: http.MethodPost, path: "/todos/restore", body: `{"ids":[3]}`, code: http.StatusNotFound},
+		"Restore no ids":     {method: http.MethodPost, path: "/todos/restore", body: `{"ids":[]}`, code: http.StatusBadRequest},
+		"Restore malformed":  {method: http.MethodPost, path: "/todos/restore", body: `{"ids":`, code: http.StatusBadRequest},
+		"Method not allowed": {method: http.MethodPost, path: "/todos/trash", code: http.StatusMethodNotAllowed},
+		"Restore by GET":     {method: http.MethodGet, path: "/todos/restore", code: http.StatusMethodNotAllowed},
+	}
+	for name, c := range cases {
+		resp := send(c.method, c.path, c.body)
+		resp.Body.Close()
+		if resp.StatusCode != c.code {
+			t.Errorf("%s: unexpected status code, given = %d, expected = %d", name, resp.StatusCode, c.code)
+		}
+	}
+
+	resp = send(http.MethodGet, "/todos", "")
+	defer resp.Body.Close()
+	var body model.ReadTODOResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal("failed to decode response, err =", err)
+	}
+	if len(body.TODOs) != 2 || body.TODOs[0].ID != 3 || body.TODOs[1].ID != 2 {
+		t.Errorf("unexpected todos, given = %+v", body.TODOs)
+	}
+}
+
+func TestNewRouterWithRepository_Revisions(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(router.NewRouterWithRepository(service.NewMemoryTODORepository()))
+	t.Cleanup(srv.Close)
+
+	send := func(method, path, body string) *http.Response {
+		req, err := http.NewRequest(method, srv.URL+path, bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatal("failed to create request, err =", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal("failed to send request, err =", err)
+		}
+		return resp
+	}
+
+	send(http.MethodPost, "/todos", `{"subject":"subject"}`).Body.Close()
+	send(http.MethodPut, "/todos", `{"id":1,"subject":"updated"}`).Body.Close()
+
+	resp := send(http.MethodPost, "/todos/1/revert?revision=1", "")
+	defer resp.Body.Close()
+	var reverted model.RevertResponse
+	if err := json.NewDecoder(resp.Body).Decode(&reverted); err != nil {
+		t.Fatal("failed to decode response, err =", err)
+	}
+	if reverted.TODO.Subject != "subject" || reverted.Revision == nil || reverted.Revision.Number != 3 {
+		t.Errorf("unexpected response, given = %+v", reverted)
+	}
+
+	resp = send(http.MethodGet, "/todos/1/diff?from=2&to=3", "")
+	defer resp.Body.Close()
+	var diff model.DiffResponse
+	if err := json.NewDecoder(resp.Body).Decode(&diff); err != nil {
+		t.Fatal("failed to decode response, err =", err)
+	}
+	if len(diff.Changes) != 1 || diff.Changes[0].Field != "subject" || diff.Changes[0].From != "updated" || diff.Changes[0].To != "subject" {
+		t.Errorf("unexpected changes, given = %+v", diff.Changes)
+	}
+
+	cases := map[string]struct {
+		method, path string
+		code         int
+	}{
+		"Revisions":          {method: http.MethodGet, path: "/todos/1/revisions?prev_revision=3&size=1", code: http.StatusOK},
+		"Missing todo":       {method: http.MethodGet, path: "/todos/2/revisions", code: http.StatusNotFound},
+		"Invalid size":       {method: http.MethodGet, path: "/todos/1/revisions?size=-1", code: http.StatusBadRequest},
+		"Missing revision":   {method: http.MethodGet, path: "/todos/1/diff?from=1&to=4", code: http.StatusNotFound},
+		"Diff without from":  {method: http.MethodGet, path: "/todos/1/diff?to=2", code: http.StatusBadRequest},
+		"Revert without rev": {method: http.MethodPost, path: "/todos/1/revert", code: http.StatusBadRequest},
+		"Revert by GET":      {method: http.MethodGet, path: "/todos/1/revert?revision=1", code: http.StatusMethodNotAllowed},
+	}
+	for name, c := range cases {
+		resp := send(c.method, c.path, "")
+		resp.Body.Close()
+		if resp.StatusCode != c.code {
+			t.Errorf("%s: unexpected status code, given = %d, expected = %d", name, resp.StatusCode, c.code)
+		}
+	}
+}
+
+func TestNewRouterWithRepository_IfMatch(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(router.NewRouterWithRepository(service.NewMemoryTODORepository()))
+	t.Cleanup(srv.Close)
+
+	put := func(ifMatch, body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPut, srv.URL+"/todos", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatal("failed to create request, err =", err)
+		}
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal("failed to send request, err =", err)
+		}
+		return resp
+	}
+
+	resp, err := http.Post(srv.URL+"/todos", "application/json", bytes.NewBufferString(`{"subject":"subject"}`))
+	if err != nil {
+		t.Fatal("failed to send request, err =", err)
+	}
+	resp.Body.Close()
+	created := resp.Header.Get("ETag")
+	if !strings.HasPrefix(created, `"`) || !strings.HasSuffix(created, `"`) || len(created) < 3 {
+		t.Fatalf("unexpected etag, given = %s", created)
+	}
+
+	resp = put(created, `{"id":1,"subject":"web"}`)
+	resp.Body.Close()
+	updated := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || updated == "" || updated == created {
+		t.Errorf("unexpected response, status = %d, etag = %s", resp.StatusCode, updated)
+	}
+
+	// the mobile client still has the created version
+	resp = put(created, `{"id":1,"subject":"mobile"}`)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPreconditionFailed || resp.Header.Get("ETag") != updated {
+		t.Errorf("unexpected response, status = %d, etag = %s", resp.StatusCode, resp.Header.Get("ETag"))
+	}
+	var current model.UpdateTODOResponse
+	if err := json.NewDecoder(resp.Body).Decode(&current); err != nil {
+		t.Fatal("failed to decode response, err =", err)
+	}
+	if current.TODO.Subject != "web" {
+		t.Errorf("unexpected todo, given = %+v", current.TODO)
+	}
+
+	version := strings.Trim(created, `"`)
+	cases := map[string]struct {
+		ifMatch, body string
+		code          int
+	}{
+		"Stale version field": {body: `{"id":1,"subject":"mobile","version":"` + version + `"}`, code: http.StatusPreconditionFailed},
+		"Any version":         {ifMatch: "*", body: `{"id":1,"subject":"web"}`, code: http.StatusOK},
+		"Weak etag":           {ifMatch: "W/" + created, body: `{"id":1,"subject":"web"}`, code: http.StatusPreconditionFailed},
+		"Unquoted etag":       {ifMatch: version, body: `{"id":1,"subject":"web"}`, code: http.StatusBadRequest},
+		"Stale etag list":     {ifMatch: `"other", ` + created, body: `{"id":1,"subject":"web"}`, code: http.StatusPreconditionFailed},
+		"Disagreeing version": {ifMatch: created, body: `{"id":1,"subject":"web","version":"other"}`, code: http.StatusPreconditionFailed},
+		"Missing todo":        {ifMatch: created, body: `{"id":2,"subject":"web"}`, code: http.StatusNotFound},
+	}
+	for name, c := range cases {
+		resp := put(c.ifMatch, c.body)
+		resp.Body.Close()
+		if resp.StatusCode != c.code {
+			t.Errorf("%s: unexpected status code, given = %d, expected = %d", name, resp.StatusCode, c.code)
+		}
+	}
+
+	// the strong comparison matches any ETag of the list, but never the weak ETag of the current version
+	resp = put("*", `{"id":1,"subject":"web"}`)
+	resp.Body.Close()
+	latest := resp.Header.Get("ETag")
+	resp = put("W/"+latest, `{"id":1,"subject":"weak"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPreconditionFailed || resp.Header.Get("ETag") != latest {
+		t.Errorf("unexpected response, status = %d, etag = %s", resp.StatusCode, resp.Header.Get("ETag"))
+	}
+	resp = put(created+", W/"+latest+", "+latest, `{"id":1,"subject":"listed"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") == latest {
+		t.Errorf("unexpected response, status = %d, etag = %s", resp.StatusCode, resp.Header.Get("ETag"))
+	}
+}
+
+func TestNewRouterWithRepository_Patch(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(router.NewRouterWithRepository(service.NewMemoryTODORepository()))
+	t.Cleanup(srv.Close)
+
+	patch := func(path, contentType, ifMatch, body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPatch, srv.URL+path, bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatal("failed to create request, err =", err)
+		}
+		req.Header.Set("Content-Type", contentType)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal("failed to send request, err =", err)
+		}
+		return resp
+	}
+	decode := func(resp *http.Response) *model.TODO {
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("unexpected status code, given = %d, expected = %d", resp.StatusCode, http.StatusOK)
+		}
+		var body model.UpdateTODOResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal("failed to decode response, err =", err)
+		}
+		return &body.TODO
+	}
+
+	resp, err := http.Post(srv.URL+"/todos", "application/json", bytes.NewBufferString(`{"subject":"subject","description":"keep","tags":["work"]}`))
+	if err != nil {
+		t.Fatal("failed to send request, err =", err)
+	}
+	resp.Body.Close()
+	created := resp.Header.Get("ETag")
+
+	resp = patch("/todos/1", "application/merge-patch+json", created, `{"subject":"merged","tags":["home"]}`)
+	etag := resp.Header.Get("ETag")
+	todo := decode(resp)
+	if todo.Subject != "merged" || todo.Description != "keep" || len(todo.Tags) != 1 || todo.Tags[0] != "home" {
+		t.Errorf("unexpected todo, given = %+v", todo)
+	}
+	if etag == "" || etag == created {
+		t.Errorf("unexpected etag, given = %s", etag)
+	}
+
+	todo = decode(patch("/todos/1", "application/json-patch+json; charset=utf-8", "", `[
+		{"op":"test","path":"/subject","value":"merged"},
+		{"op":"add","path":"/tags/-","value":"work"},
+		{"op":"remove","path":"/description"},
+		{"op":"add","path":"/due_at","value":"2026-10-20T09:00:00Z"}
+	]`))
+	if todo.Subject != "merged" || todo.Description != "" || len(todo.Tags) != 2 || todo.DueAt == nil {
+		t.Errorf("unexpected todo, given = %+v", todo)
+	}
+
+	cases := map[string]struct {
+		path, contentType, ifMatch, body string
+		code                             int
+	}{
+		"Unsupported media type": {path: "/todos/1", contentType: "application/json", body: `{"subject":"x"}`, code: http.StatusUnsupportedMediaType},
+		"Failed test":            {path: "/todos/1", contentType: "application/json-patch+json", body: `[{"op":"replace","path":"/subject","value":"x"},{"op":"test","path":"/description","value":"keep"}]`, code: http.StatusConflict},
+		"Missing member":         {path: "/todos/1", contentType: "application/json-patch+json", body: `[{"op":"remove","path":"/recurrence"}]`, code: http.StatusConflict},
+		"Malformed patch":        {path: "/todos/1", contentType: "application/json-patch+json", body: `{"subject":"x"}`, code: http.StatusBadRequest},
+		"Read-only member":       {path: "/todos/1", contentType: "application/merge-patch+json", body: `{"subject":"x","id":2}`, code: http.StatusBadRequest},
+		"Unknown member":         {path: "/todos/1", contentType: "application/merge-patch+json", body: `{"subject":"x","priority":1}`, code: http.StatusBadRequest},
+		"Removed subject":        {path: "/todos/1", contentType: "application/merge-patch+json", body: `{"subject":null}`, code: http.StatusBadRequest},
+		"Invalid tag":            {path: "/todos/1", contentType: "application/merge-patch+json", body: `{"tags":[1]}`, code: http.StatusBadRequest},
+		"Invalid recurrence":     {path: "/todos/1", contentType: "application/merge-patch+json", body: `{"recurrence":{"frequency":"yearly"}}`, code: http.StatusBadRequest},
+		"Stale version":          {path: "/todos/1", contentType: "application/merge-patch+json", ifMatch: created, body: `{"subject":"x"}`, code: http.StatusPreconditionFailed},
+		"Missing todo":           {path: "/todos/9", contentType: "application/merge-patch+json", body: `{"subject":"x"}`, code: http.StatusNotFound},
+	}
+	for name, c := range cases {
+		resp := patch(c.path, c.contentType, c.ifMatch, c.body)
+		resp.Body.Close()
+		if resp.StatusCode != c.code {
+			t.Errorf("%s: unexpected status code, given = %d, expected = %d", name, resp.StatusCode, c.code)
+		}
+	}
+
+	// none of the failed patches is applied even partially
+	resp, err = http.Get(srv.URL + "/todos")
+	if err != nil {
+		t.Fatal("failed to send request, err =", err)
+	}
+	defer resp.Body.Close()
+	var body model.ReadTODOResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal("failed to decode response, err =", err)
+	}
+	if len(body.TODOs) != 1 || body.TODOs[0].Subject != "merged" {
+		t.Errorf("unexpected todos, given = %+v", body.TODOs)
+	}
+
+}
+
+func TestNewRouterWithRepository_Resource(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(router.NewRouterWithRepository(service.NewMemoryTODORepository()))
+	t.Cleanup(srv.Close)
+
+	send := func(method, path, body string) *http.Response {
+		req, err := http.NewRequest(method, srv.URL+path, bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatal("failed to create request, err =", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal("failed to send request, err =", err)
+		}
+		return resp
+	}
+
+	for _, subject := range []string{"first", "second"} {
+		send(http.MethodPost, "/todos", `{"subject":"`+subject+`"}`).Body.Close()
+	}
+
+	resp := send(http.MethodPut, "/todos/1", `{"subject":"updated","description":"description"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("unexpected status code, given = %d, expected = %d", resp.StatusCode, http.StatusOK)
+	}
+
+	resp = send(http.MethodGet, "/todos/1", "")
+	defer resp.Body.Close()
+	var got model.GetTODOResponse
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal("failed to decode response, err =", err)
+	}
+	if got.TODO.ID != 1 || got.TODO.Subject != "updated" || got.TODO.Description != "description" || resp.Header.Get("ETag") == "" {
+		t.Errorf("unexpected response, given = %+v, etag = %s", got.TODO, resp.Header.Get("ETag"))
+	}
+
+	resp = send(http.MethodDelete, "/todos/2", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("unexpected status code, given = %d, expected = %d", resp.StatusCode, http.StatusOK)
+	}
+
+	cases := map[string]struct {
+		method, path, body string
+		code               int
+		allow              string
+	}{
+		"Get deleted":        {method: http.MethodGet, path: "/todos/2", code: http.StatusNotFound},
+		"Get malformed id":   {method: http.MethodGet, path: "/todos/x", code: http.StatusNotFound},
+		"Put disagreeing id": {method: http.MethodPut, path: "/todos/1", body: `{"id":2,"subject":"x"}`, code: http.StatusBadRequest},
+		"Put missing":        {method: http.MethodPut, path: "/todos/9", body: `{"subject":"x"}`, code: http.StatusNotFound},
+		"Delete deleted":     {method: http.MethodDelete, path: "/todos/2", code: http.StatusNotFound},
+		"Resource options":   {method: http.MethodOptions, path: "/todos/1", code: http.StatusNoContent, allow: "DELETE, GET, HEAD, OPTIONS, PATCH, PUT"},
+		"Collection options": {method: http.MethodOptions, path: "/todos", code: http.StatusNoContent, allow: "DELETE, GET, HEAD, OPTIONS, POST, PUT"},
+		"Post resource":      {method: http.MethodPost, path: "/todos/1", code: http.StatusMethodNotAllowed, allow: "DELETE, GET, HEAD, OPTIONS, PATCH, PUT"},
+		"Delete trash":       {method: http.MethodDelete, path: "/todos/trash", code: http.StatusMethodNotAllowed, allow: "GET, HEAD, OPTIONS"},
+		"Get complete":       {method: http.MethodGet, path: "/todos/1/complete", code: http.StatusMethodNotAllowed, allow: "OPTIONS, POST"},
+		"Post healthz":       {method: http.MethodPost, path: "/healthz", code: http.StatusMethodNotAllowed, allow: "GET, HEAD, OPTIONS"},
+		"Unknown path":       {method: http.MethodGet, path: "/todos/1/unknown", code: http.StatusNotFound},
+	}
+	for name, c := range cases {
+		resp := send(c.method, c.path, c.body)
+		resp.Body.Close()
+		if resp.StatusCode != c.code {
+			t.Errorf("%s: unexpected status code, given = %d, expected = %d", name, resp.StatusCode, c.code)
+		}
+		if got := resp.Header.Get("Allow"); got != c.allow {
+			t.Errorf("%s: unexpected allow, given = %q, expected = %q", name, got, c.allow)
+		}
+	}
+}
+
+func TestNewRouterWithRepository_Problem(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(router.NewRouterWithRepository(service.NewMemoryTODORepository()))
+	t.Cleanup(srv.Close)
+
+	cases := map[string]struct {
+		method, path, body, lang string
+		code                     int
+		problem                  model.Problem
+		contentLanguage          string
+	}{
+		"Malformed body": {
+			method: http.MethodPost, path: "/todos", body: `{`,
+			code:            http.StatusBadRequest,
+			problem:         model.Problem{Type: "urn:todo:problem:bad-request", Title: "Bad request", Status: http.StatusBadRequest, Detail: "unexpected EOF"},
+			contentLanguage: "en",
+		},
+		"Validation": {
+			method: http.MethodPut, path: "/todos", body: `{"subject":""}`,
+			code: http.StatusBadRequest,
+			problem: model.Problem{
+				Type: "urn:todo:problem:validation", Title: "Validation failed", Status: http.StatusBadRequest, Detail: "Some parameters are invalid.",
+				InvalidParams: []*model.InvalidParam{
+					{Name: "id", Code: "required", Reason: "must not be empty"},
+					{Name: "subject", Code: "required", Reason: "must not be empty"},
+				},
+			},
+			contentLanguage: "en",
+		},
+		"Validation in Japanese": {
+			method: http.MethodGet, path: "/todos?size=x", lang: "fr, ja-JP;q=0.9, en;q=0.8",
+			code: http.StatusBadRequest,
+			problem: model.Problem{
+				Type: "urn:todo:problem:validation", Title: "入力値が不正です", Status: http.StatusBadRequest, Detail: "不正なパラメータがあります。",
+				InvalidParams: []*model.InvalidParam{{Name: "size", Code: "invalid", Reason: "不正な値です"}},
+			},
+			contentLanguage: "ja",
+		},
+		"Recurrence": {
+			method: http.MethodPost, path: "/todos", body: `{"subject":"x","recurrence":{"frequency":"daily"}}`,
+			code: http.StatusBadRequest,
+			problem: model.Problem{
+				Type: "urn:todo:problem:validation", Title: "Validation failed", Status: http.StatusBadRequest, Detail: "Some parameters are invalid.",
+				InvalidParams: []*model.InvalidParam{{Name: "recurrence", Code: "invalid", Reason: "recurring TODO requires due date"}},
+			},
+			contentLanguage: "en",
+		},
+		"Not found": {
+			method: http.MethodGet, path: "/todos/9", lang: "ja",
+			code:            http.StatusNotFound,
+			problem:         model.Problem{Type: "urn:todo:problem:not-found", Title: "見つかりません", Status: http.StatusNotFound, Detail: "リソースが存在しません。"},
+			contentLanguage: "ja",
+		},
+		"Unknown path": {
+			method: http.MethodGet, path: "/unknown", lang: "ja;q=0, de",
+			code:            http.StatusNotFound,
+			problem:         model.Problem{Type: "urn:todo:problem:not-found", Title: "Not found", Status: http.StatusNotFound, Detail: "The resource does not exist."},
+			contentLanguage: "en",
+		},
+		"Method not allowed": {
+			method: http.MethodPost, path: "/healthz",
+			code:            http.StatusMethodNotAllowed,
+			problem:         model.Problem{Type: "urn:todo:problem:method-not-allowed", Title: "Method not allowed", Status: http.StatusMethodNotAllowed, Detail: "The resource does not support the method."},
+			contentLanguage: "en",
+		},
+		"Unsupported media type": {
+			method: http.MethodPatch, path: "/todos/1", body: `{}`,
+			code:            http.StatusUnsupportedMediaType,
+			problem:         model.Problem{Type: "urn:todo:problem:unsupported-media-type", Title: "Unsupported media type", Status: http.StatusUnsupportedMediaType, Detail: "The body is not of a supported media type."},
+			contentLanguage: "en",
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequest(c.method, srv.URL+c.path, bytes.NewBufferString(c.body))
+			if err != nil {
+				t.Fatal("failed to create request, err =", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept-Language", c.lang)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal("failed to send request, err =", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != c.code {
+				t.Errorf("unexpected status code, given = %d, expected = %d", resp.StatusCode, c.code)
+			}
+			if got := resp.Header.Get("Content-Type"); got != "application/problem+json" {
+				t.Errorf("unexpected content type, given = %s", got)
+			}
+			if got := resp.Header.Get("Content-Language"); got != c.contentLanguage {
+				t.Errorf("unexpected content language, given = %s, expected = %s", got, c.contentLanguage)
+			}
+			var got model.Problem
+			if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+				t.Fatal("failed to decode response, err =", err)
+			}
+			if got.RequestID != resp.Header.Get(router.RequestIDHeader) || got.RequestID == "" {
+				t.Errorf("unexpected request id, given = %s, expected = %s", got.RequestID, resp.Header.Get(router.RequestIDHeader))
+			}
+			got.RequestID = ""
+			if diff := cmp.Diff(c.problem, got); diff != "" {
+				t.Errorf("unexpected problem (-expected +given):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewRouterWithRepository_Validation(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(router.NewRouterWithRepository(service.NewMemoryTODORepository()))
+	t.Cleanup(srv.Close)
+
+	send := func(method, path, body string) *http.Response {
+		req, err := http.NewRequest(method, srv.URL+path, bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatal("failed to create request, err =", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal("failed to send request, err =", err)
+		}
+		return resp
+	}
+
+	// the subject is trimmed and composed into "caf\u00e9"
+	resp := send(http.MethodPost, "/todos", `{"subject":"  cafe\u0301  ","tags":[" tag "]}`+"\n")
+	defer resp.Body.Close()
+	var created model.CreateTODOResponse
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatal("failed to decode response, err =", err)
+	}
+	if created.TODO.Subject != "caf\u00e9" || len(created.TODO.Tags) != 1 || created.TODO.Tags[0] != "tag" {
+		t.Errorf("unexpected todo, given = %+v", created.TODO)
+	}
+
+	cases := map[string]struct {
+		method, path, body string
+		code               int
+		params             []*model.InvalidParam
+	}{
+		"Every violation": {
+			method: http.MethodPut, path: "/todos",
+			body: `{"subject":"` + strings.Repeat("あ", 201) + `","tags":"x","extra":1,"another":2}`,
+			code: http.StatusBadRequest,
+			params: []*model.InvalidParam{
+				{Name: "another", Code: "unknown", Reason: "is unknown"},
+				{Name: "extra", Code: "unknown", Reason: "is unknown"},
+				{Name: "tags", Code: "invalid", Reason: "is invalid"},
+				{Name: "id", Code: "required", Reason: "must not be empty"},
+				{Name: "subject", Code: "too_long", Reason: "must be at most 200 characters"},
+			},
+		},
+		"Blank subject": {
+			method: http.MethodPost, path: "/todos", body: `{"subject":" \t "}`,
+			code:   http.StatusBadRequest,
+			params: []*model.InvalidParam{{Name: "subject", Code: "required", Reason: "must not be empty"}},
+		},
+		"Longest subject": {
+			method: http.MethodPut, path: "/todos/1", body: `{"subject":"` + strings.Repeat("あ", 200) + `"}`,
+			code: http.StatusOK,
+		},
+		"Too many ids": {
+			method: http.MethodDelete, path: "/todos", body: `{"ids":[` + strings.Repeat("1,", 100) + `1]}`,
+			code:   http.StatusBadRequest,
+			params: []*model.InvalidParam{{Name: "ids", Code: "too_long", Reason: "must have at most 100 elements"}},
+		},
+		"Trailing garbage": {method: http.MethodPost, path: "/todos", body: `{"subject":"x"} {}`, code: http.StatusBadRequest},
+		"Not an object":    {method: http.MethodPost, path: "/todos", body: `["x"]`, code: http.StatusBadRequest},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			resp := send(c.method, c.path, c.body)
+			defer resp.Body.Close()
+			if resp.StatusCode != c.code {
+				t.Errorf("unexpected status code, given = %d, expected = %d", resp.StatusCode, c.code)
+			}
+			if c.code == http.StatusOK {
+				return
+			}
+			var got model.Problem
+			if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+				t.Fatal("failed to decode response, err =", err)
+			}
+			if diff := cmp.Diff(c.params, got.InvalidParams); diff != "" {
+				t.Errorf("unexpected invalid params (-expected +given):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewRouterWithOptions_Healthz(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		checkers []*service.Checker
+		path     string
+		code     int
+		body     string
+	}{
+		"Healthz": {path: "/healthz", code: http.StatusOK, body: `{"message":"OK"}` + "\n"},
+		"Live": {
+			checkers: []*service.Checker{{Name: "down", Critical: true, Check: func(context.Context) error { return errors.New("down") }}},
+			path:     "/healthz/live",
+			code:     http.StatusOK,
+			body:     `{"status":"ok","checks":[]}` + "\n",
+		},
+		"Ready":    {path: "/healthz/ready", code: http.StatusOK},
+		"Degraded": {checkers: []*service.Checker{{Name: "cache", Check: func(context.Context) error { return errors.New("down") }}}, path: "/healthz/ready", code: http.StatusOK},
+		"Not ready": {
+			checkers: []*service.Checker{{Name: "down", Critical: true, Check: func(context.Context) error { return errors.New("down") }}},
+			path:     "/healthz/ready",
+			code:     http.StatusServiceUnavailable,
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			h := router.NewRouterWithOptions(service.NewMemoryTODORepository(), &router.Options{Checkers: c.checkers})
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, c.path, nil))
+			if rec.Code != c.code {
+				t.Errorf("unexpected status code, given = %d, expected = %d", rec.Code, c.code)
+			}
+			if c.body != "" && rec.Body.String() != c.body {
+				t.Errorf("unexpected body, given = %q, expected = %q", rec.Body.String(), c.body)
+			}
+			if c.path != "/healthz/ready" {
+				return
+			}
+			var report model.HealthReport
+			if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+				t.Fatal("failed to decode response, err =", err)
+			}
+			if len(report.Checks) != len(c.checkers) {
+				t.Errorf("unexpected checks, given = %+v", report.Checks)
+			}
+		})
+	}
+}
+
+func TestNewRouterWithOptions_Metrics(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		serve     bool
+		code      int
+		unmatched string
+	}{
+		"Served": {serve: true, code: http.StatusOK, unmatched: "1"},
+		// /metrics is unmatched as well
+		"Not served": {serve: false, code: http.StatusNotFound, unmatched: "2"},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			m := metrics.New()
+			srv := httptest.NewServer(router.NewRouterWithOptions(service.NewMemoryTODORepository(), &router.Options{Metrics: m, ServeMetrics: c.serve}))
+			t.Cleanup(srv.Close)
+
+			for _, path := range []string{"/todos/1", "/unknown"} {
+				resp, err := http.Get(srv.URL + path)
+				if err != nil {
+					t.Fatal("failed to send request, err =", err)
+				}
+				resp.Body.Close()
+			}
+			resp, err := http.Post(srv.URL+"/todos", "application/json", bytes.NewBufferString(`{"subject":"subject"}`))
+			if err != nil {
+				t.Fatal("failed to send request, err =", err)
+			}
+			resp.Body.Close()
+			// a client may send any token as the method
+			req, err := http.NewRequest("PURGE", srv.URL+"/todos", nil)
+			if err != nil {
+				t.Fatal("failed to create request, err =", err)
+			}
+			resp, err = http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal("failed to send request, err =", err)
+			}
+			resp.Body.Close()
+
+			resp, err = http.Get(srv.URL + "/metrics")
+			if err != nil {
+				t.Fatal("failed to send request, err =", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != c.code {
+				t.Errorf("unexpected status code, given = %d, expected = %d", resp.StatusCode, c.code)
+			}
+
+			w := httptest.NewRecorder()
+			m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+			for _, line := range []string{
+				`todo_http_requests_total{method="GET",route="/todos/{id}",status="404"} 1`,
+				`todo_http_requests_total{method="GET",route="unmatched",status="404"} ` + c.unmatched,
+				`todo_http_requests_total{method="POST",route="/todos",status="200"} 1`,
+				`todo_http_requests_total{method="OTHER",route="/todos",status="405"} 1`,
+				`todo_service_call_duration_seconds_count{method="CreateTODO"} 1`,
+				`todo_service_call_errors_total{method="CreateTODO"} 0`,
+			} {
+				if !strings.Contains(w.Body.String(), line) {
+					t.Errorf("unexpected metrics without %s, given =\n%s", line, w.Body.String())
+				}
+			}
+			if strings.Contains(w.Body.String(), `method="PURGE"`) {
+				t.Errorf("unexpected metrics labeled by non-standard method, given =\n%s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestNewRouterWithOptions_Auth(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := service.NewMemoryTODORepository()
+	users := service.NewUserServiceWithRepository(repo)
+	alice, err := users.CreateUser(ctx, "alice")
+	if err != nil {
+		t.Fatal("failed to create user, err =", err)
+	}
+	token, _, err := users.IssueToken(ctx, "alice", "test")
+	if err != nil {
+		t.Fatal("failed to issue token, err =", err)
+	}
+	todo, err := service.NewTODOServiceWithRepository(repo).CreateTODO(service.WithUser(ctx, alice), "subject", "")
+	if err != nil {
+		t.Fatal("failed to create todo, err =", err)
+	}
+	path := "/todos/" + strconv.FormatInt(todo.ID, 10)
+
+	cases := map[string]struct {
+		required      bool
+		authorization string
+		path          string
+		code          int
+		challenge     string
+	}{
+		"Anonymous":        {path: path, code: http.StatusNotFound},
+		"Owner":            {authorization: "Bearer " + token, path: path, code: http.StatusOK},
+		"Lowercase scheme": {authorization: "bearer " + token, path: path, code: http.StatusOK},
+		"Required":         {required: true, path: "/todos", code: http.StatusUnauthorized, challenge: "Bearer"},
+		"Required tags":    {required: true, path: "/tags", code: http.StatusUnauthorized, challenge: "Bearer"},
+		"Healthz":          {required: true, path: "/healthz", code: http.StatusOK},
+		"Invalid token":    {authorization: "Bearer todo_unknown", path: "/todos", code: http.StatusUnauthorized, challenge: `Bearer error="invalid_token"`},
+		"Other scheme":     {authorization: "Basic YWxpY2U6", path: "/todos", code: http.StatusUnauthorized, challenge: `Bearer error="invalid_request"`},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			h := router.NewRouterWithOptions(repo, &router.Options{RequireAuth: c.required})
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			if c.authorization != "" {
+				req.Header.Set("Authorization", c.authorization)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != c.code {
+				t.Errorf("unexpected status code, given = %d, expected = %d", rec.Code, c.code)
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); got != c.challenge {
+				t.Errorf("unexpected challenge, given = %q, expected = %q", got, c.challenge)
+			}
+			if c.code != http.StatusUnauthorized {
+				return
+			}
+			var problem model.Problem
+			if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+				t.Fatal("failed to decode response, err =", err)
+			}
+			if problem.Type != "urn:todo:problem:unauthenticated" {
+				t.Errorf("unexpected problem type, given = %s", problem.Type)
+			}
+		})
+	}
+}
+
+func TestNewRouterWithOptions_Lists(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := service.NewMemoryTODORepository()
+	users := service.NewUserServiceWithRepository(repo)
+	tokens := make(map[string]string)
+	for _, name := range []string{"alice", "bob", "carol"} {
+		if _, err := users.CreateUser(ctx, name); err != nil {
+			t.Fatal("failed to create user, err =", err)
+		}
+		token, _, err := users.IssueToken(ctx, name, "test")
+		if err != nil {
+			t.Fatal("failed to issue token, err =", err)
+		}
+		tokens[name] = token
+	}
+	alice, err := users.Authenticate(ctx, tokens["alice"])
+	if err != nil {
+		t.Fatal("failed to authenticate, err =", err)
+	}
+	lists := service.NewListServiceWithRepository(repo)
+	list, err := lists.CreateList(service.WithUser(ctx, alice), "shared")
+	if err != nil {
+		t.Fatal("failed to create list, err =", err)
+	}
+	if _, err := lists.PutMember(service.WithUser(ctx, alice), list.ID, "bob", model.RoleViewer); err != nil {
+		t.Fatal("failed to add member, err =", err)
+	}
+	todo, err := service.NewTODOServiceWithRepository(repo).CreateTODOWithInput(service.WithUser(ctx, alice), &service.TODOInput{Subject: "shared", ListID: list.ID})
+	if err != nil {
+		t.Fatal("failed to create todo, err =", err)
+	}
+	listPath := "/lists/" + strconv.FormatInt(list.ID, 10)
+	todoPath := "/todos/" + strconv.FormatInt(todo.ID, 10)
+
+	cases := map[string]struct {
+		user, method, path, body string
+		code                     int
+		problem                  string
+	}{
+		"Owner reads list":          {user: "alice", method: http.MethodGet, path: listPath, code: http.StatusOK},
+		"Viewer reads list":         {user: "bob", method: http.MethodGet, path: listPath, code: http.StatusOK},
+		"Viewer reads members":      {user: "bob", method: http.MethodGet, path: listPath + "/members", code: http.StatusOK},
+		"Viewer reads TODO":         {user: "bob", method: http.MethodGet, path: todoPath, code: http.StatusOK},
+		"Viewer renames list":       {user: "bob", method: http.MethodPut, path: listPath, body: `{"name":"mine"}`, code: http.StatusForbidden, problem: "forbidden"},
+		"Viewer adds member":        {user: "bob", method: http.MethodPut, path: listPath + "/members/carol", body: `{"role":"owner"}`, code: http.StatusForbidden, problem: "forbidden"},
+		"Viewer updates TODO":       {user: "bob", method: http.MethodPut, path: todoPath, body: `{"subject":"changed"}`, code: http.StatusForbidden, problem: "forbidden"},
+		"Viewer deletes TODO":       {user: "bob", method: http.MethodDelete, path: todoPath, code: http.StatusForbidden, problem: "forbidden"},
+		"Viewer creates TODO":       {user: "bob", method: http.MethodPost, path: "/todos", body: `{"subject":"new","list_id":` + strconv.FormatInt(list.ID, 10) + `}`, code: http.StatusForbidden, problem: "forbidden"},
+		"Stranger reads list":       {user: "carol", method: http.MethodGet, path: listPath, code: http.StatusNotFound, problem: "not-found"},
+		"Stranger reads members":    {user: "carol", method: http.MethodGet, path: listPath + "/members", code: http.StatusNotFound, problem: "not-found"},
+		"Stranger deletes list":     {user: "carol", method: http.MethodDelete, path: listPath, code: http.StatusNotFound, problem: "not-found"},
+		"Stranger reads TODO":       {user: "carol", method: http.MethodGet, path: todoPath, code: http.StatusNotFound, problem: "not-found"},
+		"Stranger updates TODO":     {user: "carol", method: http.MethodPut, path: todoPath, body: `{"subject":"changed"}`, code: http.StatusNotFound, problem: "not-found"},
+		"Owner demotes last owner":  {user: "alice", method: http.MethodPut, path: listPath + "/members/alice", body: `{"role":"viewer"}`, code: http.StatusConflict, problem: "conflict"},
+		"Owner adds unknown role":   {user: "alice", method: http.MethodPut, path: listPath + "/members/carol", body: `{"role":"admin"}`, code: http.StatusBadRequest, problem: "validation"},
+		"Anonymous creates list":    {method: http.MethodPost, path: "/lists", body: `{"name":"mine"}`, code: http.StatusUnauthorized, problem: "unauthenticated"},
+		"Stranger creates own list": {user: "carol", method: http.MethodPost, path: "/lists", body: `{"name":"mine"}`, code: http.StatusOK},
+	}
+	h := router.NewRouterWithOptions(repo, &router.Options{})
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(c.method, c.path, strings.NewReader(c.body))
+			if c.user != "" {
+				req.Header.Set("Authorization", "Bearer "+tokens[c.user])
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != c.code {
+				t.Errorf("unexpected status code, given = %d, expected = %d, body = %s", rec.Code, c.code, rec.Body)
+			}
+			if c.problem == "" {
+				return
+			}
+			var problem model.Problem
+			if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+				t.Fatal("failed to decode response, err =", err)
+			}
+			if problem.Type != "urn:todo:problem:"+c.problem {
+				t.Errorf("unexpected problem type, given = %s, expected = urn:todo:problem:%s", problem.Type, c.problem)
+			}
+		})
+	}
+}
+
+func TestNewRouterWithOptions_RateLimit(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := service.NewMemoryTODORepository()
+	users := service.NewUserServiceWithRepository(repo)
+	if _, err := users.CreateUser(ctx, "alice"); err != nil {
+		t.Fatal("failed to create user, err =", err)
+	}
+	token, _, err := users.IssueToken(ctx, "alice", "test")
+	if err != nil {
+		t.Fatal("failed to issue token, err =", err)
+	}
+
+	// the buckets are not refilled during the test
+	now := time.Now()
+	read, write := ratelimit.New(0.5, 2), ratelimit.New(0.5, 1)
+	read.SetClock(func() time.Time { return now })
+	write.SetClock(func() time.Time { return now })
+	h := router.NewRouterWithOptions(repo, &router.Options{ReadLimit: read, WriteLimit: write})
+
+	// the steps share the buckets, so that they run in order
+	steps := []struct {
+		name, method, path, authorization, remoteAddr string
+		code                                          int
+		remaining, retryAfter                         string
+	}{
+		{name: "First read", method: http.MethodGet, path: "/todos", code: http.StatusOK, remaining: "1"},
+		{name: "Second read", method: http.MethodGet, path: "/todos", code: http.StatusOK, remaining: "0"},
+		{name: "Read over burst", method: http.MethodGet, path: "/todos", code: http.StatusTooManyRequests, remaining: "0", retryAfter: "2"},
+		{name: "Write in its own budget", method: http.MethodPost, path: "/todos", code: http.StatusOK, remaining: "0"},
+		{name: "Write over burst", method: http.MethodPost, path: "/todos", code: http.StatusTooManyRequests, remaining: "0", retryAfter: "2"},
+		{name: "Another address", method: http.MethodGet, path: "/todos", remoteAddr: "192.0.2.2:1234", code: http.StatusOK, remaining: "1"},
+		// a token is limited by the address until authenticated, and then by the user across addresses
+		{name: "Token over address", method: http.MethodGet, path: "/todos", authorization: "Bearer " + token, code: http.StatusTooManyRequests, remaining: "0", retryAfter: "2"},
+		{name: "Token", method: http.MethodGet, path: "/todos", authorization: "Bearer " + token, remoteAddr: "192.0.2.3:1234", code: http.StatusOK, remaining: "1"},
+		{name: "Token of user", method: http.MethodGet, path: "/todos", authorization: "Bearer " + token, remoteAddr: "192.0.2.4:1234", code: http.StatusOK, remaining: "0"},
+		{name: "Token over user", method: http.MethodGet, path: "/todos", authorization: "Bearer " + token, remoteAddr: "192.0.2.5:1234", code: http.StatusTooManyRequests, remaining: "0", retryAfter: "2"},
+		{name: "Health check", method: http.MethodGet, path: "/healthz", code: http.StatusOK},
+	}
+	for _, s := range steps {
+		req := httptest.NewRequest(s.method, s.path, strings.NewReader(`{"subject":"subject"}`))
+		if s.authorization != "" {
+			req.Header.Set("Authorization", s.authorization)
+		}
+		if s.remoteAddr != "" {
+			req.RemoteAddr = s.remoteAddr
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != s.code {
+			t.Errorf("%s: unexpected status code, given = %d, expected = %d", s.name, rec.Code, s.code)
+		}
+		if got := rec.Header().Get("RateLimit-Remaining"); got != s.remaining {
+			t.Errorf("%s: unexpected RateLimit-Remaining, given = %q, expected = %q", s.name, got, s.remaining)
+		}
+		if got := rec.Header().Get("Retry-After"); got != s.retryAfter {
+			t.Errorf("%s: unexpected Retry-After, given = %q, expected = %q", s.name, got, s.retryAfter)
+		}
+		if s.code != http.StatusTooManyRequests {
+			continue
+		}
+		var problem model.Problem
+		if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+			t.Fatal("failed to decode response, err =", err)
+		}
+		if problem.Type != "urn:todo:problem:rate-limited" {
+			t.Errorf("%s: unexpected problem type, given = %s", s.name, problem.Type)
+		}
+	}
+}
+
+// A countingRepository counts the API tokens looked up.
+type countingRepository struct {
+	*service.MemoryTODORepository
+	used int64
+}
+
+// UseToken implements service.UserRepository interface.
+func (r *countingRepository) UseToken(ctx context.Context, hash string) (*model.User, error) {
+	atomic.AddInt64(&r.used, 1)
+	return r.MemoryTODORepository.UseToken(ctx, hash)
+}
+
+func TestNewRouterWithOptions_RateLimit_BogusTokens(t *testing.T) {
+	t.Parallel()
+
+	// the buckets are not refilled during the test
+	now := time.Now()
+	read, write := ratelimit.New(0.5, 1), ratelimit.New(0.5, 1)
+	read.SetClock(func() time.Time { return now })
+	write.SetClock(func() time.Time { return now })
+	repo := &countingRepository{MemoryTODORepository: service.NewMemoryTODORepository()}
+	h := router.NewRouterWithOptions(repo, &router.Options{ReadLimit: read, WriteLimit: write})
+
+	// a new token for each request gets no new bucket
+	codes := make(map[int]int)
+	for i := 0; i < 50; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/todos", strings.NewReader(`{"subject":"subject"}`))
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer todo_bogus%d", i))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		codes[rec.Code]++
+	}
+	if codes[http.StatusUnauthorized] != 1 || codes[http.StatusTooManyRequests] != 49 {
+		t.Errorf("unexpected status codes, given = %v", codes)
+	}
+	if used := atomic.LoadInt64(&repo.used); used != 1 {
+		t.Errorf("unexpected tokens looked up, given = %d, expected = %d", used, 1)
+	}
+}
+
+func TestNewRouterWithOptions_Session(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := service.NewMemoryTODORepository()
+	alice, err := repo.CreateUser(ctx, "alice")
+	if err != nil {
+		t.Fatal("failed to create user, err =", err)
+	}
+	if err := service.NewSessionServiceWithRepository(repo).SetPassword(ctx, "alice", "correct horse"); err != nil {
+		t.Fatal("failed to set password, err =", err)
+	}
+	// the cookies are sent over plain HTTP of httptest
+	srv := httptest.NewServer(router.NewRouterWithOptions(repo, &router.Options{RequireAuth: true, InsecureCookies: true}))
+	t.Cleanup(srv.Close)
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal("failed to create cookie jar, err =", err)
+	}
+	client := &http.Client{Jar: jar}
+
+	send := func(method, path, body string, csrf bool) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal("failed to create request, err =", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if csrf {
+			for _, c := range jar.Cookies(req.URL) {
+				if c.Name == handler.CSRFCookie {
+					req.Header.Set(handler.CSRFHeader, c.Value)
+				}
+			}
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal("failed to send request, err =", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	const login = `{"name":"alice","password":"correct horse"}`
+	steps := []struct {
+		name         string
+		method, path string
+		body         string
+		csrf         bool
+		code         int
+	}{
+		{name: "Anonymous", method: http.MethodGet, path: "/todos", code: http.StatusUnauthorized},
+		{name: "Login without CSRF token", method: http.MethodPost, path: "/login", body: login, code: http.StatusForbidden},
+		{name: "CSRF token", method: http.MethodGet, path: "/login", code: http.StatusNoContent},
+		{name: "Wrong password", method: http.MethodPost, path: "/login", body: `{"name":"alice","password":"wrong password"}`, csrf: true, code: http.StatusUnauthorized},
+		{name: "Login", method: http.MethodPost, path: "/login", body: login, csrf: true, code: http.StatusOK},
+		{name: "Read", method: http.MethodGet, path: "/todos", code: http.StatusOK},
+		{name: "Create without CSRF token", method: http.MethodPost, path: "/todos", body: `{"subject":"subject"}`, code: http.StatusForbidden},
+		{name: "Create", method: http.MethodPost, path: "/todos", body: `{"subject":"subject"}`, csrf: true, code: http.StatusOK},
+		{name: "Logout without CSRF token", method: http.MethodPost, path: "/logout", code: http.StatusForbidden},
+		{name: "Logout", method: http.MethodPost, path: "/logout", csrf: true, code: http.StatusNoContent},
+		{name: "Logged out", method: http.MethodGet, path: "/todos", code: http.StatusUnauthorized},
+	}
+	for _, s := range steps {
+		resp := send(s.method, s.path, s.body, s.csrf)
+		if resp.StatusCode != s.code {
+			t.Errorf("%s: unexpected status code, given = %d, expected = %d", s.name, resp.StatusCode, s.code)
+		}
+		if s.name != "Login" {
+			continue
+		}
+		for _, c := range resp.Cookies() {
+			if c.SameSite != http.SameSiteLaxMode || c.HttpOnly != (c.Name == handler.SessionCookie) {
+				t.Errorf("unexpected cookie, given = %s", c)
+			}
+		}
+	}
+
+	todos, err := service.NewTODOServiceWithRepository(repo).ReadTODO(service.WithUser(ctx, alice), 0, 5)
+	if err != nil {
+		t.Fatal("failed to read todos, err =", err)
+	}
+	if len(todos) != 1 {
+		t.Errorf("unexpected todos of alice, given = %v", todos)
+	}
+}
+
+func TestNewRouterWithOptions_OIDC(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	provider := oidctest.NewProvider("todo", "secret")
+	t.Cleanup(provider.Close)
+
+	// the redirect URL is registered before the router serving it is created
+	repo := service.NewMemoryTODORepository()
+	srv := httptest.NewUnstartedServer(nil)
+	base := "http://" + srv.Listener.Addr().String()
+	rp, err := oidc.New(ctx, oidc.Config{
+		Issuer:       provider.Issuer(),
+		ClientID:     "todo",
+		ClientSecret: "secret",
+		RedirectURL:  base + "/login/oidc/callback",
+	})
+	if err != nil {
+		t.Fatal("failed to create relying party, err =", err)
+	}
+	srv.Config.Handler = router.NewRouterWithOptions(repo, &router.Options{
+		RequireAuth:     true,
+		InsecureCookies: true,
+		OIDC:            rp,
+		OIDCRedirect:    "/todos",
+	})
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	newClient := func() (*http.Client, http.CookieJar) {
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			t.Fatal("failed to create cookie jar, err =", err)
+		}
+		return &http.Client{Jar: jar}, jar
+	}
+	get := func(client *http.Client, path string) *http.Response {
+		t.Helper()
+		resp, err := client.Get(base + path)
+		if err != nil {
+			t.Fatal("failed to send request, err =", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	// the user agent follows the redirects to the provider and back to the TODOs
+	client, jar := newClient()
+	if resp := get(client, "/login/oidc"); resp.StatusCode != http.StatusOK || resp.Request.URL.Path != "/todos" {
+		t.Fatalf("unexpected response of login, given = %d %s", resp.StatusCode, resp.Request.URL)
+	}
+	req, err := http.NewRequest(http.MethodPost, base+"/todos", strings.NewReader(`{"subject":"subject"}`))
+	if err != nil {
+		t.Fatal("failed to create request, err =", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for _, c := range jar.Cookies(req.URL) {
+		if c.Name == handler.CSRFCookie {
+			req.Header.Set(handler.CSRFHeader, c.Value)
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal("failed to send request, err =", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("unexpected status code of create, given = %d, expected = %d", resp.StatusCode, http.StatusOK)
+	}
+
+	// the user created on the first login is logged in again by the same subject
+	again, _ := newClient()
+	if resp := get(again, "/login/oidc"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status code of second login, given = %d, expected = %d", resp.StatusCode, http.StatusOK)
+	}
+	user, err := repo.GetIdentity(ctx, provider.Issuer(), oidctest.DefaultUser.Subject)
+	if err != nil {
+		t.Fatal("failed to get identity, err =", err)
+	}
+	if user.Name != oidctest.DefaultUser.PreferredUsername {
+		t.Errorf("unexpected user, given = %+v", user)
+	}
+	todos, err := service.NewTODOServiceWithRepository(repo).ReadTODO(service.WithUser(ctx, user), 0, 5)
+	if err != nil {
+		t.Fatal("failed to read todos, err =", err)
+	}
+	if len(todos) != 1 {
+		t.Errorf("unexpected todos of user, given = %v", todos)
+	}
+
+	// the callback is rejected unless it answers the authorization request in flight of the user agent
+	other, _ := newClient()
+	if resp := get(other, "/login/oidc/callback?code=code&state=state"); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unexpected status code of callback without request, given = %d, expected = %d", resp.StatusCode, http.StatusBadRequest)
+	}
+	other.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	if resp := get(other, "/login/oidc"); resp.StatusCode != http.StatusFound {
+		t.Fatalf("unexpected status code of login, given = %d, expected = %d", resp.StatusCode, http.StatusFound)
+	}
+	if resp := get(other, "/login/oidc/callback?code=code&state=forged"); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unexpected status code of callback with forged state, given = %d, expected = %d", resp.StatusCode, http.StatusBadRequest)
+	}
+	other.CheckRedirect = nil
+	provider.SetUser(nil)
+	if resp := get(other, "/login/oidc"); resp.StatusCode != http.StatusBadRequest || resp.Request.URL.Query().Get("error") != "login_required" {
+		t.Errorf("unexpected response of denied login, given = %d %s", resp.StatusCode, resp.Request.URL)
+	}
+	if resp := get(other, "/todos"); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("unexpected status code after denied login, given = %d, expected = %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+	if provider.Issued() != 2 {
+		t.Errorf("unexpected number of ID tokens issued, given = %d, expected = %d", provider.Issued(), 2)
+	}
+}
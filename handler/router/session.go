@@ -0,0 +1,57 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/TechBowl-japan/go-stations/handler"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+// Sessions returns the middleware authenticating each request by the cookie of the session h logged in,
+// unless the request gives the Authorization header, which takes precedence. The request context carries
+// the user of the session and the user's name as the actor of the revisions, and the cookie is replaced
+// when the token of the session is rotated. A request with an unknown or expired session is answered
+// with 401 clearing the cookies, and a state-changing request without the CSRF token of the session
+// is answered with 403, since the browser sends the cookie even with requests forged by other sites.
+func Sessions(h *handler.SessionHandler) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c, err := r.Cookie(handler.SessionCookie)
+			if r.Header.Get("Authorization") != "" || err != nil || c.Value == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, session, err := h.Resume(r.Context(), c.Value)
+			if err == service.ErrUnauthenticated {
+				h.ClearCookies(w)
+				unauthenticated(w, r, `Bearer`)
+				return
+			}
+			if err != nil {
+				handler.WriteError(w, r, err)
+				return
+			}
+			if !safeMethod(r.Method) {
+				if err := handler.VerifyCSRF(r, session.CSRFToken); err != nil {
+					handler.WriteError(w, r, err)
+					return
+				}
+			}
+			if token != "" {
+				h.SetCookies(w, token, session)
+			}
+			ctx := service.WithActor(service.WithUser(r.Context(), session.User), session.User.Name)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// safeMethod reports whether method does not change the state of the server (RFC 9110).
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+// Names of the cookies of sessions and of the header the CSRF token is submitted in.
+const (
+	// SessionCookie carries the token of the session, which scripts cannot read.
+	SessionCookie = "todo_session"
+	// CSRFCookie carries the CSRF token, which the scripts of the front end copy into CSRFHeader.
+	CSRFCookie = "todo_csrf"
+	// CSRFHeader is the header submitting the CSRF token with state-changing requests.
+	CSRFHeader = "X-CSRF-Token"
+)
+
+// ErrCSRFTokenMismatch is written when a state-changing request does not submit the CSRF token
+// in both CSRFCookie and CSRFHeader, or submits one not bound to the session.
+var ErrCSRFTokenMismatch = errors.New("handler: CSRF token mismatch")
+
+// A SessionHandler implements handling the endpoints of logging in and out with cookie sessions.
+type SessionHandler struct {
+	svc    *service.SessionService
+	secure bool
+}
+
+// NewSessionHandler returns SessionHandler, of which the methods prefixed by Serve are registered to the router.
+// The cookies are limited to HTTPS when secure.
+func NewSessionHandler(svc *service.SessionService, secure bool) *SessionHandler {
+	return &SessionHandler{
+		svc:    svc,
+		secure: secure,
+	}
+}
+
+// ServeCSRF serves GET /login, which issues the CSRF token submitted by the login request.
+func (h *SessionHandler) ServeCSRF(w http.ResponseWriter, r *http.Request) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	http.SetCookie(w, h.csrfCookie(base64.RawURLEncoding.EncodeToString(b), time.Time{}))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ServeLogin serves POST /login, which requires the CSRF token issued by GET /login.
+func (h *SessionHandler) ServeLogin(w http.ResponseWriter, r *http.Request) {
+	if err := VerifyCSRF(r, ""); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	req := &model.LoginRequest{}
+	if err := decodeJSON(r, req); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	token, resp, err := h.Login(r.Context(), req)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	h.SetCookies(w, token, resp.Session)
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ServeLogout serves POST /logout, which ends the session of the request if any.
+func (h *SessionHandler) ServeLogout(w http.ResponseWriter, r *http.Request) {
+	if c, err := r.Cookie(SessionCookie); err == nil && c.Value != "" {
+		if err := h.svc.Logout(r.Context(), c.Value); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+	}
+	h.ClearCookies(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Login handles the endpoint that logs the user in, returning the token of the new session.
+func (h *SessionHandler) Login(ctx context.Context, req *model.LoginRequest) (string, *model.LoginResponse, error) {
+	if err := service.Validate(req); err != nil {
+		return "", nil, err
+	}
+
+	token, session, err := h.svc.Login(ctx, req.Name, req.Password)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, &model.LoginResponse{Session: session}, nil
+}
+
+// Start starts a new session of the user authenticated otherwise, returning its token.
+func (h *SessionHandler) Start(ctx context.Context, user *model.User) (string, *model.Session, error) {
+	return h.svc.Start(ctx, user)
+}
+
+// Resume returns the session of the token with the token replacing it, which is empty unless rotated.
+func (h *SessionHandler) Resume(ctx context.Context, token string) (string, *model.Session, error) {
+	return h.svc.Resume(ctx, token)
+}
+
+// SetCookies sets the cookies of the token and the CSRF token of the session, which expire with the session.
+func (h *SessionHandler) SetCookies(w http.ResponseWriter, token string, session *model.Session) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		Secure:   h.secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.SetCookie(w, h.csrfCookie(session.CSRFToken, session.ExpiresAt))
+}
+
+// ClearCookies removes the cookies of the session.
+func (h *SessionHandler) ClearCookies(w http.ResponseWriter) {
+	for _, name := range []string{SessionCookie, CSRFCookie} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Path:     "/",
+			MaxAge:   -1,
+			Secure:   h.secure,
+			HttpOnly: name == SessionCookie,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+}
+
+// csrfCookie returns the cookie of the CSRF token, which the scripts of the front end read.
+// A zero expires makes it last until the browser is closed.
+func (h *SessionHandler) csrfCookie(token string, expires time.Time) *http.Cookie {
+	return &http.Cookie{
+		Name:     CSRFCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		Secure:   h.secure,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// VerifyCSRF returns ErrCSRFTokenMismatch unless r submits the same CSRF token in both CSRFCookie
+// and CSRFHeader, which must also be bound unless bound is empty. Another site can make a browser send
+// the cookie but cannot read it to copy it into the header.
+func VerifyCSRF(r *http.Request, bound string) error {
+	header := r.Header.Get(CSRFHeader)
+	c, err := r.Cookie(CSRFCookie)
+	if err != nil || header == "" || subtle.ConstantTimeCompare([]byte(c.Value), []byte(header)) != 1 {
+		return ErrCSRFTokenMismatch
+	}
+	if bound != "" && subtle.ConstantTimeCompare([]byte(bound), []byte(header)) != 1 {
+		return ErrCSRFTokenMismatch
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+var _ TagService = (*service.TagService)(nil)
+
+// A TagService is the service TagHandler handles the requests by, implemented by *service.TagService.
+type TagService interface {
+	ReadTags(ctx context.Context) ([]*model.Tag, error)
+	RenameTag(ctx context.Context, from, to string) (*model.Tag, error)
+	MergeTags(ctx context.Context, sources []string, into string) (*model.Tag, error)
+}
+
+// A TagHandler implements handling REST endpoints of tags.
+type TagHandler struct {
+	svc TagService
+}
+
+// NewTagHandler returns TagHandler, of which the methods prefixed by Serve are registered to the router.
+func NewTagHandler(svc TagService) *TagHandler {
+	return &TagHandler{
+		svc: svc,
+	}
+}
+
+// ServeRead serves GET /tags.
+func (h *TagHandler) ServeRead(w http.ResponseWriter, r *http.Request) {
+	resp, err := h.Read(r.Context())
+	respond(w, r, resp, err)
+}
+
+// ServeRename serves POST /tags/rename.
+func (h *TagHandler) ServeRename(w http.ResponseWriter, r *http.Request) {
+	req := &model.RenameTagRequest{}
+	if err := decodeJSON(r, req); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	resp, err := h.Rename(r.Context(), req)
+	respond(w, r, resp, err)
+}
+
+// ServeMerge serves POST /tags/merge.
+func (h *TagHandler) ServeMerge(w http.ResponseWriter, r *http.Request) {
+	req := &model.MergeTagRequest{}
+	if err := decodeJSON(r, req); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	resp, err := h.Merge(r.Context(), req)
+	respond(w, r, resp, err)
+}
+
+// Read handles the endpoint that reads the tags.
+func (h *TagHandler) Read(ctx context.Context) (*model.ReadTagResponse, error) {
+	tags, err := h.svc.ReadTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ReadTagResponse{Tags: tags}, nil
+}
+
+// Rename handles the endpoint that renames the tag.
+func (h *TagHandler) Rename(ctx context.Context, req *model.RenameTagRequest) (*model.RenameTagResponse, error) {
+	if err := service.Validate(req); err != nil {
+		return nil, err
+	}
+
+	tag, err := h.svc.RenameTag(ctx, req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+	return &model.RenameTagResponse{Tag: tag}, nil
+}
+
+// Merge handles the endpoint that merges the tags.
+func (h *TagHandler) Merge(ctx context.Context, req *model.MergeTagRequest) (*model.MergeTagResponse, error) {
+	if err := service.Validate(req); err != nil {
+		return nil, err
+	}
+
+	tag, err := h.svc.MergeTags(ctx, req.Sources, req.Into)
+	if err != nil {
+		return nil, err
+	}
+	return &model.MergeTagResponse{Tag: tag}, nil
+}
@@ -2,43 +2,836 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/TechBowl-japan/go-stations/handler/mux"
 	"github.com/TechBowl-japan/go-stations/model"
 	"github.com/TechBowl-japan/go-stations/service"
 )
 
+const (
+	// defaultReadSize is the number of TODOs read when size is not given.
+	defaultReadSize = 5
+	// defaultUpcomingDays is the number of days listed as upcoming when days is not given.
+	defaultUpcomingDays = 7
+	// maxUpcomingDays is the maximum number of days listed as upcoming.
+	maxUpcomingDays = 366
+	// defaultOccurrences is the number of occurrences previewed when count is not given.
+	defaultOccurrences = 5
+	// maxOccurrences is the maximum number of occurrences previewed at once.
+	maxOccurrences = 100
+)
+
+var _ TODOService = (*service.TODOService)(nil)
+
+// A TODOService is the service TODOHandler handles the requests by, implemented by *service.TODOService.
+// Tests and demos may give another implementation, such as a stub answering errors.
+type TODOService interface {
+	CreateTODOWithInput(ctx context.Context, in *service.TODOInput) (*model.TODO, error)
+	GetTODO(ctx context.Context, id int64) (*model.TODO, error)
+	FilterTODO(ctx context.Context, filter *service.TODOFilter, prevID, size int64) ([]*model.TODO, error)
+	ReadDueTODO(ctx context.Context, view model.DueView, days, prevID, size int64) ([]*model.TODO, error)
+	SearchTODO(ctx context.Context, mode model.SearchMode, query string, prevID, size int64) ([]*model.TODOSearchResult, error)
+	UpdateTODOWithInput(ctx context.Context, id int64, in *service.TODOInput) (*model.TODO, error)
+	PatchTODO(ctx context.Context, id int64, version string, patch service.TODOPatch) (*model.TODO, error)
+	CompleteTODO(ctx context.Context, id int64) (todo, next *model.TODO, err error)
+	ReopenTODO(ctx context.Context, id int64) (*model.TODO, error)
+	PreviewOccurrences(ctx context.Context, id, n int64) ([]time.Time, error)
+	ReadTODOHistory(ctx context.Context, id, prevID, size int64) ([]*model.TODO, error)
+	DeleteTODO(ctx context.Context, ids []int64) error
+	ReadTrash(ctx context.Context, prevID, size int64) ([]*model.TODO, error)
+	RestoreTODO(ctx context.Context, ids []int64) error
+	ReadRevisions(ctx context.Context, id, prevRevision, size int64) ([]*model.Revision, error)
+	DiffRevisions(ctx context.Context, id, from, to int64) ([]*model.FieldChange, error)
+	RevertTODO(ctx context.Context, id, number int64) (*model.TODO, *model.Revision, error)
+}
+
 // A TODOHandler implements handling REST endpoints.
 type TODOHandler struct {
-	svc *service.TODOService
+	svc TODOService
 }
 
-// NewTODOHandler returns TODOHandler based http.Handler.
-func NewTODOHandler(svc *service.TODOService) *TODOHandler {
+// NewTODOHandler returns TODOHandler, of which the methods prefixed by Serve are registered to the router.
+func NewTODOHandler(svc TODOService) *TODOHandler {
 	return &TODOHandler{
 		svc: svc,
 	}
 }
 
+// ServeRead serves GET /todos, which searches TODOs when q is given.
+func (h *TODOHandler) ServeRead(w http.ResponseWriter, r *http.Request) {
+	var (
+		req = &model.ReadTODORequest{}
+		err error
+	)
+	if req.PrevID, err = queryInt64(r, "prev_id", 0); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if req.Size, err = queryInt64(r, "size", defaultReadSize); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if q := r.URL.Query().Get("q"); strings.TrimSpace(q) != "" {
+		resp, err := h.Search(r.Context(), &model.SearchTODORequest{
+			Query:  q,
+			Mode:   model.SearchMode(r.URL.Query().Get("mode")),
+			PrevID: req.PrevID,
+			Size:   req.Size,
+		})
+		respond(w, r, resp, err)
+		return
+	}
+
+	req.Tags = r.URL.Query()["tag"]
+	switch r.URL.Query().Get("tag_mode") {
+	case "", "all":
+	case "any":
+		req.AnyTag = true
+	default:
+		WriteError(w, r, service.Invalid("tag_mode", service.ViolationInvalid))
+		return
+	}
+	req.Status = model.TODOStatus(r.URL.Query().Get("status"))
+	if req.ListID, err = queryInt64(r, "list_id", 0); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	resp, err := h.Read(r.Context(), req)
+	respond(w, r, resp, err)
+}
+
+// ServeCreate serves POST /todos.
+func (h *TODOHandler) ServeCreate(w http.ResponseWriter, r *http.Request) {
+	req := &model.CreateTODORequest{}
+	if err := decodeJSON(r, req); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	resp, err := h.Create(r.Context(), req)
+	respond(w, r, resp, err)
+}
+
+// ServeGet serves GET /todos/{id}.
+func (h *TODOHandler) ServeGet(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	resp, err := h.Get(r.Context(), &model.GetTODORequest{ID: id})
+	respond(w, r, resp, err)
+}
+
+// ServeUpdate serves PUT /todos and PUT /todos/{id}.
+// The id of the body may be omitted for the latter, and must agree with the path otherwise.
+func (h *TODOHandler) ServeUpdate(w http.ResponseWriter, r *http.Request) {
+	var (
+		id  int64
+		err error
+	)
+	if mux.Param(r, "id") != "" {
+		if id, err = pathID(r); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+	}
+	// the id of the body, if any, replaces that of the path
+	req := &model.UpdateTODORequest{ID: id}
+	if err := decodeJSON(r, req); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if id != 0 && req.ID != id {
+		WriteError(w, r, service.Invalid("id", service.ViolationInvalid))
+		return
+	}
+
+	if req.Version, err = h.ifMatchVersion(r, req.ID, req.Version); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	resp, err := h.Update(r.Context(), req)
+	respond(w, r, resp, err)
+}
+
+// ServeDelete serves DELETE /todos and DELETE /todos/{id}.
+func (h *TODOHandler) ServeDelete(w http.ResponseWriter, r *http.Request) {
+	req := &model.DeleteTODORequest{}
+	if mux.Param(r, "id") != "" {
+		id, err := pathID(r)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		req.IDs = []int64{id}
+	} else if err := decodeJSON(r, req); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	resp, err := h.Delete(r.Context(), req)
+	respond(w, r, resp, err)
+}
+
+// ServeComplete serves POST /todos/{id}/complete.
+func (h *TODOHandler) ServeComplete(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	resp, err := h.Complete(r.Context(), &model.CompleteTODORequest{ID: id})
+	respond(w, r, resp, err)
+}
+
+// ServeReopen serves POST /todos/{id}/reopen.
+func (h *TODOHandler) ServeReopen(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	resp, err := h.Reopen(r.Context(), &model.CompleteTODORequest{ID: id})
+	respond(w, r, resp, err)
+}
+
+// ServeOccurrences serves GET /todos/{id}/occurrences.
+func (h *TODOHandler) ServeOccurrences(w http.ResponseWriter, r *http.Request) {
+	var (
+		req = &model.OccurrencesRequest{}
+		err error
+	)
+	if req.ID, err = pathID(r); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if req.Count, err = queryInt64(r, "count", defaultOccurrences); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	resp, err := h.Occurrences(r.Context(), req)
+	respond(w, r, resp, err)
+}
+
+// ServeHistory serves GET /todos/{id}/history.
+func (h *TODOHandler) ServeHistory(w http.ResponseWriter, r *http.Request) {
+	var (
+		req = &model.HistoryRequest{}
+		err error
+	)
+	if req.ID, err = pathID(r); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if req.PrevID, err = queryInt64(r, "prev_id", 0); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if req.Size, err = queryInt64(r, "size", defaultReadSize); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	resp, err := h.History(r.Context(), req)
+	respond(w, r, resp, err)
+}
+
+// ServeRevisions serves GET /todos/{id}/revisions.
+func (h *TODOHandler) ServeRevisions(w http.ResponseWriter, r *http.Request) {
+	var (
+		req = &model.RevisionsRequest{}
+		err error
+	)
+	if req.ID, err = pathID(r); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if req.PrevRevision, err = queryInt64(r, "prev_revision", 0); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if req.Size, err = queryInt64(r, "size", defaultReadSize); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	resp, err := h.Revisions(r.Context(), req)
+	respond(w, r, resp, err)
+}
+
+// ServeDiff serves GET /todos/{id}/diff.
+func (h *TODOHandler) ServeDiff(w http.ResponseWriter, r *http.Request) {
+	var (
+		req = &model.DiffRequest{}
+		err error
+	)
+	if req.ID, err = pathID(r); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if req.From, err = queryInt64(r, "from", 0); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if req.To, err = queryInt64(r, "to", 0); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	resp, err := h.Diff(r.Context(), req)
+	respond(w, r, resp, err)
+}
+
+// ServeRevert serves POST /todos/{id}/revert.
+func (h *TODOHandler) ServeRevert(w http.ResponseWriter, r *http.Request) {
+	var (
+		req = &model.RevertRequest{}
+		err error
+	)
+	if req.ID, err = pathID(r); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if req.Revision, err = queryInt64(r, "revision", 0); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	resp, err := h.Revert(r.Context(), req)
+	respond(w, r, resp, err)
+}
+
+// ServeDue returns the handler serving GET /todos/{today,overdue,upcoming} of view.
+func (h *TODOHandler) ServeDue(view model.DueView) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, err := dueRequest(r, view)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		resp, err := h.ReadDue(r.Context(), req)
+		respond(w, r, resp, err)
+	}
+}
+
+// ServeTrash serves GET /todos/trash.
+func (h *TODOHandler) ServeTrash(w http.ResponseWriter, r *http.Request) {
+	var (
+		req = &model.TrashTODORequest{}
+		err error
+	)
+	if req.PrevID, err = queryInt64(r, "prev_id", 0); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if req.Size, err = queryInt64(r, "size", defaultReadSize); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	resp, err := h.ReadTrash(r.Context(), req)
+	respond(w, r, resp, err)
+}
+
+// ServeRestore serves POST /todos/restore.
+func (h *TODOHandler) ServeRestore(w http.ResponseWriter, r *http.Request) {
+	req := &model.RestoreTODORequest{}
+	if err := decodeJSON(r, req); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	resp, err := h.Restore(r.Context(), req)
+	respond(w, r, resp, err)
+}
+
+// dueRequest parses the query parameters of reading TODOs of view.
+func dueRequest(r *http.Request, view model.DueView) (*model.DueTODORequest, error) {
+	req := &model.DueTODORequest{View: view}
+	var err error
+	if req.PrevID, err = queryInt64(r, "prev_id", 0); err != nil {
+		return nil, err
+	}
+	if req.Size, err = queryInt64(r, "size", defaultReadSize); err != nil {
+		return nil, err
+	}
+	if req.Days, err = queryInt64(r, "days", defaultUpcomingDays); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
 // Create handles the endpoint that creates the TODO.
 func (h *TODOHandler) Create(ctx context.Context, req *model.CreateTODORequest) (*model.CreateTODOResponse, error) {
-	_, _ = h.svc.CreateTODO(ctx, "", "")
-	return &model.CreateTODOResponse{}, nil
+	if err := service.Validate(req); err != nil {
+		return nil, err
+	}
+
+	in := &service.TODOInput{
+		Subject:     req.Subject,
+		Description: req.Description,
+		Tags:        req.Tags,
+		DueAt:       req.DueAt,
+		ListID:      req.ListID,
+	}
+	if req.Recurrence != nil {
+		rule, err := service.RRule(req.Recurrence)
+		if err != nil {
+			return nil, err
+		}
+		in.Recurrence = &model.Recurrence{RRule: rule}
+	}
+
+	todo, err := h.svc.CreateTODOWithInput(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return &model.CreateTODOResponse{TODO: *todo}, nil
+}
+
+// Get handles the endpoint that reads the TODO.
+func (h *TODOHandler) Get(ctx context.Context, req *model.GetTODORequest) (*model.GetTODOResponse, error) {
+	todo, err := h.svc.GetTODO(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.GetTODOResponse{TODO: *todo}, nil
 }
 
 // Read handles the endpoint that reads the TODOs.
 func (h *TODOHandler) Read(ctx context.Context, req *model.ReadTODORequest) (*model.ReadTODOResponse, error) {
-	_, _ = h.svc.ReadTODO(ctx, 0, 0)
-	return &model.ReadTODOResponse{}, nil
+	if err := pageViolations(req.PrevID, req.Size, "prev_id"); err != nil {
+		return nil, err
+	}
+	switch req.Status {
+	case "", model.TODOStatusOpen, model.TODOStatusDone, model.TODOStatusAll:
+	default:
+		return nil, service.Invalid("status", service.ViolationInvalid)
+	}
+
+	filter := &service.TODOFilter{Tags: req.Tags, AnyTag: req.AnyTag, Status: req.Status, ListID: req.ListID}
+	todos, err := h.svc.FilterTODO(ctx, filter, req.PrevID, req.Size)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ReadTODOResponse{TODOs: todos}, nil
+}
+
+// ReadDue handles the endpoints that read the TODOs by their due date.
+func (h *TODOHandler) ReadDue(ctx context.Context, req *model.DueTODORequest) (*model.ReadTODOResponse, error) {
+	err := pageViolations(req.PrevID, req.Size, "prev_id")
+	if req.Days < 1 || req.Days > maxUpcomingDays {
+		err = appendViolation(err, "days", service.ViolationOutOfRange)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	todos, err := h.svc.ReadDueTODO(ctx, req.View, req.Days, req.PrevID, req.Size)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ReadTODOResponse{TODOs: todos}, nil
+}
+
+// Search handles the endpoint that searches the TODOs.
+func (h *TODOHandler) Search(ctx context.Context, req *model.SearchTODORequest) (*model.SearchTODOResponse, error) {
+	if err := pageViolations(req.PrevID, req.Size, "prev_id"); err != nil {
+		return nil, err
+	}
+	switch req.Mode {
+	case "", model.SearchModeWord, model.SearchModeNgram:
+	default:
+		return nil, service.Invalid("mode", service.ViolationInvalid)
+	}
+
+	todos, err := h.svc.SearchTODO(ctx, req.Mode, req.Query, req.PrevID, req.Size)
+	if err != nil {
+		return nil, err
+	}
+	return &model.SearchTODOResponse{TODOs: todos}, nil
 }
 
 // Update handles the endpoint that updates the TODO.
 func (h *TODOHandler) Update(ctx context.Context, req *model.UpdateTODORequest) (*model.UpdateTODOResponse, error) {
-	_, _ = h.svc.UpdateTODO(ctx, 0, "", "")
-	return &model.UpdateTODOResponse{}, nil
+	if err := service.Validate(req); err != nil {
+		return nil, err
+	}
+
+	in := &service.TODOInput{
+		Subject:       req.Subject,
+		Description:   req.Description,
+		Tags:          req.Tags,
+		DueAt:         req.DueAt.Time,
+		SetDueAt:      req.DueAt.Set,
+		SetRecurrence: req.Recurrence.Set,
+		Version:       req.Version,
+	}
+	if req.Recurrence.Recurrence != nil {
+		rule, err := service.RRule(req.Recurrence.Recurrence)
+		if err != nil {
+			return nil, err
+		}
+		in.Recurrence = &model.Recurrence{RRule: rule}
+	}
+
+	todo, err := h.svc.UpdateTODOWithInput(ctx, req.ID, in)
+	if err != nil {
+		return nil, err
+	}
+	return &model.UpdateTODOResponse{TODO: *todo}, nil
+}
+
+// Complete handles the endpoint that marks the TODO completed.
+func (h *TODOHandler) Complete(ctx context.Context, req *model.CompleteTODORequest) (*model.CompleteTODOResponse, error) {
+	todo, next, err := h.svc.CompleteTODO(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.CompleteTODOResponse{TODO: *todo, Next: next}, nil
+}
+
+// Reopen handles the endpoint that marks the TODO open again.
+func (h *TODOHandler) Reopen(ctx context.Context, req *model.CompleteTODORequest) (*model.CompleteTODOResponse, error) {
+	todo, err := h.svc.ReopenTODO(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.CompleteTODOResponse{TODO: *todo}, nil
+}
+
+// Occurrences handles the endpoint that previews the next occurrences of the recurring TODO.
+func (h *TODOHandler) Occurrences(ctx context.Context, req *model.OccurrencesRequest) (*model.OccurrencesResponse, error) {
+	if req.Count < 1 || req.Count > maxOccurrences {
+		return nil, service.Invalid("count", service.ViolationOutOfRange)
+	}
+
+	occurrences, err := h.svc.PreviewOccurrences(ctx, req.ID, req.Count)
+	if err != nil {
+		return nil, err
+	}
+	return &model.OccurrencesResponse{Occurrences: occurrences}, nil
+}
+
+// History handles the endpoint that reads the completed occurrences of the recurring TODO.
+func (h *TODOHandler) History(ctx context.Context, req *model.HistoryRequest) (*model.ReadTODOResponse, error) {
+	if err := pageViolations(req.PrevID, req.Size, "prev_id"); err != nil {
+		return nil, err
+	}
+
+	todos, err := h.svc.ReadTODOHistory(ctx, req.ID, req.PrevID, req.Size)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ReadTODOResponse{TODOs: todos}, nil
 }
 
-// Delete handles the endpoint that deletes the TODOs.
+// Delete handles the endpoint that moves the TODOs to the trash.
 func (h *TODOHandler) Delete(ctx context.Context, req *model.DeleteTODORequest) (*model.DeleteTODOResponse, error) {
-	_ = h.svc.DeleteTODO(ctx, nil)
+	if err := service.Validate(req); err != nil {
+		return nil, err
+	}
+
+	if err := h.svc.DeleteTODO(ctx, req.IDs); err != nil {
+		return nil, err
+	}
 	return &model.DeleteTODOResponse{}, nil
 }
+
+// Revisions handles the endpoint that reads the revisions of the TODO.
+func (h *TODOHandler) Revisions(ctx context.Context, req *model.RevisionsRequest) (*model.RevisionsResponse, error) {
+	if err := pageViolations(req.PrevRevision, req.Size, "prev_revision"); err != nil {
+		return nil, err
+	}
+
+	revisions, err := h.svc.ReadRevisions(ctx, req.ID, req.PrevRevision, req.Size)
+	if err != nil {
+		return nil, err
+	}
+	return &model.RevisionsResponse{Revisions: revisions}, nil
+}
+
+// Diff handles the endpoint that compares two revisions of the TODO.
+func (h *TODOHandler) Diff(ctx context.Context, req *model.DiffRequest) (*model.DiffResponse, error) {
+	var err error
+	if req.From < 1 {
+		err = appendViolation(err, "from", service.ViolationOutOfRange)
+	}
+	if req.To < 1 {
+		err = appendViolation(err, "to", service.ViolationOutOfRange)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := h.svc.DiffRevisions(ctx, req.ID, req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+	return &model.DiffResponse{From: req.From, To: req.To, Changes: changes}, nil
+}
+
+// Revert handles the endpoint that reverts the TODO to the revision.
+func (h *TODOHandler) Revert(ctx context.Context, req *model.RevertRequest) (*model.RevertResponse, error) {
+	if req.Revision < 1 {
+		return nil, service.Invalid("revision", service.ViolationOutOfRange)
+	}
+
+	todo, rev, err := h.svc.RevertTODO(ctx, req.ID, req.Revision)
+	if err != nil {
+		return nil, err
+	}
+	return &model.RevertResponse{TODO: *todo, Revision: rev}, nil
+}
+
+// ReadTrash handles the endpoint that reads the TODOs in the trash.
+func (h *TODOHandler) ReadTrash(ctx context.Context, req *model.TrashTODORequest) (*model.ReadTODOResponse, error) {
+	if err := pageViolations(req.PrevID, req.Size, "prev_id"); err != nil {
+		return nil, err
+	}
+
+	todos, err := h.svc.ReadTrash(ctx, req.PrevID, req.Size)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ReadTODOResponse{TODOs: todos}, nil
+}
+
+// Restore handles the endpoint that restores the TODOs from the trash.
+func (h *TODOHandler) Restore(ctx context.Context, req *model.RestoreTODORequest) (*model.RestoreTODOResponse, error) {
+	if err := service.Validate(req); err != nil {
+		return nil, err
+	}
+
+	if err := h.svc.RestoreTODO(ctx, req.IDs); err != nil {
+		return nil, err
+	}
+	return &model.RestoreTODOResponse{}, nil
+}
+
+// errBadRequest is returned by handlers when the request is malformed.
+var errBadRequest = errors.New("handler: bad request")
+
+// pageViolations returns *service.ErrValidation when the cursor named by cursorField or size is negative.
+func pageViolations(cursor, size int64, cursorField string) error {
+	var err error
+	if cursor < 0 {
+		err = appendViolation(err, cursorField, service.ViolationOutOfRange)
+	}
+	if size < 0 {
+		err = appendViolation(err, "size", service.ViolationOutOfRange)
+	}
+	return err
+}
+
+// appendViolation returns err, which is nil or *service.ErrValidation, with the violation of field appended.
+func appendViolation(err error, field string, code service.ViolationCode) error {
+	validation, _ := err.(*service.ErrValidation)
+	if validation == nil {
+		validation = &service.ErrValidation{}
+	}
+	validation.Violations = append(validation.Violations, &service.Violation{Field: field, Code: code})
+	return validation
+}
+
+// decodeJSON decodes the request body of a JSON object into the struct v points to, and validates it
+// by service.Validate. A malformed body, including one followed by anything but white space, is a bad request.
+// The members v does not define, matched exactly by name, and the members of the wrong type
+// are reported as violations together with those of service.Validate.
+func decodeJSON(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(r.Body)
+	var body json.RawMessage
+	if err := dec.Decode(&body); err != nil {
+		return fmt.Errorf("%w: %v", errBadRequest, err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("%w: trailing data after the body", errBadRequest)
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(body, &members); err != nil || members == nil {
+		return fmt.Errorf("%w: body is not an object", errBadRequest)
+	}
+
+	var (
+		violations []*service.Violation
+		// reported lists the fields already violating, so that a field is reported once
+		reported = map[string]bool{}
+	)
+	fields := jsonFields(reflect.TypeOf(v).Elem())
+	for name := range members {
+		if !fields[name] {
+			violations = append(violations, &service.Violation{Field: name, Code: service.ViolationUnknown})
+		}
+	}
+	sort.Slice(violations, func(i, j int) bool { return violations[i].Field < violations[j].Field })
+
+	if err := json.Unmarshal(body, v); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if !errors.As(err, &typeErr) {
+			return fmt.Errorf("%w: %v", errBadRequest, err)
+		}
+		violations = append(violations, &service.Violation{Field: typeErr.Field, Code: service.ViolationInvalid})
+		reported[typeErr.Field] = true
+	}
+	var validation *service.ErrValidation
+	if err := service.Validate(v); errors.As(err, &validation) {
+		for _, violation := range validation.Violations {
+			if !reported[violation.Field] {
+				violations = append(violations, violation)
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		return &service.ErrValidation{Violations: violations}
+	}
+	return nil
+}
+
+// jsonFields returns the names of the fields of the struct type t in JSON.
+func jsonFields(t reflect.Type) map[string]bool {
+	fields := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		switch {
+		case name == "-" || f.PkgPath != "":
+		case name == "":
+			fields[f.Name] = true
+		default:
+			fields[name] = true
+		}
+	}
+	return fields
+}
+
+// queryInt64 parses the query parameter by key, returning def when it is absent.
+func queryInt64(r *http.Request, key string, def int64) (int64, error) {
+	s := r.URL.Query().Get(key)
+	if s == "" {
+		return def, nil
+	}
+
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, service.Invalid(key, service.ViolationInvalid)
+	}
+	return v, nil
+}
+
+// pathID parses the id path parameter. A malformed id is not found.
+func pathID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(mux.Param(r, "id"), 10, 64)
+	if err != nil {
+		return 0, &model.ErrNotFound{}
+	}
+	return id, nil
+}
+
+// ifMatchVersion returns the version of the TODO by id the If-Match header requires, or version given
+// by the request body when the header is absent or "*". The header lists ETags compared by the strong
+// comparison of RFC 9110, so that a weak ETag never matches, and version must match as well unless empty.
+// A single candidate is returned for the service to compare atomically with the change, and more are
+// compared with the current TODO, whose version is then required. It returns *service.ErrPreconditionFailed
+// with the current TODO when no ETag matches.
+func (h *TODOHandler) ifMatchVersion(r *http.Request, id int64, version string) (string, error) {
+	v := strings.TrimSpace(r.Header.Get("If-Match"))
+	if v == "" || v == "*" {
+		return version, nil
+	}
+
+	tags, err := parseETags(v)
+	if err != nil {
+		return "", err
+	}
+	candidates := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, "W/") && (version == "" || tag[1:len(tag)-1] == version) {
+			candidates = append(candidates, tag[1:len(tag)-1])
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	}
+
+	todo, err := h.svc.GetTODO(r.Context(), id)
+	if err != nil {
+		return "", err
+	}
+	current := service.Version(todo)
+	for _, candidate := range candidates {
+		if candidate == current {
+			return current, nil
+		}
+	}
+	return "", &service.ErrPreconditionFailed{TODO: todo}
+}
+
+// parseETags parses the comma-separated list of entity tags, each of which is a quoted opaque tag
+// optionally prefixed with "W/" for a weak one, skipping empty elements.
+func parseETags(v string) ([]string, error) {
+	var tags []string
+	for _, tag := range strings.Split(v, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		opaque := strings.TrimPrefix(tag, "W/")
+		if len(opaque) < 2 || opaque[0] != '"' || opaque[len(opaque)-1] != '"' || strings.ContainsAny(opaque[1:len(opaque)-1], "\" ") {
+			return nil, errBadRequest
+		}
+		tags = append(tags, tag)
+	}
+	if len(tags) == 0 {
+		return nil, errBadRequest
+	}
+	return tags, nil
+}
+
+// setETag sets the ETag header to the version of the TODO resp carries, if any.
+func setETag(w http.ResponseWriter, resp interface{}) {
+	var todo *model.TODO
+	switch resp := resp.(type) {
+	case *model.GetTODOResponse:
+		todo = &resp.TODO
+	case *model.CreateTODOResponse:
+		todo = &resp.TODO
+	case *model.UpdateTODOResponse:
+		todo = &resp.TODO
+	case *model.CompleteTODOResponse:
+		todo = &resp.TODO
+	case *model.RevertResponse:
+		todo = &resp.TODO
+	default:
+		return
+	}
+	w.Header().Set("ETag", etag(todo))
+}
+
+// etag returns the strong ETag of the version of todo.
+func etag(todo *model.TODO) string {
+	return `"` + service.Version(todo) + `"`
+}
+
+// respond writes resp with the ETag of the TODO it carries if err is nil, and err otherwise.
+func respond(w http.ResponseWriter, r *http.Request, resp interface{}, err error) {
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	setETag(w, resp)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// writeJSON writes v as the JSON response body with status code.
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("handler: failed to encode response, err =", err)
+	}
+}
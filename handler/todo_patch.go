@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"reflect"
+
+	"github.com/TechBowl-japan/go-stations/jsonpatch"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+const (
+	// mergePatchType is the media type of JSON Merge Patch (RFC 7396).
+	mergePatchType = "application/merge-patch+json"
+	// jsonPatchType is the media type of JSON Patch (RFC 6902).
+	jsonPatchType = "application/json-patch+json"
+)
+
+// patchableMembers lists the members of the TODO representation a patch may change.
+// The other members must be left as they are.
+var patchableMembers = map[string]bool{
+	"subject":     true,
+	"description": true,
+	"tags":        true,
+	"due_at":      true,
+	"recurrence":  true,
+}
+
+// ServePatch serves PATCH /todos/{id}.
+func (h *TODOHandler) ServePatch(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	contentType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (contentType != mergePatchType && contentType != jsonPatchType) {
+		w.Header().Set("Accept-Patch", mergePatchType+", "+jsonPatchType)
+		WriteError(w, r, errUnsupportedMediaType)
+		return
+	}
+
+	req := &model.PatchTODORequest{ID: id, ContentType: contentType}
+	if req.Patch, err = ioutil.ReadAll(r.Body); err != nil {
+		WriteError(w, r, errBadRequest)
+		return
+	}
+	if req.Version, err = h.ifMatchVersion(r, req.ID, ""); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	resp, err := h.Patch(r.Context(), req)
+	respond(w, r, resp, err)
+}
+
+// Patch handles the endpoint that patches the TODO.
+// The patch is applied to the TODO representation, of which only the members in patchableMembers may change.
+func (h *TODOHandler) Patch(ctx context.Context, req *model.PatchTODORequest) (*model.UpdateTODOResponse, error) {
+	var apply func(doc, patch []byte) ([]byte, error)
+	switch req.ContentType {
+	case mergePatchType:
+		apply = jsonpatch.MergePatch
+	case jsonPatchType:
+		apply = jsonpatch.Apply
+	default:
+		return nil, errBadRequest
+	}
+
+	todo, err := h.svc.PatchTODO(ctx, req.ID, req.Version, func(current *model.TODO) (*service.TODOInput, error) {
+		doc, err := patchDocument(current)
+		if err != nil {
+			return nil, err
+		}
+		patched, err := apply(doc, req.Patch)
+		switch {
+		case errors.Is(err, jsonpatch.ErrFailed):
+			return nil, &model.ErrConflict{Message: err.Error()}
+		case err != nil:
+			return nil, fmt.Errorf("%w: %v", errBadRequest, err)
+		}
+		return patchedInput(doc, patched)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &model.UpdateTODOResponse{TODO: *todo}, nil
+}
+
+// patchDocument returns the representation of todo a patch is applied to.
+// Unlike responses, it always has tags so that JSON Patch can add a tag to a TODO without tags.
+func patchDocument(todo *model.TODO) ([]byte, error) {
+	b, err := json.Marshal(todo)
+	if err != nil {
+		return nil, err
+	}
+	members := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &members); err != nil {
+		return nil, err
+	}
+	if _, ok := members["tags"]; !ok {
+		members["tags"] = json.RawMessage(`[]`)
+	}
+	return json.Marshal(members)
+}
+
+// patchedInput returns the values of the TODO the patched document expresses,
+// validated by the same rules as creating a TODO.
+func patchedInput(doc, patched []byte) (*service.TODOInput, error) {
+	var before, after map[string]interface{}
+	if err := json.Unmarshal(doc, &before); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patched, &after); err != nil || after == nil {
+		return nil, fmt.Errorf("%w: patched TODO is not an object", errBadRequest)
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok && !patchableMembers[name] {
+			return nil, fmt.Errorf("%w: unknown member %s", errBadRequest, name)
+		}
+	}
+	for name, v := range before {
+		if w, ok := after[name]; !patchableMembers[name] && (!ok || !reflect.DeepEqual(v, w)) {
+			return nil, fmt.Errorf("%w: %s cannot be changed", errBadRequest, name)
+		}
+	}
+
+	todo := &model.CreateTODORequest{}
+	if err := json.Unmarshal(patched, todo); err != nil {
+		return nil, fmt.Errorf("%w: %v", errBadRequest, err)
+	}
+	if err := service.Validate(todo); err != nil {
+		return nil, err
+	}
+
+	in := &service.TODOInput{
+		Subject:     todo.Subject,
+		Description: todo.Description,
+		Tags:        todo.Tags,
+		DueAt:       todo.DueAt,
+		SetDueAt:    true,
+		// the recurrence is kept unless changed, because its start is derived from the due date
+		SetRecurrence: !reflect.DeepEqual(before["recurrence"], after["recurrence"]),
+	}
+	if in.Tags == nil {
+		in.Tags = []string{}
+	}
+	if in.SetRecurrence && todo.Recurrence != nil {
+		rule, err := service.RRule(todo.Recurrence)
+		if err != nil {
+			return nil, err
+		}
+		in.Recurrence = &model.Recurrence{RRule: rule}
+	}
+	return in, nil
+}
@@ -0,0 +1,392 @@
+// Package jsonpatch implements JSON Merge Patch (RFC 7396) and JSON Patch (RFC 6902).
+//
+// Documents are decoded with numbers kept in their literal form,
+// so that patching does not change numbers it does not touch.
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalid is wrapped by the errors of a malformed document or patch.
+	ErrInvalid = errors.New("jsonpatch: invalid patch")
+	// ErrFailed is wrapped by the errors of a JSON Patch which cannot be applied to the document,
+	// such as one referring to a missing value or having a failing test operation.
+	ErrFailed = errors.New("jsonpatch: patch cannot be applied")
+)
+
+// MergePatch applies the JSON Merge Patch patch to doc and returns the patched document.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	p, err := decode(patch)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(merge(target, p))
+}
+
+// merge merges patch into target as described in section 2 of RFC 7396.
+func merge(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	for name, value := range p {
+		if value == nil {
+			delete(t, name)
+			continue
+		}
+		t[name] = merge(t[name], value)
+	}
+	return t
+}
+
+// An operation expresses an operation of JSON Patch. Members not used by the operation are ignored.
+type operation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Apply applies the JSON Patch patch to doc and returns the patched document.
+// Either every operation is applied or an error is returned.
+func Apply(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	var ops []*operation
+	if err := json.Unmarshal(patch, &ops); err != nil || ops == nil {
+		return nil, fmt.Errorf("%w: patch is not an array of operations", ErrInvalid)
+	}
+
+	for i, op := range ops {
+		if target, err = apply(target, op); err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+	return json.Marshal(target)
+}
+
+// apply applies op to doc and returns the patched document. doc may be modified even on errors.
+func apply(doc interface{}, op *operation) (interface{}, error) {
+	if op == nil || op.Path == nil {
+		return nil, fmt.Errorf("%w: path is required", ErrInvalid)
+	}
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		value interface{}
+		from  []string
+	)
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: value is required by %s", ErrInvalid, op.Op)
+		}
+		if value, err = decode(op.Value); err != nil {
+			return nil, err
+		}
+	case "move", "copy":
+		if op.From == nil {
+			return nil, fmt.Errorf("%w: from is required by %s", ErrInvalid, op.Op)
+		}
+		if from, err = parsePointer(*op.From); err != nil {
+			return nil, err
+		}
+	case "remove":
+	default:
+		return nil, fmt.Errorf("%w: unknown op %q", ErrInvalid, op.Op)
+	}
+
+	switch op.Op {
+	case "add":
+		return add(doc, path, value)
+	case "remove":
+		doc, _, err = remove(doc, path)
+		return doc, err
+	case "replace":
+		if len(path) == 0 {
+			return value, nil
+		}
+		if doc, _, err = remove(doc, path); err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	case "move":
+		if len(from) < len(path) && isPrefix(from, path) {
+			return nil, fmt.Errorf("%w: cannot move %s into its child", ErrInvalid, *op.From)
+		}
+		if doc, value, err = remove(doc, from); err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	case "copy":
+		if value, err = get(doc, from); err != nil {
+			return nil, err
+		}
+		return add(doc, path, clone(value))
+	default: // test
+		current, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(current, value) {
+			return nil, fmt.Errorf("%w: test of %s failed", ErrFailed, *op.Path)
+		}
+		return doc, nil
+	}
+}
+
+// add adds value at path to doc and returns the patched document.
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return update(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch parent := parent.(type) {
+		case map[string]interface{}:
+			parent[token] = value
+			return parent, nil
+		case []interface{}:
+			if token == "-" {
+				return append(parent, value), nil
+			}
+			i, err := index(token, len(parent)+1)
+			if err != nil {
+				return nil, err
+			}
+			parent = append(parent, nil)
+			copy(parent[i+1:], parent[i:])
+			parent[i] = value
+			return parent, nil
+		default:
+			return nil, fmt.Errorf("%w: cannot add %q to a scalar", ErrFailed, token)
+		}
+	})
+}
+
+// remove removes the value at path from doc and returns the patched document and the removed value.
+func remove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("%w: cannot remove the whole document", ErrInvalid)
+	}
+
+	var removed interface{}
+	doc, err := update(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch parent := parent.(type) {
+		case map[string]interface{}:
+			v, ok := parent[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: member %q does not exist", ErrFailed, token)
+			}
+			removed = v
+			delete(parent, token)
+			return parent, nil
+		case []interface{}:
+			i, err := index(token, len(parent))
+			if err != nil {
+				return nil, err
+			}
+			removed = parent[i]
+			return append(parent[:i], parent[i+1:]...), nil
+		default:
+			return nil, fmt.Errorf("%w: cannot remove %q from a scalar", ErrFailed, token)
+		}
+	})
+	return doc, removed, err
+}
+
+// update replaces the parent of the value at the non-empty path in doc by fn
+// and returns the patched document.
+func update(doc interface{}, path []string, fn func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+
+	switch doc := doc.(type) {
+	case map[string]interface{}:
+		child, ok := doc[path[0]]
+		if !ok {
+			return nil, fmt.Errorf("%w: member %q does not exist", ErrFailed, path[0])
+		}
+		child, err := update(child, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		doc[path[0]] = child
+		return doc, nil
+	case []interface{}:
+		i, err := index(path[0], len(doc))
+		if err != nil {
+			return nil, err
+		}
+		if doc[i], err = update(doc[i], path[1:], fn); err != nil {
+			return nil, err
+		}
+		return doc, nil
+	default:
+		return nil, fmt.Errorf("%w: %q of a scalar does not exist", ErrFailed, path[0])
+	}
+}
+
+// get returns the value at path in doc.
+func get(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			v, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: member %q does not exist", ErrFailed, token)
+			}
+			doc = v
+		case []interface{}:
+			i, err := index(token, len(node))
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, fmt.Errorf("%w: %q of a scalar does not exist", ErrFailed, token)
+		}
+	}
+	return doc, nil
+}
+
+// parsePointer splits the JSON Pointer (RFC 6901) s into its unescaped reference tokens.
+func parsePointer(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if s[0] != '/' {
+		return nil, fmt.Errorf("%w: pointer %q does not start with /", ErrInvalid, s)
+	}
+
+	tokens := strings.Split(s[1:], "/")
+	for i, token := range tokens {
+		for j := 0; j < len(token); j++ {
+			if token[j] == '~' && (j+1 == len(token) || (token[j+1] != '0' && token[j+1] != '1')) {
+				return nil, fmt.Errorf("%w: pointer %q has an invalid escape", ErrInvalid, s)
+			}
+		}
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// index parses token as an array index less than n.
+func index(token string, n int) (int, error) {
+	if token == "" || strings.Trim(token, "0123456789") != "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: %q is not an array index", ErrFailed, token)
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is not an array index", ErrFailed, token)
+	}
+	if i >= n {
+		return 0, fmt.Errorf("%w: index %d is out of range", ErrFailed, i)
+	}
+	return i, nil
+}
+
+// isPrefix reports whether prefix is a prefix of path.
+func isPrefix(prefix, path []string) bool {
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// equal reports whether the JSON values a and b are equal as described in section 4.6 of RFC 6902.
+func equal(a, b interface{}) bool {
+	switch a := a.(type) {
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for name, v := range a {
+			w, ok := b[name]
+			if !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		x, okX := new(big.Rat).SetString(a.String())
+		y, okY := new(big.Rat).SetString(b.String())
+		return okX && okY && x.Cmp(y) == 0
+	default:
+		return a == b
+	}
+}
+
+// clone returns a deep copy of the JSON value v.
+func clone(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		cloned := make(map[string]interface{}, len(v))
+		for name, w := range v {
+			cloned[name] = clone(w)
+		}
+		return cloned
+	case []interface{}:
+		cloned := make([]interface{}, len(v))
+		for i, w := range v {
+			cloned[i] = clone(w)
+		}
+		return cloned
+	default:
+		return v
+	}
+}
+
+// decode decodes the single JSON value b.
+func decode(b []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if len(bytes.TrimSpace(b[dec.InputOffset():])) != 0 {
+		return nil, fmt.Errorf("%w: trailing data after the value", ErrInvalid)
+	}
+	return v, nil
+}
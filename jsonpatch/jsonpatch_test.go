@@ -0,0 +1,147 @@
+package jsonpatch_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/TechBowl-japan/go-stations/jsonpatch"
+)
+
+func TestMergePatch(t *testing.T) {
+	t.Parallel()
+
+	// the examples of appendix A of RFC 7396
+	cases := map[string]struct {
+		doc, patch, want string
+	}{
+		"Replace":             {doc: `{"a":"b"}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		"Add":                 {doc: `{"a":"b"}`, patch: `{"b":"c"}`, want: `{"a":"b","b":"c"}`},
+		"Remove":              {doc: `{"a":"b"}`, patch: `{"a":null}`, want: `{}`},
+		"Remove one":          {doc: `{"a":"b","b":"c"}`, patch: `{"a":null}`, want: `{"b":"c"}`},
+		"Array replaces":      {doc: `{"a":["b"]}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		"Replaces array":      {doc: `{"a":"c"}`, patch: `{"a":["b"]}`, want: `{"a":["b"]}`},
+		"Nested":              {doc: `{"a":{"b":"c"}}`, patch: `{"a":{"b":"d","c":null}}`, want: `{"a":{"b":"d"}}`},
+		"Array of objects":    {doc: `{"a":[{"b":"c"}]}`, patch: `{"a":[1]}`, want: `{"a":[1]}`},
+		"Whole array":         {doc: `["a","b"]`, patch: `["c","d"]`, want: `["c","d"]`},
+		"Array by object":     {doc: `{"a":"b"}`, patch: `["c"]`, want: `["c"]`},
+		"Null by object":      {doc: `{"a":"foo"}`, patch: `null`, want: `null`},
+		"String by object":    {doc: `{"a":"foo"}`, patch: `"bar"`, want: `"bar"`},
+		"Null value kept":     {doc: `{"e":null}`, patch: `{"a":1}`, want: `{"e":null,"a":1}`},
+		"Array into object":   {doc: `[1,2]`, patch: `{"a":"b","c":null}`, want: `{"a":"b"}`},
+		"Object into nothing": {doc: `{}`, patch: `{"a":{"bb":{"ccc":null}}}`, want: `{"a":{"bb":{}}}`},
+		"Number kept as is":   {doc: `{"a":1.50,"b":1}`, patch: `{"b":2}`, want: `{"a":1.50,"b":2}`},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := jsonpatch.MergePatch([]byte(c.doc), []byte(c.patch))
+			if err != nil {
+				t.Fatal("failed to merge patch, err =", err)
+			}
+			assertJSON(t, got, c.want)
+		})
+	}
+
+	for _, patch := range []string{``, `{"a":`, `{"a":1} {}`} {
+		if _, err := jsonpatch.MergePatch([]byte(`{}`), []byte(patch)); !errors.Is(err, jsonpatch.ErrInvalid) {
+			t.Errorf("unexpected error of %q, given = %v", patch, err)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+
+	// mostly the examples of appendix A of RFC 6902
+	cases := map[string]struct {
+		doc, patch, want string
+	}{
+		"Add member":          {doc: `{"foo":"bar"}`, patch: `[{"op":"add","path":"/baz","value":"qux"}]`, want: `{"baz":"qux","foo":"bar"}`},
+		"Add element":         {doc: `{"foo":["bar","baz"]}`, patch: `[{"op":"add","path":"/foo/1","value":"qux"}]`, want: `{"foo":["bar","qux","baz"]}`},
+		"Append element":      {doc: `{"foo":["bar"]}`, patch: `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, want: `{"foo":["bar",["abc","def"]]}`},
+		"Add at end":          {doc: `{"foo":["bar"]}`, patch: `[{"op":"add","path":"/foo/1","value":"baz"}]`, want: `{"foo":["bar","baz"]}`},
+		"Add nested member":   {doc: `{"foo":"bar"}`, patch: `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, want: `{"foo":"bar","child":{"grandchild":{}}}`},
+		"Add null":            {doc: `{"foo":"bar"}`, patch: `[{"op":"add","path":"/baz","value":null}]`, want: `{"foo":"bar","baz":null}`},
+		"Replace document":    {doc: `{"foo":"bar"}`, patch: `[{"op":"replace","path":"","value":[1]}]`, want: `[1]`},
+		"Remove member":       {doc: `{"baz":"qux","foo":"bar"}`, patch: `[{"op":"remove","path":"/baz"}]`, want: `{"foo":"bar"}`},
+		"Remove element":      {doc: `{"foo":["bar","qux","baz"]}`, patch: `[{"op":"remove","path":"/foo/1"}]`, want: `{"foo":["bar","baz"]}`},
+		"Replace":             {doc: `{"baz":"qux","foo":"bar"}`, patch: `[{"op":"replace","path":"/baz","value":"boo"}]`, want: `{"baz":"boo","foo":"bar"}`},
+		"Replace last":        {doc: `{"foo":[1,2]}`, patch: `[{"op":"replace","path":"/foo/1","value":3}]`, want: `{"foo":[1,3]}`},
+		"Move member":         {doc: `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, patch: `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, want: `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		"Move element":        {doc: `{"foo":["all","grass","cows","eat"]}`, patch: `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, want: `{"foo":["all","cows","eat","grass"]}`},
+		"Copy":                {doc: `{"foo":{"a":1}}`, patch: `[{"op":"copy","from":"/foo","path":"/bar"},{"op":"add","path":"/bar/b","value":2}]`, want: `{"foo":{"a":1},"bar":{"a":1,"b":2}}`},
+		"Test":                {doc: `{"baz":"qux","foo":["a",2,"c"]}`, patch: `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, want: `{"baz":"qux","foo":["a",2,"c"]}`},
+		"Test numbers":        {doc: `{"foo":1.0}`, patch: `[{"op":"test","path":"/foo","value":1}]`, want: `{"foo":1.0}`},
+		"Test objects":        {doc: `{"foo":{"a":[1],"b":null}}`, patch: `[{"op":"test","path":"/foo","value":{"b":null,"a":[1]}}]`, want: `{"foo":{"a":[1],"b":null}}`},
+		"Escaped pointer":     {doc: `{"/":9,"~1":10}`, patch: `[{"op":"test","path":"/~01","value":10},{"op":"remove","path":"/~1"}]`, want: `{"~1":10}`},
+		"Ignored members":     {doc: `{"foo":"bar"}`, patch: `[{"op":"add","path":"/baz","value":"qux","xyz":123}]`, want: `{"foo":"bar","baz":"qux"}`},
+		"Empty patch":         {doc: `{"foo":"bar"}`, patch: `[]`, want: `{"foo":"bar"}`},
+		"Empty member name":   {doc: `{"":1}`, patch: `[{"op":"replace","path":"/","value":2}]`, want: `{"":2}`},
+		"Numeric member name": {doc: `{"1":1}`, patch: `[{"op":"remove","path":"/1"}]`, want: `{}`},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := jsonpatch.Apply([]byte(c.doc), []byte(c.patch))
+			if err != nil {
+				t.Fatal("failed to apply patch, err =", err)
+			}
+			assertJSON(t, got, c.want)
+		})
+	}
+
+	errCases := map[string]struct {
+		patch string
+		err   error
+	}{
+		"Not an array":         {patch: `{"op":"add","path":"/a","value":1}`, err: jsonpatch.ErrInvalid},
+		"Unknown op":           {patch: `[{"op":"merge","path":"/a","value":1}]`, err: jsonpatch.ErrInvalid},
+		"Missing path":         {patch: `[{"op":"add","value":1}]`, err: jsonpatch.ErrInvalid},
+		"Missing value":        {patch: `[{"op":"add","path":"/a"}]`, err: jsonpatch.ErrInvalid},
+		"Missing from":         {patch: `[{"op":"copy","path":"/a"}]`, err: jsonpatch.ErrInvalid},
+		"Relative pointer":     {patch: `[{"op":"remove","path":"foo"}]`, err: jsonpatch.ErrInvalid},
+		"Invalid escape":       {patch: `[{"op":"remove","path":"/a~2"}]`, err: jsonpatch.ErrInvalid},
+		"Move into child":      {patch: `[{"op":"move","from":"/foo","path":"/foo/x"}]`, err: jsonpatch.ErrInvalid},
+		"Remove document":      {patch: `[{"op":"remove","path":""}]`, err: jsonpatch.ErrInvalid},
+		"Missing member":       {patch: `[{"op":"remove","path":"/baz"}]`, err: jsonpatch.ErrFailed},
+		"Missing parent":       {patch: `[{"op":"add","path":"/baz/bat","value":"qux"}]`, err: jsonpatch.ErrFailed},
+		"Index out of range":   {patch: `[{"op":"add","path":"/foo/3","value":"x"}]`, err: jsonpatch.ErrFailed},
+		"Index with zero":      {patch: `[{"op":"replace","path":"/foo/01","value":"x"}]`, err: jsonpatch.ErrFailed},
+		"Negative index":       {patch: `[{"op":"remove","path":"/foo/-1"}]`, err: jsonpatch.ErrFailed},
+		"Into scalar":          {patch: `[{"op":"add","path":"/bar/x","value":1}]`, err: jsonpatch.ErrFailed},
+		"Failed test":          {patch: `[{"op":"test","path":"/bar","value":"1"}]`, err: jsonpatch.ErrFailed},
+		"Failed after applied": {patch: `[{"op":"add","path":"/baz","value":1},{"op":"test","path":"/bar","value":2}]`, err: jsonpatch.ErrFailed},
+	}
+	for name, c := range errCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			doc := []byte(`{"foo":["a","b"],"bar":1}`)
+			if _, err := jsonpatch.Apply(doc, []byte(c.patch)); !errors.Is(err, c.err) {
+				t.Errorf("unexpected error, given = %v, expected = %v", err, c.err)
+			}
+		})
+	}
+}
+
+func assertJSON(t *testing.T, got []byte, want string) {
+	t.Helper()
+
+	var g, w interface{}
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatal("failed to unmarshal result, err =", err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatal("failed to unmarshal expected, err =", err)
+	}
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("unexpected document, given = %s, expected = %s", got, want)
+	}
+}
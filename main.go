@@ -1,56 +1,197 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/TechBowl-japan/go-stations/config"
 	"github.com/TechBowl-japan/go-stations/db"
 	"github.com/TechBowl-japan/go-stations/handler/router"
+	"github.com/TechBowl-japan/go-stations/metrics"
+	"github.com/TechBowl-japan/go-stations/oidc"
+	"github.com/TechBowl-japan/go-stations/ratelimit"
+	"github.com/TechBowl-japan/go-stations/service"
 )
 
 func main() {
-	err := realMain()
+	err := realMain(os.Args[1:])
 	if err != nil {
 		log.Fatalln("main: failed to exit successfully, err =", err)
 	}
 }
 
-func realMain() error {
-	// config values
-	const (
-		defaultPort   = ":8080"
-		defaultDBPath = ".sqlite3/todo.db"
-	)
-
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = defaultPort
+func realMain(args []string) error {
+	// config values, of which the flags are defined to fs
+	fs := flag.NewFlagSet("station", flag.ContinueOnError)
+	printConfig := fs.Bool("print-config", false, "print the configuration with secrets redacted and exit")
+	cfg, err := config.Load(fs, args, os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
 	}
-
-	dbPath := os.Getenv("DB_PATH")
-	if dbPath == "" {
-		dbPath = defaultDBPath
+	if err != nil {
+		return err
+	}
+	if *printConfig {
+		return cfg.Print(os.Stdout)
 	}
 
-	// set time zone
-	var err error
-	time.Local, err = time.LoadLocation("Asia/Tokyo")
+	// set time zone, in which days of due dates are delimited
+	time.Local, err = time.LoadLocation(cfg.TimeZone)
 	if err != nil {
 		return err
 	}
 
-	// set up sqlite3
-	todoDB, err := db.NewDB(dbPath)
+	// set up sqlite3, which the sub commands share except migrate
+	open := db.Open
+	if cfg.Features.AutoMigrate {
+		open = db.NewDB
+	}
+	openDB := func() (*sql.DB, error) {
+		return open(cfg.DB.DSN())
+	}
+
+	// sub commands
+	if args := fs.Args(); len(args) > 0 {
+		switch args[0] {
+		case "migrate":
+			return runMigrate(cfg.DB.DSN(), args[1:], os.Stdout)
+		case "user":
+			return runUser(openDB, args[1:], os.Stdin, os.Stdout)
+		case "token":
+			return runToken(openDB, args[1:], os.Stdout)
+		case "adopt":
+			return runAdopt(openDB, args[1:], os.Stdout)
+		default:
+			return fmt.Errorf("main: unknown sub command, given = %s", args[0])
+		}
+	}
+
+	todoDB, err := openDB()
 	if err != nil {
 		return err
 	}
-	defer todoDB.Close()
+	todoDB.SetMaxOpenConns(cfg.DB.MaxOpenConns)
+
+	// SIGINT and SIGTERM stop the server and the purger
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// the provider is discovered on startup, which fails unless it is reachable
+	var rp *oidc.RelyingParty
+	if cfg.OIDC.Issuer != "" {
+		rp, err = oidc.New(ctx, oidc.Config{
+			Issuer:       cfg.OIDC.Issuer,
+			ClientID:     cfg.OIDC.ClientID,
+			ClientSecret: string(cfg.OIDC.ClientSecret),
+			RedirectURL:  cfg.OIDC.RedirectURL,
+		})
+		if err != nil {
+			todoDB.Close()
+			return err
+		}
+	}
 
+	// purge trashed TODOs in background
+	purgerCtx, cancelPurger := context.WithCancel(ctx)
+	purgerDone := make(chan struct{})
+	go func() {
+		defer close(purgerDone)
+		if cfg.Features.TrashPurge {
+			service.NewTODOService(todoDB).RunPurger(purgerCtx, time.Duration(cfg.Trash.Retention), time.Duration(cfg.Trash.PurgeInterval))
+		}
+	}()
+
+	// access logs are written at info
+	opts := &router.Options{
+		HealthCheckTimeout: time.Duration(cfg.Health.CheckTimeout),
+		RequireAuth:        cfg.Auth.Required,
+		// sessions are rotated and cookies limited to HTTPS by default
+		SessionTTL:            time.Duration(cfg.Session.TTL),
+		SessionRotateInterval: time.Duration(cfg.Session.RotateInterval),
+		InsecureCookies:       !cfg.Session.SecureCookies,
+		OIDC:                  rp,
+		OIDCRedirect:          cfg.OIDC.PostLoginURL,
+		MaxInFlight:           cfg.Server.MaxInFlight,
+	}
+	if cfg.RateLimit.Enabled {
+		opts.ReadLimit = ratelimit.New(float64(cfg.RateLimit.ReadRate), cfg.RateLimit.ReadBurst)
+		opts.WriteLimit = ratelimit.New(float64(cfg.RateLimit.WriteRate), cfg.RateLimit.WriteBurst)
+	}
+	if cfg.Log.Level.Enabled(config.LogInfo) {
+		opts.AccessLog = log.Default()
+	}
+	// metrics are served by the server unless the admin listener is given
+	if cfg.Metrics.Enabled {
+		opts.Metrics = metrics.New()
+		opts.Metrics.RegisterDB(todoDB, cfg.DB.File())
+		opts.ServeMetrics = cfg.Metrics.Addr == ""
+	}
 	// NOTE: 新しいエンドポイントの登録はrouter.NewRouterの内部で行うようにする
-	mux := router.NewRouter(todoDB)
+	mux := router.NewRouterWithOptions(service.NewSQLiteTODORepository(todoDB), opts)
 
-	// TODO: サーバーをlistenする
+	srv := &http.Server{
+		Addr:              cfg.Server.Addr,
+		Handler:           mux,
+		ReadHeaderTimeout: time.Duration(cfg.Server.ReadHeaderTimeout),
+		ReadTimeout:       time.Duration(cfg.Server.ReadTimeout),
+		WriteTimeout:      time.Duration(cfg.Server.WriteTimeout),
+		IdleTimeout:       time.Duration(cfg.Server.IdleTimeout),
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+	}
+	ln, err := net.Listen("tcp", cfg.Server.Addr)
+	if err == nil {
+		log.Println("main: listening on", ln.Addr())
+		listeners := []*listener{{srv: srv, ln: ln}}
+		if opts.Metrics != nil && !opts.ServeMetrics {
+			var admin *listener
+			admin, err = listenAdmin(cfg, opts.Metrics)
+			if err != nil {
+				ln.Close()
+			} else {
+				listeners = append(listeners, admin)
+			}
+		}
+		if err == nil {
+			err = serveAll(ctx, listeners, time.Duration(cfg.Server.ShutdownTimeout))
+		}
+	}
 
-	return nil
+	// the database is closed after every request and the purger are finished with it
+	cancelPurger()
+	<-purgerDone
+	if cerr := todoDB.Close(); cerr != nil && err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// listenAdmin returns the admin listener serving m at /metrics on cfg.Metrics.Addr.
+func listenAdmin(cfg *config.Config, m *metrics.Metrics) (*listener, error) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	srv := &http.Server{
+		Addr:              cfg.Metrics.Addr,
+		Handler:           mux,
+		ReadHeaderTimeout: time.Duration(cfg.Server.ReadHeaderTimeout),
+		ReadTimeout:       time.Duration(cfg.Server.ReadTimeout),
+		WriteTimeout:      time.Duration(cfg.Server.WriteTimeout),
+		IdleTimeout:       time.Duration(cfg.Server.IdleTimeout),
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+	}
+	ln, err := net.Listen("tcp", cfg.Metrics.Addr)
+	if err != nil {
+		return nil, err
+	}
+	log.Println("main: serving metrics on", ln.Addr())
+	return &listener{srv: srv, ln: ln}, nil
 }
@@ -0,0 +1,152 @@
+// Package metrics exports the metrics of the server in the Prometheus text format.
+//
+// The metrics of HTTP requests are labeled by the route pattern rather than the path,
+// such as /todos/{id}, so that the number of series stays bounded. Requests routed to
+// no pattern are labeled by the route unmatched, and requests of methods other than
+// the standard ones are labeled by the method OTHER for the same reason.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes the names of the metrics of the server.
+const namespace = "todo"
+
+// UnmatchedRoute is the route label of the requests routed to no pattern.
+const UnmatchedRoute = "unmatched"
+
+// OtherMethod is the method label of the requests of methods other than the standard ones.
+const OtherMethod = "OTHER"
+
+// A Metrics collects the metrics of the server into its own registry.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	callDuration    *prometheus.HistogramVec
+	callErrors      *prometheus.CounterVec
+}
+
+// New returns new Metrics with the metrics of the Go runtime and the process.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Number of HTTP requests served.",
+		}, []string{"route", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of HTTP requests served.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		callDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "service",
+			Name:      "call_duration_seconds",
+			Help:      "Latency of calls of TODOService methods.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method"}),
+		callErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "service",
+			Name:      "call_errors_total",
+			Help:      "Number of calls of TODOService methods returning an error.",
+		}, []string{"method"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.callDuration,
+		m.callErrors,
+	)
+	return m
+}
+
+// Handler returns the handler exposing the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records an HTTP request routed to the pattern route, answered with status after elapsed.
+func (m *Metrics) ObserveRequest(route, method string, status int, elapsed time.Duration) {
+	if route == "" {
+		route = UnmatchedRoute
+	}
+	method = Method(method)
+	code := strconv.Itoa(status)
+	m.requests.WithLabelValues(route, method, code).Inc()
+	m.requestDuration.WithLabelValues(route, method, code).Observe(elapsed.Seconds())
+}
+
+// Method returns the method label of the requests of method, which is OtherMethod unless method is
+// one of the standard methods, since a client may send any token as the method.
+func Method(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	default:
+		return OtherMethod
+	}
+}
+
+// ObserveCall implements service.Observer interface.
+func (m *Metrics) ObserveCall(method string, elapsed time.Duration, err error) {
+	m.callDuration.WithLabelValues(method).Observe(elapsed.Seconds())
+	// the counter is exported as 0 until the method fails
+	failures := m.callErrors.WithLabelValues(method)
+	if err != nil {
+		failures.Inc()
+	}
+}
+
+// RegisterDB adds the connection pool statistics of db and the size of its SQLite file at path.
+// The size is not exported while the file cannot be stated, such as for an in-memory database.
+// It panics when a database is already registered.
+func (m *Metrics) RegisterDB(db *sql.DB, path string) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, namespace), &fileSizeCollector{
+		path: path,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "sqlite", "file_size_bytes"),
+			"Size of the SQLite database file.",
+			nil, nil,
+		),
+	})
+}
+
+// A fileSizeCollector collects the size of the file at path.
+type fileSizeCollector struct {
+	path string
+	desc *prometheus.Desc
+}
+
+// Describe implements prometheus.Collector interface.
+func (c *fileSizeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect implements prometheus.Collector interface.
+func (c *fileSizeCollector) Collect(ch chan<- prometheus.Metric) {
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(info.Size()))
+}
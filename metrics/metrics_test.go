@@ -0,0 +1,82 @@
+package metrics_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "metrics_test.db")
+	d, err := db.NewDB(path)
+	if err != nil {
+		t.Fatal("failed to create db, err =", err)
+	}
+	t.Cleanup(func() {
+		if err := d.Close(); err != nil {
+			t.Error("failed to close db, err =", err)
+		}
+	})
+
+	m := metrics.New()
+	m.RegisterDB(d, path)
+	m.ObserveRequest("/todos/{id}", "GET", 200, 10*time.Millisecond)
+	m.ObserveRequest("/todos/{id}", "GET", 200, 20*time.Millisecond)
+	m.ObserveRequest("", "GET", 404, time.Millisecond)
+	m.ObserveRequest("", "FOO", 405, time.Millisecond)
+	m.ObserveRequest("", "BAR", 405, time.Millisecond)
+	m.ObserveCall("CreateTODO", time.Millisecond, nil)
+	m.ObserveCall("DeleteTODO", time.Millisecond, errors.New("failed"))
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	for _, line := range []string{
+		`todo_http_requests_total{method="GET",route="/todos/{id}",status="200"} 2`,
+		`todo_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`todo_http_requests_total{method="OTHER",route="unmatched",status="405"} 2`,
+		`todo_http_request_duration_seconds_count{method="GET",route="/todos/{id}",status="200"} 2`,
+		`todo_service_call_duration_seconds_count{method="CreateTODO"} 1`,
+		`todo_service_call_errors_total{method="CreateTODO"} 0`,
+		`todo_service_call_errors_total{method="DeleteTODO"} 1`,
+		`go_sql_open_connections{db_name="todo"}`,
+		`go_sql_in_use_connections{db_name="todo"}`,
+		`go_sql_wait_count_total{db_name="todo"}`,
+		`todo_sqlite_file_size_bytes `,
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("unexpected metrics without %s, given =\n%s", line, body)
+		}
+	}
+}
+
+func TestMetrics_RegisterDB(t *testing.T) {
+	t.Parallel()
+
+	d, err := db.NewDB(filepath.Join(t.TempDir(), "metrics_test.db"))
+	if err != nil {
+		t.Fatal("failed to create db, err =", err)
+	}
+	t.Cleanup(func() {
+		if err := d.Close(); err != nil {
+			t.Error("failed to close db, err =", err)
+		}
+	})
+
+	// the size of a missing file is not exported
+	m := metrics.New()
+	m.RegisterDB(d, filepath.Join(t.TempDir(), "missing.db"))
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if strings.Contains(w.Body.String(), "todo_sqlite_file_size_bytes ") {
+		t.Errorf("unexpected file size, given =\n%s", w.Body.String())
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/db"
+)
+
+const migrateUsage = "usage: migrate up|down|status"
+
+// runMigrate runs the migrate sub command against the database at dbPath.
+func runMigrate(dbPath string, args []string, w io.Writer) error {
+	if len(args) != 1 {
+		return errors.New(migrateUsage)
+	}
+
+	todoDB, err := db.Open(dbPath)
+	if err != nil {
+		return err
+	}
+	defer todoDB.Close()
+
+	m, err := db.NewMigrator(todoDB)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		if err := m.Up(ctx); err != nil {
+			return err
+		}
+	case "down":
+		if err := m.Down(ctx); err != nil {
+			return err
+		}
+	case "status":
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT")
+	for _, st := range statuses {
+		appliedAt := "pending"
+		if len(st.Missing) > 0 {
+			appliedAt = fmt.Sprintf("pending (requires %s)", strings.Join(st.Missing, ", "))
+		}
+		if st.AppliedAt != nil {
+			appliedAt = st.AppliedAt.In(time.Local).Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%04d\t%s\t%s\n", st.Version, st.Name, appliedAt)
+	}
+	return tw.Flush()
+}
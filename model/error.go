@@ -0,0 +1,22 @@
+package model
+
+// An ErrNotFound expresses that the requested entity does not exist.
+type ErrNotFound struct{}
+
+// Error implements error interface.
+func (e *ErrNotFound) Error() string {
+	return "not found"
+}
+
+// An ErrConflict expresses that the request conflicts with the current state of the entity.
+type ErrConflict struct {
+	Message string
+}
+
+// Error implements error interface.
+func (e *ErrConflict) Error() string {
+	if e.Message == "" {
+		return "conflict"
+	}
+	return "conflict: " + e.Message
+}
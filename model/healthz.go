@@ -1,4 +1,36 @@
 package model
 
 // A HealthzResponse expresses health check message.
-type HealthzResponse struct{}
+type HealthzResponse struct {
+	Message string `json:"message"`
+}
+
+// A HealthStatus is the status of a health check or of the server.
+type HealthStatus string
+
+// Statuses of health checks.
+const (
+	// HealthStatusOK is the status of a passed check, and of the server when every check passed.
+	HealthStatusOK HealthStatus = "ok"
+	// HealthStatusDegraded is the status of the server when only non-critical checks failed.
+	HealthStatusDegraded HealthStatus = "degraded"
+	// HealthStatusFail is the status of a failed check, and of the server when a critical check failed.
+	HealthStatusFail HealthStatus = "fail"
+)
+
+type (
+	// A HealthReport expresses the results of the health checks of the server.
+	HealthReport struct {
+		Status HealthStatus   `json:"status"`
+		Checks []*HealthCheck `json:"checks"`
+	}
+
+	// A HealthCheck expresses the result of a health check.
+	HealthCheck struct {
+		Name      string       `json:"name"`
+		Status    HealthStatus `json:"status"`
+		Critical  bool         `json:"critical"`
+		LatencyMS float64      `json:"latency_ms"`
+		Error     string       `json:"error,omitempty"`
+	}
+)
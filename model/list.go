@@ -0,0 +1,112 @@
+package model
+
+import "time"
+
+// A Role expresses what a member of a list may do with it.
+type Role string
+
+const (
+	// RoleViewer reads the list and its TODOs.
+	RoleViewer Role = "viewer"
+	// RoleEditor also creates, updates, completes and deletes the TODOs of the list.
+	RoleEditor Role = "editor"
+	// RoleOwner also renames and deletes the list and manages its members.
+	RoleOwner Role = "owner"
+)
+
+// roleRanks orders the roles, each of which allows everything the lower ones do.
+var roleRanks = map[Role]int{RoleViewer: 1, RoleEditor: 2, RoleOwner: 3}
+
+// Valid reports whether r is one of the defined roles.
+func (r Role) Valid() bool {
+	return roleRanks[r] > 0
+}
+
+// Allows reports whether r allows what required does.
+func (r Role) Allows(required Role) bool {
+	return r.Valid() && roleRanks[r] >= roleRanks[required]
+}
+
+type (
+	// A List expresses a group of TODOs shared by its members.
+	List struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+		// Role is the role of the caller in the list.
+		Role      Role      `json:"role"`
+		CreatedAt time.Time `json:"created_at"`
+		UpdatedAt time.Time `json:"updated_at"`
+	}
+
+	// A Member expresses a user sharing a list with the role.
+	Member struct {
+		UserID    int64     `json:"user_id"`
+		Name      string    `json:"name"`
+		Role      Role      `json:"role"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	// A CreateListRequest expresses the request body of creating a list.
+	CreateListRequest struct {
+		Name string `json:"name" validate:"trim,nfc,required,max=100"`
+	}
+	// A CreateListResponse expresses the response body of creating a list.
+	CreateListResponse struct {
+		List *List `json:"list"`
+	}
+
+	// A ReadListResponse expresses the response body of reading lists.
+	ReadListResponse struct {
+		Lists []*List `json:"lists"`
+	}
+
+	// A GetListRequest expresses the path parameters of reading a list or its members.
+	GetListRequest struct {
+		ID int64
+	}
+	// A GetListResponse expresses the response body of reading a list.
+	GetListResponse struct {
+		List *List `json:"list"`
+	}
+
+	// A UpdateListRequest expresses the request body of renaming a list.
+	UpdateListRequest struct {
+		ID   int64  `json:"-"`
+		Name string `json:"name" validate:"trim,nfc,required,max=100"`
+	}
+	// A UpdateListResponse expresses the response body of renaming a list.
+	UpdateListResponse struct {
+		List *List `json:"list"`
+	}
+
+	// A DeleteListRequest expresses the path parameters of deleting a list.
+	DeleteListRequest struct {
+		ID int64
+	}
+	// A DeleteListResponse expresses the response body of deleting a list.
+	DeleteListResponse struct{}
+
+	// A ReadMemberResponse expresses the response body of reading the members of a list.
+	ReadMemberResponse struct {
+		Members []*Member `json:"members"`
+	}
+
+	// A PutMemberRequest expresses the request of adding a user to a list or changing the role of a member.
+	PutMemberRequest struct {
+		ListID   int64  `json:"-"`
+		UserName string `json:"-"`
+		Role     Role   `json:"role" validate:"required"`
+	}
+	// A PutMemberResponse expresses the response body of adding or changing a member.
+	PutMemberResponse struct {
+		Member *Member `json:"member"`
+	}
+
+	// A DeleteMemberRequest expresses the path parameters of removing a member from a list.
+	DeleteMemberRequest struct {
+		ListID   int64
+		UserName string
+	}
+	// A DeleteMemberResponse expresses the response body of removing a member.
+	DeleteMemberResponse struct{}
+)
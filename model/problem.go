@@ -0,0 +1,26 @@
+package model
+
+type (
+	// A Problem expresses an error response body in the form of RFC 7807 problem details.
+	Problem struct {
+		// Type is the URI identifying the type of the problem, which is stable across versions.
+		Type   string `json:"type"`
+		Title  string `json:"title"`
+		Status int    `json:"status"`
+		Detail string `json:"detail,omitempty"`
+		// InvalidParams lists the violations of validation rules.
+		InvalidParams []*InvalidParam `json:"invalid_params,omitempty"`
+		// RequestID is the ID of the request the problem occurred in, if any.
+		RequestID string `json:"request_id,omitempty"`
+		// TODO is the current TODO when the TODO is not at the version the request expects.
+		TODO *TODO `json:"todo,omitempty"`
+	}
+
+	// A InvalidParam expresses a parameter of a request violating a validation rule.
+	InvalidParam struct {
+		Name string `json:"name"`
+		// Code is the stable identifier of the violated rule, such as required.
+		Code   string `json:"code"`
+		Reason string `json:"reason"`
+	}
+)
@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+type (
+	// A Recurrence expresses how a recurring TODO repeats.
+	// The occurrences of a recurring TODO share the same recurrence.
+	Recurrence struct {
+		ID int64 `json:"id"`
+		// RRule is the RFC 5545 RRULE value in the canonical form.
+		RRule string `json:"rrule"`
+		// Start is the due date of the first occurrence.
+		Start time.Time `json:"dtstart"`
+		// TimeZone is the time zone in which the wall clock of Start is repeated.
+		TimeZone string `json:"tzid"`
+	}
+
+	// A RecurrenceRequest expresses a recurrence in a request body.
+	// It is given either by RRule or by Frequency and the fields following it.
+	RecurrenceRequest struct {
+		// RRule is an RFC 5545 RRULE value.
+		RRule string `json:"rrule"`
+		// Frequency is one of daily, weekly and monthly.
+		Frequency string `json:"frequency"`
+		// Interval is the number of days, weeks or months between occurrences. 0 means 1.
+		Interval int `json:"interval"`
+		// Weekdays lists the weekdays of weekly recurrences by their two letter names such as mo.
+		Weekdays []string `json:"weekdays"`
+		// MonthDay is the day of monthly recurrences. Negative days count from the end of the month.
+		MonthDay int `json:"month_day"`
+	}
+
+	// A OccurrencesRequest expresses the parameters of previewing the occurrences of a recurring TODO.
+	OccurrencesRequest struct {
+		ID    int64
+		Count int64
+	}
+	// A OccurrencesResponse expresses the response body of previewing the occurrences of a recurring TODO.
+	OccurrencesResponse struct {
+		Occurrences []time.Time `json:"occurrences"`
+	}
+
+	// A HistoryRequest expresses the parameters of reading the completed occurrences of a recurring TODO.
+	HistoryRequest struct {
+		ID     int64
+		PrevID int64
+		Size   int64
+	}
+)
@@ -0,0 +1,85 @@
+package model
+
+import "time"
+
+// A RevisionAction expresses the kind of change a revision records.
+type RevisionAction string
+
+const (
+	// RevisionActionCreate records a created TODO.
+	RevisionActionCreate RevisionAction = "create"
+	// RevisionActionUpdate records an updated TODO.
+	RevisionActionUpdate RevisionAction = "update"
+	// RevisionActionComplete records a completed TODO.
+	RevisionActionComplete RevisionAction = "complete"
+	// RevisionActionReopen records a reopened TODO.
+	RevisionActionReopen RevisionAction = "reopen"
+	// RevisionActionDelete records a TODO moved to the trash.
+	RevisionActionDelete RevisionAction = "delete"
+	// RevisionActionRestore records a TODO restored from the trash.
+	RevisionActionRestore RevisionAction = "restore"
+	// RevisionActionRevert records a TODO reverted to an earlier revision.
+	RevisionActionRevert RevisionAction = "revert"
+)
+
+type (
+	// A Revision expresses a change of a TODO.
+	// Revisions of a TODO are numbered from 1 in the order they are made.
+	Revision struct {
+		Number int64          `json:"revision"`
+		TODOID int64          `json:"todo_id"`
+		Action RevisionAction `json:"action"`
+		// Actor is who made the change, omitted when the change is made anonymously.
+		Actor string `json:"actor,omitempty"`
+		// Reverted is the number of the revision a revert restored.
+		Reverted int64 `json:"reverted,omitempty"`
+		// Before is the TODO before the change, which is null on create and restore.
+		Before *TODO `json:"before"`
+		// After is the TODO after the change, which is null on delete.
+		After     *TODO     `json:"after"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	// A FieldChange expresses a field of a TODO which differs between two revisions.
+	// A field of a deleted TODO is null.
+	FieldChange struct {
+		Field string      `json:"field"`
+		From  interface{} `json:"from"`
+		To    interface{} `json:"to"`
+	}
+
+	// A RevisionsRequest expresses the parameters of reading the revisions of a TODO.
+	RevisionsRequest struct {
+		ID           int64
+		PrevRevision int64
+		Size         int64
+	}
+	// A RevisionsResponse expresses the response body of reading the revisions of a TODO.
+	RevisionsResponse struct {
+		Revisions []*Revision `json:"revisions"`
+	}
+
+	// A DiffRequest expresses the parameters of comparing two revisions of a TODO.
+	DiffRequest struct {
+		ID   int64
+		From int64
+		To   int64
+	}
+	// A DiffResponse expresses the response body of comparing two revisions of a TODO.
+	DiffResponse struct {
+		From    int64          `json:"from"`
+		To      int64          `json:"to"`
+		Changes []*FieldChange `json:"changes"`
+	}
+
+	// A RevertRequest expresses the parameters of reverting a TODO to a revision.
+	RevertRequest struct {
+		ID       int64
+		Revision int64
+	}
+	// A RevertResponse expresses the response body of reverting a TODO to a revision.
+	RevertResponse struct {
+		TODO     TODO      `json:"todo"`
+		Revision *Revision `json:"revision"`
+	}
+)
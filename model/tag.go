@@ -0,0 +1,34 @@
+package model
+
+type (
+	// A Tag expresses a label of TODOs with the number of TODOs it is attached to.
+	Tag struct {
+		Name  string `json:"name"`
+		Count int64  `json:"count"`
+	}
+
+	// A ReadTagResponse expresses the response body of reading tags.
+	ReadTagResponse struct {
+		Tags []*Tag `json:"tags"`
+	}
+
+	// A RenameTagRequest expresses the request body of renaming a tag.
+	RenameTagRequest struct {
+		From string `json:"from" validate:"trim,nfc,required"`
+		To   string `json:"to" validate:"trim,nfc,required"`
+	}
+	// A RenameTagResponse expresses the response body of renaming a tag.
+	RenameTagResponse struct {
+		Tag *Tag `json:"tag"`
+	}
+
+	// A MergeTagRequest expresses the request body of merging tags.
+	MergeTagRequest struct {
+		Sources []string `json:"sources" validate:"trim,nfc,required"`
+		Into    string   `json:"into" validate:"trim,nfc,required"`
+	}
+	// A MergeTagResponse expresses the response body of merging tags.
+	MergeTagResponse struct {
+		Tag *Tag `json:"tag"`
+	}
+)
@@ -1,26 +1,235 @@
 package model
 
+import (
+	"encoding/json"
+	"time"
+)
+
+// A SearchMode expresses how TODOs are matched by full-text search.
+type SearchMode string
+
+const (
+	// SearchModeWord matches whole words split by spaces and punctuation.
+	SearchModeWord SearchMode = "word"
+	// SearchModeNgram matches substrings by normalized bigrams,
+	// which works for Japanese text without spaces between words.
+	SearchModeNgram SearchMode = "ngram"
+)
+
+// A TODOStatus expresses which TODOs are listed by their completion.
+type TODOStatus string
+
+const (
+	// TODOStatusOpen matches TODOs not completed yet.
+	TODOStatusOpen TODOStatus = "open"
+	// TODOStatusDone matches completed TODOs.
+	TODOStatusDone TODOStatus = "done"
+	// TODOStatusAll matches every TODO.
+	TODOStatusAll TODOStatus = "all"
+)
+
+// A DueView expresses which TODOs are listed by their due date.
+// Days are delimited in the time zone of the server.
+type DueView string
+
+const (
+	// DueViewOverdue matches open TODOs due before today.
+	DueViewOverdue DueView = "overdue"
+	// DueViewToday matches open TODOs due today.
+	DueViewToday DueView = "today"
+	// DueViewUpcoming matches open TODOs due within the given days after today.
+	DueViewUpcoming DueView = "upcoming"
+)
+
 type (
-	// A TODO expresses ...
-	TODO struct{}
-
-	// A CreateTODORequest expresses ...
-	CreateTODORequest struct{}
-	// A CreateTODOResponse expresses ...
-	CreateTODOResponse struct{}
-
-	// A ReadTODORequest expresses ...
-	ReadTODORequest struct{}
-	// A ReadTODOResponse expresses ...
-	ReadTODOResponse struct{}
-
-	// A UpdateTODORequest expresses ...
-	UpdateTODORequest struct{}
-	// A UpdateTODOResponse expresses ...
-	UpdateTODOResponse struct{}
-
-	// A DeleteTODORequest expresses ...
-	DeleteTODORequest struct{}
-	// A DeleteTODOResponse expresses ...
+	// A TODO expresses a task with its subject and description.
+	TODO struct {
+		ID          int64    `json:"id"`
+		Subject     string   `json:"subject"`
+		Description string   `json:"description"`
+		Tags        []string `json:"tags,omitempty"`
+		// Completed and CompletedAt are omitted while the TODO is open.
+		Completed   bool       `json:"completed,omitempty"`
+		CompletedAt *time.Time `json:"completed_at,omitempty"`
+		DueAt       *time.Time `json:"due_at,omitempty"`
+		// Recurrence is omitted unless the TODO is an occurrence of a recurring TODO.
+		Recurrence *Recurrence `json:"recurrence,omitempty"`
+		// DeletedAt is omitted unless the TODO is in the trash.
+		DeletedAt *time.Time `json:"deleted_at,omitempty"`
+		// OwnerID is the ID of the user owning the TODO, omitted for the TODOs of anonymous callers.
+		OwnerID int64 `json:"owner_id,omitempty"`
+		// ListID is the ID of the list the TODO belongs to, omitted for the TODOs of no list.
+		ListID    int64     `json:"list_id,omitempty"`
+		CreatedAt time.Time `json:"created_at"`
+		UpdatedAt time.Time `json:"updated_at"`
+	}
+
+	// A CreateTODORequest expresses the request body of creating a TODO.
+	// Strings are normalized to NFC, and Subject is limited to 200 characters after trimmed.
+	CreateTODORequest struct {
+		Subject     string     `json:"subject" validate:"trim,nfc,required,max=200"`
+		Description string     `json:"description" validate:"nfc"`
+		Tags        []string   `json:"tags" validate:"trim,nfc"`
+		DueAt       *time.Time `json:"due_at"`
+		// Recurrence makes the TODO recurring, which requires DueAt.
+		Recurrence *RecurrenceRequest `json:"recurrence"`
+		// ListID creates the TODO in the list, which requires the editor role.
+		ListID int64 `json:"list_id"`
+	}
+	// A CreateTODOResponse expresses the response body of creating a TODO.
+	CreateTODOResponse struct {
+		TODO TODO `json:"todo"`
+	}
+
+	// A GetTODORequest expresses the parameters of reading a TODO.
+	GetTODORequest struct {
+		ID int64
+	}
+	// A GetTODOResponse expresses the response body of reading a TODO.
+	GetTODOResponse struct {
+		TODO TODO `json:"todo"`
+	}
+
+	// A ReadTODORequest expresses the query parameters of reading TODOs.
+	ReadTODORequest struct {
+		PrevID int64
+		Size   int64
+		// Tags lists the tags TODOs must have.
+		Tags []string
+		// AnyTag makes TODOs having any one of Tags match instead of all of them.
+		AnyTag bool
+		Status TODOStatus
+		// ListID narrows down TODOs to those of the list unless 0.
+		ListID int64
+	}
+	// A ReadTODOResponse expresses the response body of reading TODOs.
+	ReadTODOResponse struct {
+		TODOs []*TODO `json:"todos"`
+	}
+
+	// A UpdateTODORequest expresses the request body of updating a TODO.
+	// It is normalized and limited as CreateTODORequest.
+	UpdateTODORequest struct {
+		ID          int64  `json:"id" validate:"required"`
+		Subject     string `json:"subject" validate:"trim,nfc,required,max=200"`
+		Description string `json:"description" validate:"nfc"`
+		// Tags replaces the tags of the TODO unless omitted.
+		Tags []string `json:"tags" validate:"trim,nfc"`
+		// DueAt replaces the due date of the TODO unless omitted. null removes it.
+		DueAt OptionalTime `json:"due_at"`
+		// Recurrence replaces the recurrence of the TODO unless omitted. null stops the recurrence.
+		Recurrence OptionalRecurrence `json:"recurrence"`
+		// Version makes the update fail unless the TODO is at the version, which is its ETag without quotes.
+		Version string `json:"version"`
+	}
+	// A UpdateTODOResponse expresses the response body of updating a TODO.
+	UpdateTODOResponse struct {
+		TODO TODO `json:"todo"`
+	}
+
+	// A PatchTODORequest expresses the request of patching a TODO.
+	PatchTODORequest struct {
+		ID int64
+		// ContentType is the media type of Patch, either JSON Merge Patch or JSON Patch.
+		ContentType string
+		Patch       []byte
+		// Version makes the patch fail unless the TODO is at the version. An empty Version means any version.
+		Version string
+	}
+
+	// A DueTODORequest expresses the query parameters of reading TODOs by their due date.
+	DueTODORequest struct {
+		View   DueView
+		Days   int64
+		PrevID int64
+		Size   int64
+	}
+
+	// A CompleteTODORequest expresses the path parameters of completing or reopening a TODO.
+	CompleteTODORequest struct {
+		ID int64
+	}
+	// A CompleteTODOResponse expresses the response body of completing or reopening a TODO.
+	CompleteTODOResponse struct {
+		TODO TODO `json:"todo"`
+		// Next is the next occurrence created by completing an occurrence of a recurring TODO.
+		Next *TODO `json:"next,omitempty"`
+	}
+
+	// A SearchTODORequest expresses the query parameters of searching TODOs.
+	SearchTODORequest struct {
+		Query  string
+		Mode   SearchMode
+		PrevID int64
+		Size   int64
+	}
+	// A SearchTODOResponse expresses the response body of searching TODOs.
+	SearchTODOResponse struct {
+		TODOs []*TODOSearchResult `json:"todos"`
+	}
+	// A TODOSearchResult expresses a TODO matched by full-text search.
+	TODOSearchResult struct {
+		TODO
+		// Rank is the bm25 score of the match. Smaller is more relevant.
+		Rank      float64       `json:"rank"`
+		Highlight TODOHighlight `json:"highlight"`
+	}
+	// A TODOHighlight expresses HTML escaped fragments of a matched TODO
+	// with the matched terms wrapped in <mark> elements.
+	TODOHighlight struct {
+		Subject     string `json:"subject"`
+		Description string `json:"description"`
+	}
+
+	// A DeleteTODORequest expresses the request body of deleting TODOs.
+	// At most 100 TODOs are deleted at once.
+	DeleteTODORequest struct {
+		IDs []int64 `json:"ids" validate:"required,max=100"`
+	}
+	// A DeleteTODOResponse expresses the response body of deleting TODOs.
 	DeleteTODOResponse struct{}
+
+	// A TrashTODORequest expresses the query parameters of reading TODOs in the trash.
+	TrashTODORequest struct {
+		PrevID int64
+		Size   int64
+	}
+
+	// A RestoreTODORequest expresses the request body of restoring TODOs from the trash.
+	// At most 100 TODOs are restored at once.
+	RestoreTODORequest struct {
+		IDs []int64 `json:"ids" validate:"required,max=100"`
+	}
+	// A RestoreTODOResponse expresses the response body of restoring TODOs from the trash.
+	RestoreTODOResponse struct{}
 )
+
+// An OptionalTime expresses a nullable time in a request body
+// which tells an omitted value from null.
+type OptionalTime struct {
+	// Set reports whether the value is given, including null.
+	Set  bool
+	Time *time.Time
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+// It is called only when the value is given.
+func (t *OptionalTime) UnmarshalJSON(b []byte) error {
+	t.Set = true
+	return json.Unmarshal(b, &t.Time)
+}
+
+// An OptionalRecurrence expresses a nullable recurrence in a request body
+// which tells an omitted value from null.
+type OptionalRecurrence struct {
+	// Set reports whether the value is given, including null.
+	Set        bool
+	Recurrence *RecurrenceRequest
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+// It is called only when the value is given.
+func (r *OptionalRecurrence) UnmarshalJSON(b []byte) error {
+	r.Set = true
+	return json.Unmarshal(b, &r.Recurrence)
+}
@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+type (
+	// A User expresses an owner of TODOs, who authenticates with API tokens.
+	User struct {
+		ID        int64     `json:"id"`
+		Name      string    `json:"name"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	// An APIToken expresses an API token of a user, of which only the hash is stored.
+	APIToken struct {
+		ID     int64  `json:"id"`
+		UserID int64  `json:"user_id"`
+		Name   string `json:"name"`
+		// LastUsedAt is omitted until the token authenticates a request.
+		LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+		// RevokedAt is omitted unless the token is revoked.
+		RevokedAt *time.Time `json:"revoked_at,omitempty"`
+		CreatedAt time.Time  `json:"created_at"`
+	}
+
+	// A Session expresses a cookie session of a user, of which only the hash of the token is stored.
+	// The CSRF token is bound to the session and submitted by the client in both a cookie and a header.
+	Session struct {
+		ID        int64     `json:"id"`
+		User      *User     `json:"user"`
+		CSRFToken string    `json:"csrf_token"`
+		CreatedAt time.Time `json:"created_at"`
+		// RotatedAt is when the token of the session is replaced last, which is CreatedAt at first.
+		RotatedAt time.Time `json:"rotated_at"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+
+	// A LoginRequest expresses the request body of logging in.
+	LoginRequest struct {
+		Name     string `json:"name" validate:"trim,required"`
+		Password string `json:"password" validate:"required"`
+	}
+	// A LoginResponse expresses the response body of logging in.
+	LoginResponse struct {
+		Session *Session `json:"session"`
+	}
+)
@@ -0,0 +1,155 @@
+package ngram
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// Index returns the normalized bigrams of s joined by spaces, suitable for
+// the FTS5 ascii tokenizer. Each run of letters and digits yields its
+// overlapping bigrams followed by its last rune, so that a single rune query
+// can match by prefix.
+func Index(s string) string {
+	var b strings.Builder
+	for _, run := range runs(normalizeRunes(s)) {
+		for _, token := range tokens(run) {
+			if b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(token)
+		}
+	}
+	return b.String()
+}
+
+// Query returns the FTS5 query matching every whitespace separated term of s
+// as a substring after normalization. It returns an empty string when s has no terms.
+func Query(s string) string {
+	var phrases []string
+	for _, run := range runs(normalizeRunes(s)) {
+		if len(run) == 1 {
+			phrases = append(phrases, `"`+string(run)+`"*`)
+			continue
+		}
+
+		tokens := tokens(run)
+		// the trailing rune is implied by the last bigram
+		phrases = append(phrases, `"`+strings.Join(tokens[:len(tokens)-1], " ")+`"`)
+	}
+	return strings.Join(phrases, " ")
+}
+
+// Highlight returns s HTML escaped with every normalized occurrence of the terms
+// of query wrapped in <mark> elements. When width is positive, the result is cut
+// down to about width runes around the first occurrence.
+func Highlight(s, query string, width int) string {
+	text, spans := normalize(s)
+
+	marked := make([]bool, len(text))
+	first := -1
+	for _, term := range runs(normalizeRunes(query)) {
+		for i := 0; i+len(term) <= len(text); i++ {
+			if !hasPrefix(text[i:], term) {
+				continue
+			}
+			for j := i; j < i+len(term); j++ {
+				marked[j] = true
+			}
+			if first < 0 || i < first {
+				first = i
+			}
+		}
+	}
+
+	lo, hi := 0, len(text)
+	if width > 0 && len(text) > width {
+		if first < 0 {
+			first = 0
+		}
+		lo = first - width/4
+		if lo < 0 {
+			lo = 0
+		}
+		hi = lo + width
+		if hi > len(text) {
+			hi = len(text)
+			lo = hi - width
+		}
+	}
+
+	var b strings.Builder
+	if lo > 0 {
+		b.WriteString("…")
+	}
+	for i := lo; i < hi; {
+		j := i
+		for j < hi && marked[j] == marked[i] {
+			j++
+		}
+		fragment := html.EscapeString(s[spans[i].Start:spans[j-1].End])
+		if marked[i] {
+			fragment = "<mark>" + fragment + "</mark>"
+		}
+		b.WriteString(fragment)
+		i = j
+	}
+	if hi < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+func normalizeRunes(s string) []rune {
+	runes, _ := normalize(s)
+	return runes
+}
+
+// runs splits runes into the runs of letters and digits.
+func runs(runes []rune) [][]rune {
+	var (
+		runs  [][]rune
+		start = -1
+	)
+	for i, r := range runes {
+		if isTokenRune(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			runs = append(runs, runes[start:i])
+			start = -1
+		}
+	}
+	if start >= 0 {
+		runs = append(runs, runes[start:])
+	}
+	return runs
+}
+
+// tokens returns the bigrams of run followed by its last rune.
+func tokens(run []rune) []string {
+	tokens := make([]string, 0, len(run))
+	for i := 0; i+1 < len(run); i++ {
+		tokens = append(tokens, string(run[i:i+2]))
+	}
+	return append(tokens, string(run[len(run)-1:]))
+}
+
+func isTokenRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.Is(unicode.Mn, r)
+}
+
+func hasPrefix(s, prefix []rune) bool {
+	if len(s) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if s[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,120 @@
+package ngram_test
+
+import (
+	"testing"
+
+	"github.com/TechBowl-japan/go-stations/ngram"
+)
+
+func TestNormalize(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		in, want string
+	}{
+		"Katakana":                  {in: "カイモノ", want: "かいもの"},
+		"Half-width katakana":       {in: "ｶｲﾓﾉ", want: "かいもの"},
+		"Half-width voiced kana":    {in: "ｶﾞｯｺｳﾆﾊﾟﾝ", want: "がっこうにぱん"},
+		"Combining voiced mark":     {in: "がぱ", want: "がぱ"},
+		"Full-width ASCII":          {in: "ＴＯＤＯ１２３！", want: "todo123!"},
+		"Ideographic space":         {in: "買い物　リスト", want: "買い物 りすと"},
+		"Kanji are kept":            {in: "買い物", want: "買い物"},
+		"Unvoicable kana are kept":  {in: "ナ゙", want: "な゙"},
+		"Long vowel mark is kept":   {in: "ｽｰﾊﾟｰ", want: "すーぱー"},
+		"Katakana only letters":     {in: "ヴヵヶヷ", want: "ゔゕゖヷ"},
+		"Upper case is lower cased": {in: "Buy MILK", want: "buy milk"},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := ngram.Normalize(c.in); got != c.want {
+				t.Errorf("unexpected value, given = %q, expected = %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestIndex(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		in, want string
+	}{
+		"Japanese":     {in: "買い物リスト", want: "買い い物 物り りす すと と"},
+		"Single rune":  {in: "猫", want: "猫"},
+		"Punctuation":  {in: "牛乳、パン", want: "牛乳 乳 ぱん ん"},
+		"Latin":        {in: "Buy milk", want: "bu uy y mi il lk k"},
+		"Empty string": {in: "", want: ""},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := ngram.Index(c.in); got != c.want {
+				t.Errorf("unexpected value, given = %q, expected = %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestQuery(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		in, want string
+	}{
+		"Partial term":   {in: "物リス", want: `"物り りす"`},
+		"Two runes":      {in: "ｶｲ", want: `"かい"`},
+		"Single rune":    {in: "猫", want: `"猫"*`},
+		"Multiple terms": {in: "牛乳　パン", want: `"牛乳" "ぱん"`},
+		"Quotes":         {in: `"milk`, want: `"mi il lk"`},
+		"No terms":       {in: " 、 ", want: ""},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := ngram.Query(c.in); got != c.want {
+				t.Errorf("unexpected value, given = %q, expected = %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		in, query string
+		width     int
+		want      string
+	}{
+		"Folded match":      {in: "カイモノ", query: "かいもの", want: "<mark>カイモノ</mark>"},
+		"Half-width match":  {in: "ｶﾞｯｺｳへ行く", query: "がっこう", want: "<mark>ｶﾞｯｺｳ</mark>へ行く"},
+		"Partial match":     {in: "買い物リスト", query: "物リス", want: "買い<mark>物リス</mark>ト"},
+		"Escaped":           {in: "<b>牛乳</b>", query: "牛乳", want: "&lt;b&gt;<mark>牛乳</mark>&lt;/b&gt;"},
+		"Multiple matches":  {in: "パンとぱん", query: "パン", want: "<mark>パン</mark>と<mark>ぱん</mark>"},
+		"No match":          {in: "牛乳", query: "パン", want: "牛乳"},
+		"Snippet":           {in: "あいうえおかきくけこさしすせそ", query: "さし", width: 4, want: "…こ<mark>さし</mark>す…"},
+		"Snippet at head":   {in: "あいうえおかきくけこ", query: "あい", width: 4, want: "<mark>あい</mark>うえ…"},
+		"Snippet not found": {in: "あいうえおかきくけこ", query: "ぱん", width: 4, want: "あいうえ…"},
+	}
+
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := ngram.Highlight(c.in, c.query, c.width); got != c.want {
+				t.Errorf("unexpected value, given = %q, expected = %q", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+// Package ngram implements Japanese-aware text normalization and bigram tokenization for full-text search.
+package ngram
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// halfWidthKana lists the full-width forms of U+FF61 through U+FF9F in order.
+// The half-width voiced sound marks map to the combining ones so that they are composed afterwards.
+var halfWidthKana = []rune("。「」、・ヲァィゥェォャュョッーアイウエオカキクケコサシスセソタチツテトナニヌネノハヒフヘホマミムメモヤユヨラリルレロワン\u3099\u309A")
+
+const (
+	// voicable lists the kana that take the voiced sound mark at the next code point.
+	voicable = "かきくけこさしすせそたちつてとはひふへほカキクケコサシスセソタチツテトハヒフヘホゝヽ"
+	// semiVoicable lists the kana that take the semi-voiced sound mark two code points after.
+	semiVoicable = "はひふへほハヒフヘホ"
+)
+
+// A Span expresses the byte range of the original text a normalized rune comes from.
+type Span struct {
+	Start, End int
+}
+
+// Normalize folds s for matching: full-width ASCII and half-width katakana are
+// converted to their usual width, voiced sound marks are composed,
+// katakana is folded into hiragana and letters are lower cased.
+func Normalize(s string) string {
+	runes, _ := normalize(s)
+	return string(runes)
+}
+
+// normalize returns the normalized runes of s and the Span of s each of them comes from.
+func normalize(s string) ([]rune, []Span) {
+	runes := make([]rune, 0, len(s))
+	spans := make([]Span, 0, len(s))
+
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		span := Span{Start: i, End: i + size}
+		i += size
+
+		r = foldWidth(r)
+		if isSoundMark(r) && len(runes) > 0 {
+			if composed, ok := compose(runes[len(runes)-1], r); ok {
+				runes[len(runes)-1] = composed
+				spans[len(spans)-1].End = span.End
+				continue
+			}
+		}
+
+		runes = append(runes, r)
+		spans = append(spans, span)
+	}
+
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(foldKana(r))
+	}
+
+	return runes, spans
+}
+
+// foldWidth converts full-width ASCII and half-width katakana into their usual width.
+func foldWidth(r rune) rune {
+	switch {
+	case r == '　':
+		return ' '
+	case r >= '！' && r <= '～':
+		return r - 0xFEE0
+	case r >= '｡' && r <= 'ﾟ':
+		return halfWidthKana[r-'｡']
+	}
+	return r
+}
+
+// isSoundMark reports whether r is a combining or spacing (semi-)voiced sound mark.
+func isSoundMark(r rune) bool {
+	return r >= '\u3099' && r <= '\u309C'
+}
+
+// compose returns the kana r voiced by mark.
+func compose(r, mark rune) (rune, bool) {
+	switch mark {
+	case '\u3099', '\u309B':
+		switch {
+		case containsRune(voicable, r):
+			return r + 1, true
+		case r == 'う':
+			return 'ゔ', true
+		case r == 'ウ':
+			return 'ヴ', true
+		case r >= 'ワ' && r <= 'ヲ':
+			return r + 8, true
+		}
+	case '\u309A', '\u309C':
+		if containsRune(semiVoicable, r) {
+			return r + 2, true
+		}
+	}
+	return r, false
+}
+
+// foldKana converts katakana into hiragana.
+func foldKana(r rune) rune {
+	switch {
+	case r >= 'ァ' && r <= 'ヶ', r == 'ヽ' || r == 'ヾ':
+		return r - 0x60
+	}
+	return r
+}
+
+func containsRune(s string, r rune) bool {
+	for _, c := range s {
+		if c == r {
+			return true
+		}
+	}
+	return false
+}
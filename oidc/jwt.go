@@ -0,0 +1,192 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+// keyRefetchInterval is how often the key set may be fetched again for an unknown key ID,
+// so that tokens of made-up key IDs do not make the relying party hammer the provider.
+const keyRefetchInterval = 10 * time.Second
+
+// A JSONWebKey is a key of a JSON Web Key Set as defined by RFC 7517. Only RSA keys are used.
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid,omitempty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+}
+
+// A JSONWebKeySet is the document served at the jwks_uri of a provider.
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// NewJSONWebKey returns the JSONWebKey of the RSA public key identified by keyID, signing by RS256.
+func NewJSONWebKey(key *rsa.PublicKey, keyID string) JSONWebKey {
+	return JSONWebKey{
+		KeyType:   "RSA",
+		KeyID:     keyID,
+		Use:       "sig",
+		Algorithm: "RS256",
+		N:         base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+// publicKey returns the RSA public key of k.
+func (k *JSONWebKey) publicKey() (*rsa.PublicKey, error) {
+	if k.KeyType != "RSA" {
+		return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+	}
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	exp := new(big.Int).SetBytes(e)
+	if !exp.IsInt64() || exp.Int64() < 3 || exp.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+}
+
+// A keySet caches the signing keys published by a provider, fetching them again for an unknown key ID.
+type keySet struct {
+	uri     string
+	getJSON func(ctx context.Context, u string, v interface{}) error
+	now     func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+// newKeySet returns keySet of the keys served at uri, which are fetched on the first use.
+func newKeySet(uri string, getJSON func(ctx context.Context, u string, v interface{}) error, now func() time.Time) *keySet {
+	return &keySet{uri: uri, getJSON: getJSON, now: now}
+}
+
+// key returns the key by kid, which may be empty when the provider publishes a single key.
+func (s *keySet) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key := s.lookup(kid); key != nil {
+		return key, nil
+	}
+	if s.keys != nil && s.now().Sub(s.fetchedAt) < keyRefetchInterval {
+		return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, kid)
+	}
+
+	var set JSONWebKeySet
+	if err := s.getJSON(ctx, s.uri, &set); err != nil {
+		return nil, fmt.Errorf("oidc: failed to fetch keys: %w", err)
+	}
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.KeyID] = key
+	}
+	s.keys, s.fetchedAt = keys, s.now()
+
+	if key := s.lookup(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, kid)
+}
+
+// lookup returns the cached key by kid, or the only key when kid is empty.
+func (s *keySet) lookup(kid string) *rsa.PublicKey {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key
+		}
+	}
+	return s.keys[kid]
+}
+
+// verifyJWT verifies the signature of the compact serialized JWT raw by RS256, and decodes its claims into v.
+func verifyJWT(ctx context.Context, keys *keySet, raw string, v interface{}) error {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("%w: malformed", ErrInvalidToken)
+	}
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return fmt.Errorf("%w: malformed header: %v", ErrInvalidToken, err)
+	}
+	// the algorithm is fixed rather than taken from the header, which the sender chooses
+	if header.Algorithm != "RS256" {
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, header.Algorithm)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return fmt.Errorf("%w: malformed signature", ErrInvalidToken)
+	}
+
+	key, err := keys.key(ctx, header.KeyID)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+		return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+	if err := decodeSegment(parts[1], v); err != nil {
+		return fmt.Errorf("%w: malformed claims: %v", ErrInvalidToken, err)
+	}
+	return nil
+}
+
+// decodeSegment decodes the base64url encoded JSON segment of a JWT into v.
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// SignRS256 returns the compact serialized JWT of the claims signed by the key identified by keyID.
+func SignRS256(key *rsa.PrivateKey, keyID string, claims interface{}) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": keyID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signing := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signing))
+	sig, err := rsa.SignPKCS1v15(nil, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signing + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
@@ -0,0 +1,256 @@
+// Package oidc implements the relying party of OpenID Connect logging users in by the authorization code flow.
+//
+// The authorization requests are protected by PKCE with the S256 method, a state and a nonce.
+// ID tokens are accepted only when signed by RS256 with a key published in the JWKS of the provider,
+// issued by the provider to the client, unexpired and bound to the nonce of the authorization request.
+// The package oidctest implements a provider serving in process for the tests of the flow.
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultScopes are the scopes requested unless Config gives them.
+var DefaultScopes = []string{"openid", "profile", "email"}
+
+var (
+	// ErrInvalidToken is wrapped by the errors of ID tokens rejected by Verify.
+	ErrInvalidToken = errors.New("oidc: invalid ID token")
+	// ErrExchangeRejected is wrapped by the errors of codes the token endpoint rejects, such as used or expired ones.
+	ErrExchangeRejected = errors.New("oidc: code exchange rejected")
+)
+
+// Config configures a relying party.
+type Config struct {
+	// Issuer is the URL of the provider, at which the discovery document is served under /.well-known/openid-configuration.
+	Issuer string
+	// ClientID and ClientSecret identify the client registered to the provider.
+	// The secret is sent by HTTP basic authentication unless it is empty.
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the URL of the callback registered to the provider.
+	RedirectURL string
+	// Scopes are the scopes requested, DefaultScopes when it is empty.
+	Scopes []string
+	// HTTPClient requests the provider, a client timing out in 10 seconds when it is nil.
+	HTTPClient *http.Client
+}
+
+// A RelyingParty logs users in with a provider.
+type RelyingParty struct {
+	cfg      Config
+	client   *http.Client
+	endpoint endpoint
+	keys     *keySet
+	now      func() time.Time
+}
+
+// endpoint is the part of the discovery document used by the relying party.
+type endpoint struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Claims are the claims of a verified ID token.
+type Claims struct {
+	Issuer            string   `json:"iss"`
+	Subject           string   `json:"sub"`
+	Audience          audience `json:"aud"`
+	AuthorizedParty   string   `json:"azp"`
+	Expiry            int64    `json:"exp"`
+	IssuedAt          int64    `json:"iat"`
+	Nonce             string   `json:"nonce"`
+	Name              string   `json:"name"`
+	PreferredUsername string   `json:"preferred_username"`
+	Email             string   `json:"email"`
+	EmailVerified     bool     `json:"email_verified"`
+}
+
+// audience decodes the aud claim, which is either a string or an array of strings.
+type audience []string
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+	var ss []string
+	if err := json.Unmarshal(b, &ss); err != nil {
+		return err
+	}
+	*a = ss
+	return nil
+}
+
+// New returns the RelyingParty of the provider configured by the discovery document at the issuer of cfg.
+func New(ctx context.Context, cfg Config) (*RelyingParty, error) {
+	if cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, errors.New("oidc: issuer, client ID and redirect URL are required")
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = DefaultScopes
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	rp := &RelyingParty{cfg: cfg, client: client, now: time.Now}
+	discovery := strings.TrimSuffix(cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := rp.getJSON(ctx, discovery, &rp.endpoint); err != nil {
+		return nil, fmt.Errorf("oidc: failed to discover provider: %w", err)
+	}
+	if rp.endpoint.Issuer != cfg.Issuer {
+		return nil, fmt.Errorf("oidc: discovered issuer %q does not match %q", rp.endpoint.Issuer, cfg.Issuer)
+	}
+	if rp.endpoint.AuthorizationEndpoint == "" || rp.endpoint.TokenEndpoint == "" || rp.endpoint.JWKSURI == "" {
+		return nil, errors.New("oidc: discovery document lacks endpoints")
+	}
+	rp.keys = newKeySet(rp.endpoint.JWKSURI, rp.getJSON, func() time.Time { return rp.now() })
+	return rp, nil
+}
+
+// Issuer returns the issuer of the provider.
+func (rp *RelyingParty) Issuer() string {
+	return rp.cfg.Issuer
+}
+
+// SetClock replaces the clock ID tokens expire by, which also limits how often the keys are fetched again.
+// It must be called before the relying party is used.
+func (rp *RelyingParty) SetClock(now func() time.Time) {
+	rp.now = now
+}
+
+// AuthCodeURL returns the URL of the provider the user agent is redirected to for the authorization,
+// which redirects it back to the redirect URL with the code and the state.
+func (rp *RelyingParty) AuthCodeURL(state, nonce, verifier string) string {
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {rp.cfg.ClientID},
+		"redirect_uri":          {rp.cfg.RedirectURL},
+		"scope":                 {strings.Join(rp.cfg.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {Challenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(rp.endpoint.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return rp.endpoint.AuthorizationEndpoint + sep + q.Encode()
+}
+
+// Exchange exchanges the code with the verifier of its authorization request for an ID token,
+// and returns the claims of the ID token verified with the nonce of the request.
+func (rp *RelyingParty) Exchange(ctx context.Context, code, verifier, nonce string) (*Claims, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {rp.cfg.RedirectURL},
+		"code_verifier": {verifier},
+		"client_id":     {rp.cfg.ClientID},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rp.endpoint.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if rp.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(rp.cfg.ClientID), url.QueryEscape(rp.cfg.ClientSecret))
+	}
+	resp, err := rp.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token); err != nil {
+		return nil, fmt.Errorf("oidc: failed to decode token response of status %d: %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s %s", ErrExchangeRejected, token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("oidc: token response lacks ID token")
+	}
+	return rp.Verify(ctx, token.IDToken, nonce)
+}
+
+// Verify returns the claims of the raw ID token, which must be signed by the provider, issued to the client,
+// unexpired and bound to nonce. The errors of the tokens rejected wrap ErrInvalidToken.
+func (rp *RelyingParty) Verify(ctx context.Context, raw, nonce string) (*Claims, error) {
+	claims := &Claims{}
+	if err := verifyJWT(ctx, rp.keys, raw, claims); err != nil {
+		return nil, err
+	}
+
+	// the clocks of the provider and the relying party may be off by a minute
+	const leeway = time.Minute
+	now := rp.now()
+	switch {
+	case claims.Issuer != rp.cfg.Issuer:
+		return nil, fmt.Errorf("%w: issued by %q", ErrInvalidToken, claims.Issuer)
+	case claims.Subject == "":
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidToken)
+	case !claims.Audience.contains(rp.cfg.ClientID):
+		return nil, fmt.Errorf("%w: issued to %q", ErrInvalidToken, []string(claims.Audience))
+	case len(claims.Audience) > 1 && claims.AuthorizedParty != rp.cfg.ClientID:
+		return nil, fmt.Errorf("%w: authorized party %q", ErrInvalidToken, claims.AuthorizedParty)
+	case !now.Before(time.Unix(claims.Expiry, 0).Add(leeway)):
+		return nil, fmt.Errorf("%w: expired", ErrInvalidToken)
+	case now.Add(leeway).Before(time.Unix(claims.IssuedAt, 0)):
+		return nil, fmt.Errorf("%w: issued in the future", ErrInvalidToken)
+	case nonce == "" || claims.Nonce != nonce:
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidToken)
+	}
+	return claims, nil
+}
+
+// contains reports whether a includes clientID.
+func (a audience) contains(clientID string) bool {
+	for _, v := range a {
+		if v == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+// getJSON decodes the JSON served at u into v.
+func (rp *RelyingParty) getJSON(ctx context.Context, u string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := rp.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(ioutil.Discard, resp.Body)
+		return fmt.Errorf("GET %s answered %s", u, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
@@ -0,0 +1,222 @@
+package oidc_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/oidc"
+	"github.com/TechBowl-japan/go-stations/oidc/oidctest"
+)
+
+const redirectURL = "http://rp.example.com/callback"
+
+// newRelyingParty returns the relying party of a new provider.
+func newRelyingParty(t *testing.T) (*oidc.RelyingParty, *oidctest.Provider) {
+	t.Helper()
+
+	p := oidctest.NewProvider("todo", "secret")
+	t.Cleanup(p.Close)
+	rp, err := oidc.New(context.Background(), oidc.Config{
+		Issuer:       p.Issuer(),
+		ClientID:     "todo",
+		ClientSecret: "secret",
+		RedirectURL:  redirectURL,
+	})
+	if err != nil {
+		t.Fatal("failed to create relying party, err =", err)
+	}
+	return rp, p
+}
+
+// authorize follows the authorization request of the relying party, returning the query of the redirect back.
+func authorize(t *testing.T, rp *oidc.RelyingParty, state, nonce, verifier string) url.Values {
+	t.Helper()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(rp.AuthCodeURL(state, nonce, verifier))
+	if err != nil {
+		t.Fatal("failed to request authorization, err =", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("unexpected status of authorization, given = %d, expected = %d", resp.StatusCode, http.StatusFound)
+	}
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal("failed to parse location, err =", err)
+	}
+	if !strings.HasPrefix(loc.String(), redirectURL+"?") {
+		t.Fatalf("unexpected redirect, given = %s", loc)
+	}
+	return loc.Query()
+}
+
+func TestRelyingParty(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	rp, p := newRelyingParty(t)
+	p.SetUser(&oidctest.User{Subject: "42", PreferredUsername: "alice", Email: "alice@example.com"})
+
+	q := authorize(t, rp, "state", "nonce", "verifier-of-at-least-43-characters-0123456789")
+	if q.Get("state") != "state" || q.Get("code") == "" {
+		t.Fatalf("unexpected callback, given = %v", q)
+	}
+	if _, err := rp.Exchange(ctx, q.Get("code"), "another-verifier-of-at-least-43-characters-01", "nonce"); !errors.Is(err, oidc.ErrExchangeRejected) {
+		t.Errorf("unexpected error with wrong verifier, given = %v, expected = %v", err, oidc.ErrExchangeRejected)
+	}
+
+	q = authorize(t, rp, "state", "nonce", "verifier-of-at-least-43-characters-0123456789")
+	claims, err := rp.Exchange(ctx, q.Get("code"), "verifier-of-at-least-43-characters-0123456789", "nonce")
+	if err != nil {
+		t.Fatal("failed to exchange code, err =", err)
+	}
+	if claims.Issuer != p.Issuer() || claims.Subject != "42" || claims.PreferredUsername != "alice" || !claims.EmailVerified {
+		t.Errorf("unexpected claims, given = %+v", claims)
+	}
+	if _, err := rp.Exchange(ctx, q.Get("code"), "verifier-of-at-least-43-characters-0123456789", "nonce"); !errors.Is(err, oidc.ErrExchangeRejected) {
+		t.Errorf("unexpected error of used code, given = %v, expected = %v", err, oidc.ErrExchangeRejected)
+	}
+
+	p.SetUser(nil)
+	if q := authorize(t, rp, "state", "nonce", "verifier"); q.Get("error") != "login_required" || q.Get("state") != "state" {
+		t.Errorf("unexpected callback without user, given = %v", q)
+	}
+}
+
+func TestRelyingParty_Verify(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	rp, p := newRelyingParty(t)
+	now := time.Now()
+	claims := func(overrides map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"iss":   p.Issuer(),
+			"sub":   "42",
+			"aud":   "todo",
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Hour).Unix(),
+			"nonce": "nonce",
+		}
+		for k, v := range overrides {
+			c[k] = v
+		}
+		return c
+	}
+	sign := func(overrides map[string]interface{}) string {
+		raw, err := p.Sign(claims(overrides))
+		if err != nil {
+			t.Fatal("failed to sign, err =", err)
+		}
+		return raw
+	}
+
+	valid := sign(nil)
+	if _, err := rp.Verify(ctx, valid, "nonce"); err != nil {
+		t.Fatal("failed to verify, err =", err)
+	}
+	if _, err := rp.Verify(ctx, sign(map[string]interface{}{"aud": []string{"other", "todo"}, "azp": "todo"}), "nonce"); err != nil {
+		t.Error("failed to verify token of multiple audiences, err =", err)
+	}
+
+	parts := strings.Split(valid, ".")
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + parts[1] + "."
+	tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"`+p.Issuer()+`","sub":"1","aud":"todo"}`)) + "." + parts[2]
+
+	cases := map[string]struct {
+		raw, nonce string
+	}{
+		"Malformed":        {raw: "not a token", nonce: "nonce"},
+		"AlgorithmNone":    {raw: unsigned, nonce: "nonce"},
+		"Tampered":         {raw: tampered, nonce: "nonce"},
+		"NonceMismatch":    {raw: valid, nonce: "other"},
+		"EmptyNonce":       {raw: sign(map[string]interface{}{"nonce": ""}), nonce: ""},
+		"OtherIssuer":      {raw: sign(map[string]interface{}{"iss": "https://evil.example.com"}), nonce: "nonce"},
+		"OtherAudience":    {raw: sign(map[string]interface{}{"aud": "other"}), nonce: "nonce"},
+		"OtherParty":       {raw: sign(map[string]interface{}{"aud": []string{"other", "todo"}, "azp": "other"}), nonce: "nonce"},
+		"Expired":          {raw: sign(map[string]interface{}{"exp": now.Add(-2 * time.Minute).Unix()}), nonce: "nonce"},
+		"IssuedInFuture":   {raw: sign(map[string]interface{}{"iat": now.Add(time.Hour).Unix()}), nonce: "nonce"},
+		"WithoutSubject":   {raw: sign(map[string]interface{}{"sub": ""}), nonce: "nonce"},
+		"TruncatedPayload": {raw: parts[0] + "." + parts[1][:10] + "." + parts[2], nonce: "nonce"},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := rp.Verify(ctx, c.raw, c.nonce); !errors.Is(err, oidc.ErrInvalidToken) {
+				t.Errorf("unexpected error, given = %v, expected = %v", err, oidc.ErrInvalidToken)
+			}
+		})
+	}
+}
+
+func TestRelyingParty_RotateKey(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	rp, p := newRelyingParty(t)
+	now := time.Now()
+	clock := now
+	rp.SetClock(func() time.Time { return clock })
+	claims := map[string]interface{}{
+		"iss": p.Issuer(), "sub": "42", "aud": "todo", "iat": now.Unix(), "exp": now.Add(time.Hour).Unix(), "nonce": "nonce",
+	}
+
+	old, err := p.Sign(claims)
+	if err != nil {
+		t.Fatal("failed to sign, err =", err)
+	}
+	if _, err := rp.Verify(ctx, old, "nonce"); err != nil {
+		t.Fatal("failed to verify, err =", err)
+	}
+
+	p.RotateKey()
+	rotated, err := p.Sign(claims)
+	if err != nil {
+		t.Fatal("failed to sign, err =", err)
+	}
+	// the keys are fetched again for an unknown key ID only once in a while
+	if _, err := rp.Verify(ctx, rotated, "nonce"); !errors.Is(err, oidc.ErrInvalidToken) {
+		t.Errorf("unexpected error of key fetched too soon, given = %v, expected = %v", err, oidc.ErrInvalidToken)
+	}
+	clock = clock.Add(time.Minute)
+	if _, err := rp.Verify(ctx, rotated, "nonce"); err != nil {
+		t.Error("failed to verify token of rotated key, err =", err)
+	}
+	if _, err := rp.Verify(ctx, old, "nonce"); !errors.Is(err, oidc.ErrInvalidToken) {
+		t.Errorf("unexpected error of retired key, given = %v, expected = %v", err, oidc.ErrInvalidToken)
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	p := oidctest.NewProvider("todo", "")
+	t.Cleanup(p.Close)
+
+	cases := map[string]oidc.Config{
+		"WithoutClientID": {Issuer: p.Issuer(), RedirectURL: redirectURL},
+		"IssuerMismatch":  {Issuer: p.Issuer() + "/", ClientID: "todo", RedirectURL: redirectURL},
+		"NotProvider":     {Issuer: p.Issuer() + "/none", ClientID: "todo", RedirectURL: redirectURL},
+	}
+	for name, cfg := range cases {
+		cfg := cfg
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := oidc.New(context.Background(), cfg); err == nil {
+				t.Error("unexpected relying party created")
+			}
+		})
+	}
+}
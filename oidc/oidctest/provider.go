@@ -0,0 +1,336 @@
+// Package oidctest implements an OpenID Connect provider serving in process, against which
+// the relying party of the package oidc is tested without an external identity service.
+//
+// The provider registers a single client and authorizes every authorization request as its current user
+// without prompting, redirecting the user agent straight back to the relying party. It serves
+// the discovery document, the authorization and token endpoints with PKCE, and the JWKS of its key.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/oidc"
+)
+
+// Paths of the endpoints served by Provider.
+const (
+	DiscoveryPath     = "/.well-known/openid-configuration"
+	AuthorizationPath = "/authorize"
+	TokenPath         = "/token"
+	JWKSPath          = "/jwks"
+)
+
+// codeTTL is how long an authorization code may be exchanged.
+const codeTTL = time.Minute
+
+// A User is the end user the provider authorizes as.
+type User struct {
+	Subject           string
+	Name              string
+	PreferredUsername string
+	Email             string
+}
+
+// DefaultUser is the user the provider authorizes as until SetUser is called.
+var DefaultUser = User{
+	Subject:           "248289761001",
+	Name:              "Jane Doe",
+	PreferredUsername: "jane",
+	Email:             "jane@example.com",
+}
+
+// A Provider is an OpenID Connect provider serving on a local httptest.Server.
+type Provider struct {
+	server       *httptest.Server
+	clientID     string
+	clientSecret string
+
+	mu     sync.Mutex
+	key    *rsa.PrivateKey
+	keyID  int
+	user   *User
+	codes  map[string]*grant
+	issued int
+}
+
+// grant is an authorization code waiting for the exchange.
+type grant struct {
+	redirectURI string
+	challenge   string
+	nonce       string
+	user        User
+	expiresAt   time.Time
+}
+
+// NewProvider starts a Provider registering the client. The secret is not checked when it is empty.
+// The caller should call Close when finished, to shut it down.
+func NewProvider(clientID, clientSecret string) *Provider {
+	p := &Provider{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		codes:        map[string]*grant{},
+	}
+	p.SetUser(&DefaultUser)
+	p.RotateKey()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(DiscoveryPath, p.serveDiscovery)
+	mux.HandleFunc(AuthorizationPath, p.serveAuthorization)
+	mux.HandleFunc(TokenPath, p.serveToken)
+	mux.HandleFunc(JWKSPath, p.serveJWKS)
+	p.server = httptest.NewServer(mux)
+	return p
+}
+
+// Issuer returns the issuer URL of the provider, which the relying party discovers the provider by.
+func (p *Provider) Issuer() string {
+	return p.server.URL
+}
+
+// Client returns the HTTP client of the server of the provider.
+func (p *Provider) Client() *http.Client {
+	return p.server.Client()
+}
+
+// Close shuts down the provider.
+func (p *Provider) Close() {
+	p.server.Close()
+}
+
+// SetUser sets the user the following authorization requests are authorized as.
+// They are denied with login_required when user is nil.
+func (p *Provider) SetUser(user *User) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if user == nil {
+		p.user = nil
+		return
+	}
+	u := *user
+	p.user = &u
+}
+
+// RotateKey replaces the signing key by a new one with a new key ID, publishing only the new one.
+func (p *Provider) RotateKey() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic("oidctest: failed to generate key: " + err.Error())
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.key = key
+	p.keyID++
+}
+
+// Sign returns the ID token of the claims signed by the current key, which lets tests make up
+// tokens the token endpoint does not issue, such as expired ones.
+func (p *Provider) Sign(claims interface{}) (string, error) {
+	p.mu.Lock()
+	key, keyID := p.key, p.kid()
+	p.mu.Unlock()
+	return oidc.SignRS256(key, keyID, claims)
+}
+
+// Issued returns the number of the ID tokens issued by the token endpoint.
+func (p *Provider) Issued() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.issued
+}
+
+// kid returns the ID of the current key. The mutex must be held.
+func (p *Provider) kid() string {
+	return "key-" + strconv.Itoa(p.keyID)
+}
+
+// serveDiscovery serves the discovery document.
+func (p *Provider) serveDiscovery(w http.ResponseWriter, r *http.Request) {
+	issuer := p.Issuer()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + AuthorizationPath,
+		"token_endpoint":                        issuer + TokenPath,
+		"jwks_uri":                              issuer + JWKSPath,
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"scopes_supported":                      []string{"openid", "profile", "email"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+// serveAuthorization authorizes the request as the current user, redirecting back with a code.
+func (p *Provider) serveAuthorization(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	redirectURI, err := url.Parse(q.Get("redirect_uri"))
+	if q.Get("client_id") != p.clientID || err != nil || !redirectURI.IsAbs() {
+		// the user agent must not be redirected to an unverified URI
+		http.Error(w, "invalid client or redirect URI", http.StatusBadRequest)
+		return
+	}
+
+	redirect := func(params url.Values) {
+		params.Set("state", q.Get("state"))
+		u := *redirectURI
+		u.RawQuery = params.Encode()
+		http.Redirect(w, r, u.String(), http.StatusFound)
+	}
+	switch {
+	case q.Get("response_type") != "code":
+		redirect(url.Values{"error": {"unsupported_response_type"}})
+		return
+	case q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256":
+		redirect(url.Values{"error": {"invalid_request"}, "error_description": {"PKCE with S256 is required"}})
+		return
+	}
+
+	code, err := oidc.RandomString()
+	if err != nil {
+		redirect(url.Values{"error": {"server_error"}})
+		return
+	}
+	p.mu.Lock()
+	user := p.user
+	if user != nil {
+		p.codes[code] = &grant{
+			redirectURI: redirectURI.String(),
+			challenge:   q.Get("code_challenge"),
+			nonce:       q.Get("nonce"),
+			user:        *user,
+			expiresAt:   time.Now().Add(codeTTL),
+		}
+	}
+	p.mu.Unlock()
+	if user == nil {
+		redirect(url.Values{"error": {"login_required"}})
+		return
+	}
+	redirect(url.Values{"code": {code}})
+}
+
+// serveToken exchanges a code for an ID token.
+func (p *Provider) serveToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		tokenError(w, http.StatusMethodNotAllowed, "invalid_request", "method not allowed")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	if !p.authenticateClient(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oidctest"`)
+		tokenError(w, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, http.StatusBadRequest, "unsupported_grant_type", "")
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	code := r.PostForm.Get("code")
+	g, ok := p.codes[code]
+	// codes are usable once even when the exchange fails
+	delete(p.codes, code)
+	switch {
+	case !ok || time.Now().After(g.expiresAt):
+		tokenError(w, http.StatusBadRequest, "invalid_grant", "unknown or expired code")
+		return
+	case r.PostForm.Get("redirect_uri") != g.redirectURI:
+		tokenError(w, http.StatusBadRequest, "invalid_grant", "redirect URI mismatch")
+		return
+	case oidc.Challenge(r.PostForm.Get("code_verifier")) != g.challenge:
+		tokenError(w, http.StatusBadRequest, "invalid_grant", "code verifier mismatch")
+		return
+	}
+
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss":   p.Issuer(),
+		"sub":   g.user.Subject,
+		"aud":   p.clientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"nonce": g.nonce,
+	}
+	for name, value := range map[string]string{"name": g.user.Name, "preferred_username": g.user.PreferredUsername, "email": g.user.Email} {
+		if value != "" {
+			claims[name] = value
+		}
+	}
+	if g.user.Email != "" {
+		claims["email_verified"] = true
+	}
+	idToken, err := oidc.SignRS256(p.key, p.kid(), claims)
+	if err != nil {
+		tokenError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	accessToken, err := oidc.RandomString()
+	if err != nil {
+		tokenError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	p.issued++
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+// authenticateClient reports whether r is sent by the registered client,
+// which authenticates by either HTTP basic authentication or the form.
+func (p *Provider) authenticateClient(r *http.Request) bool {
+	id, secret, ok := r.BasicAuth()
+	if ok {
+		var err error
+		if id, err = url.QueryUnescape(id); err != nil {
+			return false
+		}
+		if secret, err = url.QueryUnescape(secret); err != nil {
+			return false
+		}
+	} else {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	return id == p.clientID && (p.clientSecret == "" || secret == p.clientSecret)
+}
+
+// serveJWKS serves the public key of the current signing key.
+func (p *Provider) serveJWKS(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	key := oidc.NewJSONWebKey(&p.key.PublicKey, p.kid())
+	p.mu.Unlock()
+	writeJSON(w, http.StatusOK, &oidc.JSONWebKeySet{Keys: []oidc.JSONWebKey{key}})
+}
+
+// tokenError writes the error response of the token endpoint defined by RFC 6749.
+func tokenError(w http.ResponseWriter, status int, code, description string) {
+	body := map[string]string{"error": code}
+	if description != "" {
+		body["error_description"] = description
+	}
+	writeJSON(w, status, body)
+}
+
+// writeJSON writes v as the JSON body of the response with status.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
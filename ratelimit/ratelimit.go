@@ -0,0 +1,174 @@
+// Package ratelimit limits the rate of requests per client by token buckets,
+// and the number of requests served at once by a gate shedding the excess.
+//
+// A bucket of each client holds at most burst tokens and is refilled at rate tokens per second.
+// A request takes a token, and is rejected when the bucket is empty, so that a client makes
+// burst requests at once and rate requests per second on average.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// minSweepInterval is the least interval of removing the buckets refilled up,
+// which are the same as the buckets of the clients not seen yet.
+const minSweepInterval = time.Minute
+
+// A Limiter limits the rate of requests of each client identified by a key. It is safe for concurrent use.
+type Limiter struct {
+	rate  float64
+	burst int
+	now   func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	sweptAt time.Time
+}
+
+// bucket is the token bucket of a client.
+type bucket struct {
+	tokens float64
+	// at is when tokens was computed.
+	at time.Time
+}
+
+// A Decision is the result of taking a token for a request.
+type Decision struct {
+	// Allowed reports whether the request may be served.
+	Allowed bool
+	// Limit is the number of the requests allowed at once, which is the capacity of the bucket.
+	Limit int
+	// Remaining is the number of the requests allowed at once after the request.
+	Remaining int
+	// Reset is how long until the bucket is refilled up.
+	Reset time.Duration
+	// RetryAfter is how long until a request is allowed, which is zero when the request is allowed.
+	RetryAfter time.Duration
+}
+
+// New returns a Limiter allowing each client burst requests at once and rate requests per second on average.
+// It panics unless both rate and burst are positive.
+func New(rate float64, burst int) *Limiter {
+	if rate <= 0 || burst <= 0 {
+		panic("ratelimit: rate and burst must be positive")
+	}
+	return &Limiter{
+		rate:    rate,
+		burst:   burst,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// SetClock replaces the clock the buckets are refilled by. It must be called before the limiter is used.
+func (l *Limiter) SetClock(now func() time.Time) {
+	l.now = now
+}
+
+// Allow takes a token for a request of the client by key and reports whether the request is allowed.
+func (l *Limiter) Allow(key string) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), at: now}
+		l.buckets[key] = b
+	}
+	b.refill(now, l.rate, l.burst)
+
+	d := Decision{Limit: l.burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfter = l.duration(1 - b.tokens)
+	}
+	d.Remaining = int(math.Floor(b.tokens))
+	d.Reset = l.duration(float64(l.burst) - b.tokens)
+	return d
+}
+
+// Len returns the number of the clients tracked, whose buckets are not refilled up yet.
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+// refill adds the tokens refilled since the bucket was last computed up to burst.
+func (b *bucket) refill(now time.Time, rate float64, burst int) {
+	if elapsed := now.Sub(b.at); elapsed > 0 {
+		b.tokens = math.Min(float64(burst), b.tokens+elapsed.Seconds()*rate)
+	}
+	b.at = now
+}
+
+// sweep removes the buckets refilled up once in a while, so that the buckets of
+// the clients gone, or of made-up keys, do not pile up. The mutex must be held.
+func (l *Limiter) sweep(now time.Time) {
+	interval := l.duration(float64(l.burst))
+	if interval < minSweepInterval {
+		interval = minSweepInterval
+	}
+	if now.Sub(l.sweptAt) < interval {
+		return
+	}
+	for key, b := range l.buckets {
+		b.refill(now, l.rate, l.burst)
+		if b.tokens >= float64(l.burst) {
+			delete(l.buckets, key)
+		}
+	}
+	l.sweptAt = now
+}
+
+// duration returns how long it takes to refill the tokens.
+func (l *Limiter) duration(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
+
+// A Gate admits at most a fixed number of requests at once. It is safe for concurrent use.
+type Gate struct {
+	slots chan struct{}
+}
+
+// NewGate returns a Gate admitting at most n requests at once. It panics unless n is positive.
+func NewGate(n int) *Gate {
+	if n <= 0 {
+		panic("ratelimit: capacity of gate must be positive")
+	}
+	return &Gate{slots: make(chan struct{}, n)}
+}
+
+// Enter admits a request unless the gate is full, without waiting. A request admitted must call Leave when finished.
+func (g *Gate) Enter() bool {
+	select {
+	case g.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Leave lets a request admitted by Enter out.
+func (g *Gate) Leave() {
+	<-g.slots
+}
+
+// InFlight returns the number of the requests admitted and not left yet.
+func (g *Gate) InFlight() int {
+	return len(g.slots)
+}
+
+// Cap returns the number of the requests admitted at once.
+func (g *Gate) Cap() int {
+	return cap(g.slots)
+}
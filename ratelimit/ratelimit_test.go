@@ -0,0 +1,87 @@
+package ratelimit_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/ratelimit"
+)
+
+func TestLimiter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	l := ratelimit.New(2, 3)
+	l.SetClock(func() time.Time { return now })
+
+	for i := 2; i >= 0; i-- {
+		d := l.Allow("alice")
+		if !d.Allowed || d.Limit != 3 || d.Remaining != i || d.RetryAfter != 0 {
+			t.Errorf("unexpected decision of request in burst, given = %+v", d)
+		}
+	}
+	d := l.Allow("alice")
+	if d.Allowed || d.Remaining != 0 || d.RetryAfter != 500*time.Millisecond || d.Reset != 1500*time.Millisecond {
+		t.Errorf("unexpected decision of request over burst, given = %+v", d)
+	}
+	if d := l.Allow("bob"); !d.Allowed || d.Remaining != 2 {
+		t.Errorf("unexpected decision of another client, given = %+v", d)
+	}
+
+	// a token is refilled every half a second
+	now = now.Add(500 * time.Millisecond)
+	if d := l.Allow("alice"); !d.Allowed || d.Remaining != 0 {
+		t.Errorf("unexpected decision of refilled request, given = %+v", d)
+	}
+	if d := l.Allow("alice"); d.Allowed {
+		t.Errorf("unexpected decision of request over rate, given = %+v", d)
+	}
+
+	// the buckets never hold more than burst
+	now = now.Add(time.Hour)
+	if d := l.Allow("alice"); !d.Allowed || d.Remaining != 2 {
+		t.Errorf("unexpected decision of request after idle, given = %+v", d)
+	}
+}
+
+func TestLimiter_Sweep(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	l := ratelimit.New(1, 1)
+	l.SetClock(func() time.Time { return now })
+
+	for i := 0; i < 100; i++ {
+		l.Allow(strconv.Itoa(i))
+	}
+	if n := l.Len(); n != 100 {
+		t.Fatalf("unexpected number of clients, given = %d, expected = %d", n, 100)
+	}
+
+	now = now.Add(time.Minute)
+	l.Allow("alice")
+	if n := l.Len(); n != 1 {
+		t.Errorf("unexpected number of clients after sweep, given = %d, expected = %d", n, 1)
+	}
+}
+
+func TestGate(t *testing.T) {
+	t.Parallel()
+
+	g := ratelimit.NewGate(2)
+	if !g.Enter() || !g.Enter() {
+		t.Fatal("unexpected request not admitted under capacity")
+	}
+	if g.Enter() {
+		t.Error("unexpected request admitted over capacity")
+	}
+	if g.InFlight() != 2 || g.Cap() != 2 {
+		t.Errorf("unexpected requests in flight, given = %d of %d", g.InFlight(), g.Cap())
+	}
+
+	g.Leave()
+	if !g.Enter() {
+		t.Error("unexpected request not admitted after leave")
+	}
+}
@@ -0,0 +1,330 @@
+// Package rrule implements the subset of the RFC 5545 recurrence rules used by recurring TODOs.
+//
+// The supported rule parts are FREQ (DAILY, WEEKLY or MONTHLY), INTERVAL,
+// BYDAY of WEEKLY rules without ordinals, BYMONTHDAY of MONTHLY rules, COUNT and UNTIL.
+// Weeks start on Monday.
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Frequency expresses the period in which a rule repeats.
+type Frequency string
+
+const (
+	// Daily repeats every INTERVAL days.
+	Daily Frequency = "DAILY"
+	// Weekly repeats on the weekdays of BYDAY every INTERVAL weeks.
+	Weekly Frequency = "WEEKLY"
+	// Monthly repeats on the days of BYMONTHDAY every INTERVAL months.
+	Monthly Frequency = "MONTHLY"
+)
+
+// untilFormat is the UTC form of the DATE-TIME value of UNTIL.
+const untilFormat = "20060102T150405Z"
+
+// maxPeriods bounds the number of periods searched for the next occurrence,
+// so that a rule which never matches, such as BYMONTHDAY=31 every 12 months from February, terminates.
+const maxPeriods = 10000
+
+// ErrInvalid is wrapped by the errors of Parse.
+var ErrInvalid = errors.New("rrule: invalid rule")
+
+// A Rule expresses a recurrence rule.
+type Rule struct {
+	Freq Frequency
+	// Interval is the number of periods between occurrences, at least 1.
+	Interval int
+	// ByDay lists the weekdays of Weekly rules in the order from Monday.
+	// An empty ByDay means the weekday of the start.
+	ByDay []time.Weekday
+	// ByMonthDay lists the days of Monthly rules in ascending order.
+	// Negative days count from the end of the month, -1 being the last day.
+	// An empty ByMonthDay means the day of the start.
+	ByMonthDay []int
+	// Count is the number of occurrences including the start. 0 means unlimited.
+	Count int
+	// Until is the last time an occurrence may start at. The zero value means unlimited.
+	Until time.Time
+}
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+var weekdayNames = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// Parse parses the RRULE value s, optionally prefixed by "RRULE:".
+// Names are case insensitive.
+func Parse(s string) (*Rule, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimPrefix(s, "RRULE:")
+	if s == "" {
+		return nil, fmt.Errorf("%w: empty rule", ErrInvalid)
+	}
+
+	r := &Rule{Interval: 1}
+	seen := make(map[string]struct{})
+	for _, part := range strings.Split(s, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return nil, fmt.Errorf("%w: malformed part %q", ErrInvalid, part)
+		}
+		name, value := kv[0], kv[1]
+		if _, ok := seen[name]; ok {
+			return nil, fmt.Errorf("%w: duplicated part %s", ErrInvalid, name)
+		}
+		seen[name] = struct{}{}
+
+		var err error
+		switch name {
+		case "FREQ":
+			switch Frequency(value) {
+			case Daily, Weekly, Monthly:
+				r.Freq = Frequency(value)
+			default:
+				err = fmt.Errorf("unsupported frequency %s", value)
+			}
+		case "INTERVAL":
+			r.Interval, err = positive(value)
+		case "COUNT":
+			r.Count, err = positive(value)
+		case "UNTIL":
+			r.Until, err = parseUntil(value)
+		case "BYDAY":
+			r.ByDay, err = parseByDay(value)
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseByMonthDay(value)
+		case "WKST":
+			if value != "MO" {
+				err = errors.New("only WKST=MO is supported")
+			}
+		default:
+			err = fmt.Errorf("unsupported part %s", name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+	}
+
+	switch {
+	case r.Freq == "":
+		return nil, fmt.Errorf("%w: FREQ is required", ErrInvalid)
+	case len(r.ByDay) > 0 && r.Freq != Weekly:
+		return nil, fmt.Errorf("%w: BYDAY is supported only by WEEKLY", ErrInvalid)
+	case len(r.ByMonthDay) > 0 && r.Freq != Monthly:
+		return nil, fmt.Errorf("%w: BYMONTHDAY is supported only by MONTHLY", ErrInvalid)
+	case r.Count > 0 && !r.Until.IsZero():
+		return nil, fmt.Errorf("%w: COUNT and UNTIL are exclusive", ErrInvalid)
+	}
+
+	return r, nil
+}
+
+// String returns the canonical RRULE value of r, which Parse accepts.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			days[i] = weekdayNames[d]
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(untilFormat))
+	}
+	return strings.Join(parts, ";")
+}
+
+// After returns the first occurrence of r started at start which is after t.
+// Occurrences keep the wall clock of start in its location across daylight saving time changes,
+// and start itself is always the first occurrence as RFC 5545 defines.
+// It returns false when r has no more occurrences.
+func (r *Rule) After(start, t time.Time) (time.Time, bool) {
+	occurrences := r.Occurrences(start, t, 1)
+	if len(occurrences) == 0 {
+		return time.Time{}, false
+	}
+	return occurrences[0], true
+}
+
+// Occurrences returns at most n occurrences of r started at start which are after t in ascending order.
+func (r *Rule) Occurrences(start, t time.Time, n int) []time.Time {
+	if n <= 0 {
+		return nil
+	}
+
+	var occurrences []time.Time
+	count := 0
+	r.iterate(start, func(o time.Time) bool {
+		count++
+		if r.Count > 0 && count > r.Count {
+			return false
+		}
+		if !r.Until.IsZero() && o.After(r.Until) {
+			return false
+		}
+		if o.After(t) {
+			occurrences = append(occurrences, o)
+		}
+		return len(occurrences) < n
+	})
+	return occurrences
+}
+
+// iterate calls yield with every occurrence in ascending order until it returns false.
+func (r *Rule) iterate(start time.Time, yield func(time.Time) bool) {
+	if !yield(start) {
+		return
+	}
+
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+	y, m, d := start.Date()
+	hh, mm, ss := start.Clock()
+	loc := start.Location()
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, hh, mm, ss, start.Nanosecond(), loc)
+	}
+
+	for period := 0; period < maxPeriods; period++ {
+		var candidates []time.Time
+		switch r.Freq {
+		case Daily:
+			candidates = []time.Time{at(y, m, d+period*interval)}
+		case Weekly:
+			days := r.ByDay
+			if len(days) == 0 {
+				days = []time.Weekday{start.Weekday()}
+			}
+			// Monday of the week of start
+			monday := d - (int(start.Weekday())+6)%7 + period*interval*7
+			for _, wd := range days {
+				candidates = append(candidates, at(y, m, monday+(int(wd)+6)%7))
+			}
+		case Monthly:
+			days := r.ByMonthDay
+			if len(days) == 0 {
+				days = []int{d}
+			}
+			first := time.Date(y, m+time.Month(period*interval), 1, 0, 0, 0, 0, loc)
+			last := first.AddDate(0, 1, -1).Day()
+			var resolved []int
+			for _, day := range days {
+				if day < 0 {
+					day = last + day + 1
+				}
+				if day >= 1 && day <= last {
+					resolved = append(resolved, day)
+				}
+			}
+			sort.Ints(resolved)
+			for i, day := range resolved {
+				if i > 0 && resolved[i-1] == day {
+					continue
+				}
+				candidates = append(candidates, at(first.Year(), first.Month(), day))
+			}
+		default:
+			return
+		}
+
+		for _, c := range candidates {
+			if !c.After(start) {
+				continue
+			}
+			if !yield(c) {
+				return
+			}
+		}
+	}
+}
+
+// positive parses s as a positive integer.
+func positive(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%q is not a positive integer", s)
+	}
+	return n, nil
+}
+
+// parseUntil parses the UTC DATE-TIME or the DATE value of UNTIL.
+// A DATE value means the end of the day in UTC.
+func parseUntil(s string) (time.Time, error) {
+	if t, err := time.Parse(untilFormat, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("20060102", s); err == nil {
+		return t.Add(24*time.Hour - time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("UNTIL %q is neither a UTC date-time nor a date", s)
+}
+
+// parseByDay parses the weekdays of BYDAY, sorted from Monday.
+func parseByDay(s string) ([]time.Weekday, error) {
+	seen := make(map[time.Weekday]struct{})
+	var days []time.Weekday
+	for _, name := range strings.Split(s, ",") {
+		wd, ok := weekdays[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported weekday %q", name)
+		}
+		if _, ok := seen[wd]; ok {
+			continue
+		}
+		seen[wd] = struct{}{}
+		days = append(days, wd)
+	}
+	sort.Slice(days, func(i, j int) bool {
+		return (days[i]+6)%7 < (days[j]+6)%7
+	})
+	return days, nil
+}
+
+// parseByMonthDay parses the days of BYMONTHDAY in ascending order.
+func parseByMonthDay(s string) ([]int, error) {
+	seen := make(map[int]struct{})
+	var days []int
+	for _, v := range strings.Split(s, ",") {
+		day, err := strconv.Atoi(v)
+		if err != nil || day == 0 || day < -31 || day > 31 {
+			return nil, fmt.Errorf("invalid month day %q", v)
+		}
+		if _, ok := seen[day]; ok {
+			continue
+		}
+		seen[day] = struct{}{}
+		days = append(days, day)
+	}
+	sort.Ints(days)
+	return days, nil
+}
@@ -0,0 +1,171 @@
+package rrule_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/rrule"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		in, want string
+	}{
+		"Daily":                 {in: "FREQ=DAILY", want: "FREQ=DAILY"},
+		"Prefix and lower case": {in: "rrule:freq=daily;interval=2", want: "FREQ=DAILY;INTERVAL=2"},
+		"Interval 1 is omitted": {in: "FREQ=DAILY;INTERVAL=1", want: "FREQ=DAILY"},
+		"Weekdays are sorted":   {in: "FREQ=WEEKLY;BYDAY=SU,WE,MO,WE", want: "FREQ=WEEKLY;BYDAY=MO,WE,SU"},
+		"Month days are sorted": {in: "FREQ=MONTHLY;BYMONTHDAY=-1,15,1", want: "FREQ=MONTHLY;BYMONTHDAY=-1,1,15"},
+		"Count":                 {in: "FREQ=DAILY;COUNT=3", want: "FREQ=DAILY;COUNT=3"},
+		"Until date-time":       {in: "FREQ=DAILY;UNTIL=20261020T000000Z", want: "FREQ=DAILY;UNTIL=20261020T000000Z"},
+		"Until date":            {in: "FREQ=DAILY;UNTIL=20261020", want: "FREQ=DAILY;UNTIL=20261020T235959Z"},
+		"Week start on Monday":  {in: "FREQ=WEEKLY;WKST=MO", want: "FREQ=WEEKLY"},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := rrule.Parse(c.in)
+			if err != nil {
+				t.Fatal("failed to parse, err =", err)
+			}
+			if got := r.String(); got != c.want {
+				t.Errorf("unexpected value, given = %q, expected = %q", got, c.want)
+			}
+		})
+	}
+
+	for _, in := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=YEARLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;BYDAY=MO",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=DAILY;COUNT=2;UNTIL=20261020",
+		"FREQ=DAILY;UNTIL=tomorrow",
+		"FREQ=DAILY;BYHOUR=9",
+		"FREQ=WEEKLY;WKST=SU",
+		"FREQ",
+	} {
+		if _, err := rrule.Parse(in); !errors.Is(err, rrule.ErrInvalid) {
+			t.Errorf("unexpected error of %q, given = %v", in, err)
+		}
+	}
+}
+
+func TestRule_Occurrences(t *testing.T) {
+	t.Parallel()
+
+	jst, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal("failed to load location, err =", err)
+	}
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal("failed to load location, err =", err)
+	}
+	date := func(loc *time.Location, y int, m time.Month, d, hh int) time.Time {
+		return time.Date(y, m, d, hh, 0, 0, 0, loc)
+	}
+	// Sunday, 18 October 2026
+	start := date(jst, 2026, time.October, 18, 9)
+
+	cases := map[string]struct {
+		rule  string
+		start time.Time
+		after time.Time
+		n     int
+		want  []time.Time
+	}{
+		"Daily": {
+			rule: "FREQ=DAILY", start: start, after: start, n: 3,
+			want: []time.Time{date(jst, 2026, 10, 19, 9), date(jst, 2026, 10, 20, 9), date(jst, 2026, 10, 21, 9)},
+		},
+		"Start is the first occurrence": {
+			rule: "FREQ=DAILY", start: start, after: start.Add(-time.Second), n: 2,
+			want: []time.Time{start, date(jst, 2026, 10, 19, 9)},
+		},
+		"Every other day": {
+			rule: "FREQ=DAILY;INTERVAL=2", start: start, after: date(jst, 2026, 10, 21, 0), n: 2,
+			want: []time.Time{date(jst, 2026, 10, 22, 9), date(jst, 2026, 10, 24, 9)},
+		},
+		"Weekly on the weekday of start": {
+			rule: "FREQ=WEEKLY", start: start, after: start, n: 2,
+			want: []time.Time{date(jst, 2026, 10, 25, 9), date(jst, 2026, 11, 1, 9)},
+		},
+		"Weekly on weekdays": {
+			rule: "FREQ=WEEKLY;BYDAY=MO,WE", start: start, after: start, n: 4,
+			want: []time.Time{
+				date(jst, 2026, 10, 19, 9), date(jst, 2026, 10, 21, 9),
+				date(jst, 2026, 10, 26, 9), date(jst, 2026, 10, 28, 9),
+			},
+		},
+		"Biweekly weeks start on Monday": {
+			rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,SU", start: start, after: start, n: 3,
+			want: []time.Time{date(jst, 2026, 10, 26, 9), date(jst, 2026, 11, 1, 9), date(jst, 2026, 11, 9, 9)},
+		},
+		"Monthly on the day of start": {
+			rule: "FREQ=MONTHLY", start: date(jst, 2026, 1, 31, 9), after: date(jst, 2026, 1, 31, 9), n: 3,
+			want: []time.Time{date(jst, 2026, 3, 31, 9), date(jst, 2026, 5, 31, 9), date(jst, 2026, 7, 31, 9)},
+		},
+		"Monthly on the last day": {
+			rule: "FREQ=MONTHLY;BYMONTHDAY=-1", start: date(jst, 2026, 1, 31, 9), after: date(jst, 2026, 1, 31, 9), n: 2,
+			want: []time.Time{date(jst, 2026, 2, 28, 9), date(jst, 2026, 3, 31, 9)},
+		},
+		"Monthly on days": {
+			rule: "FREQ=MONTHLY;BYMONTHDAY=1,15", start: start, after: start, n: 3,
+			want: []time.Time{date(jst, 2026, 11, 1, 9), date(jst, 2026, 11, 15, 9), date(jst, 2026, 12, 1, 9)},
+		},
+		"Count includes start": {
+			rule: "FREQ=DAILY;COUNT=3", start: start, after: start, n: 5,
+			want: []time.Time{date(jst, 2026, 10, 19, 9), date(jst, 2026, 10, 20, 9)},
+		},
+		"Until is inclusive": {
+			rule: "FREQ=DAILY;UNTIL=20261020T000000Z", start: start, after: start, n: 5,
+			want: []time.Time{date(jst, 2026, 10, 19, 9), date(jst, 2026, 10, 20, 9)},
+		},
+		"Wall clock is kept across DST": {
+			rule: "FREQ=DAILY", start: date(ny, 2026, 10, 31, 9), after: date(ny, 2026, 10, 31, 9), n: 2,
+			want: []time.Time{date(ny, 2026, 11, 1, 9), date(ny, 2026, 11, 2, 9)},
+		},
+		"Never matching rule": {
+			rule: "FREQ=MONTHLY;INTERVAL=12;BYMONTHDAY=31", start: date(jst, 2026, 2, 1, 9), after: date(jst, 2026, 2, 1, 9), n: 1,
+			want: nil,
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := rrule.Parse(c.rule)
+			if err != nil {
+				t.Fatal("failed to parse, err =", err)
+			}
+			got := r.Occurrences(c.start, c.after, c.n)
+			if len(got) != len(c.want) {
+				t.Fatalf("unexpected occurrences, given = %v, expected = %v", got, c.want)
+			}
+			for i := range got {
+				if !got[i].Equal(c.want[i]) {
+					t.Errorf("unexpected occurrences, given = %v, expected = %v", got, c.want)
+					break
+				}
+			}
+
+			next, ok := r.After(c.start, c.after)
+			if ok != (len(c.want) > 0) || (ok && !next.Equal(c.want[0])) {
+				t.Errorf("unexpected next occurrence, given = %v, %v", next, ok)
+			}
+		})
+	}
+}
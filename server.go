@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+// errDrainTimeout is returned by serve when in-flight requests are not finished within the shutdown timeout.
+var errDrainTimeout = errors.New("main: in-flight requests are not drained within shutdown timeout")
+
+// serve serves srv on ln until ctx is done, then stops accepting connections and waits for
+// in-flight requests to finish within shutdownTimeout. The remaining connections are closed
+// and errDrainTimeout is returned when they do not finish in time.
+func serve(ctx context.Context, srv *http.Server, ln net.Listener, shutdownTimeout time.Duration) error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("main: shutting down, draining in-flight requests within %s", shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		if cerr := srv.Close(); cerr != nil {
+			log.Println("main: failed to close connections, err =", cerr)
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			return errDrainTimeout
+		}
+		return fmt.Errorf("main: failed to shut down, err = %w", err)
+	}
+
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	log.Println("main: drained in-flight requests")
+	return nil
+}
+
+// A listener is an HTTP server with the listener it serves on.
+type listener struct {
+	srv *http.Server
+	ln  net.Listener
+}
+
+// serveAll serves every listener by serve until ctx is done or any of them stops,
+// which shuts down the others, and returns the first error of them.
+func serveAll(ctx context.Context, listeners []*listener, shutdownTimeout time.Duration) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errc := make(chan error, len(listeners))
+	for _, l := range listeners {
+		go func(l *listener) {
+			err := serve(ctx, l.srv, l.ln, shutdownTimeout)
+			cancel()
+			errc <- err
+		}(l)
+	}
+	var err error
+	for range listeners {
+		if e := <-errc; e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestServe(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		handling        time.Duration
+		shutdownTimeout time.Duration
+		err             error
+	}{
+		"Drained":       {handling: 100 * time.Millisecond, shutdownTimeout: 5 * time.Second},
+		"Drain timeout": {handling: 5 * time.Second, shutdownTimeout: 100 * time.Millisecond, err: errDrainTimeout},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal("failed to listen, err =", err)
+			}
+			started := make(chan struct{})
+			srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				select {
+				case <-time.After(c.handling):
+					w.Write([]byte("done"))
+				case <-r.Context().Done():
+				}
+			})}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			served := make(chan error, 1)
+			go func() {
+				served <- serve(ctx, srv, ln, c.shutdownTimeout)
+			}()
+
+			responded := make(chan string, 1)
+			go func() {
+				resp, err := http.Get("http://" + ln.Addr().String())
+				if err != nil {
+					responded <- ""
+					return
+				}
+				defer resp.Body.Close()
+				b, _ := ioutil.ReadAll(resp.Body)
+				responded <- string(b)
+			}()
+
+			<-started
+			cancel()
+			if err := <-served; !errors.Is(err, c.err) {
+				t.Errorf("unexpected error, given = %v, expected = %v", err, c.err)
+			}
+			if body := <-responded; (body == "done") != (c.err == nil) {
+				t.Errorf("unexpected response, given = %q", body)
+			}
+			if _, err := http.Get("http://" + ln.Addr().String()); err == nil {
+				t.Error("unexpected success after shutdown")
+			}
+		})
+	}
+}
+
+func TestServeAll(t *testing.T) {
+	t.Parallel()
+
+	api, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("failed to listen, err =", err)
+	}
+	admin, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("failed to listen, err =", err)
+	}
+	// the admin listener is broken, which stops the API as well
+	admin.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- serveAll(context.Background(), []*listener{
+			{srv: &http.Server{Handler: http.NotFoundHandler()}, ln: api},
+			{srv: &http.Server{Handler: http.NotFoundHandler()}, ln: admin},
+		}, time.Second)
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("unexpected success of broken listener")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("unexpected serving after listener is broken")
+	}
+	if _, err := http.Get("http://" + api.Addr().String()); err == nil {
+		t.Error("unexpected success after shutdown")
+	}
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+type (
+	// ErrNotFound expresses that the requested entity does not exist.
+	ErrNotFound = model.ErrNotFound
+	// ErrConflict expresses that the request conflicts with the current state of the entity.
+	ErrConflict = model.ErrConflict
+)
+
+// A ViolationCode classifies the violations of validation rules.
+type ViolationCode string
+
+const (
+	// ViolationRequired means that the value is missing or empty.
+	ViolationRequired ViolationCode = "required"
+	// ViolationInvalid means that the value is malformed or not one of the allowed values.
+	ViolationInvalid ViolationCode = "invalid"
+	// ViolationOutOfRange means that the value is less or greater than allowed.
+	ViolationOutOfRange ViolationCode = "out_of_range"
+	// ViolationTooLong means that the value has more characters or elements than allowed.
+	ViolationTooLong ViolationCode = "too_long"
+	// ViolationUnknown means that the field is not defined by the request.
+	ViolationUnknown ViolationCode = "unknown"
+)
+
+// A Violation expresses a value violating a validation rule.
+type Violation struct {
+	// Field is the name of the field of the request the value is given by.
+	Field string
+	Code  ViolationCode
+	// Message optionally explains the violation in English.
+	Message string
+}
+
+// An ErrValidation expresses that the values given by a request violate validation rules.
+type ErrValidation struct {
+	Violations []*Violation
+}
+
+// Error implements error interface.
+func (e *ErrValidation) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = v.Field + " " + string(v.Code)
+		if v.Message != "" {
+			parts[i] += " (" + v.Message + ")"
+		}
+	}
+	return "service: validation failed: " + strings.Join(parts, ", ")
+}
+
+// Invalid returns *ErrValidation of the single violation of field.
+func Invalid(field string, code ViolationCode) *ErrValidation {
+	return &ErrValidation{Violations: []*Violation{{Field: field, Code: code}}}
+}
+
+// A ConstraintKind classifies the constraints of the storage.
+type ConstraintKind string
+
+const (
+	// ConstraintCheck is a CHECK constraint.
+	ConstraintCheck ConstraintKind = "check"
+	// ConstraintNotNull is a NOT NULL constraint.
+	ConstraintNotNull ConstraintKind = "not_null"
+	// ConstraintUnique is a UNIQUE or PRIMARY KEY constraint.
+	ConstraintUnique ConstraintKind = "unique"
+	// ConstraintForeignKey is a foreign key constraint.
+	ConstraintForeignKey ConstraintKind = "foreign_key"
+)
+
+// An ErrConstraint expresses that a change violates a constraint of the storage.
+type ErrConstraint struct {
+	// Kind is empty when the kind of the constraint is unknown.
+	Kind ConstraintKind
+	// Violation is the violation of a validation rule the constraint expresses, if known.
+	Violation *Violation
+	// Err is the error of the storage.
+	Err error
+}
+
+// Error implements error interface.
+func (e *ErrConstraint) Error() string {
+	return "service: constraint violation: " + e.Err.Error()
+}
+
+// Unwrap returns the error of the storage.
+func (e *ErrConstraint) Unwrap() error {
+	return e.Err
+}
+
+// checkViolations maps the expressions of the CHECK constraints, by which SQLite names them in its errors,
+// to the violations of validation rules they express.
+var checkViolations = map[string]*Violation{
+	"subject <> ''": {Field: "subject", Code: ViolationRequired},
+	"name <> ''":    {Field: "tags", Code: ViolationInvalid, Message: "tag must not be empty"},
+}
+
+// DomainError returns err as *ErrConstraint when it is a constraint violation of SQLite, and err as it is otherwise.
+// TODOService returns the errors of the storage as they are, which callers translate by DomainError.
+func DomainError(err error) error {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.Code != sqlite3.ErrConstraint {
+		return err
+	}
+
+	constraint := &ErrConstraint{Err: err}
+	switch sqliteErr.ExtendedCode {
+	case sqlite3.ErrConstraintCheck:
+		constraint.Kind = ConstraintCheck
+		expr := strings.TrimPrefix(sqliteErr.Error(), "CHECK constraint failed: ")
+		if v, ok := checkViolations[expr]; ok {
+			copied := *v
+			constraint.Violation = &copied
+		}
+	case sqlite3.ErrConstraintNotNull:
+		constraint.Kind = ConstraintNotNull
+	case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+		constraint.Kind = ConstraintUnique
+	case sqlite3.ErrConstraintForeignKey:
+		constraint.Kind = ConstraintForeignKey
+	}
+	return constraint
+}
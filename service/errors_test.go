@@ -0,0 +1,50 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestDomainError(t *testing.T) {
+	t.Parallel()
+
+	for name, newRepo := range repositories() {
+		name, newRepo := name, newRepo
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			svc := service.NewTODOServiceWithRepository(newRepo(t))
+			todo, err := svc.CreateTODO(ctx, "subject", "", "work")
+			if err != nil {
+				t.Fatal("failed to create todo, err =", err)
+			}
+
+			_, err = svc.UpdateTODO(ctx, todo.ID, "", "")
+			var constraint *service.ErrConstraint
+			if !errors.As(service.DomainError(err), &constraint) {
+				t.Fatalf("unexpected error, given = %v, expected = *service.ErrConstraint", err)
+			}
+			if constraint.Kind != service.ConstraintCheck {
+				t.Errorf("unexpected kind, given = %s, expected = %s", constraint.Kind, service.ConstraintCheck)
+			}
+			if !errors.Is(constraint, err) {
+				t.Errorf("unexpected unwrapped error, given = %v, expected = %v", errors.Unwrap(constraint), err)
+			}
+			// only SQLite names the violated constraint
+			if name == "SQLite" {
+				if v := constraint.Violation; v == nil || v.Field != "subject" || v.Code != service.ViolationRequired {
+					t.Errorf("unexpected violation, given = %+v", v)
+				}
+			}
+
+			_, err = svc.GetTODO(ctx, todo.ID+1)
+			if got := service.DomainError(err); got != err {
+				t.Errorf("unexpected translation, given = %v, expected = %v", got, err)
+			}
+		})
+	}
+}
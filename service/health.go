@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// A Checker probes a dependency of the server for the readiness check.
+type Checker struct {
+	// Name identifies the check in the report.
+	Name string
+	// Critical makes the server unready when the check fails.
+	Critical bool
+	// Check returns an error when the dependency is unavailable.
+	Check func(ctx context.Context) error
+}
+
+// A CheckedRepository is a repository which provides the checkers of its storage.
+type CheckedRepository interface {
+	Checkers() []*Checker
+}
+
+// A HealthService runs the registered checkers.
+type HealthService struct {
+	timeout time.Duration
+
+	mu       sync.RWMutex
+	checkers []*Checker
+}
+
+// NewHealthService returns new HealthService, of which every check fails when it does not finish within timeout.
+func NewHealthService(timeout time.Duration) *HealthService {
+	return &HealthService{
+		timeout: timeout,
+	}
+}
+
+// Register adds c to the checks. It panics when another checker is registered with the same name.
+func (s *HealthService) Register(c *Checker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c.Name == "" {
+		panic("service: checker without name")
+	}
+	for _, registered := range s.checkers {
+		if registered.Name == c.Name {
+			panic("service: checker registered twice, name = " + c.Name)
+		}
+	}
+	s.checkers = append(s.checkers, c)
+}
+
+// Check runs every checker concurrently and reports their results in the registered order.
+// The server fails when a critical check fails, and is degraded when only non-critical checks fail.
+func (s *HealthService) Check(ctx context.Context) *model.HealthReport {
+	s.mu.RLock()
+	checkers := append([]*Checker(nil), s.checkers...)
+	s.mu.RUnlock()
+
+	checks := make([]*model.HealthCheck, len(checkers))
+	var wg sync.WaitGroup
+	for i, c := range checkers {
+		wg.Add(1)
+		go func(i int, c *Checker) {
+			defer wg.Done()
+			checks[i] = s.run(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := &model.HealthReport{Status: model.HealthStatusOK, Checks: checks}
+	for _, check := range checks {
+		switch {
+		case check.Status == model.HealthStatusOK:
+		case check.Critical:
+			report.Status = model.HealthStatusFail
+		case report.Status == model.HealthStatusOK:
+			report.Status = model.HealthStatusDegraded
+		}
+	}
+	return report
+}
+
+// run runs c within the timeout. A check ignoring ctx is abandoned when the timeout expires.
+func (s *HealthService) run(ctx context.Context, c *Checker) *model.HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	start := time.Now()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.Check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = fmt.Errorf("not finished within %s, err = %w", s.timeout, ctx.Err())
+	}
+
+	check := &model.HealthCheck{
+		Name:      c.Name,
+		Status:    model.HealthStatusOK,
+		Critical:  c.Critical,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		check.Status = model.HealthStatusFail
+		check.Error = err.Error()
+	}
+	return check
+}
@@ -0,0 +1,25 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+var _ CheckedRepository = (*SQLiteTODORepository)(nil)
+
+// Checkers implements CheckedRepository interface, which pings the database
+// and reads a TODO to find the database file unreadable.
+func (r *SQLiteTODORepository) Checkers() []*Checker {
+	return []*Checker{
+		{Name: "db", Critical: true, Check: r.db.PingContext},
+		{Name: "todos", Critical: true, Check: func(ctx context.Context) error {
+			const read = `SELECT id FROM todos LIMIT 1`
+			var id int64
+			if err := r.db.QueryRowContext(ctx, read).Scan(&id); err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			return nil
+		}},
+	}
+}
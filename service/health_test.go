@@ -0,0 +1,135 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestHealthService_Check(t *testing.T) {
+	t.Parallel()
+
+	pass := func(context.Context) error { return nil }
+	fail := func(context.Context) error { return errors.New("unavailable") }
+	// hang ignores ctx, which is abandoned on the timeout
+	hang := func(context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}
+
+	cases := map[string]struct {
+		checkers []*service.Checker
+		status   model.HealthStatus
+		checks   []model.HealthStatus
+		err      string
+	}{
+		"No checker": {status: model.HealthStatusOK},
+		"Passed": {
+			checkers: []*service.Checker{{Name: "a", Critical: true, Check: pass}, {Name: "b", Check: pass}},
+			status:   model.HealthStatusOK,
+			checks:   []model.HealthStatus{model.HealthStatusOK, model.HealthStatusOK},
+		},
+		"Non-critical failed": {
+			checkers: []*service.Checker{{Name: "a", Critical: true, Check: pass}, {Name: "b", Check: fail}},
+			status:   model.HealthStatusDegraded,
+			checks:   []model.HealthStatus{model.HealthStatusOK, model.HealthStatusFail},
+			err:      "unavailable",
+		},
+		"Critical failed": {
+			checkers: []*service.Checker{{Name: "a", Check: fail}, {Name: "b", Critical: true, Check: fail}},
+			status:   model.HealthStatusFail,
+			checks:   []model.HealthStatus{model.HealthStatusFail, model.HealthStatusFail},
+			err:      "unavailable",
+		},
+		"Timeout": {
+			checkers: []*service.Checker{{Name: "a", Critical: true, Check: hang}},
+			status:   model.HealthStatusFail,
+			checks:   []model.HealthStatus{model.HealthStatusFail},
+			err:      "not finished within 50ms",
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			svc := service.NewHealthService(50 * time.Millisecond)
+			for _, checker := range c.checkers {
+				svc.Register(checker)
+			}
+			start := time.Now()
+			report := svc.Check(context.Background())
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("unexpected elapsed time, given = %s", elapsed)
+			}
+			if report.Status != c.status {
+				t.Errorf("unexpected status, given = %s, expected = %s", report.Status, c.status)
+			}
+			if len(report.Checks) != len(c.checks) {
+				t.Fatalf("unexpected number of checks, given = %d, expected = %d", len(report.Checks), len(c.checks))
+			}
+			for i, check := range report.Checks {
+				if check.Name != c.checkers[i].Name || check.Critical != c.checkers[i].Critical {
+					t.Errorf("unexpected check, given = %+v, expected = %+v", check, c.checkers[i])
+				}
+				if check.Status != c.checks[i] {
+					t.Errorf("unexpected status of %s, given = %s, expected = %s", check.Name, check.Status, c.checks[i])
+				}
+				if expected := check.Status == model.HealthStatusFail; (check.Error != "" && strings.Contains(check.Error, c.err)) != expected {
+					t.Errorf("unexpected error of %s, given = %q, expected = %q", check.Name, check.Error, c.err)
+				}
+			}
+		})
+	}
+}
+
+func TestHealthService_Register(t *testing.T) {
+	t.Parallel()
+
+	svc := service.NewHealthService(time.Second)
+	svc.Register(&service.Checker{Name: "db", Check: func(context.Context) error { return nil }})
+	defer func() {
+		if recover() == nil {
+			t.Error("unexpected registration of duplicated name")
+		}
+	}()
+	svc.Register(&service.Checker{Name: "db", Check: func(context.Context) error { return nil }})
+}
+
+func TestSQLiteTODORepository_Checkers(t *testing.T) {
+	t.Parallel()
+
+	d := newTestDB(t)
+	svc := service.NewHealthService(time.Second)
+	for _, c := range service.NewSQLiteTODORepository(d).Checkers() {
+		svc.Register(c)
+	}
+
+	if report := svc.Check(context.Background()); report.Status != model.HealthStatusOK {
+		t.Errorf("unexpected status of empty db, given = %+v", report.Checks)
+	}
+	if _, err := service.NewTODOService(d).CreateTODO(context.Background(), "subject", ""); err != nil {
+		t.Fatal("failed to create todo, err =", err)
+	}
+	if report := svc.Check(context.Background()); report.Status != model.HealthStatusOK {
+		t.Errorf("unexpected status, given = %+v", report.Checks)
+	}
+
+	if _, err := d.Exec(`ALTER TABLE todos RENAME TO unreadable`); err != nil {
+		t.Fatal("failed to rename table, err =", err)
+	}
+	report := svc.Check(context.Background())
+	if report.Status != model.HealthStatusFail {
+		t.Errorf("unexpected status without todos, given = %s, expected = %s", report.Status, model.HealthStatusFail)
+	}
+	for _, check := range report.Checks {
+		if expected := check.Name == "db"; (check.Status == model.HealthStatusOK) != expected {
+			t.Errorf("unexpected status of %s, given = %s", check.Name, check.Status)
+		}
+	}
+}
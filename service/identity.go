@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// An IdentityRepository persists the links of users to the subjects of OpenID Connect providers.
+type IdentityRepository interface {
+	// GetIdentity reads the user linked to the subject of the issuer, returning *model.ErrNotFound when none is.
+	GetIdentity(ctx context.Context, issuer, subject string) (*model.User, error)
+	// CreateIdentity creates the user by name linked to the subject of the issuer. It returns *model.ErrConflict
+	// when the name is taken or the subject is linked already, in which case nothing is created.
+	CreateIdentity(ctx context.Context, issuer, subject, name string) (*model.User, error)
+}
+
+// An IdentityService implements provisioning the users logging in with OpenID Connect providers.
+type IdentityService struct {
+	repo IdentityRepository
+}
+
+// NewIdentityService returns new IdentityService backed by the SQLite database.
+func NewIdentityService(db *sql.DB) *IdentityService {
+	return NewIdentityServiceWithRepository(NewSQLiteTODORepository(db))
+}
+
+// NewIdentityServiceWithRepository returns new IdentityService backed by repo.
+func NewIdentityServiceWithRepository(repo IdentityRepository) *IdentityService {
+	return &IdentityService{
+		repo: repo,
+	}
+}
+
+// Provision returns the user linked to the subject of the issuer, creating the user just in time
+// on the first login. The user is named name, or the name suffixed by a hash of the subject when
+// it is taken, since linking an existing user of the same name would let the provider take it over.
+func (s *IdentityService) Provision(ctx context.Context, issuer, subject, name string) (*model.User, error) {
+	if issuer == "" || subject == "" {
+		return nil, ErrUnauthenticated
+	}
+	user, err := s.repo.GetIdentity(ctx, issuer, subject)
+	if !isNotFound(err) {
+		return user, err
+	}
+
+	name = strings.TrimSpace(name)
+	sum := sha256.Sum256([]byte(issuer + " " + subject))
+	suffixed := hex.EncodeToString(sum[:4])
+	if name != "" {
+		suffixed = name + "-" + suffixed
+	}
+	for _, candidate := range []string{name, suffixed} {
+		if candidate == "" {
+			continue
+		}
+		user, err = s.repo.CreateIdentity(ctx, issuer, subject, candidate)
+		var conflict *model.ErrConflict
+		if !errors.As(err, &conflict) {
+			return user, err
+		}
+		// the conflict may be of the subject linked by a concurrent login
+		if user, err := s.repo.GetIdentity(ctx, issuer, subject); !isNotFound(err) {
+			return user, err
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+var _ IdentityRepository = (*MemoryTODORepository)(nil)
+
+// A memoryIdentity is the subject of a user by the issuer of the provider.
+type memoryIdentity struct {
+	issuer, subject string
+}
+
+// GetIdentity implements IdentityRepository interface.
+func (r *MemoryTODORepository) GetIdentity(ctx context.Context, issuer, subject string) (*model.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, ok := r.identities[memoryIdentity{issuer: issuer, subject: subject}]
+	if !ok {
+		return nil, &model.ErrNotFound{}
+	}
+	copied := *r.users[id-1]
+	return &copied, nil
+}
+
+// CreateIdentity implements IdentityRepository interface.
+func (r *MemoryTODORepository) CreateIdentity(ctx context.Context, issuer, subject, name string) (*model.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return nil, errConstraintCheck
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := memoryIdentity{issuer: issuer, subject: subject}
+	if _, ok := r.identities[key]; ok || r.findUser(name) != nil {
+		return nil, &model.ErrConflict{Message: fmt.Sprintf("user %q or the identity already exists", name)}
+	}
+	user := &model.User{
+		ID:        int64(len(r.users)) + 1,
+		Name:      name,
+		CreatedAt: memoryNow(),
+	}
+	r.users = append(r.users, user)
+	r.identities[key] = user.ID
+
+	copied := *user
+	return &copied, nil
+}
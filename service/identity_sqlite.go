@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+var _ IdentityRepository = (*SQLiteTODORepository)(nil)
+
+// GetIdentity implements IdentityRepository interface.
+func (r *SQLiteTODORepository) GetIdentity(ctx context.Context, issuer, subject string) (*model.User, error) {
+	return r.getUser(ctx, `id = (SELECT user_id FROM user_identities WHERE issuer = ? AND subject = ?)`, issuer, subject)
+}
+
+// CreateIdentity implements IdentityRepository interface.
+func (r *SQLiteTODORepository) CreateIdentity(ctx context.Context, issuer, subject, name string) (*model.User, error) {
+	const (
+		insertUser     = `INSERT INTO users(name) VALUES(?)`
+		insertIdentity = `INSERT INTO user_identities(user_id, issuer, subject) VALUES(?, ?, ?)`
+		read           = `SELECT id, name, created_at FROM users WHERE id = ?`
+	)
+
+	user := &model.User{}
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, insertUser, name)
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, insertIdentity, id, issuer, subject); err != nil {
+			return err
+		}
+		return tx.QueryRowContext(ctx, read, id).Scan(&user.ID, &user.Name, &user.CreatedAt)
+	})
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return nil, &model.ErrConflict{Message: fmt.Sprintf("user %q or the identity already exists", name)}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,63 @@
+package service_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestIdentityService(t *testing.T) {
+	t.Parallel()
+
+	for name, newRepo := range repositories() {
+		newRepo := newRepo
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			repo := newRepo(t)
+			alice, err := service.NewUserServiceWithRepository(repo.(service.UserRepository)).CreateUser(ctx, "alice")
+			if err != nil {
+				t.Fatal("failed to create user, err =", err)
+			}
+			svc := service.NewIdentityServiceWithRepository(repo.(service.IdentityRepository))
+
+			bob, err := svc.Provision(ctx, "https://idp.example.com", "1", " bob ")
+			if err != nil {
+				t.Fatal("failed to provision user, err =", err)
+			}
+			if bob.Name != "bob" {
+				t.Errorf("unexpected name, given = %q, expected = %q", bob.Name, "bob")
+			}
+			again, err := svc.Provision(ctx, "https://idp.example.com", "1", "robert")
+			if err != nil {
+				t.Fatal("failed to provision user, err =", err)
+			}
+			if again.ID != bob.ID || again.Name != "bob" {
+				t.Errorf("unexpected user of linked subject, given = %+v, expected = %+v", again, bob)
+			}
+
+			// a provider cannot take over the existing user of the same name
+			impostor, err := svc.Provision(ctx, "https://idp.example.com", "2", "Alice")
+			if err != nil {
+				t.Fatal("failed to provision user, err =", err)
+			}
+			if impostor.ID == alice.ID || !strings.HasPrefix(impostor.Name, "Alice-") {
+				t.Errorf("unexpected user of taken name, given = %+v", impostor)
+			}
+			other, err := svc.Provision(ctx, "https://other.example.com", "1", "")
+			if err != nil {
+				t.Fatal("failed to provision user, err =", err)
+			}
+			if other.ID == bob.ID || other.Name == "" {
+				t.Errorf("unexpected user of other issuer, given = %+v", other)
+			}
+
+			if _, err := svc.Provision(ctx, "https://idp.example.com", "", "carol"); err != service.ErrUnauthenticated {
+				t.Errorf("unexpected error without subject, given = %v, expected = %v", err, service.ErrUnauthenticated)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// ErrForbidden is returned when the caller may see the list or the TODO but lacks the role the action requires.
+// The lists and TODOs the caller may not see are not found instead, so that their existence does not leak.
+var ErrForbidden = errors.New("service: forbidden")
+
+// A ListRepository persists the lists sharing TODOs and their members.
+// A list is visible only to its members, and GetList returns *model.ErrNotFound for the lists
+// the caller of ctx is not a member of. The methods changing a list do not check the role of the caller,
+// which is checked by ListService.
+type ListRepository interface {
+	// CreateList creates the list by name with the caller of ctx as its owner.
+	CreateList(ctx context.Context, name string) (*model.List, error)
+	// ListLists returns the lists the caller of ctx is a member of in id order.
+	ListLists(ctx context.Context) ([]*model.List, error)
+	// GetList returns the list by id with the role of the caller of ctx.
+	GetList(ctx context.Context, id int64) (*model.List, error)
+	// RenameList renames the list by id and returns it with the role of the caller of ctx.
+	RenameList(ctx context.Context, id int64, name string) (*model.List, error)
+	// DeleteList deletes the list by id. Its TODOs are kept as the TODOs of no list of the users who created them.
+	DeleteList(ctx context.Context, id int64) error
+	// ListMembers returns the members of the list by id in the order they joined.
+	ListMembers(ctx context.Context, id int64) ([]*model.Member, error)
+	// PutMember adds the user by name to the list by id with role, or changes the role of the member.
+	// It returns *model.ErrNotFound when the user does not exist and *model.ErrConflict when
+	// the list would be left without owner, in which case nothing changes.
+	PutMember(ctx context.Context, id int64, userName string, role model.Role) (*model.Member, error)
+	// DeleteMember removes the user by name from the list by id.
+	// It returns *model.ErrNotFound when the user is not a member and *model.ErrConflict when
+	// the list would be left without owner, in which case nothing changes.
+	DeleteMember(ctx context.Context, id int64, userName string) error
+}
+
+// A ListService implements sharing TODOs in lists with the roles of their members.
+type ListService struct {
+	repo ListRepository
+}
+
+// NewListService returns new ListService backed by the SQLite database.
+func NewListService(db *sql.DB) *ListService {
+	return NewListServiceWithRepository(NewSQLiteTODORepository(db))
+}
+
+// NewListServiceWithRepository returns new ListService backed by repo.
+func NewListServiceWithRepository(repo ListRepository) *ListService {
+	return &ListService{
+		repo: repo,
+	}
+}
+
+// CreateList creates the list by name owned by the caller, who must be a user.
+func (s *ListService) CreateList(ctx context.Context, name string) (*model.List, error) {
+	if ownerID(ctx) == 0 {
+		return nil, ErrUnauthenticated
+	}
+	return s.repo.CreateList(ctx, strings.TrimSpace(name))
+}
+
+// ReadLists reads the lists the caller is a member of.
+func (s *ListService) ReadLists(ctx context.Context) ([]*model.List, error) {
+	return s.repo.ListLists(ctx)
+}
+
+// GetList reads the list by id.
+func (s *ListService) GetList(ctx context.Context, id int64) (*model.List, error) {
+	return s.repo.GetList(ctx, id)
+}
+
+// RenameList renames the list by id, which requires the owner role.
+func (s *ListService) RenameList(ctx context.Context, id int64, name string) (*model.List, error) {
+	if _, err := s.authorize(ctx, id, model.RoleOwner); err != nil {
+		return nil, err
+	}
+	return s.repo.RenameList(ctx, id, strings.TrimSpace(name))
+}
+
+// DeleteList deletes the list by id, which requires the owner role.
+func (s *ListService) DeleteList(ctx context.Context, id int64) error {
+	if _, err := s.authorize(ctx, id, model.RoleOwner); err != nil {
+		return err
+	}
+	return s.repo.DeleteList(ctx, id)
+}
+
+// ReadMembers reads the members of the list by id.
+func (s *ListService) ReadMembers(ctx context.Context, id int64) ([]*model.Member, error) {
+	if _, err := s.repo.GetList(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListMembers(ctx, id)
+}
+
+// PutMember adds the user by name to the list by id with role or changes the role of the member,
+// which requires the owner role. The last owner of the list cannot be demoted.
+func (s *ListService) PutMember(ctx context.Context, id int64, userName string, role model.Role) (*model.Member, error) {
+	if !role.Valid() {
+		return nil, Invalid("role", ViolationInvalid)
+	}
+	if _, err := s.authorize(ctx, id, model.RoleOwner); err != nil {
+		return nil, err
+	}
+	return s.repo.PutMember(ctx, id, strings.TrimSpace(userName), role)
+}
+
+// DeleteMember removes the user by name from the list by id, which requires the owner role
+// unless the caller leaves the list. The last owner of the list cannot leave it.
+func (s *ListService) DeleteMember(ctx context.Context, id int64, userName string) error {
+	userName = strings.TrimSpace(userName)
+	list, err := s.repo.GetList(ctx, id)
+	if err != nil {
+		return err
+	}
+	user := UserFromContext(ctx)
+	leaving := user != nil && tagKey(user.Name) == tagKey(userName)
+	if !leaving && !list.Role.Allows(model.RoleOwner) {
+		return ErrForbidden
+	}
+	return s.repo.DeleteMember(ctx, id, userName)
+}
+
+// authorize returns the list by id unless the caller lacks role in it.
+func (s *ListService) authorize(ctx context.Context, id int64, role model.Role) (*model.List, error) {
+	list, err := s.repo.GetList(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !list.Role.Allows(role) {
+		return nil, ErrForbidden
+	}
+	return list, nil
+}
+
+// authorizeList returns ErrForbidden unless the caller has role in the list by id,
+// and *model.ErrNotFound when the caller is not a member of it.
+func (s *TODOService) authorizeList(ctx context.Context, role model.Role, id int64) error {
+	if s.lists == nil {
+		return &model.ErrNotFound{}
+	}
+	list, err := s.lists.GetList(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !list.Role.Allows(role) {
+		return ErrForbidden
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+var _ ListRepository = (*MemoryTODORepository)(nil)
+
+// A memoryMember is the membership of a user in a list.
+type memoryMember struct {
+	role      model.Role
+	createdAt time.Time
+}
+
+// CreateList implements ListRepository interface.
+func (r *MemoryTODORepository) CreateList(ctx context.Context, name string) (*model.List, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if name == "" || len([]rune(name)) > 100 {
+		return nil, errConstraintCheck
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user := ownerID(ctx)
+	if user == 0 {
+		return nil, errConstraintNotNull
+	}
+	now := memoryNow()
+	r.lastListID++
+	list := &model.List{ID: r.lastListID, Name: name, CreatedAt: now, UpdatedAt: now}
+	r.lists = append(r.lists, list)
+	r.members[list.ID] = map[int64]*memoryMember{user: {role: model.RoleOwner, createdAt: now}}
+
+	return r.copyList(ctx, list), nil
+}
+
+// ListLists implements ListRepository interface.
+func (r *MemoryTODORepository) ListLists(ctx context.Context) ([]*model.List, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	lists := []*model.List{}
+	for _, list := range r.lists {
+		if _, ok := r.members[list.ID][ownerID(ctx)]; ok {
+			lists = append(lists, r.copyList(ctx, list))
+		}
+	}
+	return lists, nil
+}
+
+// GetList implements ListRepository interface.
+func (r *MemoryTODORepository) GetList(ctx context.Context, id int64) (*model.List, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := r.findList(id)
+	if list == nil {
+		return nil, &model.ErrNotFound{}
+	}
+	if _, ok := r.members[id][ownerID(ctx)]; !ok {
+		return nil, &model.ErrNotFound{}
+	}
+	return r.copyList(ctx, list), nil
+}
+
+// RenameList implements ListRepository interface.
+func (r *MemoryTODORepository) RenameList(ctx context.Context, id int64, name string) (*model.List, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if name == "" || len([]rune(name)) > 100 {
+		return nil, errConstraintCheck
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := r.findList(id)
+	if list == nil {
+		return nil, &model.ErrNotFound{}
+	}
+	if _, ok := r.members[id][ownerID(ctx)]; !ok {
+		return nil, &model.ErrNotFound{}
+	}
+	list.Name = name
+	list.UpdatedAt = memoryNow()
+	return r.copyList(ctx, list), nil
+}
+
+// DeleteList implements ListRepository interface.
+func (r *MemoryTODORepository) DeleteList(ctx context.Context, id int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, list := range r.lists {
+		if list.ID != id {
+			continue
+		}
+		r.lists = append(r.lists[:i], r.lists[i+1:]...)
+		delete(r.members, id)
+		for _, todo := range r.todos {
+			if todo.ListID == id {
+				todo.ListID = 0
+			}
+		}
+		return nil
+	}
+	return &model.ErrNotFound{}
+}
+
+// ListMembers implements ListRepository interface.
+func (r *MemoryTODORepository) ListMembers(ctx context.Context, id int64) ([]*model.Member, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	members := []*model.Member{}
+	for userID, m := range r.members[id] {
+		members = append(members, &model.Member{
+			UserID:    userID,
+			Name:      r.users[userID-1].Name,
+			Role:      m.role,
+			CreatedAt: m.createdAt,
+		})
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if !members[i].CreatedAt.Equal(members[j].CreatedAt) {
+			return members[i].CreatedAt.Before(members[j].CreatedAt)
+		}
+		return members[i].UserID < members[j].UserID
+	})
+	return members, nil
+}
+
+// PutMember implements ListRepository interface.
+func (r *MemoryTODORepository) PutMember(ctx context.Context, id int64, userName string, role model.Role) (*model.Member, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !role.Valid() {
+		return nil, errConstraintCheck
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user := r.findUser(userName)
+	if user == nil {
+		return nil, &model.ErrNotFound{}
+	}
+	members, ok := r.members[id]
+	if !ok {
+		return nil, errConstraintForeignKey
+	}
+	m, ok := members[user.ID]
+	if !ok {
+		m = &memoryMember{createdAt: memoryNow()}
+	}
+	if m.role == model.RoleOwner && role != model.RoleOwner && r.owners(id) == 1 {
+		return nil, errLastOwner()
+	}
+	m.role = role
+	members[user.ID] = m
+
+	return &model.Member{UserID: user.ID, Name: user.Name, Role: m.role, CreatedAt: m.createdAt}, nil
+}
+
+// DeleteMember implements ListRepository interface.
+func (r *MemoryTODORepository) DeleteMember(ctx context.Context, id int64, userName string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user := r.findUser(userName)
+	if user == nil {
+		return &model.ErrNotFound{}
+	}
+	m, ok := r.members[id][user.ID]
+	if !ok {
+		return &model.ErrNotFound{}
+	}
+	if m.role == model.RoleOwner && r.owners(id) == 1 {
+		return errLastOwner()
+	}
+	delete(r.members[id], user.ID)
+	return nil
+}
+
+// errLastOwner returns the error of the changes leaving a list without owner, as keepOwner does.
+func errLastOwner() error {
+	return &model.ErrConflict{Message: "list must keep at least one owner"}
+}
+
+// findList returns the list by id, or nil if it does not exist.
+func (r *MemoryTODORepository) findList(id int64) *model.List {
+	for _, list := range r.lists {
+		if list.ID == id {
+			return list
+		}
+	}
+	return nil
+}
+
+// owners returns the number of the owners of the list by id.
+func (r *MemoryTODORepository) owners(id int64) int {
+	n := 0
+	for _, m := range r.members[id] {
+		if m.role == model.RoleOwner {
+			n++
+		}
+	}
+	return n
+}
+
+// copyList returns a copy of list with the role of the caller of ctx.
+func (r *MemoryTODORepository) copyList(ctx context.Context, list *model.List) *model.List {
+	copied := *list
+	if m, ok := r.members[list.ID][ownerID(ctx)]; ok {
+		copied.Role = m.role
+	}
+	return &copied
+}
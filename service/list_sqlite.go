@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+var _ ListRepository = (*SQLiteTODORepository)(nil)
+
+const (
+	// listColumns lists the columns of a list joined with the membership of the caller as l and m,
+	// in the order scanned by listFields.
+	listColumns = `l.id, l.name, m.role, l.created_at, l.updated_at`
+	// memberColumns lists the columns of a member joined with the user as m and u, in the order scanned by memberFields.
+	memberColumns = `m.user_id, u.name, m.role, m.created_at`
+	// countOwners counts the owners of a list, which must not drop to zero.
+	countOwners = `SELECT COUNT(*) FROM list_members WHERE list_id = ? AND role = 'owner'`
+)
+
+// CreateList implements ListRepository interface.
+func (r *SQLiteTODORepository) CreateList(ctx context.Context, name string) (*model.List, error) {
+	const (
+		insert = `INSERT INTO lists(name) VALUES(?)`
+		join   = `INSERT INTO list_members(list_id, user_id, role) VALUES(?, ?, 'owner')`
+	)
+
+	var list *model.List
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, insert, name)
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, join, id, ownerArg(ctx)); err != nil {
+			return err
+		}
+		list, err = getList(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ListLists implements ListRepository interface.
+func (r *SQLiteTODORepository) ListLists(ctx context.Context) ([]*model.List, error) {
+	const read = `SELECT ` + listColumns + ` FROM lists l JOIN list_members m ON m.list_id = l.id WHERE m.user_id = ? ORDER BY l.id`
+
+	rows, err := r.db.QueryContext(ctx, read, ownerArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := []*model.List{}
+	for rows.Next() {
+		list := &model.List{}
+		if err := rows.Scan(listFields(list)...); err != nil {
+			return nil, err
+		}
+		lists = append(lists, list)
+	}
+	return lists, rows.Err()
+}
+
+// GetList implements ListRepository interface.
+func (r *SQLiteTODORepository) GetList(ctx context.Context, id int64) (*model.List, error) {
+	return getList(ctx, r.db, id)
+}
+
+// RenameList implements ListRepository interface.
+func (r *SQLiteTODORepository) RenameList(ctx context.Context, id int64, name string) (*model.List, error) {
+	const update = `UPDATE lists SET name = ? WHERE id = ?`
+
+	var list *model.List
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, update, name, id)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return &model.ErrNotFound{}
+		}
+		list, err = getList(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// DeleteList implements ListRepository interface.
+func (r *SQLiteTODORepository) DeleteList(ctx context.Context, id int64) error {
+	const remove = `DELETE FROM lists WHERE id = ?`
+
+	res, err := r.db.ExecContext(ctx, remove, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return &model.ErrNotFound{}
+	}
+	return nil
+}
+
+// ListMembers implements ListRepository interface.
+func (r *SQLiteTODORepository) ListMembers(ctx context.Context, id int64) ([]*model.Member, error) {
+	const read = `SELECT ` + memberColumns + ` FROM list_members m JOIN users u ON u.id = m.user_id
+WHERE m.list_id = ? ORDER BY m.created_at, m.user_id`
+
+	rows, err := r.db.QueryContext(ctx, read, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []*model.Member{}
+	for rows.Next() {
+		member := &model.Member{}
+		if err := rows.Scan(memberFields(member)...); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+// PutMember implements ListRepository interface.
+func (r *SQLiteTODORepository) PutMember(ctx context.Context, id int64, userName string, role model.Role) (*model.Member, error) {
+	const (
+		upsert = `INSERT INTO list_members(list_id, user_id, role) SELECT ?, id, ? FROM users WHERE name = ?
+ON CONFLICT(list_id, user_id) DO UPDATE SET role = excluded.role`
+		read = `SELECT ` + memberColumns + ` FROM list_members m JOIN users u ON u.id = m.user_id
+WHERE m.list_id = ? AND u.name = ?`
+	)
+
+	member := &model.Member{}
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, upsert, id, string(role), userName)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return &model.ErrNotFound{}
+		}
+		if err := keepOwner(ctx, tx, id); err != nil {
+			return err
+		}
+		return tx.QueryRowContext(ctx, read, id, userName).Scan(memberFields(member)...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// DeleteMember implements ListRepository interface.
+func (r *SQLiteTODORepository) DeleteMember(ctx context.Context, id int64, userName string) error {
+	const remove = `DELETE FROM list_members WHERE list_id = ? AND user_id = (SELECT id FROM users WHERE name = ?)`
+
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, remove, id, userName)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return &model.ErrNotFound{}
+		}
+		return keepOwner(ctx, tx, id)
+	})
+}
+
+// authorizeIDs returns ErrForbidden when the caller of ctx may see but not change any of the TODOs by ids,
+// even in the trash. It is called in the transaction of the change, so that the role cannot change in between.
+func authorizeIDs(ctx context.Context, q queryer, ids []int64) error {
+	const readOnly = `SELECT COUNT(*) FROM todos WHERE id IN (?%s) AND ` + withAccess + ` AND NOT ` + withEditAccess
+
+	args := append(int64Args(ids), ownerArg(ctx), ownerArg(ctx))
+	var n int64
+	if err := q.QueryRowContext(ctx, fmt.Sprintf(readOnly, strings.Repeat(",?", len(ids)-1)), args...).Scan(&n); err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// getList reads the list by id with the role of the caller of ctx, who must be a member of it.
+func getList(ctx context.Context, q queryer, id int64) (*model.List, error) {
+	const read = `SELECT ` + listColumns + ` FROM lists l JOIN list_members m ON m.list_id = l.id WHERE m.user_id = ? AND l.id = ?`
+
+	list := &model.List{}
+	err := q.QueryRowContext(ctx, read, ownerArg(ctx), id).Scan(listFields(list)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &model.ErrNotFound{}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// keepOwner returns *model.ErrConflict when the list by id has no owner left.
+func keepOwner(ctx context.Context, q queryer, id int64) error {
+	var owners int64
+	if err := q.QueryRowContext(ctx, countOwners, id).Scan(&owners); err != nil {
+		return err
+	}
+	if owners == 0 {
+		return &model.ErrConflict{Message: "list must keep at least one owner"}
+	}
+	return nil
+}
+
+// listFields returns the scan destinations of listColumns.
+func listFields(list *model.List) []interface{} {
+	return []interface{}{&list.ID, &list.Name, &list.Role, &list.CreatedAt, &list.UpdatedAt}
+}
+
+// memberFields returns the scan destinations of memberColumns.
+func memberFields(member *model.Member) []interface{} {
+	return []interface{}{&member.UserID, &member.Name, &member.Role, &member.CreatedAt}
+}
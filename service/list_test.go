@@ -0,0 +1,271 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestListService(t *testing.T) {
+	t.Parallel()
+
+	for name, newRepo := range repositories() {
+		newRepo := newRepo
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			repo := newRepo(t)
+			users := service.NewUserServiceWithRepository(repo.(service.UserRepository))
+			svc := service.NewListServiceWithRepository(repo.(service.ListRepository))
+			ctx := context.Background()
+			as := make(map[string]context.Context)
+			for _, name := range []string{"alice", "bob", "carol"} {
+				user, err := users.CreateUser(ctx, name)
+				if err != nil {
+					t.Fatal("failed to create user, err =", err)
+				}
+				as[name] = service.WithUser(ctx, user)
+			}
+
+			if _, err := svc.CreateList(ctx, "anonymous"); err != service.ErrUnauthenticated {
+				t.Errorf("unexpected error of anonymous caller, given = %v, expected = %v", err, service.ErrUnauthenticated)
+			}
+			list, err := svc.CreateList(as["alice"], " groceries ")
+			if err != nil {
+				t.Fatal("failed to create list, err =", err)
+			}
+			if list.Name != "groceries" || list.Role != model.RoleOwner {
+				t.Errorf("unexpected list, given = %+v", list)
+			}
+
+			// lists are not found by those who are not their members
+			if _, err := svc.GetList(as["bob"], list.ID); !isNotFound(err) {
+				t.Errorf("unexpected error of non-member, given = %v", err)
+			}
+			if _, err := svc.RenameList(as["bob"], list.ID, "mine"); !isNotFound(err) {
+				t.Errorf("unexpected error of renaming by non-member, given = %v", err)
+			}
+			if lists, err := svc.ReadLists(as["bob"]); err != nil || len(lists) != 0 {
+				t.Errorf("unexpected lists of non-member, given = %v, err = %v", lists, err)
+			}
+
+			if _, err := svc.PutMember(as["alice"], list.ID, "bob", "admin"); err == nil {
+				t.Error("unexpected member of unknown role")
+			}
+			if _, err := svc.PutMember(as["alice"], list.ID, "dave", model.RoleViewer); !isNotFound(err) {
+				t.Errorf("unexpected error of unknown user, given = %v", err)
+			}
+			member, err := svc.PutMember(as["alice"], list.ID, "Bob", model.RoleViewer)
+			if err != nil {
+				t.Fatal("failed to add member, err =", err)
+			}
+			if member.Name != "bob" || member.Role != model.RoleViewer {
+				t.Errorf("unexpected member, given = %+v", member)
+			}
+			if got, err := svc.GetList(as["bob"], list.ID); err != nil || got.Role != model.RoleViewer {
+				t.Errorf("unexpected list of viewer, given = %+v, err = %v", got, err)
+			}
+
+			// viewers see the list but may not manage it
+			if _, err := svc.RenameList(as["bob"], list.ID, "mine"); err != service.ErrForbidden {
+				t.Errorf("unexpected error of renaming by viewer, given = %v, expected = %v", err, service.ErrForbidden)
+			}
+			if _, err := svc.PutMember(as["bob"], list.ID, "carol", model.RoleOwner); err != service.ErrForbidden {
+				t.Errorf("unexpected error of adding by viewer, given = %v, expected = %v", err, service.ErrForbidden)
+			}
+			if err := svc.DeleteList(as["bob"], list.ID); err != service.ErrForbidden {
+				t.Errorf("unexpected error of deleting by viewer, given = %v, expected = %v", err, service.ErrForbidden)
+			}
+
+			members, err := svc.ReadMembers(as["bob"], list.ID)
+			if err != nil {
+				t.Fatal("failed to read members, err =", err)
+			}
+			if len(members) != 2 || members[0].Name != "alice" || members[1].Name != "bob" {
+				t.Errorf("unexpected members, given = %+v", members)
+			}
+
+			// the last owner is kept
+			var conflict *model.ErrConflict
+			if _, err := svc.PutMember(as["alice"], list.ID, "alice", model.RoleEditor); !errors.As(err, &conflict) {
+				t.Errorf("unexpected error of demoting last owner, given = %v", err)
+			}
+			if err := svc.DeleteMember(as["alice"], list.ID, "alice"); !errors.As(err, &conflict) {
+				t.Errorf("unexpected error of last owner leaving, given = %v", err)
+			}
+
+			renamed, err := svc.RenameList(as["alice"], list.ID, "errands")
+			if err != nil {
+				t.Fatal("failed to rename list, err =", err)
+			}
+			if renamed.Name != "errands" {
+				t.Errorf("unexpected name, given = %q, expected = %q", renamed.Name, "errands")
+			}
+
+			// members leave by themselves
+			if err := svc.DeleteMember(as["bob"], list.ID, "bob"); err != nil {
+				t.Fatal("failed to leave list, err =", err)
+			}
+			if _, err := svc.GetList(as["bob"], list.ID); !isNotFound(err) {
+				t.Errorf("unexpected error of member left, given = %v", err)
+			}
+			if err := svc.DeleteMember(as["alice"], list.ID, "bob"); !isNotFound(err) {
+				t.Errorf("unexpected error of removing non-member, given = %v", err)
+			}
+
+			if err := svc.DeleteList(as["alice"], list.ID); err != nil {
+				t.Fatal("failed to delete list, err =", err)
+			}
+			if _, err := svc.GetList(as["alice"], list.ID); !isNotFound(err) {
+				t.Errorf("unexpected error of deleted list, given = %v", err)
+			}
+		})
+	}
+}
+
+func TestTODOService_Lists(t *testing.T) {
+	t.Parallel()
+
+	for name, newRepo := range repositories() {
+		newRepo := newRepo
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			repo := newRepo(t)
+			users := service.NewUserServiceWithRepository(repo.(service.UserRepository))
+			lists := service.NewListServiceWithRepository(repo.(service.ListRepository))
+			svc := service.NewTODOServiceWithRepository(repo)
+			ctx := context.Background()
+			as := make(map[string]context.Context)
+			for _, name := range []string{"alice", "bob", "carol", "dave"} {
+				user, err := users.CreateUser(ctx, name)
+				if err != nil {
+					t.Fatal("failed to create user, err =", err)
+				}
+				as[name] = service.WithUser(ctx, user)
+			}
+
+			list, err := lists.CreateList(as["alice"], "shared")
+			if err != nil {
+				t.Fatal("failed to create list, err =", err)
+			}
+			for name, role := range map[string]model.Role{"bob": model.RoleEditor, "carol": model.RoleViewer} {
+				if _, err := lists.PutMember(as["alice"], list.ID, name, role); err != nil {
+					t.Fatal("failed to add member, err =", err)
+				}
+			}
+
+			shared, err := svc.CreateTODOWithInput(as["bob"], &service.TODOInput{Subject: "shared", Tags: []string{"home"}, ListID: list.ID})
+			if err != nil {
+				t.Fatal("failed to create TODO of list, err =", err)
+			}
+			if shared.ListID != list.ID || len(shared.Tags) != 1 {
+				t.Errorf("unexpected TODO of list, given = %+v", shared)
+			}
+			private, err := svc.CreateTODO(as["alice"], "private", "")
+			if err != nil {
+				t.Fatal("failed to create TODO, err =", err)
+			}
+			if _, err := svc.CreateTODOWithInput(as["carol"], &service.TODOInput{Subject: "viewer", ListID: list.ID}); err != service.ErrForbidden {
+				t.Errorf("unexpected error of creating by viewer, given = %v, expected = %v", err, service.ErrForbidden)
+			}
+			if _, err := svc.CreateTODOWithInput(as["dave"], &service.TODOInput{Subject: "stranger", ListID: list.ID}); !isNotFound(err) {
+				t.Errorf("unexpected error of creating by non-member, given = %v", err)
+			}
+
+			// every member sees the TODOs of the list, and only them
+			for _, name := range []string{"alice", "bob", "carol"} {
+				todo, err := svc.GetTODO(as[name], shared.ID)
+				if err != nil {
+					t.Fatalf("failed to get TODO of list as %s, err = %v", name, err)
+				}
+				if todo.Subject != "shared" || len(todo.Tags) != 1 || todo.Tags[0] != "home" {
+					t.Errorf("unexpected TODO as %s, given = %+v", name, todo)
+				}
+				todos, err := svc.FilterTODO(as[name], &service.TODOFilter{ListID: list.ID}, 0, 10)
+				if err != nil {
+					t.Fatal("failed to filter TODOs, err =", err)
+				}
+				if got := ids(todos); len(got) != 1 || got[0] != shared.ID {
+					t.Errorf("unexpected TODOs of list as %s, given = %v", name, got)
+				}
+			}
+			if _, err := svc.GetTODO(as["dave"], shared.ID); !isNotFound(err) {
+				t.Errorf("unexpected error of getting by non-member, given = %v", err)
+			}
+			if _, err := svc.GetTODO(as["bob"], private.ID); !isNotFound(err) {
+				t.Errorf("unexpected error of getting private TODO, given = %v", err)
+			}
+
+			// viewers are forbidden to change the TODOs, and non-members do not find them
+			forbidden := map[string]func(ctx context.Context) error{
+				"Update": func(ctx context.Context) error {
+					_, err := svc.UpdateTODO(ctx, shared.ID, "changed", "")
+					return err
+				},
+				"Complete": func(ctx context.Context) error {
+					_, _, err := svc.CompleteTODO(ctx, shared.ID)
+					return err
+				},
+				"Delete": func(ctx context.Context) error {
+					return svc.DeleteTODO(ctx, []int64{shared.ID})
+				},
+			}
+			for action, fn := range forbidden {
+				if err := fn(as["carol"]); err != service.ErrForbidden {
+					t.Errorf("unexpected error of %s by viewer, given = %v, expected = %v", action, err, service.ErrForbidden)
+				}
+				if err := fn(as["dave"]); !isNotFound(err) {
+					t.Errorf("unexpected error of %s by non-member, given = %v", action, err)
+				}
+			}
+
+			// the TODOs the viewer may change are not deleted with those the viewer may not either
+			own, err := svc.CreateTODO(as["carol"], "own", "")
+			if err != nil {
+				t.Fatal("failed to create TODO, err =", err)
+			}
+			if err := svc.DeleteTODO(as["carol"], []int64{own.ID, shared.ID}); err != service.ErrForbidden {
+				t.Errorf("unexpected error of deleting by viewer, given = %v, expected = %v", err, service.ErrForbidden)
+			}
+			if _, err := svc.GetTODO(as["carol"], own.ID); err != nil {
+				t.Error("failed to get TODO left out of trash, err =", err)
+			}
+
+			updated, err := svc.UpdateTODOWithInput(as["alice"], shared.ID, &service.TODOInput{Subject: "updated", Tags: []string{"work"}})
+			if err != nil {
+				t.Fatal("failed to update TODO by owner of list, err =", err)
+			}
+			if updated.Subject != "updated" || len(updated.Tags) != 1 || updated.Tags[0] != "work" || updated.OwnerID != shared.OwnerID {
+				t.Errorf("unexpected updated TODO, given = %+v", updated)
+			}
+			if err := svc.DeleteTODO(as["bob"], []int64{shared.ID}); err != nil {
+				t.Fatal("failed to delete TODO by editor, err =", err)
+			}
+			if err := svc.RestoreTODO(as["carol"], []int64{shared.ID}); err != service.ErrForbidden {
+				t.Errorf("unexpected error of restoring by viewer, given = %v, expected = %v", err, service.ErrForbidden)
+			}
+			if err := svc.RestoreTODO(as["alice"], []int64{shared.ID}); err != nil {
+				t.Fatal("failed to restore TODO, err =", err)
+			}
+
+			// the TODOs of a deleted list are left to those who created them
+			if err := lists.DeleteList(as["alice"], list.ID); err != nil {
+				t.Fatal("failed to delete list, err =", err)
+			}
+			if _, err := svc.GetTODO(as["alice"], shared.ID); !isNotFound(err) {
+				t.Errorf("unexpected error of TODO of deleted list, given = %v", err)
+			}
+			todo, err := svc.GetTODO(as["bob"], shared.ID)
+			if err != nil {
+				t.Fatal("failed to get TODO of deleted list, err =", err)
+			}
+			if todo.ListID != 0 {
+				t.Errorf("unexpected list of TODO, given = %d, expected = 0", todo.ListID)
+			}
+		})
+	}
+}
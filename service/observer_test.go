@@ -0,0 +1,82 @@
+package service_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+// A recorder records the calls observed as method and whether it failed.
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recorder) ObserveCall(method string, elapsed time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		method += ":error"
+	}
+	r.calls = append(r.calls, method)
+}
+
+func TestTODOService_SetObserver(t *testing.T) {
+	t.Parallel()
+
+	for name, newRepo := range repositories() {
+		newRepo := newRepo
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			svc := service.NewTODOServiceWithRepository(newRepo(t))
+			rec := &recorder{}
+			svc.SetObserver(rec)
+
+			todo, err := svc.CreateTODO(ctx, "subject", "")
+			if err != nil {
+				t.Fatal("failed to create todo, err =", err)
+			}
+			if _, err := svc.ReadTODO(ctx, 0, 5); err != nil {
+				t.Fatal("failed to read todos, err =", err)
+			}
+			if _, err := svc.FilterTODO(ctx, &service.TODOFilter{Tags: []string{"work"}}, 0, 5); err != nil {
+				t.Fatal("failed to filter todos, err =", err)
+			}
+			if _, err := svc.UpdateTODO(ctx, todo.ID+1, "subject", ""); err == nil {
+				t.Fatal("unexpected update of missing todo")
+			}
+			if err := svc.DeleteTODO(ctx, []int64{todo.ID}); err != nil {
+				t.Fatal("failed to delete todo, err =", err)
+			}
+			// methods not observed
+			if _, err := svc.GetTODO(ctx, todo.ID); err == nil {
+				t.Fatal("unexpected get of deleted todo")
+			}
+
+			expected := []string{
+				service.MethodCreateTODO,
+				service.MethodReadTODO,
+				service.MethodReadTODO,
+				service.MethodUpdateTODO + ":error",
+				service.MethodDeleteTODO,
+			}
+			if !reflect.DeepEqual(rec.calls, expected) {
+				t.Errorf("unexpected calls, given = %v, expected = %v", rec.calls, expected)
+			}
+
+			svc.SetObserver(nil)
+			if _, err := svc.ReadTODO(ctx, 0, 5); err != nil {
+				t.Fatal("failed to read todos, err =", err)
+			}
+			if len(rec.calls) != len(expected) {
+				t.Errorf("unexpected calls after observer is removed, given = %v", rec.calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/rrule"
+)
+
+// ErrInvalidRecurrence is wrapped by the errors of recurrences which cannot be parsed or anchored.
+var ErrInvalidRecurrence = errors.New("service: invalid recurrence")
+
+// RRule returns the RRULE value req expresses.
+// It returns an error wrapping ErrInvalidRecurrence when req is invalid.
+func RRule(req *model.RecurrenceRequest) (string, error) {
+	if req.RRule != "" {
+		if req.Frequency != "" || req.Interval != 0 || len(req.Weekdays) > 0 || req.MonthDay != 0 {
+			return "", fmt.Errorf("%w: rrule cannot be combined with frequency", ErrInvalidRecurrence)
+		}
+		return req.RRule, nil
+	}
+
+	parts := []string{"FREQ=" + strings.ToUpper(req.Frequency)}
+	if req.Interval != 0 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(req.Interval))
+	}
+	if len(req.Weekdays) > 0 {
+		parts = append(parts, "BYDAY="+strings.Join(req.Weekdays, ","))
+	}
+	if req.MonthDay != 0 {
+		parts = append(parts, "BYMONTHDAY="+strconv.Itoa(req.MonthDay))
+	}
+	return strings.Join(parts, ";"), nil
+}
+
+// CompleteTODO marks the TODO completed.
+// Completing an occurrence of a recurring TODO creates its next occurrence, which is returned as next.
+// next is nil when the TODO is not recurring, is already completed or has no more occurrences.
+func (s *TODOService) CompleteTODO(ctx context.Context, id int64) (todo, next *model.TODO, err error) {
+	before, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if before.Recurrence == nil || before.Completed {
+		todo, err = s.setCompleted(ctx, id, true)
+		return todo, nil, err
+	}
+
+	occurrences, err := s.occurrences(before, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(occurrences) == 0 {
+		todo, err = s.setCompleted(ctx, id, true)
+		return todo, nil, err
+	}
+
+	ctx, _ = s.recording(ctx)
+	return s.repo.CompleteOccurrence(ctx, id, occurrences[0])
+}
+
+// PreviewOccurrences returns at most n due dates of the occurrences following the TODO,
+// in the time zone of its recurrence. It returns an empty slice when the TODO is not recurring.
+func (s *TODOService) PreviewOccurrences(ctx context.Context, id, n int64) ([]time.Time, error) {
+	todo, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if todo.Recurrence == nil {
+		return []time.Time{}, nil
+	}
+
+	occurrences, err := s.occurrences(todo, int(n))
+	if err != nil {
+		return nil, err
+	}
+	if occurrences == nil {
+		occurrences = []time.Time{}
+	}
+	return occurrences, nil
+}
+
+// ReadTODOHistory reads the completed occurrences of the recurrence the TODO belongs to,
+// in descending id order. It returns an empty slice when the TODO is not recurring.
+func (s *TODOService) ReadTODOHistory(ctx context.Context, id, prevID, size int64) ([]*model.TODO, error) {
+	todo, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if todo.Recurrence == nil {
+		return []*model.TODO{}, nil
+	}
+
+	filter := &TODOFilter{
+		Status:       model.TODOStatusDone,
+		RecurrenceID: todo.Recurrence.ID,
+	}
+	return s.repo.List(ctx, filter, prevID, size)
+}
+
+// anchorRecurrence returns a copy of recurrence with the canonical RRULE,
+// anchored on dueAt in the time zone of the service.
+func (s *TODOService) anchorRecurrence(recurrence *model.Recurrence, dueAt *time.Time) (*model.Recurrence, error) {
+	rule, err := rrule.Parse(recurrence.RRule)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRecurrence, err)
+	}
+	if dueAt == nil {
+		return nil, fmt.Errorf("%w: recurring TODO requires due date", ErrInvalidRecurrence)
+	}
+
+	return &model.Recurrence{
+		RRule:    rule.String(),
+		Start:    *dueAt,
+		TimeZone: s.location().String(),
+	}, nil
+}
+
+// occurrences returns at most n due dates of the occurrences following the recurring TODO.
+func (s *TODOService) occurrences(todo *model.TODO, n int) ([]time.Time, error) {
+	if todo.DueAt == nil {
+		return nil, nil
+	}
+
+	rule, err := rrule.Parse(todo.Recurrence.RRule)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := time.LoadLocation(todo.Recurrence.TimeZone)
+	if err != nil {
+		loc = s.location()
+	}
+
+	return rule.Occurrences(todo.Recurrence.Start.In(loc), todo.DueAt.In(loc), n), nil
+}
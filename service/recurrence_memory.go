@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// CompleteOccurrence implements TODORepository interface.
+func (r *MemoryTODORepository) CompleteOccurrence(ctx context.Context, id int64, dueAt time.Time) (*model.TODO, *model.TODO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	todo := r.find(ctx, id)
+	if todo == nil {
+		return nil, nil, &model.ErrNotFound{}
+	}
+	if !r.editable(ctx, todo) {
+		return nil, nil, ErrForbidden
+	}
+
+	if todo.Completed {
+		return copyTODO(todo), nil, nil
+	}
+
+	before := copyTODO(todo)
+	now := memoryNow()
+	todo.Completed = true
+	todo.CompletedAt = &now
+	todo.UpdatedAt = now
+
+	r.lastID++
+	next := &model.TODO{
+		ID:          r.lastID,
+		Subject:     todo.Subject,
+		Description: todo.Description,
+		Tags:        append([]string(nil), todo.Tags...),
+		DueAt:       copyTime(&dueAt),
+		Recurrence:  todo.Recurrence,
+		OwnerID:     todo.OwnerID,
+		ListID:      todo.ListID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	*next.DueAt = next.DueAt.UTC()
+	if len(next.Tags) == 0 {
+		next.Tags = nil
+	}
+	r.todos = append(r.todos, next)
+	r.recordRevision(ctx, id, model.RevisionActionComplete, before, todo)
+	r.recordRevision(ctx, next.ID, model.RevisionActionCreate, nil, next)
+
+	return copyTODO(todo), copyTODO(next), nil
+}
+
+// setRecurrence replaces the recurrence of todo, overwriting the recurrence it already has if any.
+// A nil recurrence stops the recurrence of todo.
+func (r *MemoryTODORepository) setRecurrence(todo *model.TODO, recurrence *model.Recurrence) {
+	if recurrence == nil {
+		todo.Recurrence = nil
+		return
+	}
+
+	if todo.Recurrence == nil {
+		r.lastRecurrenceID++
+		todo.Recurrence = &model.Recurrence{ID: r.lastRecurrenceID}
+	}
+	todo.Recurrence.RRule = recurrence.RRule
+	todo.Recurrence.Start = recurrence.Start.UTC()
+	todo.Recurrence.TimeZone = recurrence.TimeZone
+}
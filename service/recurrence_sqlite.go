@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// A recurrenceID scans the recurrence_id column into the recurrence of a TODO,
+// which is filled by loadRecurrences.
+type recurrenceID struct {
+	todo *model.TODO
+}
+
+// Scan implements sql.Scanner interface.
+func (r recurrenceID) Scan(src interface{}) error {
+	if src == nil {
+		r.todo.Recurrence = nil
+		return nil
+	}
+
+	id, ok := src.(int64)
+	if !ok {
+		return fmt.Errorf("service: unexpected recurrence_id %v", src)
+	}
+	r.todo.Recurrence = &model.Recurrence{ID: id}
+	return nil
+}
+
+// CompleteOccurrence implements TODORepository interface.
+func (r *SQLiteTODORepository) CompleteOccurrence(ctx context.Context, id int64, dueAt time.Time) (*model.TODO, *model.TODO, error) {
+	const (
+		complete = `UPDATE todos SET completed = TRUE, completed_at = DATETIME('now') WHERE id = ? AND NOT completed AND deleted_at IS NULL AND ` + withEditAccess
+		insert   = `INSERT INTO todos(subject, description, subject_ngram, description_ngram, due_at, recurrence_id, owner_id, list_id)
+SELECT subject, description, subject_ngram, description_ngram, ?, recurrence_id, owner_id, list_id FROM todos WHERE id = ?`
+		copyTags = `INSERT INTO todo_tags(todo_id, tag_id) SELECT ?, tag_id FROM todo_tags WHERE todo_id = ?`
+	)
+
+	var todo, next *model.TODO
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		if err := authorizeIDs(ctx, tx, []int64{id}); err != nil {
+			return err
+		}
+
+		var before *model.TODO
+		if revisionLogFromContext(ctx) != nil {
+			var err error
+			if before, err = get(ctx, tx, id); err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+		}
+
+		res, err := tx.ExecContext(ctx, complete, id, ownerArg(ctx))
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if n > 0 {
+			res, err := tx.ExecContext(ctx, insert, sqliteTime(&dueAt), id)
+			if err != nil {
+				return err
+			}
+			nextID, err := res.LastInsertId()
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, copyTags, nextID, id); err != nil {
+				return err
+			}
+			if next, err = get(ctx, tx, nextID); err != nil {
+				return err
+			}
+		}
+
+		todo, err = get(ctx, tx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return &model.ErrNotFound{}
+		}
+		if err != nil || next == nil {
+			return err
+		}
+		if err := recordRevision(ctx, tx, id, model.RevisionActionComplete, before, todo); err != nil {
+			return err
+		}
+		return recordRevision(ctx, tx, next.ID, model.RevisionActionCreate, nil, next)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return todo, next, nil
+}
+
+// setRecurrence replaces the recurrence of the TODO by id.
+// The recurrence the TODO already has is overwritten so that its history is kept.
+// A nil recurrence stops the recurrence of the TODO.
+func setRecurrence(ctx context.Context, q queryer, id int64, recurrence *model.Recurrence) error {
+	const (
+		stop    = `UPDATE todos SET recurrence_id = NULL WHERE id = ?`
+		current = `SELECT recurrence_id FROM todos WHERE id = ?`
+		update  = `UPDATE recurrences SET rrule = ?, dtstart = ?, tzid = ?, updated_at = DATETIME('now') WHERE id = ?`
+		insert  = `INSERT INTO recurrences(rrule, dtstart, tzid) VALUES(?, ?, ?)`
+		attach  = `UPDATE todos SET recurrence_id = ? WHERE id = ?`
+	)
+
+	if recurrence == nil {
+		_, err := q.ExecContext(ctx, stop, id)
+		return err
+	}
+
+	var rid sql.NullInt64
+	if err := q.QueryRowContext(ctx, current, id).Scan(&rid); err != nil {
+		return err
+	}
+	args := []interface{}{recurrence.RRule, sqliteTime(&recurrence.Start), recurrence.TimeZone}
+
+	if rid.Valid {
+		res, err := q.ExecContext(ctx, update, append(args, rid.Int64)...)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n > 0 {
+			return err
+		}
+	}
+
+	res, err := q.ExecContext(ctx, insert, args...)
+	if err != nil {
+		return err
+	}
+	newID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	_, err = q.ExecContext(ctx, attach, newID, id)
+	return err
+}
+
+// loadRecurrences fills the recurrences of todos scanned by recurrenceID.
+func loadRecurrences(ctx context.Context, q queryer, todos []*model.TODO) error {
+	const readFmt = `SELECT id, rrule, dtstart, tzid FROM recurrences WHERE id IN (?%s)`
+
+	byID := make(map[int64][]*model.TODO)
+	var ids []int64
+	for _, todo := range todos {
+		if todo.Recurrence == nil {
+			continue
+		}
+		if _, ok := byID[todo.Recurrence.ID]; !ok {
+			ids = append(ids, todo.Recurrence.ID)
+		}
+		byID[todo.Recurrence.ID] = append(byID[todo.Recurrence.ID], todo)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	rows, err := q.QueryContext(ctx, fmt.Sprintf(readFmt, strings.Repeat(",?", len(ids)-1)), int64Args(ids)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	found := make(map[int64]struct{}, len(ids))
+	for rows.Next() {
+		recurrence := model.Recurrence{}
+		if err := rows.Scan(&recurrence.ID, &recurrence.RRule, &recurrence.Start, &recurrence.TimeZone); err != nil {
+			return err
+		}
+		found[recurrence.ID] = struct{}{}
+		for _, todo := range byID[recurrence.ID] {
+			copied := recurrence
+			todo.Recurrence = &copied
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// recurrence_id is not a foreign key and may dangle
+	for id, todos := range byID {
+		if _, ok := found[id]; !ok {
+			for _, todo := range todos {
+				todo.Recurrence = nil
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestRRule(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		req  model.RecurrenceRequest
+		want string
+	}{
+		"RRule":   {req: model.RecurrenceRequest{RRule: "FREQ=DAILY"}, want: "FREQ=DAILY"},
+		"Daily":   {req: model.RecurrenceRequest{Frequency: "daily"}, want: "FREQ=DAILY"},
+		"Weekly":  {req: model.RecurrenceRequest{Frequency: "weekly", Interval: 2, Weekdays: []string{"mo", "fr"}}, want: "FREQ=WEEKLY;INTERVAL=2;BYDAY=mo,fr"},
+		"Monthly": {req: model.RecurrenceRequest{Frequency: "monthly", MonthDay: -1}, want: "FREQ=MONTHLY;BYMONTHDAY=-1"},
+	}
+	for name, c := range cases {
+		got, err := service.RRule(&c.req)
+		if err != nil {
+			t.Fatalf("%s: failed to convert, err = %v", name, err)
+		}
+		if got != c.want {
+			t.Errorf("%s: unexpected value, given = %q, expected = %q", name, got, c.want)
+		}
+	}
+
+	if _, err := service.RRule(&model.RecurrenceRequest{RRule: "FREQ=DAILY", Frequency: "daily"}); !errors.Is(err, service.ErrInvalidRecurrence) {
+		t.Errorf("unexpected error on both rrule and frequency, given = %v", err)
+	}
+}
+
+func TestTODOService_Recurrence(t *testing.T) {
+	t.Parallel()
+
+	jst, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal("failed to load location, err =", err)
+	}
+	at := func(day, hour int) time.Time {
+		return time.Date(2026, time.October, day, hour, 0, 0, 0, jst)
+	}
+
+	for name, newRepo := range repositories() {
+		newRepo := newRepo
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			svc := service.NewTODOServiceWithRepository(newRepo(t))
+			svc.SetClock(time.Now, jst)
+
+			recurrence := &model.Recurrence{RRule: "freq=weekly;byday=we,mo"}
+			if _, err := svc.CreateTODOWithInput(ctx, &service.TODOInput{Subject: "subject", Recurrence: recurrence}); !errors.Is(err, service.ErrInvalidRecurrence) {
+				t.Errorf("unexpected error without due date, given = %v", err)
+			}
+			invalid := &model.Recurrence{RRule: "FREQ=YEARLY"}
+			due := at(19, 9)
+			if _, err := svc.CreateTODOWithInput(ctx, &service.TODOInput{Subject: "subject", DueAt: &due, Recurrence: invalid}); !errors.Is(err, service.ErrInvalidRecurrence) {
+				t.Errorf("unexpected error on invalid rule, given = %v", err)
+			}
+
+			// Monday, 19 October 2026
+			todo, err := svc.CreateTODOWithInput(ctx, &service.TODOInput{
+				Subject:    "subject",
+				Tags:       []string{"weekly"},
+				DueAt:      &due,
+				Recurrence: recurrence,
+			})
+			if err != nil {
+				t.Fatal("failed to create todo, err =", err)
+			}
+			want := model.Recurrence{ID: todo.Recurrence.ID, RRule: "FREQ=WEEKLY;BYDAY=MO,WE", Start: due, TimeZone: "Asia/Tokyo"}
+			if got := *todo.Recurrence; got.RRule != want.RRule || !got.Start.Equal(want.Start) || got.TimeZone != want.TimeZone {
+				t.Errorf("unexpected recurrence, given = %+v, expected = %+v", got, want)
+			}
+
+			occurrences, err := svc.PreviewOccurrences(ctx, todo.ID, 3)
+			if err != nil {
+				t.Fatal("failed to preview occurrences, err =", err)
+			}
+			assertTimes(t, occurrences, []time.Time{at(21, 9), at(26, 9), at(28, 9)})
+
+			completed, next, err := svc.CompleteTODO(ctx, todo.ID)
+			if err != nil {
+				t.Fatal("failed to complete todo, err =", err)
+			}
+			if !completed.Completed || next == nil {
+				t.Fatalf("unexpected todos, given = %+v, %+v", completed, next)
+			}
+			if next.Subject != todo.Subject || !reflect.DeepEqual(next.Tags, todo.Tags) || next.Completed ||
+				next.DueAt == nil || !next.DueAt.Equal(at(21, 9)) || next.Recurrence == nil || next.Recurrence.ID != todo.Recurrence.ID {
+				t.Errorf("unexpected next occurrence, given = %+v", next)
+			}
+
+			// completing twice does not create another occurrence
+			if _, again, err := svc.CompleteTODO(ctx, todo.ID); err != nil || again != nil {
+				t.Errorf("unexpected next occurrence, given = %+v, err = %v", again, err)
+			}
+
+			if _, _, err := svc.CompleteTODO(ctx, next.ID); err != nil {
+				t.Fatal("failed to complete todo, err =", err)
+			}
+			history, err := svc.ReadTODOHistory(ctx, next.ID, 0, 5)
+			if err != nil {
+				t.Fatal("failed to read history, err =", err)
+			}
+			if got := ids(history); !equalIDs(got, []int64{next.ID, todo.ID}) {
+				t.Errorf("unexpected history, given = %v", got)
+			}
+			todos, err := svc.FilterTODO(ctx, &service.TODOFilter{Status: model.TODOStatusOpen}, 0, 5)
+			if err != nil {
+				t.Fatal("failed to read todos, err =", err)
+			}
+			if len(todos) != 1 || !todos[0].DueAt.Equal(at(26, 9)) {
+				t.Fatalf("unexpected open todos, given = %+v", todos)
+			}
+			current := todos[0]
+
+			// the due date of a recurring TODO cannot be removed
+			_, err = svc.UpdateTODOWithInput(ctx, current.ID, &service.TODOInput{Subject: "subject", SetDueAt: true})
+			if !errors.Is(err, service.ErrInvalidRecurrence) {
+				t.Errorf("unexpected error on removing due date, given = %v", err)
+			}
+
+			// replacing the recurrence keeps the history
+			updated, err := svc.UpdateTODOWithInput(ctx, current.ID, &service.TODOInput{
+				Subject:       "subject",
+				Recurrence:    &model.Recurrence{RRule: "FREQ=DAILY;COUNT=2"},
+				SetRecurrence: true,
+			})
+			if err != nil {
+				t.Fatal("failed to update todo, err =", err)
+			}
+			if updated.Recurrence.ID != todo.Recurrence.ID || updated.Recurrence.RRule != "FREQ=DAILY;COUNT=2" || !updated.Recurrence.Start.Equal(at(26, 9)) {
+				t.Errorf("unexpected recurrence, given = %+v", updated.Recurrence)
+			}
+			history, err = svc.ReadTODOHistory(ctx, current.ID, 0, 5)
+			if err != nil {
+				t.Fatal("failed to read history, err =", err)
+			}
+			if got := ids(history); !equalIDs(got, []int64{next.ID, todo.ID}) {
+				t.Errorf("unexpected history, given = %v", got)
+			}
+
+			_, last, err := svc.CompleteTODO(ctx, current.ID)
+			if err != nil {
+				t.Fatal("failed to complete todo, err =", err)
+			}
+			if last == nil || !last.DueAt.Equal(at(27, 9)) {
+				t.Fatalf("unexpected next occurrence, given = %+v", last)
+			}
+			// the recurrence ends by COUNT
+			if _, none, err := svc.CompleteTODO(ctx, last.ID); err != nil || none != nil {
+				t.Errorf("unexpected next occurrence, given = %+v, err = %v", none, err)
+			}
+			occurrences, err = svc.PreviewOccurrences(ctx, last.ID, 3)
+			if err != nil {
+				t.Fatal("failed to preview occurrences, err =", err)
+			}
+			assertTimes(t, occurrences, []time.Time{})
+
+			// stopping the recurrence
+			stopped, err := svc.UpdateTODOWithInput(ctx, last.ID, &service.TODOInput{Subject: "subject", SetRecurrence: true})
+			if err != nil {
+				t.Fatal("failed to update todo, err =", err)
+			}
+			if stopped.Recurrence != nil {
+				t.Errorf("unexpected recurrence, given = %+v", stopped.Recurrence)
+			}
+			history, err = svc.ReadTODOHistory(ctx, last.ID, 0, 5)
+			if err != nil {
+				t.Fatal("failed to read history, err =", err)
+			}
+			if len(history) != 0 {
+				t.Errorf("unexpected history, given = %v", ids(history))
+			}
+			if _, err := svc.PreviewOccurrences(ctx, 100, 3); !isNotFound(err) {
+				t.Errorf("unexpected error on missing id, given = %v", err)
+			}
+		})
+	}
+}
+
+func assertTimes(t *testing.T, got, want []time.Time) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Errorf("unexpected times, given = %v, expected = %v", got, want)
+		return
+	}
+	for i := range got {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("unexpected times, given = %v, expected = %v", got, want)
+			return
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// A RevisionRepository persists the revisions of TODOs.
+// A TODORepository implementing it records the revision of each change made with a context
+// carrying a revision log, in the same transaction as the change, so that no change is kept
+// without its revision and the revision stores the TODO as it was right before the change.
+type RevisionRepository interface {
+	// ListRevisions returns at most size revisions of the TODO in descending number order.
+	// Only revisions numbered less than prevRevision are returned unless prevRevision is 0.
+	ListRevisions(ctx context.Context, todoID, prevRevision, size int64) ([]*model.Revision, error)
+	// GetRevision returns the revision of the TODO by number.
+	// It returns *model.ErrNotFound when the revision does not exist.
+	GetRevision(ctx context.Context, todoID, number int64) (*model.Revision, error)
+}
+
+// ErrRevisionUnavailable is returned when the repository does not record revisions.
+var ErrRevisionUnavailable = errors.New("service: revisions are unavailable")
+
+// actorKey is the context key of the actor.
+type actorKey struct{}
+
+// WithActor returns a copy of ctx carrying the actor recorded in the revisions made with it.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor ctx carries, which is empty for anonymous changes.
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// ReadRevisions reads the revisions of the TODO, latest first.
+// Revisions of the TODO in the trash are read as well.
+func (s *TODOService) ReadRevisions(ctx context.Context, id, prevRevision, size int64) ([]*model.Revision, error) {
+	if s.revisions == nil {
+		return nil, ErrRevisionUnavailable
+	}
+
+	revisions, err := s.revisions.ListRevisions(ctx, id, prevRevision, size)
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 && prevRevision == 0 {
+		// the TODO may have been created before revisions were recorded
+		if _, err := s.repo.Get(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+	return revisions, nil
+}
+
+// DiffRevisions returns the fields of the TODO which differ between the revisions from and to,
+// comparing the TODO after each revision.
+func (s *TODOService) DiffRevisions(ctx context.Context, id, from, to int64) ([]*model.FieldChange, error) {
+	if s.revisions == nil {
+		return nil, ErrRevisionUnavailable
+	}
+
+	a, err := s.revisions.GetRevision(ctx, id, from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.revisions.GetRevision(ctx, id, to)
+	if err != nil {
+		return nil, err
+	}
+	return diffTODO(a.After, b.After), nil
+}
+
+// RevertTODO reverts the subject, description, tags, due date and recurrence of the TODO
+// to those after the revision by number, and records the revert as a new revision.
+// The revert is an update under the same rules as UpdateTODOWithInput.
+// It returns *model.ErrConflict when the revision deleted the TODO.
+func (s *TODOService) RevertTODO(ctx context.Context, id, number int64) (*model.TODO, *model.Revision, error) {
+	if s.revisions == nil {
+		return nil, nil, ErrRevisionUnavailable
+	}
+
+	rev, err := s.revisions.GetRevision(ctx, id, number)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rev.After == nil {
+		return nil, nil, &model.ErrConflict{Message: fmt.Sprintf("revision %d deleted the TODO, restore it instead", number)}
+	}
+
+	tags := rev.After.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	in := &TODOInput{
+		Subject:       rev.After.Subject,
+		Description:   rev.After.Description,
+		Tags:          tags,
+		DueAt:         rev.After.DueAt,
+		SetDueAt:      true,
+		Recurrence:    rev.After.Recurrence,
+		SetRecurrence: true,
+	}
+	ctx, log := s.recording(ctx)
+	log.reverted = number
+	todo, err := s.UpdateTODOWithInput(ctx, id, in)
+	if err != nil {
+		return nil, nil, err
+	}
+	return todo, log.revisions[0], nil
+}
+
+// revisionLogKey is the context key of the revision log.
+type revisionLogKey struct{}
+
+// A revisionLog is carried by the context of changes, and collects the revisions the repository records with them.
+type revisionLog struct {
+	// reverted is the number of the revision an update reverts, which is 0 unless the update is a revert.
+	reverted int64
+	// revisions are the revisions recorded in the order of the changes, with their numbers and creation times.
+	revisions []*model.Revision
+}
+
+// recording returns a copy of ctx carrying a new revision log, by which the repository records
+// the revisions of the changes made with it, unless the repository does not record revisions.
+// The log ctx already carries is kept, so that the changes made on behalf of another are recorded in its log.
+func (s *TODOService) recording(ctx context.Context) (context.Context, *revisionLog) {
+	if log := revisionLogFromContext(ctx); log != nil {
+		return ctx, log
+	}
+	log := &revisionLog{}
+	if s.revisions == nil {
+		return ctx, log
+	}
+	return context.WithValue(ctx, revisionLogKey{}, log), log
+}
+
+// revisionLogFromContext returns the revision log ctx carries, which is nil when no revision is recorded.
+func revisionLogFromContext(ctx context.Context) *revisionLog {
+	log, _ := ctx.Value(revisionLogKey{}).(*revisionLog)
+	return log
+}
+
+// revision returns the revision of the change of the TODO by id made by the actor of ctx.
+// An update is recorded as a revert when the log reverts a revision.
+func (l *revisionLog) revision(ctx context.Context, id int64, action model.RevisionAction, before, after *model.TODO) *model.Revision {
+	rev := &model.Revision{TODOID: id, Action: action, Actor: ActorFromContext(ctx), Before: before, After: after}
+	if action == model.RevisionActionUpdate && l.reverted != 0 {
+		rev.Action = model.RevisionActionRevert
+		rev.Reverted = l.reverted
+	}
+	return rev
+}
+
+// diffTODO returns the fields which differ between a and b in the order of todoFieldValues.
+func diffTODO(a, b *model.TODO) []*model.FieldChange {
+	from, to := todoFieldValues(a), todoFieldValues(b)
+
+	changes := []*model.FieldChange{}
+	for i := range from {
+		if reflect.DeepEqual(from[i].value, to[i].value) {
+			continue
+		}
+		changes = append(changes, &model.FieldChange{
+			Field: from[i].name,
+			From:  from[i].value,
+			To:    to[i].value,
+		})
+	}
+	return changes
+}
+
+// A fieldValue is a value of a TODO field compared by diffTODO.
+type fieldValue struct {
+	name  string
+	value interface{}
+}
+
+// todoFieldValues returns the values of the fields of todo compared by diffTODO.
+// Every value is nil when todo is nil, which means the TODO is deleted.
+func todoFieldValues(todo *model.TODO) []fieldValue {
+	values := []fieldValue{
+		{name: "subject"},
+		{name: "description"},
+		{name: "tags"},
+		{name: "completed"},
+		{name: "due_at"},
+		{name: "recurrence"},
+	}
+	if todo == nil {
+		return values
+	}
+
+	tags := todo.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	values[0].value = todo.Subject
+	values[1].value = todo.Description
+	values[2].value = tags
+	values[3].value = todo.Completed
+	if todo.DueAt != nil {
+		values[4].value = todo.DueAt.UTC().Format(time.RFC3339)
+	}
+	if todo.Recurrence != nil {
+		values[5].value = todo.Recurrence.RRule
+	}
+	return values
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+var _ RevisionRepository = (*MemoryTODORepository)(nil)
+
+// recordRevision records the revision of action of the TODO by id with the change, when ctx carries a revision log.
+// The mutex must be held for writing since the change, so that the revision is recorded atomically with it.
+func (r *MemoryTODORepository) recordRevision(ctx context.Context, id int64, action model.RevisionAction, before, after *model.TODO) {
+	log := revisionLogFromContext(ctx)
+	if log == nil {
+		return
+	}
+
+	stored := copyRevision(log.revision(ctx, id, action, before, after))
+	stored.Number = int64(len(r.revisions[id])) + 1
+	stored.CreatedAt = memoryNow()
+	r.revisions[id] = append(r.revisions[id], stored)
+
+	log.revisions = append(log.revisions, copyRevision(stored))
+}
+
+// ListRevisions implements RevisionRepository interface.
+func (r *MemoryTODORepository) ListRevisions(ctx context.Context, todoID, prevRevision, size int64) ([]*model.Revision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if !r.owns(ctx, todoID) {
+		return []*model.Revision{}, nil
+	}
+	stored := r.revisions[todoID]
+	revisions := []*model.Revision{}
+	for i := len(stored) - 1; i >= 0 && int64(len(revisions)) < size; i-- {
+		if prevRevision != 0 && stored[i].Number >= prevRevision {
+			continue
+		}
+		revisions = append(revisions, copyRevision(stored[i]))
+	}
+
+	return revisions, nil
+}
+
+// GetRevision implements RevisionRepository interface.
+func (r *MemoryTODORepository) GetRevision(ctx context.Context, todoID, number int64) (*model.Revision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored := r.revisions[todoID]
+	if !r.owns(ctx, todoID) || number < 1 || number > int64(len(stored)) {
+		return nil, &model.ErrNotFound{}
+	}
+	return copyRevision(stored[number-1]), nil
+}
+
+// owns reports whether the TODO by id exists and is visible to the caller of ctx, even in the trash.
+func (r *MemoryTODORepository) owns(ctx context.Context, id int64) bool {
+	i := r.index(id)
+	return i >= 0 && r.visible(ctx, r.todos[i])
+}
+
+// copyRevision returns a deep copy of rev.
+func copyRevision(rev *model.Revision) *model.Revision {
+	copied := *rev
+	if rev.Before != nil {
+		copied.Before = copyTODO(rev.Before)
+	}
+	if rev.After != nil {
+		copied.After = copyTODO(rev.After)
+	}
+	return &copied
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+var _ RevisionRepository = (*SQLiteTODORepository)(nil)
+
+// revisionColumns lists the columns of the revisions table in the order scanned by scanRevision.
+const revisionColumns = `revision, todo_id, action, actor, reverted, before_json, after_json, created_at`
+
+// withOwnedTODO is the condition of the revisions of the TODOs visible to the caller.
+const withOwnedTODO = `todo_id IN (SELECT id FROM todos WHERE ` + withAccess + `)`
+
+// A rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// recordRevision records the revision of action of the TODO by id in the transaction of the change, when ctx carries a revision log.
+func recordRevision(ctx context.Context, q queryer, id int64, action model.RevisionAction, before, after *model.TODO) error {
+	const (
+		// the number is assigned in the single statement so that concurrent revisions never share it
+		insert = `INSERT INTO revisions(todo_id, revision, action, actor, reverted, before_json, after_json)
+SELECT ?, COALESCE(MAX(revision), 0) + 1, ?, ?, ?, ?, ? FROM revisions WHERE todo_id = ?`
+		read = `SELECT ` + revisionColumns + ` FROM revisions WHERE id = ?`
+	)
+
+	log := revisionLogFromContext(ctx)
+	if log == nil {
+		return nil
+	}
+	rev := log.revision(ctx, id, action, before, after)
+
+	beforeJSON, err := revisionJSON(rev.Before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := revisionJSON(rev.After)
+	if err != nil {
+		return err
+	}
+	var reverted interface{}
+	if rev.Reverted != 0 {
+		reverted = rev.Reverted
+	}
+
+	res, err := q.ExecContext(ctx, insert, rev.TODOID, rev.Action, rev.Actor, reverted, beforeJSON, afterJSON, rev.TODOID)
+	if err != nil {
+		return err
+	}
+	revID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	if rev, err = scanRevision(q.QueryRowContext(ctx, read, revID)); err != nil {
+		return err
+	}
+	log.revisions = append(log.revisions, rev)
+	return nil
+}
+
+// ListRevisions implements RevisionRepository interface.
+func (r *SQLiteTODORepository) ListRevisions(ctx context.Context, todoID, prevRevision, size int64) ([]*model.Revision, error) {
+	const (
+		read         = `SELECT ` + revisionColumns + ` FROM revisions WHERE todo_id = ? AND ` + withOwnedTODO + ` ORDER BY revision DESC LIMIT ?`
+		readWithPrev = `SELECT ` + revisionColumns + ` FROM revisions WHERE todo_id = ? AND ` + withOwnedTODO + ` AND revision < ? ORDER BY revision DESC LIMIT ?`
+	)
+
+	if size < 0 {
+		size = 0
+	}
+
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	if prevRevision == 0 {
+		rows, err = r.db.QueryContext(ctx, read, todoID, ownerArg(ctx), size)
+	} else {
+		rows, err = r.db.QueryContext(ctx, readWithPrev, todoID, ownerArg(ctx), prevRevision, size)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*model.Revision{}
+	for rows.Next() {
+		rev, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+
+	return revisions, rows.Err()
+}
+
+// GetRevision implements RevisionRepository interface.
+func (r *SQLiteTODORepository) GetRevision(ctx context.Context, todoID, number int64) (*model.Revision, error) {
+	const read = `SELECT ` + revisionColumns + ` FROM revisions WHERE todo_id = ? AND ` + withOwnedTODO + ` AND revision = ?`
+
+	rev, err := scanRevision(r.db.QueryRowContext(ctx, read, todoID, ownerArg(ctx), number))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &model.ErrNotFound{}
+	}
+	return rev, err
+}
+
+// scanRevision scans revisionColumns into a revision.
+func scanRevision(row rowScanner) (*model.Revision, error) {
+	var (
+		rev           = &model.Revision{}
+		reverted      sql.NullInt64
+		before, after sql.NullString
+	)
+	err := row.Scan(&rev.Number, &rev.TODOID, &rev.Action, &rev.Actor, &reverted, &before, &after, &rev.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	rev.Reverted = reverted.Int64
+	if before.Valid {
+		rev.Before = &model.TODO{}
+		if err := json.Unmarshal([]byte(before.String), rev.Before); err != nil {
+			return nil, err
+		}
+	}
+	if after.Valid {
+		rev.After = &model.TODO{}
+		if err := json.Unmarshal([]byte(after.String), rev.After); err != nil {
+			return nil, err
+		}
+	}
+	return rev, nil
+}
+
+// revisionJSON encodes todo into the JSON of a revision column. A nil todo is encoded into NULL.
+func revisionJSON(todo *model.TODO) (interface{}, error) {
+	if todo == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(todo)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
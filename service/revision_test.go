@@ -0,0 +1,260 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestTODOService_Revisions(t *testing.T) {
+	t.Parallel()
+
+	for name, newRepo := range repositories() {
+		newRepo := newRepo
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := service.WithActor(context.Background(), "alice")
+			repo := newRepo(t)
+			svc := service.NewTODOServiceWithRepository(repo)
+
+			if _, err := svc.CreateTODO(ctx, "subject", "description", "work"); err != nil {
+				t.Fatal("failed to create todo, err =", err)
+			}
+			if _, err := svc.UpdateTODO(ctx, 1, "updated", "description", "home", "work"); err != nil {
+				t.Fatal("failed to update todo, err =", err)
+			}
+			for i := 0; i < 2; i++ {
+				if _, _, err := svc.CompleteTODO(ctx, 1); err != nil {
+					t.Fatal("failed to complete todo, err =", err)
+				}
+			}
+
+			revisions, err := svc.ReadRevisions(ctx, 1, 0, 5)
+			if err != nil {
+				t.Fatal("failed to read revisions, err =", err)
+			}
+			assertActions(t, revisions, model.RevisionActionComplete, model.RevisionActionUpdate, model.RevisionActionCreate)
+			for _, rev := range revisions {
+				if rev.TODOID != 1 || rev.Actor != "alice" || rev.After == nil || rev.CreatedAt.IsZero() {
+					t.Errorf("unexpected revision, given = %+v", rev)
+				}
+			}
+			if created := revisions[2]; created.Before != nil || created.After.Subject != "subject" {
+				t.Errorf("unexpected created revision, given = %+v", created)
+			}
+			if updated := revisions[1]; updated.Before.Subject != "subject" || updated.After.Subject != "updated" {
+				t.Errorf("unexpected updated revision, given = %+v", updated)
+			}
+
+			page, err := svc.ReadRevisions(ctx, 1, 3, 1)
+			if err != nil {
+				t.Fatal("failed to read revisions, err =", err)
+			}
+			if len(page) != 1 || page[0].Number != 2 {
+				t.Errorf("unexpected revisions, given = %+v", page)
+			}
+
+			changes, err := svc.DiffRevisions(ctx, 1, 1, 3)
+			if err != nil {
+				t.Fatal("failed to diff revisions, err =", err)
+			}
+			expected := []*model.FieldChange{
+				{Field: "subject", From: "subject", To: "updated"},
+				{Field: "tags", From: []string{"work"}, To: []string{"home", "work"}},
+				{Field: "completed", From: false, To: true},
+			}
+			if !reflect.DeepEqual(changes, expected) {
+				t.Errorf("unexpected changes, given = %s, expected = %s", formatChanges(changes), formatChanges(expected))
+			}
+			if _, err := svc.DiffRevisions(ctx, 1, 1, 9); !isNotFound(err) {
+				t.Errorf("unexpected error on missing revision, given = %v", err)
+			}
+
+			// revert makes a new revision and keeps the completion
+			todo, rev, err := svc.RevertTODO(ctx, 1, 1)
+			if err != nil {
+				t.Fatal("failed to revert todo, err =", err)
+			}
+			if todo.Subject != "subject" || !reflect.DeepEqual(todo.Tags, []string{"work"}) || !todo.Completed {
+				t.Errorf("unexpected todo, given = %+v", todo)
+			}
+			if rev.Number != 4 || rev.Action != model.RevisionActionRevert || rev.Reverted != 1 || rev.Before.Subject != "updated" {
+				t.Errorf("unexpected revision, given = %+v", rev)
+			}
+
+			if err := svc.DeleteTODO(ctx, []int64{1, 2}); err != nil {
+				t.Fatal("failed to delete todo, err =", err)
+			}
+			if _, _, err := svc.RevertTODO(ctx, 1, 1); !isNotFound(err) {
+				t.Errorf("unexpected error on trashed todo, given = %v", err)
+			}
+			var conflict *model.ErrConflict
+			if _, _, err := svc.RevertTODO(ctx, 1, 5); !errors.As(err, &conflict) {
+				t.Errorf("unexpected error on deleted revision, given = %v", err)
+			}
+			changes, err = svc.DiffRevisions(ctx, 1, 4, 5)
+			if err != nil {
+				t.Fatal("failed to diff revisions, err =", err)
+			}
+			if len(changes) != 4 || changes[0].Field != "subject" || changes[0].To != nil {
+				t.Errorf("unexpected changes, given = %s", formatChanges(changes))
+			}
+
+			if _, err := svc.CreateTODO(ctx, "other", ""); err != nil {
+				t.Fatal("failed to create todo, err =", err)
+			}
+			if err := svc.RestoreTODO(ctx, []int64{1, 2}); err != nil {
+				t.Fatal("failed to restore todo, err =", err)
+			}
+
+			revisions, err = svc.ReadRevisions(ctx, 1, 0, 5)
+			if err != nil {
+				t.Fatal("failed to read revisions, err =", err)
+			}
+			assertActions(t, revisions, model.RevisionActionRestore, model.RevisionActionDelete, model.RevisionActionRevert,
+				model.RevisionActionComplete, model.RevisionActionUpdate)
+			if deleted := revisions[1]; deleted.Before == nil || deleted.After != nil {
+				t.Errorf("unexpected deleted revision, given = %+v", deleted)
+			}
+			revisions, err = svc.ReadRevisions(ctx, 2, 0, 5)
+			if err != nil {
+				t.Fatal("failed to read revisions, err =", err)
+			}
+			assertActions(t, revisions, model.RevisionActionCreate)
+
+			if _, err := svc.ReadRevisions(ctx, 3, 0, 5); !isNotFound(err) {
+				t.Errorf("unexpected error on missing todo, given = %v", err)
+			}
+
+			// TODOs created without the service have no revisions
+			if _, err := repo.Create(ctx, &service.TODOInput{Subject: "legacy"}); err != nil {
+				t.Fatal("failed to create todo, err =", err)
+			}
+			revisions, err = svc.ReadRevisions(ctx, 3, 0, 5)
+			if err != nil {
+				t.Fatal("failed to read revisions, err =", err)
+			}
+			if revisions == nil || len(revisions) != 0 {
+				t.Errorf("unexpected revisions, given = %+v", revisions)
+			}
+		})
+	}
+}
+
+func TestTODOService_RevertTODO_Recurrence(t *testing.T) {
+	t.Parallel()
+
+	jst, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal("failed to load location, err =", err)
+	}
+	due := time.Date(2026, time.October, 19, 9, 0, 0, 0, jst)
+	later := due.AddDate(0, 0, 7)
+
+	for name, newRepo := range repositories() {
+		newRepo := newRepo
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			svc := service.NewTODOServiceWithRepository(newRepo(t))
+			svc.SetClock(time.Now, jst)
+
+			todo, err := svc.CreateTODOWithInput(ctx, &service.TODOInput{
+				Subject:    "subject",
+				DueAt:      &due,
+				Recurrence: &model.Recurrence{RRule: "FREQ=WEEKLY;BYDAY=MO"},
+			})
+			if err != nil {
+				t.Fatal("failed to create todo, err =", err)
+			}
+			if _, err := svc.UpdateTODOWithInput(ctx, todo.ID, &service.TODOInput{
+				Subject:       "subject",
+				DueAt:         &later,
+				SetDueAt:      true,
+				SetRecurrence: true,
+			}); err != nil {
+				t.Fatal("failed to update todo, err =", err)
+			}
+
+			// the revert is an update, which anchors the recurrence on the due date reverted
+			reverted, rev, err := svc.RevertTODO(ctx, todo.ID, 1)
+			if err != nil {
+				t.Fatal("failed to revert todo, err =", err)
+			}
+			if reverted.DueAt == nil || !reverted.DueAt.Equal(due) || reverted.Recurrence == nil ||
+				reverted.Recurrence.RRule != "FREQ=WEEKLY;BYDAY=MO" || !reverted.Recurrence.Start.Equal(due) {
+				t.Errorf("unexpected todo, given = %+v, recurrence = %+v", reverted, reverted.Recurrence)
+			}
+			if rev.Number != 3 || rev.Action != model.RevisionActionRevert || rev.Reverted != 1 || rev.After.Recurrence == nil {
+				t.Errorf("unexpected revision, given = %+v", rev)
+			}
+		})
+	}
+}
+
+func TestTODOService_Revisions_Atomic(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	d := newTestDB(t)
+	svc := service.NewTODOService(d)
+
+	todo, err := svc.CreateTODO(ctx, "subject", "description")
+	if err != nil {
+		t.Fatal("failed to create todo, err =", err)
+	}
+
+	const reject = `CREATE TRIGGER reject_revisions BEFORE INSERT ON revisions BEGIN SELECT RAISE(ABORT, 'rejected'); END`
+	if _, err := d.ExecContext(ctx, reject); err != nil {
+		t.Fatal("failed to create trigger, err =", err)
+	}
+
+	// no change is kept without its revision
+	if _, err := svc.CreateTODO(ctx, "created", ""); err == nil {
+		t.Error("unexpected success of create without revision")
+	}
+	if _, err := svc.UpdateTODO(ctx, todo.ID, "updated", ""); err == nil {
+		t.Error("unexpected success of update without revision")
+	}
+	if _, _, err := svc.CompleteTODO(ctx, todo.ID); err == nil {
+		t.Error("unexpected success of complete without revision")
+	}
+	if err := svc.DeleteTODO(ctx, []int64{todo.ID}); err == nil {
+		t.Error("unexpected success of delete without revision")
+	}
+
+	todos, err := svc.ReadTODO(ctx, 0, 5)
+	if err != nil {
+		t.Fatal("failed to read todos, err =", err)
+	}
+	if len(todos) != 1 || todos[0].Subject != "subject" || todos[0].Completed {
+		t.Errorf("unexpected todos, given = %+v", todos)
+	}
+}
+
+func assertActions(t *testing.T, revisions []*model.Revision, expected ...model.RevisionAction) {
+	t.Helper()
+
+	actions := make([]model.RevisionAction, len(revisions))
+	for i, rev := range revisions {
+		actions[i] = rev.Action
+	}
+	if !reflect.DeepEqual(actions, expected) {
+		t.Errorf("unexpected actions, given = %v, expected = %v", actions, expected)
+	}
+}
+
+func formatChanges(changes []*model.FieldChange) []model.FieldChange {
+	formatted := make([]model.FieldChange, len(changes))
+	for i, c := range changes {
+		formatted[i] = *c
+	}
+	return formatted
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// Defaults of the lifetime of sessions.
+const (
+	// DefaultSessionTTL is how long a session lasts after login.
+	DefaultSessionTTL = 12 * time.Hour
+	// DefaultSessionRotateInterval is how often the token of a session is replaced.
+	DefaultSessionRotateInterval = 15 * time.Minute
+)
+
+// sessionRotateGrace is how long the token replaced by a rotation is still accepted,
+// so that the requests sent with it concurrently are not rejected.
+const sessionRotateGrace = time.Minute
+
+// A SessionRepository persists the password hashes of users and their cookie sessions.
+type SessionRepository interface {
+	// GetPasswordHash reads the user by name case-insensitively with the hash of the password,
+	// which is empty unless the password is set. It returns *model.ErrNotFound when the user does not exist.
+	GetPasswordHash(ctx context.Context, name string) (*model.User, string, error)
+	// SetPasswordHash replaces the hash of the password of the user by id.
+	SetPasswordHash(ctx context.Context, userID int64, hash string) error
+	// CreateSession stores the hash of the token of a new session of the user by id, deleting the expired sessions.
+	CreateSession(ctx context.Context, userID int64, hash, csrfToken string, now, expiresAt time.Time) (*model.Session, error)
+	// GetSession reads the session unexpired at now by the hash of its token, or of the token it replaced
+	// when rotated after rotatedSince. It returns *model.ErrNotFound when no such session exists.
+	GetSession(ctx context.Context, hash string, now, rotatedSince time.Time) (*model.Session, error)
+	// RotateSession replaces the hash of the token of the session by newHash, keeping oldHash as the previous one.
+	// It returns *model.ErrNotFound when oldHash is no longer the current hash, such as when rotated concurrently.
+	RotateSession(ctx context.Context, oldHash, newHash string, now time.Time) error
+	// DeleteSession deletes the session by the hash of its current or previous token, if any.
+	DeleteSession(ctx context.Context, hash string) error
+}
+
+// A SessionService implements logging users in with passwords, or by other means, into cookie sessions.
+type SessionService struct {
+	repo           SessionRepository
+	ttl            time.Duration
+	rotateInterval time.Duration
+	now            func() time.Time
+}
+
+// NewSessionService returns new SessionService backed by the SQLite database.
+func NewSessionService(db *sql.DB) *SessionService {
+	return NewSessionServiceWithRepository(NewSQLiteTODORepository(db))
+}
+
+// NewSessionServiceWithRepository returns new SessionService backed by repo.
+func NewSessionServiceWithRepository(repo SessionRepository) *SessionService {
+	return &SessionService{
+		repo:           repo,
+		ttl:            DefaultSessionTTL,
+		rotateInterval: DefaultSessionRotateInterval,
+		now:            time.Now,
+	}
+}
+
+// SetLifetime sets how long sessions last after login and how often their tokens are replaced.
+// It must be called before the service is used.
+func (s *SessionService) SetLifetime(ttl, rotateInterval time.Duration) {
+	s.ttl = ttl
+	s.rotateInterval = rotateInterval
+}
+
+// SetClock replaces the clock sessions expire and rotate by. It must be called before the service is used.
+func (s *SessionService) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+// SetPassword sets the password of the user by userName, which must be of 8 to 128 characters.
+func (s *SessionService) SetPassword(ctx context.Context, userName, password string) error {
+	switch n := utf8.RuneCountInString(password); {
+	case n < minPasswordLength:
+		return &ErrValidation{Violations: []*Violation{{Field: "password", Code: ViolationInvalid, Message: "must be at least 8 characters"}}}
+	case n > maxPasswordLength:
+		return &ErrValidation{Violations: []*Violation{{Field: "password", Code: ViolationTooLong, Message: "must be at most 128 characters"}}}
+	}
+
+	user, _, err := s.repo.GetPasswordHash(ctx, strings.TrimSpace(userName))
+	if err != nil {
+		return err
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	return s.repo.SetPasswordHash(ctx, user.ID, hash)
+}
+
+// Login verifies the password of the user by name and starts a new session of the user.
+// The token of the session is returned only here, since only its hash is stored.
+// It returns ErrUnauthenticated when the user does not exist or the password does not match.
+func (s *SessionService) Login(ctx context.Context, name, password string) (string, *model.Session, error) {
+	user, hash, err := s.repo.GetPasswordHash(ctx, strings.TrimSpace(name))
+	if err != nil && !isNotFound(err) {
+		return "", nil, err
+	}
+	if hash == "" {
+		// a missing user takes as long as a wrong password not to tell whether the user exists
+		verifyPassword(dummyPasswordHash(), password)
+		return "", nil, ErrUnauthenticated
+	}
+	if !verifyPassword(hash, password) {
+		return "", nil, ErrUnauthenticated
+	}
+	return s.Start(ctx, user)
+}
+
+// Start starts a new session of the user authenticated otherwise, such as by an OpenID Connect provider.
+// The token of the session is returned only here, since only its hash is stored.
+func (s *SessionService) Start(ctx context.Context, user *model.User) (string, *model.Session, error) {
+	token, err := randomToken("")
+	if err != nil {
+		return "", nil, err
+	}
+	csrfToken, err := randomToken("")
+	if err != nil {
+		return "", nil, err
+	}
+	now := s.now()
+	session, err := s.repo.CreateSession(ctx, user.ID, hashToken(token), csrfToken, now, now.Add(s.ttl))
+	if err != nil {
+		return "", nil, err
+	}
+	return token, session, nil
+}
+
+// Resume returns the session of the token, replacing the token by a new one returned together
+// when the rotate interval has passed since the last rotation, which is empty otherwise.
+// It returns ErrUnauthenticated when the session does not exist or is expired.
+func (s *SessionService) Resume(ctx context.Context, token string) (string, *model.Session, error) {
+	now := s.now()
+	hash := hashToken(token)
+	session, err := s.repo.GetSession(ctx, hash, now, now.Add(-sessionRotateGrace))
+	if isNotFound(err) {
+		return "", nil, ErrUnauthenticated
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if now.Sub(session.RotatedAt) < s.rotateInterval {
+		return "", session, nil
+	}
+
+	rotated, err := randomToken("")
+	if err != nil {
+		return "", nil, err
+	}
+	err = s.repo.RotateSession(ctx, hash, hashToken(rotated), now)
+	if isNotFound(err) {
+		// rotated by a concurrent request, of which the client receives the new token
+		return "", session, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	session.RotatedAt = now.UTC().Truncate(time.Second)
+	return rotated, session, nil
+}
+
+// Logout ends the session of the token. Ending a session which does not exist succeeds.
+func (s *SessionService) Logout(ctx context.Context, token string) error {
+	return s.repo.DeleteSession(ctx, hashToken(token))
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// dummyPasswordHash returns the hash verified in place of the hash of a missing user.
+func dummyPasswordHash() string {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = hashPassword("")
+	})
+	return dummyHash
+}
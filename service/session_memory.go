@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+var _ SessionRepository = (*MemoryTODORepository)(nil)
+
+// A memorySession is a session with the hashes of its current and previous tokens.
+type memorySession struct {
+	session      *model.Session
+	hash         string
+	previousHash string
+}
+
+// GetPasswordHash implements SessionRepository interface.
+func (r *MemoryTODORepository) GetPasswordHash(ctx context.Context, name string) (*model.User, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user := r.findUser(name)
+	if user == nil {
+		return nil, "", &model.ErrNotFound{}
+	}
+	copied := *user
+	return &copied, r.passwords[user.ID], nil
+}
+
+// SetPasswordHash implements SessionRepository interface.
+func (r *MemoryTODORepository) SetPasswordHash(ctx context.Context, userID int64, hash string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if userID < 1 || userID > int64(len(r.users)) {
+		return &model.ErrNotFound{}
+	}
+	r.passwords[userID] = hash
+	return nil
+}
+
+// CreateSession implements SessionRepository interface.
+func (r *MemoryTODORepository) CreateSession(ctx context.Context, userID int64, hash, csrfToken string, now, expiresAt time.Time) (*model.Session, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if userID < 1 || userID > int64(len(r.users)) {
+		// mirrors the foreign key of the sessions table
+		return nil, errConstraintForeignKey
+	}
+
+	now = now.UTC().Truncate(time.Second)
+	kept := r.sessions[:0]
+	for _, s := range r.sessions {
+		if s.session.ExpiresAt.After(now) {
+			kept = append(kept, s)
+		}
+	}
+	for i := len(kept); i < len(r.sessions); i++ {
+		r.sessions[i] = nil
+	}
+	r.sessions = kept
+
+	r.lastSessionID++
+	user := *r.users[userID-1]
+	session := &model.Session{
+		ID:        r.lastSessionID,
+		User:      &user,
+		CSRFToken: csrfToken,
+		CreatedAt: now,
+		RotatedAt: now,
+		ExpiresAt: expiresAt.UTC().Truncate(time.Second),
+	}
+	r.sessions = append(r.sessions, &memorySession{session: session, hash: hash})
+
+	return copySession(session), nil
+}
+
+// GetSession implements SessionRepository interface.
+func (r *MemoryTODORepository) GetSession(ctx context.Context, hash string, now, rotatedSince time.Time) (*model.Session, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, s := range r.sessions {
+		current := s.hash == hash
+		previous := s.previousHash == hash && s.session.RotatedAt.After(rotatedSince)
+		if (current || previous) && s.session.ExpiresAt.After(now) {
+			return copySession(s.session), nil
+		}
+	}
+	return nil, &model.ErrNotFound{}
+}
+
+// RotateSession implements SessionRepository interface.
+func (r *MemoryTODORepository) RotateSession(ctx context.Context, oldHash, newHash string, now time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, s := range r.sessions {
+		if s.hash == oldHash {
+			s.previousHash, s.hash = s.hash, newHash
+			s.session.RotatedAt = now.UTC().Truncate(time.Second)
+			return nil
+		}
+	}
+	return &model.ErrNotFound{}
+}
+
+// DeleteSession implements SessionRepository interface.
+func (r *MemoryTODORepository) DeleteSession(ctx context.Context, hash string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, s := range r.sessions {
+		if s.hash == hash || s.previousHash == hash {
+			r.sessions = append(r.sessions[:i], r.sessions[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+// copySession returns a deep copy of session.
+func copySession(session *model.Session) *model.Session {
+	copied := *session
+	user := *session.User
+	copied.User = &user
+	return &copied
+}
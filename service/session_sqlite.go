@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+var _ SessionRepository = (*SQLiteTODORepository)(nil)
+
+// sessionColumns lists the columns of the sessions joined with the users in the order scanned by sessionFields.
+const sessionColumns = `s.id, s.csrf_token, s.created_at, s.rotated_at, s.expires_at, u.id, u.name, u.created_at`
+
+// GetPasswordHash implements SessionRepository interface.
+func (r *SQLiteTODORepository) GetPasswordHash(ctx context.Context, name string) (*model.User, string, error) {
+	const read = `SELECT id, name, created_at, IFNULL(password_hash, '') FROM users WHERE name = ?`
+
+	var (
+		user = &model.User{}
+		hash string
+	)
+	err := r.db.QueryRowContext(ctx, read, name).Scan(&user.ID, &user.Name, &user.CreatedAt, &hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", &model.ErrNotFound{}
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return user, hash, nil
+}
+
+// SetPasswordHash implements SessionRepository interface.
+func (r *SQLiteTODORepository) SetPasswordHash(ctx context.Context, userID int64, hash string) error {
+	const update = `UPDATE users SET password_hash = ? WHERE id = ?`
+
+	res, err := r.db.ExecContext(ctx, update, hash, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return &model.ErrNotFound{}
+	}
+	return nil
+}
+
+// CreateSession implements SessionRepository interface.
+func (r *SQLiteTODORepository) CreateSession(ctx context.Context, userID int64, hash, csrfToken string, now, expiresAt time.Time) (*model.Session, error) {
+	const (
+		expire = `DELETE FROM sessions WHERE expires_at <= ?`
+		insert = `INSERT INTO sessions(user_id, hash, csrf_token, created_at, rotated_at, expires_at) VALUES(?, ?, ?, ?, ?, ?)`
+		read   = `SELECT ` + sessionColumns + ` FROM sessions s JOIN users u ON u.id = s.user_id WHERE s.id = ?`
+	)
+
+	var session *model.Session
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, expire, sqliteTime(&now)); err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, insert, userID, hash, csrfToken, sqliteTime(&now), sqliteTime(&now), sqliteTime(&expiresAt))
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		session = &model.Session{User: &model.User{}}
+		return tx.QueryRowContext(ctx, read, id).Scan(sessionFields(session)...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// GetSession implements SessionRepository interface.
+func (r *SQLiteTODORepository) GetSession(ctx context.Context, hash string, now, rotatedSince time.Time) (*model.Session, error) {
+	const read = `SELECT ` + sessionColumns + ` FROM sessions s JOIN users u ON u.id = s.user_id
+WHERE (s.hash = ? OR (s.previous_hash = ? AND s.rotated_at > ?)) AND s.expires_at > ?`
+
+	session := &model.Session{User: &model.User{}}
+	err := r.db.QueryRowContext(ctx, read, hash, hash, sqliteTime(&rotatedSince), sqliteTime(&now)).Scan(sessionFields(session)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &model.ErrNotFound{}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// RotateSession implements SessionRepository interface.
+func (r *SQLiteTODORepository) RotateSession(ctx context.Context, oldHash, newHash string, now time.Time) error {
+	const rotate = `UPDATE sessions SET hash = ?, previous_hash = hash, rotated_at = ? WHERE hash = ?`
+
+	res, err := r.db.ExecContext(ctx, rotate, newHash, sqliteTime(&now), oldHash)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return &model.ErrNotFound{}
+	}
+	return nil
+}
+
+// DeleteSession implements SessionRepository interface.
+func (r *SQLiteTODORepository) DeleteSession(ctx context.Context, hash string) error {
+	const remove = `DELETE FROM sessions WHERE hash = ? OR previous_hash = ?`
+
+	_, err := r.db.ExecContext(ctx, remove, hash, hash)
+	return err
+}
+
+// sessionFields returns the scan destinations of sessionColumns.
+func sessionFields(session *model.Session) []interface{} {
+	return []interface{}{
+		&session.ID, &session.CSRFToken, &session.CreatedAt, &session.RotatedAt, &session.ExpiresAt,
+		&session.User.ID, &session.User.Name, &session.User.CreatedAt,
+	}
+}
@@ -0,0 +1,136 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestSessionService(t *testing.T) {
+	t.Parallel()
+
+	for name, newRepo := range repositories() {
+		newRepo := newRepo
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			repo := newRepo(t)
+			if _, err := service.NewUserServiceWithRepository(repo.(service.UserRepository)).CreateUser(ctx, "alice"); err != nil {
+				t.Fatal("failed to create user, err =", err)
+			}
+			now := time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC)
+			svc := service.NewSessionServiceWithRepository(repo.(service.SessionRepository))
+			svc.SetClock(func() time.Time { return now })
+			svc.SetLifetime(time.Hour, 15*time.Minute)
+
+			var validation *service.ErrValidation
+			if err := svc.SetPassword(ctx, "alice", "short"); !errors.As(err, &validation) {
+				t.Errorf("unexpected error on short password, given = %v", err)
+			}
+			if err := svc.SetPassword(ctx, "bob", "password"); !isNotFound(err) {
+				t.Errorf("unexpected error on missing user, given = %v", err)
+			}
+			if _, _, err := svc.Login(ctx, "alice", "password"); err != service.ErrUnauthenticated {
+				t.Errorf("unexpected error without password, given = %v, expected = %v", err, service.ErrUnauthenticated)
+			}
+			if err := svc.SetPassword(ctx, "alice", "correct horse"); err != nil {
+				t.Fatal("failed to set password, err =", err)
+			}
+
+			for _, c := range []struct{ name, password string }{{"alice", "wrong password"}, {"bob", "correct horse"}} {
+				if _, _, err := svc.Login(ctx, c.name, c.password); err != service.ErrUnauthenticated {
+					t.Errorf("unexpected error of %s, given = %v, expected = %v", c.name, err, service.ErrUnauthenticated)
+				}
+			}
+			token, session, err := svc.Login(ctx, "ALICE", "correct horse")
+			if err != nil {
+				t.Fatal("failed to log in, err =", err)
+			}
+			if session.User.Name != "alice" || session.CSRFToken == "" || !session.ExpiresAt.Equal(now.Add(time.Hour)) {
+				t.Errorf("unexpected session, given = %+v", session)
+			}
+
+			now = now.Add(10 * time.Minute)
+			rotated, resumed, err := svc.Resume(ctx, token)
+			if err != nil {
+				t.Fatal("failed to resume session, err =", err)
+			}
+			if rotated != "" || resumed.ID != session.ID || resumed.CSRFToken != session.CSRFToken {
+				t.Errorf("unexpected session before rotation, given = %q, %+v", rotated, resumed)
+			}
+
+			// the replaced token is accepted for a grace after the rotation
+			now = now.Add(10 * time.Minute)
+			rotated, _, err = svc.Resume(ctx, token)
+			if err != nil {
+				t.Fatal("failed to resume session, err =", err)
+			}
+			if rotated == "" || rotated == token {
+				t.Fatalf("unexpected token of rotation, given = %q", rotated)
+			}
+			now = now.Add(30 * time.Second)
+			if again, _, err := svc.Resume(ctx, token); err != nil || again != "" {
+				t.Errorf("unexpected resume with replaced token within grace, given = %q, err = %v", again, err)
+			}
+			now = now.Add(time.Minute)
+			if _, _, err := svc.Resume(ctx, token); err != service.ErrUnauthenticated {
+				t.Errorf("unexpected error with replaced token, given = %v, expected = %v", err, service.ErrUnauthenticated)
+			}
+			if _, _, err := svc.Resume(ctx, rotated); err != nil {
+				t.Error("failed to resume session with rotated token, err =", err)
+			}
+
+			if err := svc.Logout(ctx, rotated); err != nil {
+				t.Fatal("failed to log out, err =", err)
+			}
+			if _, _, err := svc.Resume(ctx, rotated); err != service.ErrUnauthenticated {
+				t.Errorf("unexpected error after logout, given = %v, expected = %v", err, service.ErrUnauthenticated)
+			}
+			if err := svc.Logout(ctx, rotated); err != nil {
+				t.Error("failed to log out twice, err =", err)
+			}
+
+			token, _, err = svc.Login(ctx, "alice", "correct horse")
+			if err != nil {
+				t.Fatal("failed to log in, err =", err)
+			}
+			now = now.Add(time.Hour)
+			if _, _, err := svc.Resume(ctx, token); err != service.ErrUnauthenticated {
+				t.Errorf("unexpected error of expired session, given = %v, expected = %v", err, service.ErrUnauthenticated)
+			}
+		})
+	}
+}
+
+func TestSessionService_Bcrypt(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := service.NewMemoryTODORepository()
+	user, err := repo.CreateUser(ctx, "alice")
+	if err != nil {
+		t.Fatal("failed to create user, err =", err)
+	}
+	// passwords imported from another system are hashed by bcrypt
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal("failed to hash password, err =", err)
+	}
+	if err := repo.SetPasswordHash(ctx, user.ID, string(hash)); err != nil {
+		t.Fatal("failed to set password hash, err =", err)
+	}
+
+	svc := service.NewSessionServiceWithRepository(repo)
+	if _, _, err := svc.Login(ctx, "alice", "correct horse"); err != nil {
+		t.Error("failed to log in, err =", err)
+	}
+	if _, _, err := svc.Login(ctx, "alice", "wrong password"); err != service.ErrUnauthenticated {
+		t.Errorf("unexpected error, given = %v, expected = %v", err, service.ErrUnauthenticated)
+	}
+}
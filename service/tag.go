@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// A TagRepository persists the tags attached to TODOs.
+type TagRepository interface {
+	// ListTags returns every tag with its usage count in name order.
+	// TODOs in the trash are not counted.
+	ListTags(ctx context.Context) ([]*model.Tag, error)
+	// RenameTag renames the tag from to the name to on every TODO at once.
+	// It returns *model.ErrNotFound when from does not exist and
+	// *model.ErrConflict when another tag is already named to.
+	RenameTag(ctx context.Context, from, to string) (*model.Tag, error)
+	// MergeTags replaces the tags sources with the tag into on every TODO at once.
+	// The tag into is created when it does not exist.
+	// It returns *model.ErrNotFound when none of sources exist.
+	MergeTags(ctx context.Context, sources []string, into string) (*model.Tag, error)
+}
+
+// A TagService implements operations of tags across TODOs.
+type TagService struct {
+	repo TagRepository
+}
+
+// NewTagService returns new TagService backed by the SQLite database.
+func NewTagService(db *sql.DB) *TagService {
+	return NewTagServiceWithRepository(NewSQLiteTODORepository(db))
+}
+
+// NewTagServiceWithRepository returns new TagService backed by repo.
+func NewTagServiceWithRepository(repo TagRepository) *TagService {
+	return &TagService{
+		repo: repo,
+	}
+}
+
+// ReadTags reads every tag with its usage count.
+func (s *TagService) ReadTags(ctx context.Context) ([]*model.Tag, error) {
+	return s.repo.ListTags(ctx)
+}
+
+// RenameTag renames the tag from to the name to.
+func (s *TagService) RenameTag(ctx context.Context, from, to string) (*model.Tag, error) {
+	return s.repo.RenameTag(ctx, strings.TrimSpace(from), strings.TrimSpace(to))
+}
+
+// MergeTags merges the tags sources into the tag into.
+func (s *TagService) MergeTags(ctx context.Context, sources []string, into string) (*model.Tag, error) {
+	into = strings.TrimSpace(into)
+
+	merged := make([]string, 0, len(sources))
+	for _, src := range normalizeTags(sources) {
+		if tagKey(src) != tagKey(into) {
+			merged = append(merged, src)
+		}
+	}
+	return s.repo.MergeTags(ctx, merged, into)
+}
+
+// normalizeTags trims tags and removes duplicates which differ only in case.
+// It keeps nil as nil so that callers can tell absent tags from empty ones.
+func normalizeTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+
+	seen := make(map[string]struct{}, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if _, ok := seen[tagKey(tag)]; ok {
+			continue
+		}
+		seen[tagKey(tag)] = struct{}{}
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// tagKey returns the key identifying the tag name
+// the same way as the NOCASE collation of SQLite, which folds only ASCII letters.
+func tagKey(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'A' && r <= 'Z' {
+			return r + ('a' - 'A')
+		}
+		return r
+	}, name)
+}
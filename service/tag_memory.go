@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// ListTags implements TagRepository interface, which lists the tags of the owner of ctx.
+func (r *MemoryTODORepository) ListTags(ctx context.Context) ([]*model.Tag, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	owner := ownerID(ctx)
+	tags := make([]*model.Tag, 0, len(r.tags[owner]))
+	for key := range r.tags[owner] {
+		tags = append(tags, r.tag(owner, key))
+	}
+	sortTags(tags)
+
+	return tags, nil
+}
+
+// RenameTag implements TagRepository interface.
+func (r *MemoryTODORepository) RenameTag(ctx context.Context, from, to string) (*model.Tag, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if to == "" {
+		return nil, errConstraintCheck
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	owner := ownerID(ctx)
+	names := r.tags[owner]
+	fromKey, toKey := tagKey(from), tagKey(to)
+	if _, ok := names[fromKey]; !ok {
+		return nil, &model.ErrNotFound{}
+	}
+	if _, ok := names[toKey]; ok && toKey != fromKey {
+		return nil, &model.ErrConflict{Message: fmt.Sprintf("tag %q already exists, merge the tags instead", to)}
+	}
+
+	delete(names, fromKey)
+	names[toKey] = to
+	r.replaceTag(owner, fromKey, toKey)
+
+	return r.tag(owner, toKey), nil
+}
+
+// MergeTags implements TagRepository interface.
+func (r *MemoryTODORepository) MergeTags(ctx context.Context, sources []string, into string) (*model.Tag, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	owner := ownerID(ctx)
+	names := r.tags[owner]
+	var keys []string
+	for _, src := range sources {
+		if _, ok := names[tagKey(src)]; ok {
+			keys = append(keys, tagKey(src))
+		}
+	}
+	if len(keys) == 0 {
+		return nil, &model.ErrNotFound{}
+	}
+	if into == "" {
+		return nil, errConstraintCheck
+	}
+
+	intoKey := tagKey(into)
+	if _, ok := names[intoKey]; !ok {
+		names[intoKey] = into
+	}
+	for _, key := range keys {
+		if key == intoKey {
+			continue
+		}
+		delete(names, key)
+		r.replaceTag(owner, key, intoKey)
+	}
+
+	return r.tag(owner, intoKey), nil
+}
+
+// attachTags registers tags of the owner and returns their registered names in name order.
+func (r *MemoryTODORepository) attachTags(owner int64, tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	if r.tags[owner] == nil {
+		r.tags[owner] = make(map[string]string)
+	}
+	registered := r.tags[owner]
+
+	names := make([]string, 0, len(tags))
+	seen := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		key := tagKey(tag)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		if _, ok := registered[key]; !ok {
+			registered[key] = tag
+		}
+		names = append(names, registered[key])
+	}
+	sortTagNames(names)
+
+	return names
+}
+
+// replaceTag replaces the tag of key with the tag of newKey on every TODO of the owner and bumps their updated_at.
+func (r *MemoryTODORepository) replaceTag(owner int64, key, newKey string) {
+	now := memoryNow()
+	for _, todo := range r.todos {
+		if todo.OwnerID != owner {
+			continue
+		}
+		replaced := false
+		tags := make([]string, 0, len(todo.Tags))
+		for _, tag := range todo.Tags {
+			if k := tagKey(tag); k == key || k == newKey {
+				replaced = replaced || k == key
+				continue
+			}
+			tags = append(tags, tag)
+		}
+		if !replaced {
+			continue
+		}
+		todo.Tags = r.attachTags(owner, append(tags, r.tags[owner][newKey]))
+		todo.UpdatedAt = now
+	}
+}
+
+// tag returns the tag of key of the owner with the number of TODOs out of the trash tagged with it.
+func (r *MemoryTODORepository) tag(owner int64, key string) *model.Tag {
+	tag := &model.Tag{Name: r.tags[owner][key]}
+	for _, todo := range r.todos {
+		if todo.OwnerID != owner || todo.DeletedAt != nil {
+			continue
+		}
+		for _, t := range todo.Tags {
+			if tagKey(t) == key {
+				tag.Count++
+			}
+		}
+	}
+	return tag
+}
+
+// validTags reports whether tags satisfy the CHECK constraint of the tags table.
+func validTags(tags []string) bool {
+	for _, tag := range tags {
+		if tag == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// sortTags sorts tags by name the same way as the NOCASE collation.
+func sortTags(tags []*model.Tag) {
+	sort.Slice(tags, func(i, j int) bool {
+		return tagKey(tags[i].Name) < tagKey(tags[j].Name)
+	})
+}
+
+// sortTagNames sorts names the same way as the NOCASE collation.
+func sortTagNames(names []string) {
+	sort.Slice(names, func(i, j int) bool {
+		return tagKey(names[i]) < tagKey(names[j])
+	})
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// touchTagged bumps updated_at of the TODOs tagged with the tag by id.
+const touchTagged = `UPDATE todos SET updated_at = DATETIME('now') WHERE id IN (SELECT todo_id FROM todo_tags WHERE tag_id = ?)`
+
+// ListTags implements TagRepository interface, which lists the tags of the owner of ctx.
+func (r *SQLiteTODORepository) ListTags(ctx context.Context) ([]*model.Tag, error) {
+	const read = `SELECT g.name, COUNT(t.id) FROM tags g LEFT JOIN todo_tags tt ON tt.tag_id = g.id
+LEFT JOIN todos t ON t.id = tt.todo_id AND t.deleted_at IS NULL
+WHERE g.owner_id IS ?
+GROUP BY g.id ORDER BY g.name`
+
+	rows, err := r.db.QueryContext(ctx, read, ownerArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []*model.Tag{}
+	for rows.Next() {
+		tag := &model.Tag{}
+		if err := rows.Scan(&tag.Name, &tag.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+// RenameTag implements TagRepository interface.
+func (r *SQLiteTODORepository) RenameTag(ctx context.Context, from, to string) (*model.Tag, error) {
+	const (
+		find   = `SELECT id FROM tags WHERE ` + withOwner + ` AND name = ?`
+		rename = `UPDATE tags SET name = ? WHERE id = ?`
+	)
+
+	var tag *model.Tag
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		var id int64
+		err := tx.QueryRowContext(ctx, find, ownerArg(ctx), from).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return &model.ErrNotFound{}
+		}
+		if err != nil {
+			return err
+		}
+
+		var other int64
+		err = tx.QueryRowContext(ctx, find, ownerArg(ctx), to).Scan(&other)
+		switch {
+		case err == nil && other != id:
+			return &model.ErrConflict{Message: fmt.Sprintf("tag %q already exists, merge the tags instead", to)}
+		case err != nil && !errors.Is(err, sql.ErrNoRows):
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, rename, to, id); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, touchTagged, id); err != nil {
+			return err
+		}
+
+		tag, err = getTag(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+// MergeTags implements TagRepository interface.
+func (r *SQLiteTODORepository) MergeTags(ctx context.Context, sources []string, into string) (*model.Tag, error) {
+	const (
+		findFmt = `SELECT id FROM tags WHERE ` + withOwner + ` AND name IN (?%s)`
+		create  = `INSERT INTO tags(owner_id, name) VALUES(?, ?) ON CONFLICT(COALESCE(owner_id, 0), name) DO NOTHING`
+		find    = `SELECT id FROM tags WHERE ` + withOwner + ` AND name = ?`
+		move    = `INSERT OR IGNORE INTO todo_tags(todo_id, tag_id) SELECT todo_id, ? FROM todo_tags WHERE tag_id = ?`
+		remove  = `DELETE FROM tags WHERE id = ?`
+	)
+
+	if len(sources) == 0 {
+		return nil, &model.ErrNotFound{}
+	}
+
+	var tag *model.Tag
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		args := make([]interface{}, 1, len(sources)+1)
+		args[0] = ownerArg(ctx)
+		for _, src := range sources {
+			args = append(args, src)
+		}
+		rows, err := tx.QueryContext(ctx, fmt.Sprintf(findFmt, strings.Repeat(",?", len(sources)-1)), args...)
+		if err != nil {
+			return err
+		}
+		var ids []int64
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return &model.ErrNotFound{}
+		}
+
+		if _, err := tx.ExecContext(ctx, create, ownerArg(ctx), into); err != nil {
+			return err
+		}
+		var intoID int64
+		if err := tx.QueryRowContext(ctx, find, ownerArg(ctx), into).Scan(&intoID); err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			if id == intoID {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, touchTagged, id); err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, move, intoID, id); err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, remove, id); err != nil {
+				return err
+			}
+		}
+
+		tag, err = getTag(ctx, tx, intoID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+// getTag reads the tag by id with the number of TODOs out of the trash tagged with it.
+func getTag(ctx context.Context, q queryer, id int64) (*model.Tag, error) {
+	const read = `SELECT name, (SELECT COUNT(*) FROM todo_tags tt JOIN todos t ON t.id = tt.todo_id
+WHERE tt.tag_id = tags.id AND t.deleted_at IS NULL) FROM tags WHERE id = ?`
+
+	tag := &model.Tag{}
+	if err := q.QueryRowContext(ctx, read, id).Scan(&tag.Name, &tag.Count); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
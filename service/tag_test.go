@@ -0,0 +1,199 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestTODOService_Tags(t *testing.T) {
+	t.Parallel()
+
+	for name, newRepo := range repositories() {
+		newRepo := newRepo
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			repo := newRepo(t)
+			svc := service.NewTODOServiceWithRepository(repo)
+
+			for _, tags := range [][]string{
+				{"work", " urgent "},
+				{"Work", "WORK", "home"},
+				{"home"},
+				nil,
+			} {
+				if _, err := svc.CreateTODO(ctx, "subject", "", tags...); err != nil {
+					t.Fatal("failed to create todo, err =", err)
+				}
+			}
+			if _, err := svc.CreateTODO(ctx, "subject", "", "work", " "); !isConstraintError(err) {
+				t.Errorf("unexpected error on empty tag, given = %v", err)
+			}
+
+			all, err := svc.ReadTODO(ctx, 0, 5)
+			if err != nil {
+				t.Fatal("failed to read todos, err =", err)
+			}
+			expectedTags := [][]string{nil, {"home"}, {"home", "work"}, {"urgent", "work"}}
+			for i, todo := range all {
+				if !reflect.DeepEqual(todo.Tags, expectedTags[i]) {
+					t.Errorf("unexpected tags of %d, given = %v, expected = %v", todo.ID, todo.Tags, expectedTags[i])
+				}
+			}
+
+			cases := map[string]struct {
+				filter *service.TODOFilter
+				ids    []int64
+			}{
+				"No filter":       {filter: nil, ids: []int64{4, 3, 2, 1}},
+				"Empty filter":    {filter: &service.TODOFilter{}, ids: []int64{4, 3, 2, 1}},
+				"One tag":         {filter: &service.TODOFilter{Tags: []string{"WORK"}}, ids: []int64{2, 1}},
+				"All tags":        {filter: &service.TODOFilter{Tags: []string{"work", "home"}}, ids: []int64{2}},
+				"Any tag":         {filter: &service.TODOFilter{Tags: []string{"urgent", "home"}, AnyTag: true}, ids: []int64{3, 2, 1}},
+				"Duplicated tags": {filter: &service.TODOFilter{Tags: []string{"work", "Work"}}, ids: []int64{2, 1}},
+				"Missing tag":     {filter: &service.TODOFilter{Tags: []string{"work", "missing"}}, ids: []int64{}},
+				"Missing any tag": {filter: &service.TODOFilter{Tags: []string{"missing"}, AnyTag: true}, ids: []int64{}},
+			}
+			for name, c := range cases {
+				todos, err := svc.FilterTODO(ctx, c.filter, 0, 5)
+				if err != nil {
+					t.Fatalf("%s: failed to read todos, err = %v", name, err)
+				}
+				if got := ids(todos); !equalIDs(got, c.ids) {
+					t.Errorf("%s: unexpected ids, given = %v, expected = %v", name, got, c.ids)
+				}
+			}
+			todos, err := svc.FilterTODO(ctx, &service.TODOFilter{Tags: []string{"home"}}, 3, 1)
+			if err != nil {
+				t.Fatal("failed to read todos, err =", err)
+			}
+			if got := ids(todos); !equalIDs(got, []int64{2}) {
+				t.Errorf("unexpected ids with prev id, given = %v, expected = %v", got, []int64{2})
+			}
+
+			// nil tags keep the tags and empty tags clear them
+			todo, err := svc.UpdateTODO(ctx, 1, "updated", "")
+			if err != nil {
+				t.Fatal("failed to update todo, err =", err)
+			}
+			if !reflect.DeepEqual(todo.Tags, []string{"urgent", "work"}) {
+				t.Errorf("unexpected tags, given = %v", todo.Tags)
+			}
+			todo, err = svc.UpdateTODO(ctx, 1, "updated", "", []string{}...)
+			if err != nil {
+				t.Fatal("failed to update todo, err =", err)
+			}
+			if todo.Tags != nil {
+				t.Errorf("unexpected tags, given = %v", todo.Tags)
+			}
+		})
+	}
+}
+
+func TestTagService(t *testing.T) {
+	t.Parallel()
+
+	for name, newRepo := range repositories() {
+		newRepo := newRepo
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			repo := newRepo(t)
+			svc := service.NewTODOServiceWithRepository(repo)
+			tags := service.NewTagServiceWithRepository(repo.(service.TagRepository))
+
+			for _, todoTags := range [][]string{{"work", "urgent"}, {"work", "home"}, {"errand"}} {
+				if _, err := svc.CreateTODO(ctx, "subject", "", todoTags...); err != nil {
+					t.Fatal("failed to create todo, err =", err)
+				}
+			}
+			// deleting a TODO detaches its tags but keeps them
+			if err := svc.DeleteTODO(ctx, []int64{3}); err != nil {
+				t.Fatal("failed to delete todo, err =", err)
+			}
+
+			assertTags(t, tags, []*model.Tag{
+				{Name: "errand", Count: 0},
+				{Name: "home", Count: 1},
+				{Name: "urgent", Count: 1},
+				{Name: "work", Count: 2},
+			})
+
+			if _, err := tags.RenameTag(ctx, "missing", "other"); !isNotFound(err) {
+				t.Errorf("unexpected error on missing tag, given = %v", err)
+			}
+			var conflict *model.ErrConflict
+			if _, err := tags.RenameTag(ctx, "home", "WORK"); !errors.As(err, &conflict) {
+				t.Errorf("unexpected error on existing tag, given = %v", err)
+			}
+
+			tag, err := tags.RenameTag(ctx, "Work", "Job")
+			if err != nil {
+				t.Fatal("failed to rename tag, err =", err)
+			}
+			if !reflect.DeepEqual(tag, &model.Tag{Name: "Job", Count: 2}) {
+				t.Errorf("unexpected tag, given = %+v", tag)
+			}
+			// renaming only the case is allowed
+			if _, err := tags.RenameTag(ctx, "job", "job"); err != nil {
+				t.Fatal("failed to rename tag, err =", err)
+			}
+
+			if _, err := tags.MergeTags(ctx, []string{"missing"}, "job"); !isNotFound(err) {
+				t.Errorf("unexpected error on missing tags, given = %v", err)
+			}
+			tag, err = tags.MergeTags(ctx, []string{"urgent", "home", "missing", "JOB"}, "job")
+			if err != nil {
+				t.Fatal("failed to merge tags, err =", err)
+			}
+			if !reflect.DeepEqual(tag, &model.Tag{Name: "job", Count: 2}) {
+				t.Errorf("unexpected tag, given = %+v", tag)
+			}
+			assertTags(t, tags, []*model.Tag{
+				{Name: "errand", Count: 0},
+				{Name: "job", Count: 2},
+			})
+
+			tag, err = tags.MergeTags(ctx, []string{"errand"}, "new")
+			if err != nil {
+				t.Fatal("failed to merge tags, err =", err)
+			}
+			if !reflect.DeepEqual(tag, &model.Tag{Name: "new", Count: 0}) {
+				t.Errorf("unexpected tag, given = %+v", tag)
+			}
+
+			todos, err := svc.ReadTODO(ctx, 0, 5)
+			if err != nil {
+				t.Fatal("failed to read todos, err =", err)
+			}
+			for _, todo := range todos {
+				if !reflect.DeepEqual(todo.Tags, []string{"job"}) {
+					t.Errorf("unexpected tags of %d, given = %v", todo.ID, todo.Tags)
+				}
+			}
+		})
+	}
+}
+
+func assertTags(t *testing.T, svc *service.TagService, expected []*model.Tag) {
+	t.Helper()
+
+	tags, err := svc.ReadTags(context.Background())
+	if err != nil {
+		t.Fatal("failed to read tags, err =", err)
+	}
+	if !reflect.DeepEqual(tags, expected) {
+		given := make([]model.Tag, len(tags))
+		for i, tag := range tags {
+			given[i] = *tag
+		}
+		t.Errorf("unexpected tags, given = %+v", given)
+	}
+}
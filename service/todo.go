@@ -2,56 +2,417 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/TechBowl-japan/go-stations/model"
 )
 
+// A TODOInput expresses the values of a TODO to be stored.
+type TODOInput struct {
+	Subject     string
+	Description string
+	// Tags replaces the tags of the TODO unless nil.
+	Tags []string
+	// DueAt is the due date of the TODO. On update it replaces the due date
+	// only when SetDueAt is true, so that a nil DueAt removes it.
+	DueAt    *time.Time
+	SetDueAt bool
+	// Recurrence makes the TODO recurring. On update it replaces the recurrence
+	// only when SetRecurrence is true, so that a nil Recurrence stops the recurrence.
+	// Its ID is ignored, and the recurrence the TODO already has is overwritten if any.
+	Recurrence    *model.Recurrence
+	SetRecurrence bool
+	// Version makes the update fail with *ErrPreconditionFailed unless the TODO is at the version
+	// returned by Version. An empty Version means any version. It is ignored on create.
+	Version string
+	// ListID makes the TODO shared in the list unless 0. It is ignored on update.
+	ListID int64
+}
+
+// A TODOFilter narrows down the TODOs to list.
+type TODOFilter struct {
+	// Tags lists the tags TODOs must have.
+	Tags []string
+	// AnyTag makes TODOs having any one of Tags match instead of all of them.
+	AnyTag bool
+	// Status narrows down TODOs by their completion. An empty status means model.TODOStatusAll.
+	Status model.TODOStatus
+	// DueFrom and DueBefore narrow down TODOs to those due in [DueFrom, DueBefore).
+	// Either of them may be nil for an open range, and TODOs without due date never match them.
+	DueFrom   *time.Time
+	DueBefore *time.Time
+	// RecurrenceID narrows down TODOs to the occurrences of the recurrence unless 0.
+	RecurrenceID int64
+	// ListID narrows down TODOs to those of the list unless 0.
+	ListID int64
+	// Trashed narrows down TODOs to those in the trash, which never match otherwise.
+	Trashed bool
+}
+
+// A TODORepository persists TODO entities.
+// TODOs in the trash are hidden from every method except List with TODOFilter.Trashed, Restore and Purge.
+// The methods changing TODOs return ErrForbidden without changing anything when the caller may see
+// but lacks the editor role on any of the TODOs, which is checked atomically with the change.
+type TODORepository interface {
+	// Create stores a new TODO and returns it as stored.
+	Create(ctx context.Context, in *TODOInput) (*model.TODO, error)
+	// Get returns the TODO by id.
+	// It returns *model.ErrNotFound when the TODO does not exist.
+	Get(ctx context.Context, id int64) (*model.TODO, error)
+	// List returns at most size TODOs matching filter in descending id order.
+	// Only TODOs whose id is less than prevID are returned unless prevID is 0.
+	// A nil filter matches every TODO.
+	List(ctx context.Context, filter *TODOFilter, prevID, size int64) ([]*model.TODO, error)
+	// ListByDue returns at most size TODOs matching filter which have due date,
+	// in ascending order of due date and then id.
+	// Only TODOs ordered after the TODO of prevID are returned unless prevID is 0.
+	ListByDue(ctx context.Context, filter *TODOFilter, prevID, size int64) ([]*model.TODO, error)
+	// Update overwrites the TODO and bumps its updated_at.
+	// It returns *model.ErrNotFound when the TODO does not exist and
+	// *ErrPreconditionFailed when the TODO is not at in.Version.
+	// The version and the role of the caller are checked atomically with the update.
+	Update(ctx context.Context, id int64, in *TODOInput) (*model.TODO, error)
+	// SetCompleted marks the TODO completed or open.
+	// completed_at is set when the TODO gets completed and cleared when reopened,
+	// and nothing changes when the TODO is already in the state.
+	// It returns *model.ErrNotFound when the TODO does not exist.
+	SetCompleted(ctx context.Context, id int64, completed bool) (*model.TODO, error)
+	// CompleteOccurrence marks the occurrence of a recurring TODO completed
+	// and creates its next occurrence due at dueAt, with the same subject, description, tags and recurrence.
+	// It returns a nil next occurrence without creating it when the TODO is already completed.
+	// It returns *model.ErrNotFound when the TODO does not exist.
+	CompleteOccurrence(ctx context.Context, id int64, dueAt time.Time) (todo, next *model.TODO, err error)
+	// Delete moves the TODOs by ids to the trash.
+	// It returns *model.ErrNotFound when none of the TODOs exist out of the trash.
+	Delete(ctx context.Context, ids []int64) error
+	// Restore moves the TODOs by ids back from the trash.
+	// It returns *model.ErrNotFound when none of the TODOs are in the trash.
+	Restore(ctx context.Context, ids []int64) error
+	// Purge permanently deletes the TODOs moved to the trash before the time
+	// and returns the number of the deleted TODOs.
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+// A TODOSearcher is a TODORepository that supports full-text search.
+type TODOSearcher interface {
+	// Search returns at most size TODOs matching every term of query by mode, most relevant first.
+	// Only TODOs ranked after the TODO of prevID are returned unless prevID is 0.
+	Search(ctx context.Context, mode model.SearchMode, query string, prevID, size int64) ([]*model.TODOSearchResult, error)
+}
+
+// ErrSearchUnavailable is returned when the repository has no full-text index for the search mode.
+var ErrSearchUnavailable = errors.New("service: full-text search is unavailable")
+
+// An ErrPreconditionFailed is returned when the TODO to update is not at the expected version.
+type ErrPreconditionFailed struct {
+	// TODO is the current TODO.
+	TODO *model.TODO
+}
+
+// Error implements error interface.
+func (e *ErrPreconditionFailed) Error() string {
+	return "service: TODO is not at the expected version"
+}
+
+// Version returns the version of todo, which is a fingerprint of its representation
+// and changes with any change of the TODO, including the bump of updated_at.
+func Version(todo *model.TODO) string {
+	b, err := json.Marshal(todo)
+	if err != nil {
+		// never happens for a TODO read from a repository
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:16])
+}
+
 // A TODOService implements CRUD of TODO entities.
+// Every change is recorded as a revision when the repository implements RevisionRepository.
+// When it implements ListRepository, the TODOs of lists are changed only by their editors and owners.
 type TODOService struct {
-	db *sql.DB
+	repo TODORepository
+	// revisions is nil when repo does not record revisions.
+	revisions RevisionRepository
+	// lists is nil when repo does not store lists.
+	lists ListRepository
+	// now and loc are used to compute the day boundaries of due dates.
+	now func() time.Time
+	loc *time.Location
+	// observer is nil unless calls are observed.
+	observer Observer
+}
+
+// An Observer observes the calls of the methods of TODOService, such as to export metrics.
+type Observer interface {
+	// ObserveCall is called after each call of the method with how long it took and the error it returned.
+	ObserveCall(method string, elapsed time.Duration, err error)
 }
 
-// NewTODOService returns new TODOService.
+// Names of the methods reported to Observer. Each is also reported by the variants of the method,
+// such as CreateTODOWithInput for CreateTODO and FilterTODO for ReadTODO.
+const (
+	MethodCreateTODO = "CreateTODO"
+	MethodReadTODO   = "ReadTODO"
+	MethodUpdateTODO = "UpdateTODO"
+	MethodDeleteTODO = "DeleteTODO"
+)
+
+// NewTODOService returns new TODOService backed by the SQLite database.
 func NewTODOService(db *sql.DB) *TODOService {
+	return NewTODOServiceWithRepository(NewSQLiteTODORepository(db))
+}
+
+// NewTODOServiceWithRepository returns new TODOService backed by repo.
+func NewTODOServiceWithRepository(repo TODORepository) *TODOService {
+	revisions, _ := repo.(RevisionRepository)
+	lists, _ := repo.(ListRepository)
 	return &TODOService{
-		db: db,
+		repo:      repo,
+		revisions: revisions,
+		lists:     lists,
+		now:       time.Now,
 	}
 }
 
-// CreateTODO creates a TODO on DB.
-func (s *TODOService) CreateTODO(ctx context.Context, subject, description string) (*model.TODO, error) {
-	const (
-		insert  = `INSERT INTO todos(subject, description) VALUES(?, ?)`
-		confirm = `SELECT subject, description, created_at, updated_at FROM todos WHERE id = ?`
-	)
+// SetClock replaces the current time and the time zone in which days of due dates are delimited.
+// A nil loc means time.Local.
+func (s *TODOService) SetClock(now func() time.Time, loc *time.Location) {
+	s.now = now
+	s.loc = loc
+}
+
+// SetObserver makes o observe the calls of the methods named by the Method constants. A nil o stops observing.
+func (s *TODOService) SetObserver(o Observer) {
+	s.observer = o
+}
+
+// observe reports the call of method started at start to the observer.
+func (s *TODOService) observe(method string, start time.Time, err error) {
+	if s.observer != nil {
+		s.observer.ObserveCall(method, time.Since(start), err)
+	}
+}
+
+// CreateTODO creates a TODO labeled with tags.
+func (s *TODOService) CreateTODO(ctx context.Context, subject, description string, tags ...string) (*model.TODO, error) {
+	return s.CreateTODOWithInput(ctx, &TODOInput{
+		Subject:     subject,
+		Description: description,
+		Tags:        tags,
+	})
+}
+
+// CreateTODOWithInput creates a TODO with every value of in.
+// A recurring TODO requires due date, on which its recurrence is anchored.
+// A TODO of a list requires the editor role in the list.
+func (s *TODOService) CreateTODOWithInput(ctx context.Context, in *TODOInput) (*model.TODO, error) {
+	start := time.Now()
+	todo, err := s.createTODO(ctx, in)
+	s.observe(MethodCreateTODO, start, err)
+	return todo, err
+}
+
+// createTODO implements CreateTODOWithInput.
+func (s *TODOService) createTODO(ctx context.Context, in *TODOInput) (*model.TODO, error) {
+	in = normalizeInput(in)
+	if in.ListID != 0 {
+		if err := s.authorizeList(ctx, model.RoleEditor, in.ListID); err != nil {
+			return nil, err
+		}
+	}
+	if in.Recurrence != nil {
+		recurrence, err := s.anchorRecurrence(in.Recurrence, in.DueAt)
+		if err != nil {
+			return nil, err
+		}
+		in.Recurrence = recurrence
+	}
+
+	ctx, _ = s.recording(ctx)
+	return s.repo.Create(ctx, in)
+}
 
-	return nil, nil
+// GetTODO reads the TODO by id.
+func (s *TODOService) GetTODO(ctx context.Context, id int64) (*model.TODO, error) {
+	return s.repo.Get(ctx, id)
 }
 
-// ReadTODO reads TODOs on DB.
+// ReadTODO reads TODOs.
 func (s *TODOService) ReadTODO(ctx context.Context, prevID, size int64) ([]*model.TODO, error) {
-	const (
-		read       = `SELECT id, subject, description, created_at, updated_at FROM todos ORDER BY id DESC LIMIT ?`
-		readWithID = `SELECT id, subject, description, created_at, updated_at FROM todos WHERE id < ? ORDER BY id DESC LIMIT ?`
-	)
+	return s.FilterTODO(ctx, nil, prevID, size)
+}
+
+// FilterTODO reads TODOs matching filter.
+func (s *TODOService) FilterTODO(ctx context.Context, filter *TODOFilter, prevID, size int64) ([]*model.TODO, error) {
+	if filter != nil {
+		filter = &TODOFilter{
+			Tags:         normalizeTags(filter.Tags),
+			AnyTag:       filter.AnyTag,
+			Status:       filter.Status,
+			DueFrom:      filter.DueFrom,
+			DueBefore:    filter.DueBefore,
+			RecurrenceID: filter.RecurrenceID,
+			ListID:       filter.ListID,
+			Trashed:      filter.Trashed,
+		}
+	}
+	start := time.Now()
+	todos, err := s.repo.List(ctx, filter, prevID, size)
+	s.observe(MethodReadTODO, start, err)
+	return todos, err
+}
+
+// SearchTODO searches TODOs by subject and description.
+// An empty mode means model.SearchModeWord.
+func (s *TODOService) SearchTODO(ctx context.Context, mode model.SearchMode, query string, prevID, size int64) ([]*model.TODOSearchResult, error) {
+	searcher, ok := s.repo.(TODOSearcher)
+	if !ok {
+		return nil, ErrSearchUnavailable
+	}
+	if mode == "" {
+		mode = model.SearchModeWord
+	}
+	return searcher.Search(ctx, mode, query, prevID, size)
+}
+
+// UpdateTODO updates the TODO.
+// The tags of the TODO are replaced when tags is not nil and kept otherwise,
+// so passing an empty non-nil slice removes every tag.
+func (s *TODOService) UpdateTODO(ctx context.Context, id int64, subject, description string, tags ...string) (*model.TODO, error) {
+	return s.UpdateTODOWithInput(ctx, id, &TODOInput{
+		Subject:     subject,
+		Description: description,
+		Tags:        tags,
+	})
+}
+
+// UpdateTODOWithInput updates the TODO with every value of in.
+// It returns *ErrPreconditionFailed with the current TODO when in.Version is stale.
+// A recurrence given by in is anchored on the due date the TODO has after the update,
+// and the due date of a recurring TODO cannot be removed.
+func (s *TODOService) UpdateTODOWithInput(ctx context.Context, id int64, in *TODOInput) (*model.TODO, error) {
+	start := time.Now()
+	todo, err := s.updateTODO(ctx, id, in)
+	s.observe(MethodUpdateTODO, start, err)
+	return todo, err
+}
+
+// updateTODO implements UpdateTODOWithInput.
+func (s *TODOService) updateTODO(ctx context.Context, id int64, in *TODOInput) (*model.TODO, error) {
+	in = normalizeInput(in)
+
+	recurring := in.SetRecurrence && in.Recurrence != nil
+	removingDue := in.SetDueAt && in.DueAt == nil && !(in.SetRecurrence && in.Recurrence == nil)
 
-	return nil, nil
+	// before is read only when needed, and the repository reads it again for the revision
+	if (recurring && !in.SetDueAt) || removingDue {
+		before, err := s.repo.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if in.Version != "" && Version(before) != in.Version {
+			return nil, &ErrPreconditionFailed{TODO: before}
+		}
+		if removingDue && before.Recurrence != nil {
+			return nil, fmt.Errorf("%w: due date of recurring TODO cannot be removed", ErrInvalidRecurrence)
+		}
+		if !in.SetDueAt {
+			in.DueAt = before.DueAt
+		}
+	}
+	if recurring {
+		recurrence, err := s.anchorRecurrence(in.Recurrence, in.DueAt)
+		if err != nil {
+			return nil, err
+		}
+		in.Recurrence = recurrence
+	}
+
+	ctx, _ = s.recording(ctx)
+	return s.repo.Update(ctx, id, in)
 }
 
-// UpdateTODO updates the TODO on DB.
-func (s *TODOService) UpdateTODO(ctx context.Context, id int64, subject, description string) (*model.TODO, error) {
-	const (
-		update  = `UPDATE todos SET subject = ?, description = ? WHERE id = ?`
-		confirm = `SELECT subject, description, created_at, updated_at FROM todos WHERE id = ?`
-	)
+// A TODOPatch computes the values of a TODO from the TODO as currently stored.
+type TODOPatch func(todo *model.TODO) (*TODOInput, error)
+
+// maxPatchAttempts is the number of times a patch is applied before giving up on concurrent updates.
+const maxPatchAttempts = 3
+
+// PatchTODO updates the TODO with the values patch computes from the TODO, under the same rules as UpdateTODOWithInput.
+// The values are stored only if the TODO has not changed since it was read, and patch is applied again otherwise,
+// so that a patch never overwrites a concurrent update.
+// It returns *ErrPreconditionFailed with the current TODO unless the TODO is at version, where an empty version means any version.
+func (s *TODOService) PatchTODO(ctx context.Context, id int64, version string, patch TODOPatch) (*model.TODO, error) {
+	for attempt := 1; ; attempt++ {
+		current, err := s.repo.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if version != "" && Version(current) != version {
+			return nil, &ErrPreconditionFailed{TODO: current}
+		}
+
+		in, err := patch(current)
+		if err != nil {
+			return nil, err
+		}
+		in.Version = Version(current)
+
+		todo, err := s.UpdateTODOWithInput(ctx, id, in)
+		var stale *ErrPreconditionFailed
+		if !errors.As(err, &stale) || version != "" {
+			return todo, err
+		}
+		if attempt == maxPatchAttempts {
+			return nil, &model.ErrConflict{Message: "TODO is being updated concurrently"}
+		}
+	}
+}
 
-	return nil, nil
+// ReopenTODO marks the completed TODO open again.
+func (s *TODOService) ReopenTODO(ctx context.Context, id int64) (*model.TODO, error) {
+	return s.setCompleted(ctx, id, false)
 }
 
-// DeleteTODO deletes TODOs on DB by ids.
+// setCompleted marks the TODO completed or open, and records the revision when it changes.
+func (s *TODOService) setCompleted(ctx context.Context, id int64, completed bool) (*model.TODO, error) {
+	ctx, _ = s.recording(ctx)
+	return s.repo.SetCompleted(ctx, id, completed)
+}
+
+// DeleteTODO moves TODOs by ids to the trash.
 func (s *TODOService) DeleteTODO(ctx context.Context, ids []int64) error {
-	const deleteFmt = `DELETE FROM todos WHERE id IN (?%s)`
+	start := time.Now()
+	err := s.deleteTODO(ctx, ids)
+	s.observe(MethodDeleteTODO, start, err)
+	return err
+}
+
+// deleteTODO implements DeleteTODO.
+func (s *TODOService) deleteTODO(ctx context.Context, ids []int64) error {
+	ctx, _ = s.recording(ctx)
+	return s.repo.Delete(ctx, ids)
+}
+
+// normalizeInput returns a copy of in with normalized tags and due date.
+func normalizeInput(in *TODOInput) *TODOInput {
+	normalized := *in
+	normalized.Tags = normalizeTags(in.Tags)
+	if in.DueAt != nil {
+		dueAt := in.DueAt.UTC().Truncate(time.Second)
+		normalized.DueAt = &dueAt
+	}
+	return &normalized
+}
 
-	return nil
+// isNotFound reports whether err is *model.ErrNotFound.
+func isNotFound(err error) bool {
+	var notFound *model.ErrNotFound
+	return errors.As(err, &notFound)
 }
@@ -0,0 +1,93 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestTODOService_Complete(t *testing.T) {
+	t.Parallel()
+
+	for name, newRepo := range repositories() {
+		newRepo := newRepo
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			svc := service.NewTODOServiceWithRepository(newRepo(t))
+
+			for _, subject := range []string{"subject 1", "subject 2", "subject 3", "subject 4"} {
+				if _, err := svc.CreateTODO(ctx, subject, ""); err != nil {
+					t.Fatal("failed to create todo, err =", err)
+				}
+			}
+
+			if _, _, err := svc.CompleteTODO(ctx, 5); !isNotFound(err) {
+				t.Errorf("unexpected error on missing id, given = %v", err)
+			}
+			if _, err := svc.ReopenTODO(ctx, 5); !isNotFound(err) {
+				t.Errorf("unexpected error on missing id, given = %v", err)
+			}
+
+			for _, id := range []int64{1, 3, 4} {
+				todo, _, err := svc.CompleteTODO(ctx, id)
+				if err != nil {
+					t.Fatal("failed to complete todo, err =", err)
+				}
+				if !todo.Completed || todo.CompletedAt == nil || todo.CompletedAt.Before(todo.CreatedAt) {
+					t.Errorf("unexpected todo, given = %+v", todo)
+				}
+			}
+
+			// completing twice keeps completed_at
+			first, err := svc.FilterTODO(ctx, &service.TODOFilter{Status: model.TODOStatusDone}, 2, 1)
+			if err != nil {
+				t.Fatal("failed to read todo, err =", err)
+			}
+			again, _, err := svc.CompleteTODO(ctx, 1)
+			if err != nil {
+				t.Fatal("failed to complete todo, err =", err)
+			}
+			if !again.CompletedAt.Equal(*first[0].CompletedAt) || !again.UpdatedAt.Equal(first[0].UpdatedAt) {
+				t.Errorf("completed todo is changed, before = %+v, after = %+v", first[0], again)
+			}
+
+			todo, err := svc.ReopenTODO(ctx, 4)
+			if err != nil {
+				t.Fatal("failed to reopen todo, err =", err)
+			}
+			if todo.Completed || todo.CompletedAt != nil {
+				t.Errorf("unexpected todo, given = %+v", todo)
+			}
+			if _, err := svc.ReopenTODO(ctx, 4); err != nil {
+				t.Fatal("failed to reopen todo, err =", err)
+			}
+
+			cases := map[string]struct {
+				status       model.TODOStatus
+				prevID, size int64
+				ids          []int64
+			}{
+				"Empty status":       {status: "", size: 5, ids: []int64{4, 3, 2, 1}},
+				"All":                {status: model.TODOStatusAll, size: 5, ids: []int64{4, 3, 2, 1}},
+				"Open":               {status: model.TODOStatusOpen, size: 5, ids: []int64{4, 2}},
+				"Done":               {status: model.TODOStatusDone, size: 5, ids: []int64{3, 1}},
+				"Open with size":     {status: model.TODOStatusOpen, size: 1, ids: []int64{4}},
+				"Open with prev id":  {status: model.TODOStatusOpen, prevID: 4, size: 5, ids: []int64{2}},
+				"Done after last id": {status: model.TODOStatusDone, prevID: 1, size: 5, ids: []int64{}},
+			}
+			for name, c := range cases {
+				todos, err := svc.FilterTODO(ctx, &service.TODOFilter{Status: c.status}, c.prevID, c.size)
+				if err != nil {
+					t.Fatalf("%s: failed to read todos, err = %v", name, err)
+				}
+				if got := ids(todos); !equalIDs(got, c.ids) {
+					t.Errorf("%s: unexpected ids, given = %v, expected = %v", name, got, c.ids)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// ReadDueTODO reads open TODOs of view in ascending order of due date.
+// Days are delimited at midnight in the time zone of the service, see SetClock.
+// days is the number of days after today listed by model.DueViewUpcoming and ignored by the others.
+func (s *TODOService) ReadDueTODO(ctx context.Context, view model.DueView, days, prevID, size int64) ([]*model.TODO, error) {
+	today := s.today()
+	tomorrow := today.AddDate(0, 0, 1)
+
+	filter := &TODOFilter{Status: model.TODOStatusOpen}
+	switch view {
+	case model.DueViewOverdue:
+		filter.DueBefore = &today
+	case model.DueViewToday:
+		filter.DueFrom, filter.DueBefore = &today, &tomorrow
+	case model.DueViewUpcoming:
+		end := tomorrow.AddDate(0, 0, int(days))
+		filter.DueFrom, filter.DueBefore = &tomorrow, &end
+	default:
+		return nil, fmt.Errorf("service: unknown due view %q", view)
+	}
+
+	return s.repo.ListByDue(ctx, filter, prevID, size)
+}
+
+// today returns the start of the current day in the time zone of the service.
+func (s *TODOService) today() time.Time {
+	loc := s.location()
+	now := s.now().In(loc)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+}
+
+// location returns the time zone of the service.
+func (s *TODOService) location() *time.Location {
+	if s.loc == nil {
+		return time.Local
+	}
+	return s.loc
+}
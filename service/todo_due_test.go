@@ -0,0 +1,107 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestTODOService_ReadDueTODO(t *testing.T) {
+	t.Parallel()
+
+	jst, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal("failed to load location, err =", err)
+	}
+	at := func(month time.Month, day, hour, min int) *time.Time {
+		t := time.Date(2026, month, day, hour, min, 0, 0, jst)
+		return &t
+	}
+	// 00:10 on 18th in JST is still 17th in UTC and 00:30 on 19th in JST is still 18th in UTC,
+	// which must not shift the days of those TODOs
+	now := *at(time.October, 18, 23, 30)
+
+	for name, newRepo := range repositories() {
+		newRepo := newRepo
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			svc := service.NewTODOServiceWithRepository(newRepo(t))
+			svc.SetClock(func() time.Time { return now }, jst)
+
+			for _, dueAt := range []*time.Time{
+				at(time.October, 17, 23, 59),
+				at(time.October, 18, 0, 10),
+				at(time.October, 18, 23, 59),
+				at(time.October, 19, 0, 30),
+				at(time.October, 25, 23, 59),
+				nil,
+				at(time.October, 18, 12, 0),
+				at(time.October, 18, 0, 10),
+			} {
+				todo, err := svc.CreateTODOWithInput(ctx, &service.TODOInput{Subject: "subject", DueAt: dueAt})
+				if err != nil {
+					t.Fatal("failed to create todo, err =", err)
+				}
+				if (dueAt == nil) != (todo.DueAt == nil) || (dueAt != nil && !todo.DueAt.Equal(*dueAt)) {
+					t.Errorf("unexpected due_at, given = %v, expected = %v", todo.DueAt, dueAt)
+				}
+			}
+			if _, _, err := svc.CompleteTODO(ctx, 7); err != nil {
+				t.Fatal("failed to complete todo, err =", err)
+			}
+
+			cases := map[string]struct {
+				view         model.DueView
+				days         int64
+				prevID, size int64
+				ids          []int64
+			}{
+				"Overdue":             {view: model.DueViewOverdue, size: 5, ids: []int64{1}},
+				"Today":               {view: model.DueViewToday, size: 5, ids: []int64{2, 8, 3}},
+				"Today with size":     {view: model.DueViewToday, size: 2, ids: []int64{2, 8}},
+				"Today with prev id":  {view: model.DueViewToday, prevID: 2, size: 5, ids: []int64{8, 3}},
+				"Today after last":    {view: model.DueViewToday, prevID: 3, size: 5, ids: []int64{}},
+				"Prev id without due": {view: model.DueViewToday, prevID: 6, size: 5, ids: []int64{}},
+				"Upcoming a day":      {view: model.DueViewUpcoming, days: 1, size: 5, ids: []int64{4}},
+				"Upcoming a week":     {view: model.DueViewUpcoming, days: 7, size: 5, ids: []int64{4, 5}},
+			}
+			for name, c := range cases {
+				todos, err := svc.ReadDueTODO(ctx, c.view, c.days, c.prevID, c.size)
+				if err != nil {
+					t.Fatalf("%s: failed to read todos, err = %v", name, err)
+				}
+				if got := ids(todos); !equalIDs(got, c.ids) {
+					t.Errorf("%s: unexpected ids, given = %v, expected = %v", name, got, c.ids)
+				}
+			}
+
+			// the due date is kept unless it is set
+			todo, err := svc.UpdateTODO(ctx, 1, "updated", "")
+			if err != nil {
+				t.Fatal("failed to update todo, err =", err)
+			}
+			if todo.DueAt == nil {
+				t.Error("due_at is removed by update")
+			}
+			todo, err = svc.UpdateTODOWithInput(ctx, 1, &service.TODOInput{Subject: "updated", SetDueAt: true})
+			if err != nil {
+				t.Fatal("failed to update todo, err =", err)
+			}
+			if todo.DueAt != nil {
+				t.Errorf("unexpected due_at, given = %v", todo.DueAt)
+			}
+			todos, err := svc.ReadDueTODO(ctx, model.DueViewOverdue, 0, 0, 5)
+			if err != nil {
+				t.Fatal("failed to read todos, err =", err)
+			}
+			if len(todos) != 0 {
+				t.Errorf("unexpected todos, given = %v", ids(todos))
+			}
+		})
+	}
+}
@@ -0,0 +1,497 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// A MemoryTODORepository implements TODORepository in memory.
+// It is safe for concurrent use and mirrors the behavior of SQLiteTODORepository,
+// including the CHECK constraint rejecting an empty subject.
+type MemoryTODORepository struct {
+	mu     sync.RWMutex
+	lastID int64
+	// todos is kept in ascending id order.
+	todos []*model.TODO
+	// tags maps the ids of owners to the keys of their tag names to the names as first created.
+	tags map[int64]map[string]string
+	// lastRecurrenceID is the id of the recurrence created last.
+	// A recurrence is shared by pointer among the TODOs of the same recurrence.
+	lastRecurrenceID int64
+	// revisions maps the ids of TODOs to their revisions in ascending number order.
+	revisions map[int64][]*model.Revision
+	// users and tokens are kept in ascending id order, which is their position plus one.
+	users  []*model.User
+	tokens []*memoryToken
+	// passwords maps the ids of users to the hashes of their passwords.
+	passwords map[int64]string
+	// sessions is kept in ascending id order.
+	sessions      []*memorySession
+	lastSessionID int64
+	// identities maps the issuers and subjects of identities to the ids of the users linked to them.
+	identities map[memoryIdentity]int64
+	// lists is kept in ascending id order, and members maps the ids of lists to the ids of users to their memberships.
+	lists      []*model.List
+	lastListID int64
+	members    map[int64]map[int64]*memoryMember
+}
+
+var (
+	_ TODORepository = (*MemoryTODORepository)(nil)
+	_ TagRepository  = (*MemoryTODORepository)(nil)
+)
+
+// NewMemoryTODORepository returns new empty MemoryTODORepository.
+func NewMemoryTODORepository() *MemoryTODORepository {
+	return &MemoryTODORepository{
+		tags:       make(map[int64]map[string]string),
+		revisions:  make(map[int64][]*model.Revision),
+		passwords:  make(map[int64]string),
+		identities: make(map[memoryIdentity]int64),
+		members:    make(map[int64]map[int64]*memoryMember),
+	}
+}
+
+// errConstraintCheck is the error SQLite returns when an empty subject violates the CHECK constraint.
+var errConstraintCheck = sqlite3.Error{
+	Code:         sqlite3.ErrConstraint,
+	ExtendedCode: sqlite3.ErrConstraintCheck,
+}
+
+// errConstraintNotNull is the error SQLite returns when a required column is NULL, such as the user of an anonymous caller.
+var errConstraintNotNull = sqlite3.Error{
+	Code:         sqlite3.ErrConstraint,
+	ExtendedCode: sqlite3.ErrConstraintNotNull,
+}
+
+// errConstraintForeignKey is the error SQLite returns when a row refers to a missing TODO.
+var errConstraintForeignKey = sqlite3.Error{
+	Code:         sqlite3.ErrConstraint,
+	ExtendedCode: sqlite3.ErrConstraintForeignKey,
+}
+
+// Create implements TODORepository interface.
+func (r *MemoryTODORepository) Create(ctx context.Context, in *TODOInput) (*model.TODO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if in.Subject == "" || !validTags(in.Tags) {
+		return nil, errConstraintCheck
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if in.ListID != 0 && r.findList(in.ListID) == nil {
+		return nil, errConstraintForeignKey
+	}
+	now := memoryNow()
+	owner := ownerID(ctx)
+	r.lastID++
+	todo := &model.TODO{
+		ID:          r.lastID,
+		Subject:     in.Subject,
+		Description: in.Description,
+		Tags:        r.attachTags(owner, in.Tags),
+		DueAt:       copyTime(in.DueAt),
+		OwnerID:     owner,
+		ListID:      in.ListID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if in.Recurrence != nil {
+		r.setRecurrence(todo, in.Recurrence)
+	}
+	r.todos = append(r.todos, todo)
+	r.recordRevision(ctx, todo.ID, model.RevisionActionCreate, nil, todo)
+
+	return copyTODO(todo), nil
+}
+
+// Get implements TODORepository interface.
+func (r *MemoryTODORepository) Get(ctx context.Context, id int64) (*model.TODO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	todo := r.find(ctx, id)
+	if todo == nil {
+		return nil, &model.ErrNotFound{}
+	}
+	return copyTODO(todo), nil
+}
+
+// List implements TODORepository interface.
+func (r *MemoryTODORepository) List(ctx context.Context, filter *TODOFilter, prevID, size int64) ([]*model.TODO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if size < 0 {
+		size = 0
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	todos := []*model.TODO{}
+	for i := len(r.todos) - 1; i >= 0 && int64(len(todos)) < size; i-- {
+		if prevID != 0 && r.todos[i].ID >= prevID {
+			continue
+		}
+		if !r.visible(ctx, r.todos[i]) || !filter.match(r.todos[i]) {
+			continue
+		}
+		todos = append(todos, copyTODO(r.todos[i]))
+	}
+
+	return todos, nil
+}
+
+// ListByDue implements TODORepository interface.
+func (r *MemoryTODORepository) ListByDue(ctx context.Context, filter *TODOFilter, prevID, size int64) ([]*model.TODO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var prev *model.TODO
+	if prevID != 0 {
+		i := r.index(prevID)
+		if i < 0 || r.todos[i].DueAt == nil {
+			return []*model.TODO{}, nil
+		}
+		prev = r.todos[i]
+	}
+
+	var matched []*model.TODO
+	for _, todo := range r.todos {
+		if todo.DueAt == nil || !r.visible(ctx, todo) || !filter.match(todo) {
+			continue
+		}
+		if prev != nil && !dueBefore(prev, todo) {
+			continue
+		}
+		matched = append(matched, todo)
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return dueBefore(matched[i], matched[j])
+	})
+
+	todos := []*model.TODO{}
+	for _, todo := range matched {
+		if int64(len(todos)) >= size {
+			break
+		}
+		todos = append(todos, copyTODO(todo))
+	}
+
+	return todos, nil
+}
+
+// Update implements TODORepository interface.
+func (r *MemoryTODORepository) Update(ctx context.Context, id int64, in *TODOInput) (*model.TODO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	todo := r.find(ctx, id)
+	if todo == nil {
+		return nil, &model.ErrNotFound{}
+	}
+	if !r.editable(ctx, todo) {
+		return nil, ErrForbidden
+	}
+	if in.Version != "" && Version(todo) != in.Version {
+		return nil, &ErrPreconditionFailed{TODO: copyTODO(todo)}
+	}
+	if in.Subject == "" || !validTags(in.Tags) {
+		return nil, errConstraintCheck
+	}
+
+	before := copyTODO(todo)
+	todo.Subject = in.Subject
+	todo.Description = in.Description
+	if in.Tags != nil {
+		todo.Tags = r.attachTags(todo.OwnerID, in.Tags)
+	}
+	if in.SetDueAt {
+		todo.DueAt = copyTime(in.DueAt)
+	}
+	if in.SetRecurrence {
+		r.setRecurrence(todo, in.Recurrence)
+	}
+	todo.UpdatedAt = memoryNow()
+	r.recordRevision(ctx, id, model.RevisionActionUpdate, before, todo)
+
+	return copyTODO(todo), nil
+}
+
+// SetCompleted implements TODORepository interface.
+func (r *MemoryTODORepository) SetCompleted(ctx context.Context, id int64, completed bool) (*model.TODO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	todo := r.find(ctx, id)
+	if todo == nil {
+		return nil, &model.ErrNotFound{}
+	}
+	if !r.editable(ctx, todo) {
+		return nil, ErrForbidden
+	}
+
+	if todo.Completed != completed {
+		before := copyTODO(todo)
+		now := memoryNow()
+		todo.Completed = completed
+		todo.CompletedAt = nil
+		action := model.RevisionActionReopen
+		if completed {
+			todo.CompletedAt = &now
+			action = model.RevisionActionComplete
+		}
+		todo.UpdatedAt = now
+		r.recordRevision(ctx, id, action, before, todo)
+	}
+
+	return copyTODO(todo), nil
+}
+
+// Delete implements TODORepository interface.
+func (r *MemoryTODORepository) Delete(ctx context.Context, ids []int64) error {
+	return r.setDeleted(ctx, ids, true)
+}
+
+// Restore implements TODORepository interface.
+func (r *MemoryTODORepository) Restore(ctx context.Context, ids []int64) error {
+	return r.setDeleted(ctx, ids, false)
+}
+
+// setDeleted moves the TODOs by ids to the trash or back from it, and records the revisions of the TODOs moved.
+// It returns ErrForbidden when the caller may not change any of the TODOs, and *model.ErrNotFound when none of the TODOs are moved.
+func (r *MemoryTODORepository) setDeleted(ctx context.Context, ids []int64, deleted bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, id := range ids {
+		if i := r.index(id); i >= 0 && r.visible(ctx, r.todos[i]) && !r.editable(ctx, r.todos[i]) {
+			return ErrForbidden
+		}
+	}
+
+	now := memoryNow()
+	moved := 0
+	for _, id := range ids {
+		i := r.index(id)
+		if i < 0 || !r.visible(ctx, r.todos[i]) || (r.todos[i].DeletedAt != nil) == deleted {
+			continue
+		}
+		todo := r.todos[i]
+		before := copyTODO(todo)
+		todo.DeletedAt = nil
+		if deleted {
+			todo.DeletedAt = &now
+		}
+		todo.UpdatedAt = now
+		moved++
+
+		// a TODO in the trash is recorded as nil, as a TODO deleted
+		if deleted {
+			r.recordRevision(ctx, id, model.RevisionActionDelete, before, nil)
+		} else {
+			r.recordRevision(ctx, id, model.RevisionActionRestore, nil, todo)
+		}
+	}
+
+	if moved == 0 {
+		return &model.ErrNotFound{}
+	}
+	return nil
+}
+
+// Purge implements TODORepository interface, which purges the trash of every owner.
+func (r *MemoryTODORepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.todos[:0]
+	for _, todo := range r.todos {
+		if todo.DeletedAt != nil && todo.DeletedAt.Before(before) {
+			delete(r.revisions, todo.ID)
+			continue
+		}
+		kept = append(kept, todo)
+	}
+	purged := len(r.todos) - len(kept)
+	for i := len(kept); i < len(r.todos); i++ {
+		r.todos[i] = nil
+	}
+	r.todos = kept
+
+	return int64(purged), nil
+}
+
+// match reports whether todo matches the filter. A nil filter matches every TODO out of the trash.
+func (f *TODOFilter) match(todo *model.TODO) bool {
+	if f == nil {
+		f = &TODOFilter{}
+	}
+
+	if (todo.DeletedAt != nil) != f.Trashed {
+		return false
+	}
+
+	switch f.Status {
+	case model.TODOStatusOpen:
+		if todo.Completed {
+			return false
+		}
+	case model.TODOStatusDone:
+		if !todo.Completed {
+			return false
+		}
+	}
+	if f.DueFrom != nil && (todo.DueAt == nil || todo.DueAt.Before(*f.DueFrom)) {
+		return false
+	}
+	if f.DueBefore != nil && (todo.DueAt == nil || !todo.DueAt.Before(*f.DueBefore)) {
+		return false
+	}
+	if f.RecurrenceID != 0 && (todo.Recurrence == nil || todo.Recurrence.ID != f.RecurrenceID) {
+		return false
+	}
+	if f.ListID != 0 && todo.ListID != f.ListID {
+		return false
+	}
+	if len(f.Tags) == 0 {
+		return true
+	}
+
+	has := make(map[string]struct{}, len(todo.Tags))
+	for _, tag := range todo.Tags {
+		has[tagKey(tag)] = struct{}{}
+	}
+
+	matched := 0
+	for _, tag := range f.Tags {
+		if _, ok := has[tagKey(tag)]; ok {
+			matched++
+		}
+	}
+	if f.AnyTag {
+		return matched > 0
+	}
+	return matched == len(f.Tags)
+}
+
+// find returns the TODO by id visible to the caller of ctx unless it does not exist or it is in the trash.
+func (r *MemoryTODORepository) find(ctx context.Context, id int64) *model.TODO {
+	i := r.index(id)
+	if i < 0 || !r.visible(ctx, r.todos[i]) || r.todos[i].DeletedAt != nil {
+		return nil
+	}
+	return r.todos[i]
+}
+
+// visible reports whether todo is visible to the caller of ctx, who owns it out of any list
+// or is a member of its list, in the same way as withAccess.
+func (r *MemoryTODORepository) visible(ctx context.Context, todo *model.TODO) bool {
+	if todo.ListID == 0 {
+		return todo.OwnerID == ownerID(ctx)
+	}
+	_, ok := r.members[todo.ListID][ownerID(ctx)]
+	return ok
+}
+
+// editable reports whether the caller of ctx may change todo visible to them, who owns it out of any list
+// or is an editor or owner of its list, in the same way as withEditAccess.
+func (r *MemoryTODORepository) editable(ctx context.Context, todo *model.TODO) bool {
+	if todo.ListID == 0 {
+		return todo.OwnerID == ownerID(ctx)
+	}
+	m, ok := r.members[todo.ListID][ownerID(ctx)]
+	return ok && m.role.Allows(model.RoleEditor)
+}
+
+// index returns the position of the TODO by id, or -1 if it does not exist.
+func (r *MemoryTODORepository) index(id int64) int {
+	lo, hi := 0, len(r.todos)
+	for lo < hi {
+		mid := (lo + hi) / 2
+		switch {
+		case r.todos[mid].ID == id:
+			return mid
+		case r.todos[mid].ID < id:
+			lo = mid + 1
+		default:
+			hi = mid
+		}
+	}
+	return -1
+}
+
+// copyTODO returns a deep copy of todo.
+func copyTODO(todo *model.TODO) *model.TODO {
+	copied := *todo
+	if todo.Tags != nil {
+		copied.Tags = append([]string(nil), todo.Tags...)
+	}
+	copied.CompletedAt = copyTime(todo.CompletedAt)
+	copied.DueAt = copyTime(todo.DueAt)
+	copied.DeletedAt = copyTime(todo.DeletedAt)
+	if todo.Recurrence != nil {
+		recurrence := *todo.Recurrence
+		copied.Recurrence = &recurrence
+	}
+	return &copied
+}
+
+// copyTime returns a copy of t, keeping nil as nil.
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	copied := *t
+	return &copied
+}
+
+// dueBefore reports whether a is ordered before b by due date and then id.
+func dueBefore(a, b *model.TODO) bool {
+	if !a.DueAt.Equal(*b.DueAt) {
+		return a.DueAt.Before(*b.DueAt)
+	}
+	return a.ID < b.ID
+}
+
+// memoryNow returns the current time with the precision DATETIME('now') has.
+func memoryNow() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
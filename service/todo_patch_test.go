@@ -0,0 +1,79 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestTODOService_PatchTODO(t *testing.T) {
+	t.Parallel()
+
+	for name, newRepo := range repositories() {
+		newRepo := newRepo
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			svc := service.NewTODOServiceWithRepository(newRepo(t))
+
+			todo, err := svc.CreateTODO(ctx, "subject", "description", "work")
+			if err != nil {
+				t.Fatal("failed to create todo, err =", err)
+			}
+
+			// the first application of the patch races with another update and is applied again
+			var applied []string
+			patched, err := svc.PatchTODO(ctx, todo.ID, "", func(current *model.TODO) (*service.TODOInput, error) {
+				applied = append(applied, current.Subject)
+				if len(applied) == 1 {
+					if _, err := svc.UpdateTODO(ctx, todo.ID, "concurrent", current.Description); err != nil {
+						return nil, err
+					}
+				}
+				return &service.TODOInput{Subject: current.Subject + " patched", Description: current.Description, Tags: current.Tags}, nil
+			})
+			if err != nil {
+				t.Fatal("failed to patch todo, err =", err)
+			}
+			if patched.Subject != "concurrent patched" || patched.Description != "description" || len(applied) != 2 {
+				t.Errorf("unexpected todo, given = %+v, applied = %v", patched, applied)
+			}
+
+			// a version makes the concurrent update fail the patch instead
+			_, err = svc.PatchTODO(ctx, todo.ID, service.Version(patched), func(current *model.TODO) (*service.TODOInput, error) {
+				if _, err := svc.UpdateTODO(ctx, todo.ID, "concurrent", current.Description); err != nil {
+					return nil, err
+				}
+				return &service.TODOInput{Subject: "patched"}, nil
+			})
+			var stale *service.ErrPreconditionFailed
+			if !errors.As(err, &stale) || stale.TODO.Subject != "concurrent" {
+				t.Errorf("unexpected error on concurrent update, given = %v", err)
+			}
+
+			// a patch racing every time gives up
+			_, err = svc.PatchTODO(ctx, todo.ID, "", func(current *model.TODO) (*service.TODOInput, error) {
+				if _, err := svc.UpdateTODO(ctx, todo.ID, current.Subject+"!", current.Description); err != nil {
+					return nil, err
+				}
+				return &service.TODOInput{Subject: "patched"}, nil
+			})
+			var conflict *model.ErrConflict
+			if !errors.As(err, &conflict) {
+				t.Errorf("unexpected error on endless race, given = %v", err)
+			}
+
+			failure := errors.New("failure")
+			if _, err := svc.PatchTODO(ctx, todo.ID, "", func(*model.TODO) (*service.TODOInput, error) { return nil, failure }); !errors.Is(err, failure) {
+				t.Errorf("unexpected error of patch, given = %v", err)
+			}
+			if _, err := svc.PatchTODO(ctx, 9, "", nil); !isNotFound(err) {
+				t.Errorf("unexpected error on missing todo, given = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/ngram"
+)
+
+var _ TODOSearcher = (*SQLiteTODORepository)(nil)
+
+const (
+	// highlightStart and highlightEnd delimit matched terms in FTS5 output
+	// until they are replaced by <mark> elements after HTML escaping.
+	highlightStart = "\x02"
+	highlightEnd   = "\x03"
+	// snippetTokens is the number of tokens in a description snippet.
+	snippetTokens = 16
+	// snippetRunes is the number of runes in a description snippet of ngram search.
+	snippetRunes = 48
+)
+
+var highlightReplacer = strings.NewReplacer(highlightStart, "<mark>", highlightEnd, "</mark>")
+
+// Search implements TODOSearcher interface on the todos_fts and todos_ngram tables.
+// It returns ErrSearchUnavailable when the database is not migrated with the tables,
+// which are left pending while SQLite is built without FTS5.
+func (r *SQLiteTODORepository) Search(ctx context.Context, mode model.SearchMode, query string, prevID, size int64) ([]*model.TODOSearchResult, error) {
+	const (
+		exists = `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`
+		rank   = `SELECT rank FROM %[1]s WHERE %[1]s MATCH ? AND rowid = ?`
+		search = `SELECT t.id, t.subject, t.description, t.completed, t.completed_at, t.due_at, t.recurrence_id, t.deleted_at, t.created_at, t.updated_at, IFNULL(t.owner_id, 0), IFNULL(t.list_id, 0), %[1]s.rank, %[2]s
+FROM %[1]s JOIN todos t ON t.id = %[1]s.rowid
+WHERE %[1]s MATCH ? AND t.deleted_at IS NULL AND t.id IN (SELECT id FROM todos WHERE ` + withAccess + `)
+ORDER BY %[1]s.rank, t.id DESC LIMIT ?`
+		searchWithID = `SELECT t.id, t.subject, t.description, t.completed, t.completed_at, t.due_at, t.recurrence_id, t.deleted_at, t.created_at, t.updated_at, IFNULL(t.owner_id, 0), IFNULL(t.list_id, 0), %[1]s.rank, %[2]s
+FROM %[1]s JOIN todos t ON t.id = %[1]s.rowid
+WHERE %[1]s MATCH ? AND t.deleted_at IS NULL AND t.id IN (SELECT id FROM todos WHERE ` + withAccess + `) AND (%[1]s.rank > ? OR (%[1]s.rank = ? AND %[1]s.rowid < ?))
+ORDER BY %[1]s.rank, t.id DESC LIMIT ?`
+		// highlights selects the highlighted subject and description snippet of the word search.
+		highlights = `highlight(todos_fts, 0, char(2), char(3)), snippet(todos_fts, 1, char(2), char(3), '…', %d)`
+		// noHighlights selects placeholders for the ngram search highlighted in Go.
+		noHighlights = `'', ''`
+	)
+
+	var table, match, columns string
+	switch mode {
+	case model.SearchModeWord:
+		table, match, columns = "todos_fts", ftsQuery(query), fmt.Sprintf(highlights, snippetTokens)
+	case model.SearchModeNgram:
+		table, match, columns = "todos_ngram", ngram.Query(query), noHighlights
+	default:
+		return nil, fmt.Errorf("service: unknown search mode %q", mode)
+	}
+
+	var n int
+	if err := r.db.QueryRowContext(ctx, exists, table).Scan(&n); err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrSearchUnavailable
+	}
+
+	results := []*model.TODOSearchResult{}
+	if match == "" || size <= 0 {
+		return results, nil
+	}
+
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	if prevID == 0 {
+		rows, err = r.db.QueryContext(ctx, fmt.Sprintf(search, table, columns), match, ownerArg(ctx), size)
+	} else {
+		var prevRank float64
+		err = r.db.QueryRowContext(ctx, fmt.Sprintf(rank, table), match, prevID).Scan(&prevRank)
+		if errors.Is(err, sql.ErrNoRows) {
+			// the previous page ended with a TODO that no longer matches
+			return results, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows, err = r.db.QueryContext(ctx, fmt.Sprintf(searchWithID, table, columns), match, ownerArg(ctx), prevRank, prevRank, prevID, size)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		res := &model.TODOSearchResult{}
+		err := rows.Scan(append(todoFields(&res.TODO), &res.Rank, &res.Highlight.Subject, &res.Highlight.Description)...)
+		if err != nil {
+			return nil, err
+		}
+
+		if mode == model.SearchModeNgram {
+			res.Highlight.Subject = ngram.Highlight(res.Subject, query, 0)
+			res.Highlight.Description = ngram.Highlight(res.Description, query, snippetRunes)
+		} else {
+			res.Highlight.Subject = highlightHTML(res.Highlight.Subject)
+			res.Highlight.Description = highlightHTML(res.Highlight.Description)
+		}
+		results = append(results, res)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	todos := make([]*model.TODO, len(results))
+	for i, res := range results {
+		todos[i] = &res.TODO
+	}
+	if err := loadRelations(ctx, r.db, todos); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ftsQuery converts the user input into a FTS5 query matching every whitespace
+// separated term literally, so that FTS5 operators in the input are not interpreted.
+func ftsQuery(query string) string {
+	terms := strings.Fields(query)
+	for i, term := range terms {
+		terms[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+	return strings.Join(terms, " ")
+}
+
+// highlightHTML escapes s and replaces the highlight delimiters with <mark> elements.
+func highlightHTML(s string) string {
+	return highlightReplacer.Replace(html.EscapeString(s))
+}
@@ -0,0 +1,216 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestTODOService_SearchTODO(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	svc := service.NewTODOService(newTestDB(t))
+	if _, err := svc.SearchTODO(ctx, model.SearchModeWord, "milk", 0, 5); errors.Is(err, service.ErrSearchUnavailable) {
+		t.Skip("SQLite is built without FTS5, run with -tags sqlite_fts5")
+	}
+
+	todos := []struct {
+		subject, description string
+	}{
+		{subject: "buy milk", description: "at the store"},
+		{subject: "walk the dog", description: "around the park"},
+		{subject: "milk the cow", description: "fresh milk every morning, milk is good"},
+		{subject: "<b>call</b> mom", description: `ask about "milk" prices`},
+		{subject: "read a book", description: ""},
+	}
+	for _, todo := range todos {
+		if _, err := svc.CreateTODO(ctx, todo.subject, todo.description); err != nil {
+			t.Fatal("failed to create todo, err =", err)
+		}
+	}
+	if _, err := svc.UpdateTODO(ctx, 2, "walk the dog", "buy milk on the way"); err != nil {
+		t.Fatal("failed to update todo, err =", err)
+	}
+	if err := svc.DeleteTODO(ctx, []int64{5}); err != nil {
+		t.Fatal("failed to delete todo, err =", err)
+	}
+
+	cases := map[string]struct {
+		query string
+		ids   []int64
+	}{
+		"Single term":          {query: "milk", ids: []int64{1, 2, 3, 4}},
+		"Every term must hit":  {query: "buy milk", ids: []int64{1, 2}},
+		"Updated description":  {query: "way", ids: []int64{2}},
+		"Deleted todo":         {query: "book", ids: []int64{}},
+		"Operators are quoted": {query: `milk OR "dog`, ids: []int64{}},
+		"Diacritics":           {query: "mïlk", ids: []int64{1, 2, 3, 4}},
+		"Blank":                {query: "  ", ids: []int64{}},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			results, err := svc.SearchTODO(ctx, model.SearchModeWord, c.query, 0, 10)
+			if err != nil {
+				t.Fatal("failed to search todos, err =", err)
+			}
+			got := make([]int64, 0, len(results))
+			for i, res := range results {
+				if i > 0 && res.Rank < results[i-1].Rank {
+					t.Errorf("results are not ordered by rank, given = %v", results)
+				}
+				got = append(got, res.ID)
+			}
+			sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+			if !equalIDs(got, c.ids) {
+				t.Errorf("unexpected ids, given = %v, expected = %v", got, c.ids)
+			}
+		})
+	}
+
+	t.Run("Paging", func(t *testing.T) {
+		t.Parallel()
+
+		all, err := svc.SearchTODO(ctx, model.SearchModeWord, "milk", 0, 10)
+		if err != nil {
+			t.Fatal("failed to search todos, err =", err)
+		}
+		want := make([]int64, 0, len(all))
+		for _, res := range all {
+			want = append(want, res.ID)
+		}
+		// the subject and the description repeating the term rank first
+		if len(want) == 0 || want[0] != 3 {
+			t.Errorf("unexpected best match, given = %v", want)
+		}
+
+		var (
+			got    []int64
+			prevID int64
+		)
+		for i := 0; i < 5; i++ {
+			results, err := svc.SearchTODO(ctx, model.SearchModeWord, "milk", prevID, 1)
+			if err != nil {
+				t.Fatal("failed to search todos, err =", err)
+			}
+			if len(results) == 0 {
+				break
+			}
+			prevID = results[0].ID
+			got = append(got, prevID)
+		}
+		if !equalIDs(got, want) {
+			t.Errorf("unexpected ids, given = %v, expected = %v", got, want)
+		}
+	})
+
+	t.Run("Highlight", func(t *testing.T) {
+		t.Parallel()
+
+		results, err := svc.SearchTODO(ctx, model.SearchModeWord, "call", 0, 1)
+		if err != nil {
+			t.Fatal("failed to search todos, err =", err)
+		}
+		if len(results) != 1 {
+			t.Fatalf("unexpected length, given = %d, expected = %d", len(results), 1)
+		}
+		if want := "&lt;b&gt;<mark>call</mark>&lt;/b&gt; mom"; results[0].Highlight.Subject != want {
+			t.Errorf("unexpected highlight, given = %s, expected = %s", results[0].Highlight.Subject, want)
+		}
+		if want := "ask about &#34;milk&#34; prices"; results[0].Highlight.Description != want {
+			t.Errorf("unexpected snippet, given = %s, expected = %s", results[0].Highlight.Description, want)
+		}
+	})
+}
+
+func TestTODOService_SearchTODO_Unavailable(t *testing.T) {
+	t.Parallel()
+
+	svc := service.NewTODOServiceWithRepository(service.NewMemoryTODORepository())
+	if _, err := svc.SearchTODO(context.Background(), model.SearchModeWord, "milk", 0, 5); !errors.Is(err, service.ErrSearchUnavailable) {
+		t.Errorf("unexpected error, given = %v, expected = %v", err, service.ErrSearchUnavailable)
+	}
+}
+
+func TestTODOService_SearchTODO_Ngram(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	svc := service.NewTODOService(newTestDB(t))
+	if _, err := svc.SearchTODO(ctx, model.SearchModeNgram, "かいもの", 0, 5); errors.Is(err, service.ErrSearchUnavailable) {
+		t.Skip("SQLite is built without FTS5, run with -tags sqlite_fts5")
+	}
+
+	todos := []struct {
+		subject, description string
+	}{
+		{subject: "カイモノ", description: ""},
+		{subject: "買い物リスト", description: "牛乳とパンを買う"},
+		{subject: "ｶﾞｯｺｳの準備", description: "体操服"},
+		{subject: "Buy milk", description: "ＭＩＬＫ　と　ﾊﾟﾝ"},
+	}
+	for _, todo := range todos {
+		if _, err := svc.CreateTODO(ctx, todo.subject, todo.description); err != nil {
+			t.Fatal("failed to create todo, err =", err)
+		}
+	}
+	if _, err := svc.UpdateTODO(ctx, 1, "カイモノに行く", ""); err != nil {
+		t.Fatal("failed to update todo, err =", err)
+	}
+
+	cases := map[string]struct {
+		query string
+		ids   []int64
+	}{
+		"Hiragana finds katakana":   {query: "かいもの", ids: []int64{1}},
+		"Partial term":              {query: "物リス", ids: []int64{2}},
+		"Updated subject":           {query: "行く", ids: []int64{1}},
+		"Half-width voiced kana":    {query: "がっこう", ids: []int64{3}},
+		"Full-width latin":          {query: "milk", ids: []int64{4}},
+		"Description":               {query: "ぱん", ids: []int64{2, 4}},
+		"Single rune":               {query: "牛", ids: []int64{2}},
+		"Single rune at end of run": {query: "服", ids: []int64{3}},
+		"Every term must hit":       {query: "ぱん 牛乳", ids: []int64{2}},
+		"No match":                  {query: "うどん", ids: []int64{}},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			results, err := svc.SearchTODO(ctx, model.SearchModeNgram, c.query, 0, 10)
+			if err != nil {
+				t.Fatal("failed to search todos, err =", err)
+			}
+			got := make([]int64, 0, len(results))
+			for _, res := range results {
+				got = append(got, res.ID)
+			}
+			sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+			if !equalIDs(got, c.ids) {
+				t.Errorf("unexpected ids, given = %v, expected = %v", got, c.ids)
+			}
+		})
+	}
+
+	t.Run("Highlight", func(t *testing.T) {
+		t.Parallel()
+
+		results, err := svc.SearchTODO(ctx, model.SearchModeNgram, "ﾊﾟﾝ", 0, 10)
+		if err != nil {
+			t.Fatal("failed to search todos, err =", err)
+		}
+		for _, res := range results {
+			if res.ID == 2 && res.Highlight.Description != "牛乳と<mark>パン</mark>を買う" {
+				t.Errorf("unexpected highlight, given = %s", res.Highlight.Description)
+			}
+		}
+	})
+}
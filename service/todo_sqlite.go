@@ -0,0 +1,628 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/ngram"
+)
+
+// A SQLiteTODORepository implements TODORepository on the todos table.
+type SQLiteTODORepository struct {
+	db *sql.DB
+}
+
+var (
+	_ TODORepository = (*SQLiteTODORepository)(nil)
+	_ TagRepository  = (*SQLiteTODORepository)(nil)
+)
+
+// A queryer is implemented by both *sql.DB and *sql.Tx.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// todoColumns lists the columns of the todos table in the order scanned by todoFields.
+const todoColumns = `id, subject, description, completed, completed_at, due_at, recurrence_id, deleted_at, created_at, updated_at, IFNULL(owner_id, 0), IFNULL(list_id, 0)`
+
+// withOwner is the condition of the tags owned by the caller, compared by IS so that NULL of anonymous callers matches.
+const withOwner = `owner_id IS ?`
+
+// withAccess is the condition of the TODOs visible to the caller, who is bound to its single argument:
+// the TODOs of no list owned by the caller, compared as withOwner, and the TODOs of the lists the caller
+// is a member of. It refers to the row by the table name, so the todos table must not be aliased.
+const withAccess = `EXISTS (SELECT 1 FROM (SELECT ? AS user_id) c
+WHERE (todos.list_id IS NULL AND todos.owner_id IS c.user_id) OR todos.list_id IN (SELECT list_id FROM list_members WHERE user_id = c.user_id))`
+
+// withEditAccess is the condition of the TODOs the caller may change, bound in the same way as withAccess:
+// the TODOs of no list owned by the caller, and the TODOs of the lists the caller is an editor or owner of.
+const withEditAccess = `EXISTS (SELECT 1 FROM (SELECT ? AS user_id) c
+WHERE (todos.list_id IS NULL AND todos.owner_id IS c.user_id) OR todos.list_id IN (SELECT list_id FROM list_members WHERE user_id = c.user_id AND role IN ('editor', 'owner')))`
+
+// sqliteTimeFormat is the format of DATETIME('now'), in which times are compared as text.
+const sqliteTimeFormat = "2006-01-02 15:04:05"
+
+// NewSQLiteTODORepository returns new SQLiteTODORepository.
+func NewSQLiteTODORepository(db *sql.DB) *SQLiteTODORepository {
+	return &SQLiteTODORepository{
+		db: db,
+	}
+}
+
+// Create implements TODORepository interface.
+func (r *SQLiteTODORepository) Create(ctx context.Context, in *TODOInput) (*model.TODO, error) {
+	const insert = `INSERT INTO todos(subject, description, subject_ngram, description_ngram, due_at, owner_id, list_id) VALUES(?, ?, ?, ?, ?, ?, ?)`
+
+	var todo *model.TODO
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, insert, in.Subject, in.Description, ngram.Index(in.Subject), ngram.Index(in.Description), sqliteTime(in.DueAt), ownerArg(ctx), nullInt64(in.ListID))
+		if err != nil {
+			return err
+		}
+
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		if len(in.Tags) > 0 {
+			if err := setTags(ctx, tx, id, in.Tags); err != nil {
+				return err
+			}
+		}
+		if in.Recurrence != nil {
+			if err := setRecurrence(ctx, tx, id, in.Recurrence); err != nil {
+				return err
+			}
+		}
+
+		if todo, err = get(ctx, tx, id); err != nil {
+			return err
+		}
+		return recordRevision(ctx, tx, id, model.RevisionActionCreate, nil, todo)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return todo, nil
+}
+
+// Get implements TODORepository interface.
+func (r *SQLiteTODORepository) Get(ctx context.Context, id int64) (*model.TODO, error) {
+	todo, err := get(ctx, r.db, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &model.ErrNotFound{}
+	}
+	return todo, err
+}
+
+// List implements TODORepository interface.
+func (r *SQLiteTODORepository) List(ctx context.Context, filter *TODOFilter, prevID, size int64) ([]*model.TODO, error) {
+	const (
+		read   = `SELECT ` + todoColumns + ` FROM todos%s ORDER BY id DESC LIMIT ?`
+		withID = `id < ?`
+	)
+
+	if size < 0 {
+		size = 0
+	}
+
+	conds, args := filter.sqlConds(ctx)
+	if prevID != 0 {
+		conds = append(conds, withID)
+		args = append(args, prevID)
+	}
+	args = append(args, size)
+
+	return r.query(ctx, fmt.Sprintf(read, sqlWhere(conds)), args...)
+}
+
+// ListByDue implements TODORepository interface.
+func (r *SQLiteTODORepository) ListByDue(ctx context.Context, filter *TODOFilter, prevID, size int64) ([]*model.TODO, error) {
+	const (
+		read    = `SELECT ` + todoColumns + ` FROM todos%s ORDER BY due_at, id LIMIT ?`
+		withDue = `due_at IS NOT NULL`
+		// withID compares with the due date of prevID, which never matches
+		// when the TODO of prevID no longer has due date.
+		withID = `(due_at, id) > ((SELECT due_at FROM todos WHERE id = ?), ?)`
+	)
+
+	if size < 0 {
+		size = 0
+	}
+
+	conds, args := filter.sqlConds(ctx)
+	conds = append(conds, withDue)
+	if prevID != 0 {
+		conds = append(conds, withID)
+		args = append(args, prevID, prevID)
+	}
+	args = append(args, size)
+
+	return r.query(ctx, fmt.Sprintf(read, sqlWhere(conds)), args...)
+}
+
+// query reads the TODOs selected by query with their tags.
+// query must select todoColumns.
+func (r *SQLiteTODORepository) query(ctx context.Context, query string, args ...interface{}) ([]*model.TODO, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	todos := []*model.TODO{}
+	for rows.Next() {
+		todo := &model.TODO{}
+		if err := rows.Scan(todoFields(todo)...); err != nil {
+			return nil, err
+		}
+		todos = append(todos, todo)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := loadRelations(ctx, r.db, todos); err != nil {
+		return nil, err
+	}
+	return todos, nil
+}
+
+// Update implements TODORepository interface.
+func (r *SQLiteTODORepository) Update(ctx context.Context, id int64, in *TODOInput) (*model.TODO, error) {
+	const (
+		update        = `UPDATE todos SET subject = ?, description = ?, subject_ngram = ?, description_ngram = ? WHERE id = ? AND deleted_at IS NULL AND ` + withEditAccess
+		updateWithDue = `UPDATE todos SET subject = ?, description = ?, subject_ngram = ?, description_ngram = ?, due_at = ? WHERE id = ? AND deleted_at IS NULL AND ` + withEditAccess
+	)
+
+	var todo *model.TODO
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		if err := authorizeIDs(ctx, tx, []int64{id}); err != nil {
+			return err
+		}
+
+		var before *model.TODO
+		if in.Version != "" || revisionLogFromContext(ctx) != nil {
+			current, err := get(ctx, tx, id)
+			if errors.Is(err, sql.ErrNoRows) {
+				return &model.ErrNotFound{}
+			}
+			if err != nil {
+				return err
+			}
+			if in.Version != "" && Version(current) != in.Version {
+				return &ErrPreconditionFailed{TODO: current}
+			}
+			before = current
+		}
+
+		var (
+			res sql.Result
+			err error
+		)
+		if in.SetDueAt {
+			res, err = tx.ExecContext(ctx, updateWithDue, in.Subject, in.Description, ngram.Index(in.Subject), ngram.Index(in.Description), sqliteTime(in.DueAt), id, ownerArg(ctx))
+		} else {
+			res, err = tx.ExecContext(ctx, update, in.Subject, in.Description, ngram.Index(in.Subject), ngram.Index(in.Description), id, ownerArg(ctx))
+		}
+		if err != nil {
+			return err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return &model.ErrNotFound{}
+		}
+
+		if in.Tags != nil {
+			if err := setTags(ctx, tx, id, in.Tags); err != nil {
+				return err
+			}
+		}
+		if in.SetRecurrence {
+			if err := setRecurrence(ctx, tx, id, in.Recurrence); err != nil {
+				return err
+			}
+		}
+
+		if todo, err = get(ctx, tx, id); err != nil {
+			return err
+		}
+		return recordRevision(ctx, tx, id, model.RevisionActionUpdate, before, todo)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return todo, nil
+}
+
+// SetCompleted implements TODORepository interface.
+func (r *SQLiteTODORepository) SetCompleted(ctx context.Context, id int64, completed bool) (*model.TODO, error) {
+	const (
+		complete = `UPDATE todos SET completed = TRUE, completed_at = DATETIME('now') WHERE id = ? AND NOT completed AND deleted_at IS NULL AND ` + withEditAccess
+		reopen   = `UPDATE todos SET completed = FALSE, completed_at = NULL WHERE id = ? AND completed AND deleted_at IS NULL AND ` + withEditAccess
+	)
+
+	update, action := reopen, model.RevisionActionReopen
+	if completed {
+		update, action = complete, model.RevisionActionComplete
+	}
+
+	var todo *model.TODO
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		if err := authorizeIDs(ctx, tx, []int64{id}); err != nil {
+			return err
+		}
+
+		var before *model.TODO
+		if revisionLogFromContext(ctx) != nil {
+			var err error
+			if before, err = get(ctx, tx, id); err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, update, id, ownerArg(ctx)); err != nil {
+			return err
+		}
+
+		var err error
+		todo, err = get(ctx, tx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return &model.ErrNotFound{}
+		}
+		if err != nil || before == nil || before.Completed == todo.Completed {
+			return err
+		}
+		return recordRevision(ctx, tx, id, action, before, todo)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return todo, nil
+}
+
+// Delete implements TODORepository interface.
+func (r *SQLiteTODORepository) Delete(ctx context.Context, ids []int64) error {
+	const trashFmt = `UPDATE todos SET deleted_at = DATETIME('now') WHERE id IN (?%s) AND deleted_at IS NULL AND ` + withEditAccess
+
+	return r.updateIDs(ctx, trashFmt, ids, model.RevisionActionDelete)
+}
+
+// Restore implements TODORepository interface.
+func (r *SQLiteTODORepository) Restore(ctx context.Context, ids []int64) error {
+	const restoreFmt = `UPDATE todos SET deleted_at = NULL WHERE id IN (?%s) AND deleted_at IS NOT NULL AND ` + withEditAccess
+
+	return r.updateIDs(ctx, restoreFmt, ids, model.RevisionActionRestore)
+}
+
+// Purge implements TODORepository interface, which purges the trash of every owner.
+func (r *SQLiteTODORepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	const (
+		purge = `DELETE FROM todos WHERE deleted_at < ?`
+		// orphans are the recurrences whose occurrences are all purged.
+		orphans = `DELETE FROM recurrences WHERE id NOT IN (SELECT recurrence_id FROM todos WHERE recurrence_id IS NOT NULL)`
+	)
+
+	var n int64
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, purge, sqliteTime(&before))
+		if err != nil {
+			return err
+		}
+		if n, err = res.RowsAffected(); err != nil || n == 0 {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, orphans)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// updateIDs executes the statement formatted from queryFmt with the placeholders of ids,
+// followed by the caller of ctx who may change the TODOs, which moves the TODOs to the trash or back from it.
+// It returns ErrForbidden when the caller may not change any of the TODOs, and *model.ErrNotFound when no row is updated.
+// The TODOs moved are recorded as the revisions of action in the same transaction, when ctx carries a revision log.
+func (r *SQLiteTODORepository) updateIDs(ctx context.Context, queryFmt string, ids []int64, action model.RevisionAction) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	args := append(int64Args(ids), ownerArg(ctx))
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		if err := authorizeIDs(ctx, tx, ids); err != nil {
+			return err
+		}
+
+		recording := revisionLogFromContext(ctx) != nil
+		var befores []*model.TODO
+		if recording {
+			var err error
+			if befores, err = getIDs(ctx, tx, ids); err != nil {
+				return err
+			}
+		}
+
+		res, err := tx.ExecContext(ctx, fmt.Sprintf(queryFmt, strings.Repeat(",?", len(ids)-1)), args...)
+		if err != nil {
+			return err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return &model.ErrNotFound{}
+		}
+		if !recording {
+			return nil
+		}
+
+		afters, err := getIDs(ctx, tx, ids)
+		if err != nil {
+			return err
+		}
+		for i, id := range ids {
+			// the TODOs out of the trash are read, so that only the TODOs moved are read either before or after
+			if (befores[i] == nil) == (afters[i] == nil) {
+				continue
+			}
+			if err := recordRevision(ctx, tx, id, action, befores[i], afters[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// inTx runs fn in a transaction, which is committed only when fn returns nil.
+// The error of fn is returned as is.
+func (r *SQLiteTODORepository) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// get reads the TODO by id visible to the caller of ctx with its tags unless it is in the trash.
+func get(ctx context.Context, q queryer, id int64) (*model.TODO, error) {
+	const confirm = `SELECT ` + todoColumns + ` FROM todos WHERE id = ? AND deleted_at IS NULL AND ` + withAccess
+
+	todo := &model.TODO{}
+	if err := q.QueryRowContext(ctx, confirm, id, ownerArg(ctx)).Scan(todoFields(todo)...); err != nil {
+		return nil, err
+	}
+
+	if err := loadRelations(ctx, q, []*model.TODO{todo}); err != nil {
+		return nil, err
+	}
+	return todo, nil
+}
+
+// getIDs reads the TODOs by ids as get, where the TODOs not read are nil.
+func getIDs(ctx context.Context, q queryer, ids []int64) ([]*model.TODO, error) {
+	todos := make([]*model.TODO, len(ids))
+	for i, id := range ids {
+		todo, err := get(ctx, q, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		todos[i] = todo
+	}
+	return todos, nil
+}
+
+// todoFields returns the scan destinations of todoColumns.
+func todoFields(todo *model.TODO) []interface{} {
+	return []interface{}{
+		&todo.ID, &todo.Subject, &todo.Description, &todo.Completed, &todo.CompletedAt, &todo.DueAt,
+		recurrenceID{todo}, &todo.DeletedAt, &todo.CreatedAt, &todo.UpdatedAt, &todo.OwnerID, &todo.ListID,
+	}
+}
+
+// ownerArg returns the argument of withOwner and withAccess for the caller of ctx, which is NULL for anonymous callers.
+func ownerArg(ctx context.Context) interface{} {
+	if id := ownerID(ctx); id != 0 {
+		return id
+	}
+	return nil
+}
+
+// nullInt64 converts id into a query argument, converting 0 into NULL.
+func nullInt64(id int64) interface{} {
+	if id != 0 {
+		return id
+	}
+	return nil
+}
+
+// sqlConds returns the conditions of the WHERE clause matching the filter among the TODOs
+// visible to the caller of ctx, and their arguments. A nil filter matches every TODO out of the trash.
+func (f *TODOFilter) sqlConds(ctx context.Context) ([]string, []interface{}) {
+	const (
+		withTags       = `id IN (SELECT tt.todo_id FROM todo_tags tt JOIN tags g ON g.id = tt.tag_id WHERE g.name IN (?%s) GROUP BY tt.todo_id%s)`
+		allTags        = ` HAVING COUNT(*) = ?`
+		withState      = `completed = ?`
+		withDueFrom    = `due_at >= ?`
+		withDueBefore  = `due_at < ?`
+		withRecurrence = `recurrence_id = ?`
+		withList       = `list_id = ?`
+		inTrash        = `deleted_at IS NOT NULL`
+		outOfTrash     = `deleted_at IS NULL`
+	)
+
+	if f == nil {
+		f = &TODOFilter{}
+	}
+
+	conds := []string{withAccess}
+	args := []interface{}{ownerArg(ctx)}
+	if f.Trashed {
+		conds = append(conds, inTrash)
+	} else {
+		conds = append(conds, outOfTrash)
+	}
+
+	if len(f.Tags) > 0 {
+		having := allTags
+		if f.AnyTag {
+			having = ""
+		}
+		conds = append(conds, fmt.Sprintf(withTags, strings.Repeat(",?", len(f.Tags)-1), having))
+		for _, tag := range f.Tags {
+			args = append(args, tag)
+		}
+		if !f.AnyTag {
+			args = append(args, len(f.Tags))
+		}
+	}
+	if f.Status == model.TODOStatusOpen || f.Status == model.TODOStatusDone {
+		conds = append(conds, withState)
+		args = append(args, f.Status == model.TODOStatusDone)
+	}
+	if f.DueFrom != nil {
+		conds = append(conds, withDueFrom)
+		args = append(args, sqliteTime(f.DueFrom))
+	}
+	if f.DueBefore != nil {
+		conds = append(conds, withDueBefore)
+		args = append(args, sqliteTime(f.DueBefore))
+	}
+	if f.RecurrenceID != 0 {
+		conds = append(conds, withRecurrence)
+		args = append(args, f.RecurrenceID)
+	}
+	if f.ListID != 0 {
+		conds = append(conds, withList)
+		args = append(args, f.ListID)
+	}
+
+	return conds, args
+}
+
+// sqlWhere joins conds into a WHERE clause, which is empty without conds.
+func sqlWhere(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conds, " AND ")
+}
+
+// sqliteTime converts t into a query argument in sqliteTimeFormat. A nil t is converted into NULL.
+func sqliteTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(sqliteTimeFormat)
+}
+
+// setTags replaces the tags of the TODO by id, creating missing tags of the owner of the TODO,
+// who may be another member of its list than the caller of ctx.
+func setTags(ctx context.Context, q queryer, id int64, tags []string) error {
+	const (
+		clear  = `DELETE FROM todo_tags WHERE todo_id = ?`
+		create = `INSERT INTO tags(owner_id, name) SELECT owner_id, ? FROM todos WHERE id = ? ON CONFLICT(COALESCE(owner_id, 0), name) DO NOTHING`
+		attach = `INSERT OR IGNORE INTO todo_tags(todo_id, tag_id)
+SELECT ?, id FROM tags WHERE owner_id IS (SELECT owner_id FROM todos WHERE id = ?) AND name = ?`
+	)
+
+	if _, err := q.ExecContext(ctx, clear, id); err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		if _, err := q.ExecContext(ctx, create, tag, id); err != nil {
+			return err
+		}
+		if _, err := q.ExecContext(ctx, attach, id, id, tag); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadRelations sets the tags and the recurrences of todos.
+func loadRelations(ctx context.Context, q queryer, todos []*model.TODO) error {
+	if err := loadTags(ctx, q, todos); err != nil {
+		return err
+	}
+	return loadRecurrences(ctx, q, todos)
+}
+
+// loadTags sets the tags of todos in name order.
+func loadTags(ctx context.Context, q queryer, todos []*model.TODO) error {
+	const readFmt = `SELECT tt.todo_id, g.name FROM todo_tags tt JOIN tags g ON g.id = tt.tag_id
+WHERE tt.todo_id IN (?%s) ORDER BY g.name`
+
+	if len(todos) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*model.TODO, len(todos))
+	ids := make([]int64, 0, len(todos))
+	for _, todo := range todos {
+		byID[todo.ID] = todo
+		ids = append(ids, todo.ID)
+	}
+
+	rows, err := q.QueryContext(ctx, fmt.Sprintf(readFmt, strings.Repeat(",?", len(ids)-1)), int64Args(ids)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id   int64
+			name string
+		)
+		if err := rows.Scan(&id, &name); err != nil {
+			return err
+		}
+		if todo, ok := byID[id]; ok {
+			todo.Tags = append(todo.Tags, name)
+		}
+	}
+
+	return rows.Err()
+}
+
+// int64Args converts ids into query arguments.
+func int64Args(ids []int64) []interface{} {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return args
+}
@@ -0,0 +1,208 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/TechBowl-japan/go-stations/db"
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+// repositories returns constructors of every TODORepository implementation.
+func repositories() map[string]func(t *testing.T) service.TODORepository {
+	return map[string]func(t *testing.T) service.TODORepository{
+		"SQLite": func(t *testing.T) service.TODORepository {
+			return service.NewSQLiteTODORepository(newTestDB(t))
+		},
+		"Memory": func(t *testing.T) service.TODORepository {
+			return service.NewMemoryTODORepository()
+		},
+	}
+}
+
+// newTestDB returns a migrated database that is removed after the test.
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	d, err := db.NewDB(filepath.Join(t.TempDir(), "todo_test.db"))
+	if err != nil {
+		t.Fatal("failed to create db, err =", err)
+	}
+	t.Cleanup(func() {
+		if err := d.Close(); err != nil {
+			t.Error("failed to close db, err =", err)
+		}
+	})
+	return d
+}
+
+func TestTODORepository(t *testing.T) {
+	t.Parallel()
+
+	for name, newRepo := range repositories() {
+		newRepo := newRepo
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			svc := service.NewTODOServiceWithRepository(newRepo(t))
+
+			if _, err := svc.CreateTODO(ctx, "", "description"); !isConstraintError(err) {
+				t.Errorf("unexpected error on empty subject, given = %v", err)
+			}
+
+			for _, subject := range []string{"subject 1", "subject 2", "subject 3"} {
+				todo, err := svc.CreateTODO(ctx, subject, "")
+				if err != nil {
+					t.Fatal("failed to create todo, err =", err)
+				}
+				if todo.ID == 0 || todo.Subject != subject || todo.CreatedAt.IsZero() || !todo.CreatedAt.Equal(todo.UpdatedAt) {
+					t.Errorf("unexpected todo, given = %+v", todo)
+				}
+			}
+
+			cases := map[string]struct {
+				prevID, size int64
+				ids          []int64
+			}{
+				"Zero read":          {prevID: 0, size: 0, ids: []int64{}},
+				"All read":           {prevID: 0, size: 5, ids: []int64{3, 2, 1}},
+				"One read":           {prevID: 0, size: 1, ids: []int64{3}},
+				"Read with prev id":  {prevID: 3, size: 5, ids: []int64{2, 1}},
+				"Read after last id": {prevID: 1, size: 5, ids: []int64{}},
+			}
+			for name, c := range cases {
+				todos, err := svc.ReadTODO(ctx, c.prevID, c.size)
+				if err != nil {
+					t.Fatalf("%s: failed to read todos, err = %v", name, err)
+				}
+				if todos == nil {
+					t.Errorf("%s: todos must not be nil", name)
+				}
+				if got := ids(todos); !equalIDs(got, c.ids) {
+					t.Errorf("%s: unexpected ids, given = %v, expected = %v", name, got, c.ids)
+				}
+			}
+
+			if _, err := svc.UpdateTODO(ctx, 4, "subject", ""); !isNotFound(err) {
+				t.Errorf("unexpected error on missing id, given = %v", err)
+			}
+			if _, err := svc.UpdateTODO(ctx, 1, "", ""); !isConstraintError(err) {
+				t.Errorf("unexpected error on empty subject, given = %v", err)
+			}
+
+			before, err := svc.ReadTODO(ctx, 2, 1)
+			if err != nil {
+				t.Fatal("failed to read todo, err =", err)
+			}
+			time.Sleep(1100 * time.Millisecond)
+			updated, err := svc.UpdateTODO(ctx, 1, "updated", "description")
+			if err != nil {
+				t.Fatal("failed to update todo, err =", err)
+			}
+			if updated.Subject != "updated" || updated.Description != "description" {
+				t.Errorf("unexpected todo, given = %+v", updated)
+			}
+			if !updated.CreatedAt.Equal(before[0].CreatedAt) || !updated.UpdatedAt.After(before[0].UpdatedAt) {
+				t.Errorf("updated_at is not bumped, before = %+v, after = %+v", before[0], updated)
+			}
+
+			if err := svc.DeleteTODO(ctx, []int64{4}); !isNotFound(err) {
+				t.Errorf("unexpected error on missing id, given = %v", err)
+			}
+			if err := svc.DeleteTODO(ctx, []int64{1, 3}); err != nil {
+				t.Fatal("failed to delete todos, err =", err)
+			}
+			todos, err := svc.ReadTODO(ctx, 0, 5)
+			if err != nil {
+				t.Fatal("failed to read todos, err =", err)
+			}
+			if got := ids(todos); !equalIDs(got, []int64{2}) {
+				t.Errorf("unexpected ids, given = %v, expected = %v", got, []int64{2})
+			}
+
+			// ids are never reused
+			todo, err := svc.CreateTODO(ctx, "subject 4", "")
+			if err != nil {
+				t.Fatal("failed to create todo, err =", err)
+			}
+			if todo.ID != 4 {
+				t.Errorf("unexpected id, given = %d, expected = %d", todo.ID, 4)
+			}
+		})
+	}
+}
+
+func TestMemoryTODORepository_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	repo := service.NewMemoryTODORepository()
+
+	const n = 50
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			todo, err := repo.Create(ctx, &service.TODOInput{Subject: "subject"})
+			if err != nil {
+				t.Error("failed to create todo, err =", err)
+				return
+			}
+			if _, err := repo.Update(ctx, todo.ID, &service.TODOInput{Subject: "updated"}); err != nil {
+				t.Error("failed to update todo, err =", err)
+			}
+			if _, err := repo.List(ctx, nil, 0, 5); err != nil {
+				t.Error("failed to read todos, err =", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	todos, err := repo.List(ctx, nil, 0, n+1)
+	if err != nil {
+		t.Fatal("failed to read todos, err =", err)
+	}
+	if len(todos) != n {
+		t.Errorf("unexpected length, given = %d, expected = %d", len(todos), n)
+	}
+}
+
+func isNotFound(err error) bool {
+	var notFound *model.ErrNotFound
+	return errors.As(err, &notFound)
+}
+
+func isConstraintError(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrConstraint
+}
+
+func ids(todos []*model.TODO) []int64 {
+	ids := make([]int64, 0, len(todos))
+	for _, todo := range todos {
+		ids = append(ids, todo.ID)
+	}
+	return ids
+}
+
+func equalIDs(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestTODOService_Version(t *testing.T) {
+	t.Parallel()
+
+	for name, newRepo := range repositories() {
+		newRepo := newRepo
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			svc := service.NewTODOServiceWithRepository(newRepo(t))
+
+			todo, err := svc.CreateTODO(ctx, "subject", "", "work")
+			if err != nil {
+				t.Fatal("failed to create todo, err =", err)
+			}
+			version := service.Version(todo)
+			if version == "" {
+				t.Fatal("unexpected empty version")
+			}
+
+			updated, err := svc.UpdateTODOWithInput(ctx, 1, &service.TODOInput{Subject: "first", Tags: []string{"home"}, Version: version})
+			if err != nil {
+				t.Fatal("failed to update todo, err =", err)
+			}
+			if service.Version(updated) == version {
+				t.Errorf("unexpected unchanged version, given = %s", version)
+			}
+
+			// the second client still has the first version
+			_, err = svc.UpdateTODOWithInput(ctx, 1, &service.TODOInput{Subject: "second", Version: version})
+			var stale *service.ErrPreconditionFailed
+			if !errors.As(err, &stale) {
+				t.Fatalf("unexpected error on stale version, given = %v", err)
+			}
+			if stale.TODO.Subject != "first" || service.Version(stale.TODO) != service.Version(updated) {
+				t.Errorf("unexpected current todo, given = %+v", stale.TODO)
+			}
+			if _, err := svc.UpdateTODOWithInput(ctx, 2, &service.TODOInput{Subject: "second", Version: version}); !isNotFound(err) {
+				t.Errorf("unexpected error on missing id, given = %v", err)
+			}
+
+			completed, _, err := svc.CompleteTODO(ctx, 1)
+			if err != nil {
+				t.Fatal("failed to complete todo, err =", err)
+			}
+			if _, err := svc.UpdateTODOWithInput(ctx, 1, &service.TODOInput{Subject: "second", Version: service.Version(updated)}); !errors.As(err, &stale) {
+				t.Errorf("unexpected error on version before completion, given = %v", err)
+			}
+
+			updated, err = svc.UpdateTODOWithInput(ctx, 1, &service.TODOInput{Subject: "second", Version: service.Version(completed)})
+			if err != nil {
+				t.Fatal("failed to update todo, err =", err)
+			}
+			if updated.Subject != "second" {
+				t.Errorf("unexpected todo, given = %+v", updated)
+			}
+
+			// an empty version overwrites any version
+			if _, err := svc.UpdateTODO(ctx, 1, "third", ""); err != nil {
+				t.Fatal("failed to update todo, err =", err)
+			}
+		})
+	}
+}
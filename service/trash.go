@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// ReadTrash reads TODOs in the trash.
+func (s *TODOService) ReadTrash(ctx context.Context, prevID, size int64) ([]*model.TODO, error) {
+	return s.repo.List(ctx, &TODOFilter{Trashed: true}, prevID, size)
+}
+
+// RestoreTODO moves TODOs by ids back from the trash.
+func (s *TODOService) RestoreTODO(ctx context.Context, ids []int64) error {
+	ctx, _ = s.recording(ctx)
+	return s.repo.Restore(ctx, ids)
+}
+
+// PurgeTrash permanently deletes the TODOs kept in the trash longer than retention
+// and returns the number of the deleted TODOs.
+func (s *TODOService) PurgeTrash(ctx context.Context, retention time.Duration) (int64, error) {
+	return s.repo.Purge(ctx, s.now().Add(-retention))
+}
+
+// RunPurger purges the trash by PurgeTrash every interval until ctx is done.
+// Errors are logged, and the next purge is tried after interval.
+func (s *TODOService) RunPurger(ctx context.Context, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := s.PurgeTrash(ctx, retention)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			log.Println("service: failed to purge trash, err =", err)
+		case n > 0:
+			log.Printf("service: purged %d TODOs from trash", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestTODOService_Trash(t *testing.T) {
+	t.Parallel()
+
+	for name, newRepo := range repositories() {
+		newRepo := newRepo
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			repo := newRepo(t)
+			svc := service.NewTODOServiceWithRepository(repo)
+
+			for _, subject := range []string{"subject 1", "subject 2", "subject 3", "subject 4"} {
+				if _, err := svc.CreateTODO(ctx, subject, "", "work"); err != nil {
+					t.Fatal("failed to create todo, err =", err)
+				}
+			}
+
+			if err := svc.DeleteTODO(ctx, []int64{1, 3, 5}); err != nil {
+				t.Fatal("failed to delete todos, err =", err)
+			}
+			if err := svc.DeleteTODO(ctx, []int64{1, 3}); !isNotFound(err) {
+				t.Errorf("unexpected error on trashed ids, given = %v", err)
+			}
+			if err := svc.RestoreTODO(ctx, []int64{2, 5}); !isNotFound(err) {
+				t.Errorf("unexpected error on ids out of trash, given = %v", err)
+			}
+
+			todos, err := svc.ReadTODO(ctx, 0, 5)
+			if err != nil {
+				t.Fatal("failed to read todos, err =", err)
+			}
+			if got := ids(todos); !equalIDs(got, []int64{4, 2}) {
+				t.Errorf("unexpected ids, given = %v, expected = %v", got, []int64{4, 2})
+			}
+
+			trash, err := svc.ReadTrash(ctx, 0, 5)
+			if err != nil {
+				t.Fatal("failed to read trash, err =", err)
+			}
+			if got := ids(trash); !equalIDs(got, []int64{3, 1}) {
+				t.Errorf("unexpected ids, given = %v, expected = %v", got, []int64{3, 1})
+			}
+			for _, todo := range trash {
+				if todo.DeletedAt == nil || len(todo.Tags) != 1 {
+					t.Errorf("unexpected todo, given = %+v", todo)
+				}
+			}
+
+			// trashed TODOs are hidden from every other operation
+			if _, err := repo.Get(ctx, 1); !isNotFound(err) {
+				t.Errorf("unexpected error on trashed id, given = %v", err)
+			}
+			if _, err := svc.UpdateTODO(ctx, 1, "updated", ""); !isNotFound(err) {
+				t.Errorf("unexpected error on trashed id, given = %v", err)
+			}
+			if _, _, err := svc.CompleteTODO(ctx, 1); !isNotFound(err) {
+				t.Errorf("unexpected error on trashed id, given = %v", err)
+			}
+			if tags, ok := repo.(service.TagRepository); ok {
+				list, err := tags.ListTags(ctx)
+				if err != nil {
+					t.Fatal("failed to read tags, err =", err)
+				}
+				if len(list) != 1 || list[0].Count != 2 {
+					t.Errorf("unexpected tags, given = %+v", list)
+				}
+			}
+
+			if err := svc.RestoreTODO(ctx, []int64{1}); err != nil {
+				t.Fatal("failed to restore todo, err =", err)
+			}
+			todo, err := repo.Get(ctx, 1)
+			if err != nil {
+				t.Fatal("failed to read restored todo, err =", err)
+			}
+			if todo.DeletedAt != nil || todo.Subject != "subject 1" || len(todo.Tags) != 1 {
+				t.Errorf("unexpected todo, given = %+v", todo)
+			}
+
+			// the TODO trashed just now is kept within the retention and purged after it
+			svc.SetClock(func() time.Time { return time.Now().Add(2 * time.Hour) }, nil)
+			n, err := svc.PurgeTrash(ctx, 3*time.Hour)
+			if err != nil {
+				t.Fatal("failed to purge trash, err =", err)
+			}
+			if n != 0 {
+				t.Errorf("unexpected purged count, given = %d, expected = %d", n, 0)
+			}
+			if n, err = svc.PurgeTrash(ctx, time.Hour); err != nil {
+				t.Fatal("failed to purge trash, err =", err)
+			}
+			if n != 1 {
+				t.Errorf("unexpected purged count, given = %d, expected = %d", n, 1)
+			}
+			if err := svc.RestoreTODO(ctx, []int64{3}); !isNotFound(err) {
+				t.Errorf("unexpected error on purged id, given = %v", err)
+			}
+
+			trash, err = svc.ReadTrash(ctx, 0, 5)
+			if err != nil {
+				t.Fatal("failed to read trash, err =", err)
+			}
+			if len(trash) != 0 {
+				t.Errorf("unexpected ids, given = %v", ids(trash))
+			}
+		})
+	}
+}
+
+func TestTODOService_RunPurger(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	svc := service.NewTODOServiceWithRepository(service.NewMemoryTODORepository())
+	svc.SetClock(func() time.Time { return time.Now().Add(2 * time.Hour) }, nil)
+	if _, err := svc.CreateTODO(ctx, "subject", ""); err != nil {
+		t.Fatal("failed to create todo, err =", err)
+	}
+	if err := svc.DeleteTODO(ctx, []int64{1}); err != nil {
+		t.Fatal("failed to delete todo, err =", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		svc.RunPurger(ctx, time.Hour, 10*time.Millisecond)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		trash, err := svc.ReadTrash(ctx, 0, 5)
+		if err != nil {
+			t.Fatal("failed to read trash, err =", err)
+		}
+		if len(trash) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("trash is not purged")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("purger does not stop after cancel")
+	}
+}
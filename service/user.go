@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+// ErrUnauthenticated is returned when an API token is malformed, unknown or revoked.
+var ErrUnauthenticated = errors.New("service: unauthenticated")
+
+// tokenPrefix prefixes API tokens, so that leaked tokens are found by secret scanners.
+const tokenPrefix = "todo_"
+
+// userKey is the context key of the user.
+type userKey struct{}
+
+// WithUser returns a copy of ctx carrying user, to whom every TODO read and written with it is scoped.
+func WithUser(ctx context.Context, user *model.User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFromContext returns the user ctx carries, which is nil for anonymous callers.
+func UserFromContext(ctx context.Context) *model.User {
+	user, _ := ctx.Value(userKey{}).(*model.User)
+	return user
+}
+
+// ownerID returns the id of the user ctx carries, which is 0 for anonymous callers.
+// Anonymous callers own the TODOs created anonymously, and no others.
+func ownerID(ctx context.Context) int64 {
+	if user := UserFromContext(ctx); user != nil {
+		return user.ID
+	}
+	return 0
+}
+
+// A UserRepository persists users and the hashes of their API tokens.
+type UserRepository interface {
+	// CreateUser creates the user by name, returning *model.ErrConflict when the name is taken.
+	CreateUser(ctx context.Context, name string) (*model.User, error)
+	// GetUser reads the user by name case-insensitively, returning *model.ErrNotFound when it does not exist.
+	GetUser(ctx context.Context, name string) (*model.User, error)
+	// CreateToken stores the hash of a new API token of the user by id.
+	CreateToken(ctx context.Context, userID int64, name, hash string) (*model.APIToken, error)
+	// ListTokens reads the API tokens of the user by id in creation order, revoked ones included.
+	ListTokens(ctx context.Context, userID int64) ([]*model.APIToken, error)
+	// RevokeToken revokes the API token by id, returning *model.ErrNotFound when it does not exist.
+	// A revoked token is kept revoked at the first time.
+	RevokeToken(ctx context.Context, id int64) (*model.APIToken, error)
+	// UseToken returns the user of the API token by hash unless it is revoked, recording the token used.
+	// It returns *model.ErrNotFound when no such token is usable.
+	UseToken(ctx context.Context, hash string) (*model.User, error)
+	// AdoptTODOs makes the user by id own the TODOs and the tags of no owner, which were created anonymously,
+	// and returns the number of the TODOs adopted. A tag is merged into the tag of the user of the same name if any.
+	AdoptTODOs(ctx context.Context, userID int64) (int64, error)
+}
+
+// A UserService implements managing users and authenticating them with API tokens.
+type UserService struct {
+	repo UserRepository
+}
+
+// NewUserService returns new UserService backed by the SQLite database.
+func NewUserService(db *sql.DB) *UserService {
+	return NewUserServiceWithRepository(NewSQLiteTODORepository(db))
+}
+
+// NewUserServiceWithRepository returns new UserService backed by repo.
+func NewUserServiceWithRepository(repo UserRepository) *UserService {
+	return &UserService{
+		repo: repo,
+	}
+}
+
+// CreateUser creates the user by name.
+func (s *UserService) CreateUser(ctx context.Context, name string) (*model.User, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, Invalid("name", ViolationRequired)
+	}
+	return s.repo.CreateUser(ctx, name)
+}
+
+// IssueToken issues a new API token named tokenName to the user by userName.
+// The token is returned only here, since only its hash is stored.
+func (s *UserService) IssueToken(ctx context.Context, userName, tokenName string) (string, *model.APIToken, error) {
+	user, err := s.repo.GetUser(ctx, strings.TrimSpace(userName))
+	if err != nil {
+		return "", nil, err
+	}
+
+	token, err := randomToken(tokenPrefix)
+	if err != nil {
+		return "", nil, err
+	}
+
+	issued, err := s.repo.CreateToken(ctx, user.ID, strings.TrimSpace(tokenName), hashToken(token))
+	if err != nil {
+		return "", nil, err
+	}
+	return token, issued, nil
+}
+
+// ListTokens reads the API tokens of the user by userName.
+func (s *UserService) ListTokens(ctx context.Context, userName string) ([]*model.APIToken, error) {
+	user, err := s.repo.GetUser(ctx, strings.TrimSpace(userName))
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListTokens(ctx, user.ID)
+}
+
+// RevokeToken revokes the API token by id.
+func (s *UserService) RevokeToken(ctx context.Context, id int64) (*model.APIToken, error) {
+	return s.repo.RevokeToken(ctx, id)
+}
+
+// AdoptTODOs makes the user by userName own the TODOs created anonymously with their tags,
+// so that the TODOs created before authentication was required stay reachable.
+// It returns the number of the TODOs adopted.
+func (s *UserService) AdoptTODOs(ctx context.Context, userName string) (int64, error) {
+	user, err := s.repo.GetUser(ctx, strings.TrimSpace(userName))
+	if err != nil {
+		return 0, err
+	}
+	return s.repo.AdoptTODOs(ctx, user.ID)
+}
+
+// Authenticate returns the user of the API token, or ErrUnauthenticated unless the token is usable.
+func (s *UserService) Authenticate(ctx context.Context, token string) (*model.User, error) {
+	if !strings.HasPrefix(token, tokenPrefix) {
+		return nil, ErrUnauthenticated
+	}
+	user, err := s.repo.UseToken(ctx, hashToken(token))
+	if isNotFound(err) {
+		return nil, ErrUnauthenticated
+	}
+	return user, err
+}
+
+// randomToken returns a token of 256 random bits prefixed by prefix.
+func randomToken(prefix string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the hash of token stored in place of it. A fast hash suffices for tokens
+// of 256 random bits, which cannot be guessed from their hashes.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
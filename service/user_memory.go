@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+var _ UserRepository = (*MemoryTODORepository)(nil)
+
+// A memoryToken is an API token with its hash.
+type memoryToken struct {
+	token *model.APIToken
+	hash  string
+}
+
+// CreateUser implements UserRepository interface.
+func (r *MemoryTODORepository) CreateUser(ctx context.Context, name string) (*model.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return nil, errConstraintCheck
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.findUser(name) != nil {
+		return nil, &model.ErrConflict{Message: fmt.Sprintf("user %q already exists", name)}
+	}
+	user := &model.User{
+		ID:        int64(len(r.users)) + 1,
+		Name:      name,
+		CreatedAt: memoryNow(),
+	}
+	r.users = append(r.users, user)
+
+	copied := *user
+	return &copied, nil
+}
+
+// GetUser implements UserRepository interface.
+func (r *MemoryTODORepository) GetUser(ctx context.Context, name string) (*model.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user := r.findUser(name)
+	if user == nil {
+		return nil, &model.ErrNotFound{}
+	}
+	copied := *user
+	return &copied, nil
+}
+
+// CreateToken implements UserRepository interface.
+func (r *MemoryTODORepository) CreateToken(ctx context.Context, userID int64, name, hash string) (*model.APIToken, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if userID < 1 || userID > int64(len(r.users)) {
+		// mirrors the foreign key of the api_tokens table
+		return nil, errConstraintForeignKey
+	}
+	token := &model.APIToken{
+		ID:        int64(len(r.tokens)) + 1,
+		UserID:    userID,
+		Name:      name,
+		CreatedAt: memoryNow(),
+	}
+	r.tokens = append(r.tokens, &memoryToken{token: token, hash: hash})
+
+	return copyToken(token), nil
+}
+
+// ListTokens implements UserRepository interface.
+func (r *MemoryTODORepository) ListTokens(ctx context.Context, userID int64) ([]*model.APIToken, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tokens := []*model.APIToken{}
+	for _, t := range r.tokens {
+		if t.token.UserID == userID {
+			tokens = append(tokens, copyToken(t.token))
+		}
+	}
+	return tokens, nil
+}
+
+// RevokeToken implements UserRepository interface.
+func (r *MemoryTODORepository) RevokeToken(ctx context.Context, id int64) (*model.APIToken, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id < 1 || id > int64(len(r.tokens)) {
+		return nil, &model.ErrNotFound{}
+	}
+	token := r.tokens[id-1].token
+	if token.RevokedAt == nil {
+		now := memoryNow()
+		token.RevokedAt = &now
+	}
+	return copyToken(token), nil
+}
+
+// UseToken implements UserRepository interface.
+func (r *MemoryTODORepository) UseToken(ctx context.Context, hash string) (*model.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, t := range r.tokens {
+		if t.hash != hash || t.token.RevokedAt != nil {
+			continue
+		}
+		now := memoryNow()
+		if t.token.LastUsedAt == nil || t.token.LastUsedAt.Before(now.Add(-time.Minute)) {
+			t.token.LastUsedAt = &now
+		}
+		user := *r.users[t.token.UserID-1]
+		return &user, nil
+	}
+	return nil, &model.ErrNotFound{}
+}
+
+// AdoptTODOs implements UserRepository interface.
+func (r *MemoryTODORepository) AdoptTODOs(ctx context.Context, userID int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// the tags of the names the user already has are merged into the tags of the user by attachTags
+	if r.tags[userID] == nil {
+		r.tags[userID] = make(map[string]string)
+	}
+	for key, name := range r.tags[0] {
+		if _, ok := r.tags[userID][key]; !ok {
+			r.tags[userID][key] = name
+		}
+	}
+	delete(r.tags, 0)
+
+	var n int64
+	now := memoryNow()
+	for _, todo := range r.todos {
+		if todo.OwnerID != 0 {
+			continue
+		}
+		todo.OwnerID = userID
+		todo.Tags = r.attachTags(userID, todo.Tags)
+		todo.UpdatedAt = now
+		n++
+	}
+	return n, nil
+}
+
+// findUser returns the user by name compared the same way as the NOCASE collation, or nil.
+func (r *MemoryTODORepository) findUser(name string) *model.User {
+	for _, user := range r.users {
+		if tagKey(user.Name) == tagKey(name) {
+			return user
+		}
+	}
+	return nil
+}
+
+// copyToken returns a deep copy of token.
+func copyToken(token *model.APIToken) *model.APIToken {
+	copied := *token
+	copied.LastUsedAt = copyTime(token.LastUsedAt)
+	copied.RevokedAt = copyTime(token.RevokedAt)
+	return &copied
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/TechBowl-japan/go-stations/model"
+)
+
+var _ UserRepository = (*SQLiteTODORepository)(nil)
+
+// tokenColumns lists the columns of the api_tokens table in the order scanned by tokenFields.
+const tokenColumns = `id, user_id, name, last_used_at, revoked_at, created_at`
+
+// CreateUser implements UserRepository interface.
+func (r *SQLiteTODORepository) CreateUser(ctx context.Context, name string) (*model.User, error) {
+	const insert = `INSERT INTO users(name) VALUES(?)`
+
+	res, err := r.db.ExecContext(ctx, insert, name)
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return nil, &model.ErrConflict{Message: fmt.Sprintf("user %q already exists", name)}
+	}
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return r.getUser(ctx, `id = ?`, id)
+}
+
+// GetUser implements UserRepository interface.
+func (r *SQLiteTODORepository) GetUser(ctx context.Context, name string) (*model.User, error) {
+	return r.getUser(ctx, `name = ?`, name)
+}
+
+// getUser reads the user matching cond.
+func (r *SQLiteTODORepository) getUser(ctx context.Context, cond string, args ...interface{}) (*model.User, error) {
+	user := &model.User{}
+	err := r.db.QueryRowContext(ctx, `SELECT id, name, created_at FROM users WHERE `+cond, args...).Scan(&user.ID, &user.Name, &user.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &model.ErrNotFound{}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// CreateToken implements UserRepository interface.
+func (r *SQLiteTODORepository) CreateToken(ctx context.Context, userID int64, name, hash string) (*model.APIToken, error) {
+	const (
+		insert = `INSERT INTO api_tokens(user_id, name, hash) VALUES(?, ?, ?)`
+		read   = `SELECT ` + tokenColumns + ` FROM api_tokens WHERE id = ?`
+	)
+
+	res, err := r.db.ExecContext(ctx, insert, userID, name, hash)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	token := &model.APIToken{}
+	if err := r.db.QueryRowContext(ctx, read, id).Scan(tokenFields(token)...); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// ListTokens implements UserRepository interface.
+func (r *SQLiteTODORepository) ListTokens(ctx context.Context, userID int64) ([]*model.APIToken, error) {
+	const read = `SELECT ` + tokenColumns + ` FROM api_tokens WHERE user_id = ? ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, read, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*model.APIToken{}
+	for rows.Next() {
+		token := &model.APIToken{}
+		if err := rows.Scan(tokenFields(token)...); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// RevokeToken implements UserRepository interface.
+func (r *SQLiteTODORepository) RevokeToken(ctx context.Context, id int64) (*model.APIToken, error) {
+	const (
+		revoke = `UPDATE api_tokens SET revoked_at = DATETIME('now') WHERE id = ? AND revoked_at IS NULL`
+		read   = `SELECT ` + tokenColumns + ` FROM api_tokens WHERE id = ?`
+	)
+
+	if _, err := r.db.ExecContext(ctx, revoke, id); err != nil {
+		return nil, err
+	}
+	token := &model.APIToken{}
+	err := r.db.QueryRowContext(ctx, read, id).Scan(tokenFields(token)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &model.ErrNotFound{}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// UseToken implements UserRepository interface.
+func (r *SQLiteTODORepository) UseToken(ctx context.Context, hash string) (*model.User, error) {
+	const (
+		// stale reports whether last_used_at is older than a minute, so that it is updated
+		// at most once a minute and most requests only read
+		read = `SELECT u.id, u.name, u.created_at, t.last_used_at IS NULL OR t.last_used_at < DATETIME('now', '-1 minute')
+FROM api_tokens t JOIN users u ON u.id = t.user_id WHERE t.hash = ? AND t.revoked_at IS NULL`
+		// the condition is repeated so that concurrent requests update it only once
+		use = `UPDATE api_tokens SET last_used_at = DATETIME('now') WHERE hash = ? AND revoked_at IS NULL
+AND (last_used_at IS NULL OR last_used_at < DATETIME('now', '-1 minute'))`
+	)
+
+	var (
+		user  = &model.User{}
+		stale bool
+	)
+	err := r.db.QueryRowContext(ctx, read, hash).Scan(&user.ID, &user.Name, &user.CreatedAt, &stale)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &model.ErrNotFound{}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if stale {
+		if _, err := r.db.ExecContext(ctx, use, hash); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// AdoptTODOs implements UserRepository interface.
+func (r *SQLiteTODORepository) AdoptTODOs(ctx context.Context, userID int64) (int64, error) {
+	const (
+		adopt = `UPDATE todos SET owner_id = ? WHERE owner_id IS NULL`
+		// the tags of the names the user already has are merged into the tags of the user
+		relink = `INSERT OR IGNORE INTO todo_tags(todo_id, tag_id)
+SELECT tt.todo_id, u.id FROM todo_tags tt JOIN tags a ON a.id = tt.tag_id JOIN tags u ON u.owner_id = ? AND u.name = a.name
+WHERE a.owner_id IS NULL`
+		merge     = `DELETE FROM tags WHERE owner_id IS NULL AND name IN (SELECT name FROM tags WHERE owner_id = ?)`
+		adoptTags = `UPDATE tags SET owner_id = ? WHERE owner_id IS NULL`
+	)
+
+	var n int64
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, adopt, userID)
+		if err != nil {
+			return err
+		}
+		if n, err = res.RowsAffected(); err != nil {
+			return err
+		}
+
+		for _, query := range []string{relink, merge, adoptTags} {
+			if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// tokenFields returns the scan destinations of tokenColumns.
+func tokenFields(token *model.APIToken) []interface{} {
+	return []interface{}{&token.ID, &token.UserID, &token.Name, &token.LastUsedAt, &token.RevokedAt, &token.CreatedAt}
+}
@@ -0,0 +1,298 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestUserService(t *testing.T) {
+	t.Parallel()
+
+	for name, newRepo := range repositories() {
+		newRepo := newRepo
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			svc := service.NewUserServiceWithRepository(newRepo(t).(service.UserRepository))
+
+			alice, err := svc.CreateUser(ctx, " alice ")
+			if err != nil {
+				t.Fatal("failed to create user, err =", err)
+			}
+			if alice.Name != "alice" {
+				t.Errorf("unexpected name, given = %q, expected = %q", alice.Name, "alice")
+			}
+			var conflict *model.ErrConflict
+			if _, err := svc.CreateUser(ctx, "ALICE"); !errors.As(err, &conflict) {
+				t.Errorf("unexpected error on taken name, given = %v", err)
+			}
+			var validation *service.ErrValidation
+			if _, err := svc.CreateUser(ctx, " "); !errors.As(err, &validation) {
+				t.Errorf("unexpected error on empty name, given = %v", err)
+			}
+			if _, _, err := svc.IssueToken(ctx, "bob", "cli"); !isNotFound(err) {
+				t.Errorf("unexpected error on missing user, given = %v", err)
+			}
+
+			token, issued, err := svc.IssueToken(ctx, "Alice", "cli")
+			if err != nil {
+				t.Fatal("failed to issue token, err =", err)
+			}
+			if !strings.HasPrefix(token, "todo_") || issued.UserID != alice.ID || issued.Name != "cli" {
+				t.Errorf("unexpected token, given = %s, %+v", token, issued)
+			}
+			other, _, err := svc.IssueToken(ctx, "alice", "")
+			if err != nil {
+				t.Fatal("failed to issue token, err =", err)
+			}
+			if other == token {
+				t.Error("unexpected same token issued twice")
+			}
+
+			user, err := svc.Authenticate(ctx, token)
+			if err != nil {
+				t.Fatal("failed to authenticate, err =", err)
+			}
+			if user.ID != alice.ID || user.Name != alice.Name {
+				t.Errorf("unexpected user, given = %+v, expected = %+v", user, alice)
+			}
+			for _, invalid := range []string{"", "todo_unknown", strings.TrimPrefix(token, "todo_")} {
+				if _, err := svc.Authenticate(ctx, invalid); err != service.ErrUnauthenticated {
+					t.Errorf("unexpected error of %q, given = %v, expected = %v", invalid, err, service.ErrUnauthenticated)
+				}
+			}
+
+			revoked, err := svc.RevokeToken(ctx, issued.ID)
+			if err != nil {
+				t.Fatal("failed to revoke token, err =", err)
+			}
+			if revoked.RevokedAt == nil {
+				t.Error("unexpected token not revoked")
+			}
+			if _, err := svc.Authenticate(ctx, token); err != service.ErrUnauthenticated {
+				t.Errorf("unexpected error of revoked token, given = %v, expected = %v", err, service.ErrUnauthenticated)
+			}
+			if _, err := svc.Authenticate(ctx, other); err != nil {
+				t.Error("failed to authenticate with other token, err =", err)
+			}
+			if _, err := svc.RevokeToken(ctx, 100); !isNotFound(err) {
+				t.Errorf("unexpected error on missing token, given = %v", err)
+			}
+
+			tokens, err := svc.ListTokens(ctx, "alice")
+			if err != nil {
+				t.Fatal("failed to list tokens, err =", err)
+			}
+			if len(tokens) != 2 || tokens[0].ID != issued.ID || tokens[0].RevokedAt == nil || tokens[0].LastUsedAt == nil || tokens[1].RevokedAt != nil {
+				t.Errorf("unexpected tokens, given = %+v", tokens)
+			}
+		})
+	}
+}
+
+func TestTODOService_Owner(t *testing.T) {
+	t.Parallel()
+
+	for name, newRepo := range repositories() {
+		newRepo := newRepo
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			repo := newRepo(t)
+			users := service.NewUserServiceWithRepository(repo.(service.UserRepository))
+			svc := service.NewTODOServiceWithRepository(repo)
+			tags := service.NewTagServiceWithRepository(repo.(service.TagRepository))
+
+			ctxOf := func(name string) context.Context {
+				user, err := users.CreateUser(context.Background(), name)
+				if err != nil {
+					t.Fatal("failed to create user, err =", err)
+				}
+				return service.WithUser(context.Background(), user)
+			}
+			alice, bob, anonymous := ctxOf("alice"), ctxOf("bob"), context.Background()
+
+			own, err := svc.CreateTODO(alice, "alice's", "", "work")
+			if err != nil {
+				t.Fatal("failed to create todo, err =", err)
+			}
+			if own.OwnerID == 0 {
+				t.Error("unexpected todo without owner")
+			}
+			if _, err := svc.CreateTODO(bob, "bob's", "", "Work"); err != nil {
+				t.Fatal("failed to create todo, err =", err)
+			}
+			if _, err := svc.CreateTODO(anonymous, "anonymous", ""); err != nil {
+				t.Fatal("failed to create todo, err =", err)
+			}
+
+			for ctxName, ctx := range map[string]context.Context{"Bob": bob, "Anonymous": anonymous} {
+				if _, err := svc.GetTODO(ctx, own.ID); !isNotFound(err) {
+					t.Errorf("%s: unexpected error of get, given = %v", ctxName, err)
+				}
+				if _, err := svc.UpdateTODO(ctx, own.ID, "stolen", ""); !isNotFound(err) {
+					t.Errorf("%s: unexpected error of update, given = %v", ctxName, err)
+				}
+				if _, _, err := svc.CompleteTODO(ctx, own.ID); !isNotFound(err) {
+					t.Errorf("%s: unexpected error of complete, given = %v", ctxName, err)
+				}
+				if err := svc.DeleteTODO(ctx, []int64{own.ID}); !isNotFound(err) {
+					t.Errorf("%s: unexpected error of delete, given = %v", ctxName, err)
+				}
+				if _, err := svc.ReadRevisions(ctx, own.ID, 0, 5); !isNotFound(err) {
+					t.Errorf("%s: unexpected error of revisions, given = %v", ctxName, err)
+				}
+				todos, err := svc.ReadTODO(ctx, 0, 5)
+				if err != nil {
+					t.Fatalf("%s: failed to read todos, err = %v", ctxName, err)
+				}
+				if len(todos) != 1 || todos[0].ID == own.ID {
+					t.Errorf("%s: unexpected todos, given = %v", ctxName, ids(todos))
+				}
+			}
+
+			// renaming the tag of bob keeps the tag of alice
+			if _, err := tags.RenameTag(bob, "work", "job"); err != nil {
+				t.Fatal("failed to rename tag, err =", err)
+			}
+			if _, err := tags.RenameTag(anonymous, "work", "job"); !isNotFound(err) {
+				t.Errorf("unexpected error of rename by anonymous, given = %v", err)
+			}
+			got, err := svc.GetTODO(alice, own.ID)
+			if err != nil {
+				t.Fatal("failed to get todo, err =", err)
+			}
+			if len(got.Tags) != 1 || got.Tags[0] != "work" || got.Subject != "alice's" {
+				t.Errorf("unexpected todo of alice, given = %+v", got)
+			}
+			listed, err := tags.ReadTags(alice)
+			if err != nil {
+				t.Fatal("failed to read tags, err =", err)
+			}
+			if len(listed) != 1 || listed[0].Name != "work" || listed[0].Count != 1 {
+				t.Errorf("unexpected tags of alice, given = %+v", listed)
+			}
+			if listed, err := tags.ReadTags(anonymous); err != nil || len(listed) != 0 {
+				t.Errorf("unexpected tags of anonymous, given = %+v, err = %v", listed, err)
+			}
+		})
+	}
+}
+
+func TestUserService_AdoptTODOs(t *testing.T) {
+	t.Parallel()
+
+	for name, newRepo := range repositories() {
+		newRepo := newRepo
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			repo := newRepo(t)
+			users := service.NewUserServiceWithRepository(repo.(service.UserRepository))
+			svc := service.NewTODOServiceWithRepository(repo)
+			tags := service.NewTagServiceWithRepository(repo.(service.TagRepository))
+
+			user, err := users.CreateUser(context.Background(), "alice")
+			if err != nil {
+				t.Fatal("failed to create user, err =", err)
+			}
+			alice, anonymous := service.WithUser(context.Background(), user), context.Background()
+
+			if _, err := svc.CreateTODO(alice, "alice's", "", "Work"); err != nil {
+				t.Fatal("failed to create todo, err =", err)
+			}
+			for _, subject := range []string{"first", "second"} {
+				if _, err := svc.CreateTODO(anonymous, subject, "", "work", "home"); err != nil {
+					t.Fatal("failed to create todo, err =", err)
+				}
+			}
+
+			if _, err := users.AdoptTODOs(anonymous, "bob"); !isNotFound(err) {
+				t.Errorf("unexpected error on missing user, given = %v", err)
+			}
+			n, err := users.AdoptTODOs(anonymous, "Alice")
+			if err != nil {
+				t.Fatal("failed to adopt todos, err =", err)
+			}
+			if n != 2 {
+				t.Errorf("unexpected number of todos adopted, given = %d, expected = %d", n, 2)
+			}
+
+			todos, err := svc.ReadTODO(alice, 0, 5)
+			if err != nil {
+				t.Fatal("failed to read todos, err =", err)
+			}
+			if len(todos) != 3 {
+				t.Fatalf("unexpected todos, given = %v", ids(todos))
+			}
+			for _, todo := range todos[:2] {
+				if todo.OwnerID != user.ID || len(todo.Tags) != 2 || todo.Tags[0] != "home" || todo.Tags[1] != "Work" {
+					t.Errorf("unexpected todo adopted, given = %+v", todo)
+				}
+			}
+			if todos, err := svc.ReadTODO(anonymous, 0, 5); err != nil || len(todos) != 0 {
+				t.Errorf("unexpected todos of anonymous, given = %v, err = %v", todos, err)
+			}
+
+			// the tags of the same names are merged
+			listed, err := tags.ReadTags(alice)
+			if err != nil {
+				t.Fatal("failed to read tags, err =", err)
+			}
+			if len(listed) != 2 || listed[0].Name != "home" || listed[0].Count != 2 || listed[1].Name != "Work" || listed[1].Count != 3 {
+				t.Errorf("unexpected tags of alice, given = %+v", listed)
+			}
+			if listed, err := tags.ReadTags(anonymous); err != nil || len(listed) != 0 {
+				t.Errorf("unexpected tags of anonymous, given = %+v, err = %v", listed, err)
+			}
+
+			if n, err := users.AdoptTODOs(anonymous, "alice"); err != nil || n != 0 {
+				t.Errorf("unexpected todos adopted again, given = %d, err = %v", n, err)
+			}
+		})
+	}
+}
+
+func TestUserService_Authenticate_ReadOnly(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	d := newTestDB(t)
+	svc := service.NewUserService(d)
+
+	if _, err := svc.CreateUser(ctx, "alice"); err != nil {
+		t.Fatal("failed to create user, err =", err)
+	}
+	token, _, err := svc.IssueToken(ctx, "alice", "")
+	if err != nil {
+		t.Fatal("failed to issue token, err =", err)
+	}
+	if _, err := svc.Authenticate(ctx, token); err != nil {
+		t.Fatal("failed to authenticate, err =", err)
+	}
+
+	// authenticating by the token used just now only reads, so it is not blocked by a writer
+	tx, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal("failed to begin transaction, err =", err)
+	}
+	t.Cleanup(func() {
+		_ = tx.Rollback()
+	})
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET name = name`); err != nil {
+		t.Fatal("failed to write in transaction, err =", err)
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if _, err := svc.Authenticate(timeout, token); err != nil {
+		t.Errorf("failed to authenticate while database is written, err = %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Validate normalizes and validates the fields of the struct v points to by their validate tags,
+// returning *ErrValidation of every violation, or nil when there is none.
+// Fields are named in violations by their JSON names.
+//
+// A validate tag is a comma separated list of the following, applied in order:
+//
+//	trim      removes the leading and trailing white space of a string or of each string of a slice
+//	nfc       normalizes a string or each string of a slice to Unicode NFC
+//	required  requires a non-zero number, a non-empty string or a non-empty slice
+//	max=N     limits a string to N runes or a slice to N elements
+//
+// Rules are checked after the preceding modifiers, so "trim,required" rejects a string of spaces.
+// Validate panics when a tag is malformed, as it is a mistake of the request type.
+func Validate(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("service: Validate requires a pointer to a struct, given = %T", v))
+	}
+	rv = rv.Elem()
+
+	var violations []*Violation
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Type().Field(i)
+		tag, ok := field.Tag.Lookup("validate")
+		if !ok {
+			continue
+		}
+		if violation := validateField(jsonName(field), rv.Field(i), tag); violation != nil {
+			violations = append(violations, violation)
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return &ErrValidation{Violations: violations}
+}
+
+// validateField applies the rules of tag to f named name, returning the first violation if any.
+func validateField(name string, f reflect.Value, tag string) *Violation {
+	for _, rule := range strings.Split(tag, ",") {
+		switch {
+		case rule == "trim":
+			modifyStrings(f, strings.TrimSpace)
+		case rule == "nfc":
+			modifyStrings(f, norm.NFC.String)
+		case rule == "required":
+			if f.IsZero() || (f.Kind() == reflect.Slice && f.Len() == 0) {
+				return &Violation{Field: name, Code: ViolationRequired}
+			}
+		case strings.HasPrefix(rule, "max="):
+			max, err := strconv.Atoi(strings.TrimPrefix(rule, "max="))
+			if err != nil {
+				panic("service: malformed validate tag of " + name + ", given = " + tag)
+			}
+			switch f.Kind() {
+			case reflect.String:
+				if utf8.RuneCountInString(f.String()) > max {
+					return &Violation{Field: name, Code: ViolationTooLong, Message: fmt.Sprintf("must be at most %d characters", max)}
+				}
+			case reflect.Slice:
+				if f.Len() > max {
+					return &Violation{Field: name, Code: ViolationTooLong, Message: fmt.Sprintf("must have at most %d elements", max)}
+				}
+			default:
+				panic("service: max requires a string or a slice, given = " + name)
+			}
+		default:
+			panic("service: unknown validate rule of " + name + ", given = " + rule)
+		}
+	}
+	return nil
+}
+
+// modifyStrings replaces the string f, or each string of the slice f, by its result of modify.
+func modifyStrings(f reflect.Value, modify func(string) string) {
+	switch {
+	case f.Kind() == reflect.String:
+		f.SetString(modify(f.String()))
+	case f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.String:
+		for i := 0; i < f.Len(); i++ {
+			f.Index(i).SetString(modify(f.Index(i).String()))
+		}
+	default:
+		panic("service: string modifier requires a string or a slice of strings, given = " + f.Type().String())
+	}
+}
+
+// jsonName returns the name of field in JSON.
+func jsonName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
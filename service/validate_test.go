@@ -0,0 +1,71 @@
+package service_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/TechBowl-japan/go-stations/model"
+	"github.com/TechBowl-japan/go-stations/service"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		req        *model.UpdateTODORequest
+		want       *model.UpdateTODORequest
+		violations []*service.Violation
+	}{
+		"Valid": {
+			req:  &model.UpdateTODORequest{ID: 1, Subject: "subject", Description: " description "},
+			want: &model.UpdateTODORequest{ID: 1, Subject: "subject", Description: " description "},
+		},
+		"Normalized": {
+			req:  &model.UpdateTODORequest{ID: 1, Subject: " cafe\u0301\n", Tags: []string{" ﾃｽﾄ ", "a\u030a"}},
+			want: &model.UpdateTODORequest{ID: 1, Subject: "caf\u00e9", Tags: []string{"ﾃｽﾄ", "\u00e5"}},
+		},
+		"Counted in runes": {
+			req:  &model.UpdateTODORequest{ID: 1, Subject: strings.Repeat("あ", 200)},
+			want: &model.UpdateTODORequest{ID: 1, Subject: strings.Repeat("あ", 200)},
+		},
+		"Every violation": {
+			req:  &model.UpdateTODORequest{Subject: strings.Repeat("a", 201)},
+			want: &model.UpdateTODORequest{Subject: strings.Repeat("a", 201)},
+			violations: []*service.Violation{
+				{Field: "id", Code: service.ViolationRequired},
+				{Field: "subject", Code: service.ViolationTooLong, Message: "must be at most 200 characters"},
+			},
+		},
+		"Blank": {
+			req:        &model.UpdateTODORequest{ID: 1, Subject: " 　 "},
+			want:       &model.UpdateTODORequest{ID: 1},
+			violations: []*service.Violation{{Field: "subject", Code: service.ViolationRequired}},
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := service.Validate(c.req)
+			var validation *service.ErrValidation
+			if c.violations == nil && err != nil {
+				t.Fatal("failed to validate, err =", err)
+			}
+			if c.violations != nil {
+				if !errors.As(err, &validation) {
+					t.Fatalf("unexpected error, given = %v, expected = *service.ErrValidation", err)
+				}
+				if diff := cmp.Diff(c.violations, validation.Violations); diff != "" {
+					t.Errorf("unexpected violations (-expected +given):\n%s", diff)
+				}
+			}
+			if diff := cmp.Diff(c.want, c.req); diff != "" {
+				t.Errorf("unexpected normalization (-expected +given):\n%s", diff)
+			}
+		})
+	}
+}